@@ -0,0 +1,42 @@
+// Command disasm prints a listing of a compiled script's instructions.
+// It reads the file named on the command line, or standard input if
+// none is. The script isn't verified, so broken scripts can be looked at.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mcvoid/dialogue/internal/program"
+)
+
+func disassemble(r io.Reader) error {
+	p, err := program.Decode(r)
+	if err != nil {
+		return err
+	}
+	return program.Disassemble(os.Stdout, p)
+}
+
+func disassembleFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return disassemble(f)
+}
+
+func main() {
+	var err error
+	if len(os.Args) > 1 {
+		err = disassembleFile(os.Args[1])
+	} else {
+		err = disassemble(os.Stdin)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,64 @@
+// Command link combines objects compiled with CompileObject into a
+// single script. It reads the objects named on the command line and
+// writes the script to standard output, or to the file given with -o.
+// -format picks the script's format: compressed, json or binary.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mcvoid/dialogue"
+)
+
+var formats = map[string]dialogue.Format{
+	"compressed": dialogue.CompressedFormat,
+	"json":       dialogue.JSONFormat,
+	"binary":     dialogue.BinaryFormat,
+}
+
+func link(w io.Writer, format dialogue.Format, names []string) error {
+	args := []dialogue.LinkArg{dialogue.LinkFormat(format), dialogue.LinkOutput(w)}
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		args = append(args, dialogue.LinkInput(f))
+	}
+	return dialogue.Link(args...)
+}
+
+func main() {
+	formatName := flag.String("format", "compressed", "format of the linked script: compressed, json or binary")
+	output := flag.String("o", "", "file to write the linked script to")
+	flag.Parse()
+
+	format, ok := formats[*formatName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown format %s\n", *formatName)
+		os.Exit(2)
+	}
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: link [-format name] [-o file] object...")
+		os.Exit(2)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := link(w, format, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,55 @@
+// Command verify checks compiled scripts before they're shipped. It loads
+// each file named on the command line, or standard input if none are,
+// and prints every problem it finds. It exits with status 1 if any
+// script has a problem.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mcvoid/dialogue"
+)
+
+func verify(r io.Reader) error {
+	_, err := dialogue.FromReader(dialogue.ScriptInput(r))
+	return err
+}
+
+func verifyFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return verify(f)
+}
+
+func main() {
+	errs := map[string]error{}
+	names := os.Args[1:]
+	if len(names) == 0 {
+		names = []string{"<stdin>"}
+		errs["<stdin>"] = verify(os.Stdin)
+	} else {
+		for _, name := range names {
+			errs[name] = verifyFile(name)
+		}
+	}
+
+	failed := false
+	for _, name := range names {
+		if errs[name] == nil {
+			continue
+		}
+		failed = true
+		for _, line := range strings.Split(errs[name].Error(), "\n") {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, line)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -2,27 +2,58 @@ package dialogue
 
 import (
 	"compress/zlib"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/mcvoid/dialogue/internal/codegen"
 	"github.com/mcvoid/dialogue/internal/lexer"
 	"github.com/mcvoid/dialogue/internal/parser"
 	"github.com/mcvoid/dialogue/internal/semantic_analysis"
+	"github.com/mcvoid/dialogue/internal/types/ast"
+	"github.com/mcvoid/dialogue/internal/types/diagnostic"
 )
 
 type (
 	CompileArg func(*CompileArgs)
 
+	// Format is an encoding Compile can write a script in.
+	Format int
+
 	CompileArgs struct {
+		format              Format
 		codeFolding         bool
 		deadCodeElimination bool
+		typeCheck           bool
+		object              bool
+		filename            string
 		reader              io.Reader
 		writer              io.Writer
+		sources             []compileSource
+	}
+
+	compileSource struct {
+		filename string
+		reader   io.Reader
 	}
 )
 
+const (
+	// CompressedFormat is JSON compressed with zlib. It's the format
+	// used unless NoDeadCodeElimination is given.
+	CompressedFormat Format = iota + 1
+	// JSONFormat is readable JSON. It's the format used with
+	// NoDeadCodeElimination.
+	JSONFormat
+	// BinaryFormat is the smallest of the formats and the fastest to
+	// load.
+	BinaryFormat
+)
+
 func NoCodeFolding(ca *CompileArgs) {
 	ca.codeFolding = false
 }
@@ -31,12 +62,58 @@ func NoDeadCodeElimination(ca *CompileArgs) {
 	ca.deadCodeElimination = false
 }
 
+// NoTypeCheck skips checking that expressions, conditions and function
+// arguments have the right types. Code folding expects a well-typed
+// script, so pair it with NoCodeFolding for scripts which might not be.
+func NoTypeCheck(ca *CompileArgs) {
+	ca.typeCheck = false
+}
+
+// CompileObject makes Compile write a relocatable object, to be linked
+// with others by Link, rather than a script which can run on its own.
+// Nodes are named after their file as they are with CompilerSource, so
+// the input must be named with CompilerFilename if there are no sources.
+// References to nodes in files which aren't part of the compile are
+// left to be found by Link.
+func CompileObject(ca *CompileArgs) {
+	ca.object = true
+}
+
 func CompilerInput(r io.Reader) CompileArg {
 	return func(ca *CompileArgs) {
 		ca.reader = r
 	}
 }
 
+// CompilerFilename names the input so that errors can report which
+// file they occurred in.
+func CompilerFilename(name string) CompileArg {
+	return func(ca *CompileArgs) {
+		ca.filename = name
+	}
+}
+
+// CompilerSource adds a file to a script made of several files, which
+// are linked together into one. Each file's nodes are named after the
+// file, so node greeting in shop.md is shop.greeting. Nodes in the same
+// file can still be referred to without the file's name. The script
+// starts at the first node of the first file. CompilerInput and
+// CompilerFilename are ignored once a source is given.
+func CompilerSource(filename string, r io.Reader) CompileArg {
+	return func(ca *CompileArgs) {
+		ca.sources = append(ca.sources, compileSource{filename, r})
+	}
+}
+
+// CompilerFormat sets the format the script or object is written in.
+// FromReader can load a script in any of them, and Link can read an
+// object in any of them.
+func CompilerFormat(f Format) CompileArg {
+	return func(ca *CompileArgs) {
+		ca.format = f
+	}
+}
+
 func CompilerOutput(w io.Writer) CompileArg {
 	return func(ca *CompileArgs) {
 		ca.writer = w
@@ -45,11 +122,13 @@ func CompilerOutput(w io.Writer) CompileArg {
 
 // Compile tranlates a Script from text into its executable form.
 // Any reads errors, syntax errors, semantic errors, or write errors
-// will result in an error being returned.
+// will result in an error being returned. Problems with the script
+// itself are returned together as Diagnostics.
 func Compile(options ...CompileArg) error {
 	args := CompileArgs{
 		codeFolding:         true,
 		deadCodeElimination: true,
+		typeCheck:           true,
 		reader:              os.Stdin,
 		writer:              os.Stdout,
 	}
@@ -58,36 +137,169 @@ func Compile(options ...CompileArg) error {
 		opt(&args)
 	}
 
-	b, err := ioutil.ReadAll(args.reader)
-	if err != nil {
-		return err
+	if args.format == 0 {
+		args.format = JSONFormat
+		if args.deadCodeElimination {
+			args.format = CompressedFormat
+		}
 	}
-	l := lexer.New(string(b))
-	p := parser.New()
-	tree, err := p.Parse(l)
-	if err != nil {
-		return err
+	if args.format < CompressedFormat || args.format > BinaryFormat {
+		return fmt.Errorf("unknown format %d", args.format)
+	}
+
+	sources := args.sources
+	if args.object && len(sources) == 0 {
+		if args.filename == "" {
+			return errors.New("an object needs the name of the file it is compiled from")
+		}
+		sources = []compileSource{{args.filename, args.reader}}
+	}
+
+	var script ast.Script
+	diags := diagnostic.Diagnostics{}
+	if len(sources) == 0 {
+		parsed, parseDiags, err := parseSource(args.filename, args.reader)
+		if err != nil {
+			return err
+		}
+		diags = append(diags, parseDiags...)
+		script = parsed
+		diags = append(diags, semantic_analysis.CheckNodeReferences(script)...)
+	} else {
+		linkSources := []semantic_analysis.Source{}
+		for _, src := range sources {
+			parsed, parseDiags, err := parseSource(src.filename, src.reader)
+			if err != nil {
+				return err
+			}
+			diags = append(diags, parseDiags...)
+			linkSources = append(linkSources, semantic_analysis.Source{
+				Namespace: strings.TrimSuffix(filepath.Base(src.filename), filepath.Ext(src.filename)),
+				File:      src.filename,
+				Script:    parsed,
+			})
+		}
+		link := semantic_analysis.LinkScripts
+		if args.object {
+			link = semantic_analysis.LinkScriptsWithImports
+		}
+		linked, linkDiags := link(linkSources)
+		diags = append(diags, linkDiags...)
+		script = linked
+	}
+	diags = append(diags, semantic_analysis.CheckLoopControl(script)...)
+	if args.typeCheck {
+		_, typeErrors := semantic_analysis.TypeCheckScript(script)
+		diags = append(diags, typeErrors...)
+	}
+	if diags.HasErrors() {
+		return diags
 	}
 
-	ast := semantic_analysis.BuildScriptAst(tree)
 	if args.codeFolding {
-		ast = semantic_analysis.ConstantFoldScript(ast)
+		script = semantic_analysis.ConstantFoldScript(script)
 	}
-	if args.deadCodeElimination {
-		ast = semantic_analysis.PruneScript(ast)
+	if args.deadCodeElimination && args.object {
+		// another object might go to any of the nodes
+		script = semantic_analysis.PruneCode(script)
+	} else if args.deadCodeElimination {
+		script = semantic_analysis.PruneScript(script)
+	}
+	var out encodable
+	var err error
+	if args.object {
+		names := []string{}
+		for _, src := range sources {
+			names = append(names, src.filename)
+		}
+		obj, codegenErr := codegen.CodegenObject(script, strings.Join(names, ", "))
+		out, err = &obj, codegenErr
+	} else {
+		prog, codegenErr := codegen.Codegen(script)
+		out, err = &prog, codegenErr
 	}
-	prog, err := codegen.Codegen(ast)
 	if err != nil {
-		return err
+		return diagnostic.Diagnostics{{
+			Severity: diagnostic.Error,
+			Code:     diagnostic.CodegenFailure,
+			Message:  err.Error(),
+		}}
 	}
 
-	if args.deadCodeElimination {
-		zlibTarget := zlib.NewWriter(args.writer)
-		_, err = prog.WriteTo(zlibTarget)
+	return writeFormat(args.writer, args.format, out)
+}
+
+// encodable is a compiled script or object, which can be written as
+// JSON or in the binary format.
+type encodable interface {
+	io.WriterTo
+	WriteBinaryTo(w io.Writer) (int64, error)
+}
+
+// writeFormat writes out to w in format f.
+func writeFormat(w io.Writer, f Format, out encodable) error {
+	var err error
+	switch f {
+	case CompressedFormat:
+		zlibTarget := zlib.NewWriter(w)
+		_, err = out.WriteTo(zlibTarget)
 		zlibTarget.Close()
-	} else {
-		_, err = prog.WriteTo(args.writer)
+	case JSONFormat:
+		_, err = out.WriteTo(w)
+	case BinaryFormat:
+		_, err = out.WriteBinaryTo(w)
+	default:
+		err = fmt.Errorf("unknown format %d", f)
 	}
-
 	return err
 }
+
+// parseSource builds the AST of a single file. Syntax errors the parser
+// recovered from are given as diagnostics so that the rest of the
+// script can still be checked; any other error stops the compile.
+func parseSource(filename string, r io.Reader) (ast.Script, diagnostic.Diagnostics, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return ast.Script{}, nil, err
+	}
+	l := lexer.NewFile(filename, string(b))
+	p := parser.New()
+	tree, err := p.Parse(l)
+	diags := diagnostic.Diagnostics{}
+	var recovered parser.SyntaxErrors
+	if errors.As(err, &recovered) {
+		// the parser skipped the broken parts, so the rest of
+		// the script can still be checked
+		for _, syntaxErr := range recovered {
+			diags = append(diags, syntaxDiagnostic(syntaxErr))
+		}
+	} else if err != nil {
+		return ast.Script{}, nil, diagnostic.Diagnostics{syntaxDiagnostic(err)}
+	}
+	return semantic_analysis.BuildScriptAst(tree), diags, nil
+}
+
+func syntaxDiagnostic(err error) diagnostic.Diagnostic {
+	var syntaxErr parser.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return diagnostic.Diagnostic{
+			Severity: diagnostic.Error,
+			Code:     diagnostic.SyntaxError,
+			Message:  err.Error(),
+		}
+	}
+	if syntaxErr.Lexical() {
+		return diagnostic.Diagnostic{
+			Severity: diagnostic.Error,
+			Code:     diagnostic.LexError,
+			Message:  syntaxErr.Err.Error(),
+			Span:     diagnostic.At(syntaxErr.Pos),
+		}
+	}
+	return diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Code:     diagnostic.SyntaxError,
+		Message:  syntaxErr.Err.Error(),
+		Span:     diagnostic.Token(syntaxErr.Token),
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/mcvoid/dialogue/internal/program"
 	"github.com/mcvoid/dialogue/internal/types/asm"
+	"github.com/mcvoid/dialogue/internal/vm"
 )
 
 type mockReader struct{}
@@ -63,6 +64,7 @@ func TestCompile(t *testing.T) {
 			{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "start"}},
 			{Opcode: asm.EndDialogue},
 		},
+		Nodes: map[string]int{"start": 0},
 	}
 	zlibWriter := zlib.NewWriter(&b)
 	expectedProg.WriteTo(zlibWriter)
@@ -146,6 +148,7 @@ func TestCompile(t *testing.T) {
 			{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "start"}},
 			{Opcode: asm.EndDialogue},
 		},
+		Nodes: map[string]int{"start": 0},
 		Funcs: map[string][]asm.Type{},
 	}
 	expectedProg.WriteTo(&b)
@@ -207,3 +210,1257 @@ func TestCompile(t *testing.T) {
 		t.Errorf("Expected error got nil")
 	}
 }
+
+func TestCompileErrorPosition(t *testing.T) {
+	input := "```\n" +
+		"# start\n" +
+		"\n" +
+		"Some text.\n" +
+		"\n" +
+		"```\n" +
+		"x = 1 +;\n" +
+		"```\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&b),
+		CompilerFilename("start.md"),
+	)
+	if err == nil {
+		t.Fatalf("Expected error got nil")
+	}
+	if !strings.HasPrefix(err.Error(), "start.md:7:") {
+		t.Errorf("expected error on line 7 of start.md, got %v", err)
+	}
+}
+
+func TestCompileDiagnostics(t *testing.T) {
+	input := "```\n" +
+		"# start\n" +
+		"\n" +
+		"[middle] (go on)\n" +
+		"\n" +
+		"# middle\n" +
+		"\n" +
+		"```\n" +
+		"goto nowhere;\n" +
+		"```\n" +
+		"\n" +
+		"# start\n" +
+		"\n" +
+		"- [start] (again)\n" +
+		"- [elsewhere] (away)\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&b),
+	)
+	diags, ok := err.(Diagnostics)
+	if !ok {
+		t.Fatalf("expected diagnostics got %v", err)
+	}
+	expected := []struct {
+		code DiagnosticCode
+		line int
+	}{
+		{DuplicateNodeCode, 12},
+		{UnknownNodeCode, 9},
+		{UnknownNodeCode, 15},
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d diagnostics got %v", len(expected), diags)
+	}
+	for i, e := range expected {
+		if diags[i].Code != e.code || diags[i].Span.Start.Line != e.line || diags[i].Severity != SeverityError {
+			t.Errorf("diagnostic %d: expected %v on line %d got %v", i, e.code, e.line, diags[i])
+		}
+	}
+
+	err = Compile(
+		CompilerInput(strings.NewReader("```\n# start\n\n[abc](abc\n")),
+		CompilerOutput(&b),
+	)
+	diags, ok = err.(Diagnostics)
+	if !ok || len(diags) != 1 || diags[0].Code != LexErrorCode {
+		t.Errorf("expected a lex error got %v", err)
+	}
+}
+
+func TestCompileRecovery(t *testing.T) {
+	input := "```\n" +
+		"# start\n" +
+		"\n" +
+		"```\n" +
+		"x = ;\n" +
+		"```\n" +
+		"\n" +
+		"Fine text.\n" +
+		"\n" +
+		"```\n" +
+		"goto;\n" +
+		"```\n" +
+		"\n" +
+		"[missing] (text)\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&b),
+	)
+	diags, ok := err.(Diagnostics)
+	if !ok {
+		t.Fatalf("expected diagnostics got %v", err)
+	}
+	expected := []struct {
+		code DiagnosticCode
+		line int
+	}{
+		{SyntaxErrorCode, 5},
+		{SyntaxErrorCode, 11},
+		{UnknownNodeCode, 14},
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d diagnostics got %v", len(expected), diags)
+	}
+	for i, e := range expected {
+		if diags[i].Code != e.code || diags[i].Span.Start.Line != e.line {
+			t.Errorf("diagnostic %d: expected %v on line %d got %v", i, e.code, e.line, diags[i])
+		}
+	}
+}
+
+func TestCompileTypeCheck(t *testing.T) {
+	input := "```\n" +
+		"# start\n" +
+		"\n" +
+		"Gold: `gold`.\n" +
+		"\n" +
+		"```\n" +
+		"while \"forever\" {\n" +
+		"  gold = gold + 1;\n" +
+		"}\n" +
+		"if !5 { gold = 0; }\n" +
+		"```\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&b),
+	)
+	diags, ok := err.(Diagnostics)
+	if !ok {
+		t.Fatalf("expected diagnostics got %v", err)
+	}
+	expected := []struct {
+		message string
+		line    int
+	}{
+		{"while condition is string", 7},
+		{"operand of ! expects bool, got number", 10},
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d diagnostics got %v", len(expected), diags)
+	}
+	for i, e := range expected {
+		if diags[i].Code != TypeErrorCode || diags[i].Message != e.message || diags[i].Span.Start.Line != e.line {
+			t.Errorf("diagnostic %d: expected %q on line %d got %v", i, e.message, e.line, diags[i])
+		}
+	}
+
+	err = Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&b),
+		NoTypeCheck,
+		NoCodeFolding,
+	)
+	if err != nil {
+		t.Errorf("expected no error with type checking off got %v", err)
+	}
+}
+
+func TestCompileExterns(t *testing.T) {
+	input := "extern give_item(string, number);\n" +
+		"```\n" +
+		"# start\n" +
+		"\n" +
+		"```\n" +
+		"give_item(\"sword\", 1);\n" +
+		"```\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&b),
+	)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	script, err := FromReader(ScriptInput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	proto := script.program.Funcs["give_item"]
+	if len(proto) != 2 || proto[0] != asm.StringType || proto[1] != asm.NumberType {
+		t.Fatalf("expected give_item prototype to be kept, got %v", script.program.Funcs)
+	}
+
+	called := []asm.Value{}
+	v, err := vm.New(script.program, vm.RegisterCallback(vm.Function{
+		Func: func(v *vm.VM, args ...asm.Value) (asm.Value, vm.ExecutionType) {
+			called = append(called, args...)
+			return asm.Null, vm.ContinueExecution
+		},
+	}))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := v.Run(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if len(called) != 2 || called[0].Val != "sword" || called[1].Val != 1 {
+		t.Errorf("expected give_item to be called with its arguments, got %v", called)
+	}
+}
+
+func TestCompileExternReturns(t *testing.T) {
+	input := "extern has_item(string): bool;\n" +
+		"extern player_name(): string;\n" +
+		"```\n" +
+		"# start\n" +
+		"\n" +
+		"```\n" +
+		"if has_item(\"key\") {\n" +
+		"  unlocked = true;\n" +
+		"}\n" +
+		"```\n" +
+		"\n" +
+		"Hello, `player_name()`.\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&b),
+	)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	script, err := FromReader(ScriptInput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+
+	lines := []string{}
+	var hf HandlerFunc = func(m Message) ExecutionType {
+		if m.Type == ShowLineType {
+			lines = append(lines, m.ShowLine.Line)
+		}
+		return Continue
+	}
+	proc, err := script.New(hf,
+		WithFunction("has_item", func(args ...interface{}) (interface{}, ExecutionType) {
+			return args[0] == "key", Continue
+		}),
+		WithFunction("player_name", func(args ...interface{}) (interface{}, ExecutionType) {
+			return "Alice", Continue
+		}),
+	)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := proc.Start(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if len(lines) != 1 || lines[0] != "Hello, Alice." {
+		t.Errorf("expected [Hello, Alice.] got %v", lines)
+	}
+	if val, _ := proc.vm.GetVariable("unlocked"); val.Val != true {
+		t.Errorf("expected unlocked to be set, got %v", val)
+	}
+
+	input = "extern give_item(string);\n" +
+		"```\n" +
+		"# start\n" +
+		"\n" +
+		"```\n" +
+		"x = give_item(\"sword\");\n" +
+		"```\n" +
+		"\n"
+	err = Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&bytes.Buffer{}),
+	)
+	if err == nil {
+		t.Errorf("error expected when using the result of a function without a return type")
+	}
+}
+
+func TestCompileBuiltins(t *testing.T) {
+	input := "```\n" +
+		"# start\n" +
+		"\n" +
+		"```\n" +
+		"name = \"alice\";\n" +
+		"roll = random(1, 6);\n" +
+		"```\n" +
+		"\n" +
+		"`upper(name)`\n" +
+		"\n" +
+		"`length(name)`\n" +
+		"\n" +
+		"`to_string(clamp(roll, 1, 6))`\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&b),
+	)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	script, err := FromReader(ScriptInput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+
+	run := func() []string {
+		lines := []string{}
+		var hf HandlerFunc = func(m Message) ExecutionType {
+			if m.Type == ShowLineType {
+				lines = append(lines, strings.TrimSpace(m.ShowLine.Line))
+			}
+			return Continue
+		}
+		proc, err := script.New(hf, WithSeed(7))
+		if err != nil {
+			t.Fatalf("no error expected, got %v", err)
+		}
+		if err := proc.Start(); err != nil {
+			t.Fatalf("no error expected, got %v", err)
+		}
+		return lines
+	}
+	first := run()
+	if len(first) != 3 || first[0] != "ALICE" || first[1] != "5" {
+		t.Fatalf("unexpected output %v", first)
+	}
+	if second := run(); len(second) != 3 || second[2] != first[2] {
+		t.Errorf("expected seeded runs to match, got %v and %v", first, second)
+	}
+}
+
+func TestCompileLinkInlineCode(t *testing.T) {
+	input := "```\n" +
+		"# start\n" +
+		"\n" +
+		"```\n" +
+		"price = 10;\n" +
+		"```\n" +
+		"\n" +
+		"- [start] (Buy for `price` gold)\n" +
+		"- [start] (Haggle down to `price - 2` gold)\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&b),
+	)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	script, err := FromReader(ScriptInput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+
+	options := []string{}
+	var hf HandlerFunc = func(m Message) ExecutionType {
+		if m.Type == ShowChoiceType {
+			options = m.ShowChoice.Options
+		}
+		return Continue
+	}
+	proc, err := script.New(hf)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := proc.Start(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if len(options) != 2 || options[0] != "Buy for 10 gold" || options[1] != "Haggle down to 8 gold" {
+		t.Errorf("expected options to show the price, got %q", options)
+	}
+}
+
+func TestCompileOptionGuards(t *testing.T) {
+	input := "```\n" +
+		"# start\n" +
+		"\n" +
+		"- [door] (Open the door) if has_key\n" +
+		"- [start] (Ask about the key) if !asked\n" +
+		"- [start] (Wait)\n" +
+		"\n" +
+		"# door\n" +
+		"\n" +
+		"It opens.\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&b),
+	)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	script, err := FromReader(ScriptInput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+
+	options := []string{}
+	var hf HandlerFunc = func(m Message) ExecutionType {
+		if m.Type == ShowChoiceType {
+			options = m.ShowChoice.Options
+		}
+		return Continue
+	}
+	for name, test := range map[string]struct {
+		hasKey   bool
+		asked    bool
+		expected []string
+	}{
+		"no key":    {false, false, []string{"Ask about the key", "Wait"}},
+		"has key":   {true, false, []string{"Open the door", "Ask about the key", "Wait"}},
+		"asked":     {false, true, []string{"Wait"}},
+		"key asked": {true, true, []string{"Open the door", "Wait"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			proc, err := script.New(hf)
+			if err != nil {
+				t.Fatalf("no error expected, got %v", err)
+			}
+			proc.vm.SetVariableBoolean("has_key", test.hasKey)
+			proc.vm.SetVariableBoolean("asked", test.asked)
+			if err := proc.Start(); err != nil {
+				t.Fatalf("no error expected, got %v", err)
+			}
+			if strings.Join(options, "|") != strings.Join(test.expected, "|") {
+				t.Errorf("expected %q got %q", test.expected, options)
+			}
+		})
+	}
+
+	input = "```\n" +
+		"# start\n" +
+		"\n" +
+		"- [start] (Wait) if 5\n" +
+		"\n"
+	err = Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&bytes.Buffer{}),
+	)
+	diags, ok := err.(Diagnostics)
+	if !ok || len(diags) != 1 || diags[0].Message != "option guard is number" || diags[0].Span.Start.Line != 4 {
+		t.Errorf("expected a type error for the guard, got %v", err)
+	}
+}
+
+func TestCompileVariables(t *testing.T) {
+	input := "var gold: number = 5;\n" +
+		"var met = false;\n" +
+		"extern var player: string;\n" +
+		"```\n" +
+		"# start\n" +
+		"\n" +
+		"```\n" +
+		"local bonus = 2;\n" +
+		"gold = gold + bonus;\n" +
+		"met = true;\n" +
+		"greeting = player;\n" +
+		"```\n" +
+		"\n" +
+		"[next](Onward)\n" +
+		"\n" +
+		"# next\n" +
+		"\n" +
+		"```\n" +
+		"leftover = bonus;\n" +
+		"```\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&b),
+	)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	script, err := FromReader(ScriptInput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	var hf HandlerFunc = func(m Message) ExecutionType { return Continue }
+	proc, err := script.New(hf)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	proc.SetVariableString("player", "Ann")
+	for run, expected := range []int{7, 9} {
+		if err := proc.Start(); err != nil {
+			t.Fatalf("no error expected, got %v", err)
+		}
+		if gold, ok := proc.GetVariableNumber("gold"); !ok || gold != expected {
+			t.Errorf("run %d: expected gold %d got %v", run, expected, gold)
+		}
+	}
+	if met, ok := proc.GetVariableBoolean("met"); !ok || !met {
+		t.Errorf("expected met to be true got %v", met)
+	}
+	if greeting, ok := proc.GetVariableString("greeting"); !ok || greeting != "Ann" {
+		t.Errorf("expected greeting Ann got %q", greeting)
+	}
+	if leftover, ok := proc.GetVariable("leftover"); !ok || leftover != nil {
+		t.Errorf("expected the local to be cleared, got %v", leftover)
+	}
+	if _, ok := proc.GetVariable("bonus"); ok {
+		t.Errorf("expected the local not to be stored as a variable")
+	}
+
+	input = "var gold: number = 5;\n" +
+		"```\n" +
+		"# start\n" +
+		"\n" +
+		"```\n" +
+		"gold = \"lots\";\n" +
+		"```\n" +
+		"\n"
+	err = Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&bytes.Buffer{}),
+	)
+	diags, ok := err.(Diagnostics)
+	if !ok || len(diags) != 1 || diags[0].Message != "gold is number, cannot assign string" || diags[0].Span.Start.Line != 6 {
+		t.Errorf("expected a type error for the assignment, got %v", err)
+	}
+}
+
+func TestCompileLoopControl(t *testing.T) {
+	input := "```\n" +
+		"# start\n" +
+		"\n" +
+		"```\n" +
+		"count = 0;\n" +
+		"odd = 0;\n" +
+		"while true {\n" +
+		"  count = count + 1;\n" +
+		"  if count > 9 {\n" +
+		"    break;\n" +
+		"  }\n" +
+		"  if count % 2 == 0 {\n" +
+		"    continue;\n" +
+		"  }\n" +
+		"  odd = odd + 1;\n" +
+		"}\n" +
+		"```\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&b),
+	)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	script, err := FromReader(ScriptInput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	var hf HandlerFunc = func(m Message) ExecutionType { return Continue }
+	proc, err := script.New(hf)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := proc.Start(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if count, _ := proc.GetVariableNumber("count"); count != 10 {
+		t.Errorf("expected count 10 got %d", count)
+	}
+	if odd, _ := proc.GetVariableNumber("odd"); odd != 5 {
+		t.Errorf("expected odd 5 got %d", odd)
+	}
+
+	input = "```\n" +
+		"# start\n" +
+		"\n" +
+		"```\n" +
+		"if x {\n" +
+		"  break;\n" +
+		"}\n" +
+		"```\n" +
+		"\n"
+	err = Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&bytes.Buffer{}),
+	)
+	diags, ok := err.(Diagnostics)
+	if !ok || len(diags) != 1 || diags[0].Code != LoopControlCode || diags[0].Message != "break is not inside a loop" || diags[0].Span.Start.Line != 6 {
+		t.Errorf("expected an error for the break, got %v", err)
+	}
+}
+
+func TestCompileSources(t *testing.T) {
+	shop := "extern give(string);\n" +
+		"```\n" +
+		"# greeting\n" +
+		"\n" +
+		"Welcome.\n" +
+		"\n" +
+		"```\n" +
+		"give(\"map\");\n" +
+		"goto inn.entry;\n" +
+		"```\n" +
+		"\n"
+	inn := "extern give(string);\n" +
+		"```\n" +
+		"# entry\n" +
+		"\n" +
+		"[rooms](Rooms)\n" +
+		"\n" +
+		"# rooms\n" +
+		"\n" +
+		"No vacancy.\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(
+		CompilerSource("scripts/shop.md", strings.NewReader(shop)),
+		CompilerSource("scripts/inn.md", strings.NewReader(inn)),
+		CompilerOutput(&b),
+	)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	script, err := FromReader(ScriptInput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	nodes := []string{}
+	var hf HandlerFunc = func(m Message) ExecutionType {
+		if m.Type == EnterNodeType {
+			nodes = append(nodes, m.EnterNode.NodeEntered)
+		}
+		return Continue
+	}
+	given := []interface{}{}
+	proc, err := script.New(hf, WithFunction("give", func(args ...interface{}) (interface{}, ExecutionType) {
+		given = append(given, args[0])
+		return nil, Continue
+	}))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := proc.Start(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	expected := []string{"shop.greeting", "inn.entry", "inn.rooms"}
+	if strings.Join(nodes, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %q got %q", expected, nodes)
+	}
+	if len(given) != 1 || given[0] != "map" {
+		t.Errorf("expected give to be called with map, got %v", given)
+	}
+
+	inn = "extern give(number);\n" +
+		"```\n" +
+		"# lobby\n" +
+		"\n" +
+		"Hi.\n" +
+		"\n"
+	err = Compile(
+		CompilerSource("scripts/shop.md", strings.NewReader(shop)),
+		CompilerSource("scripts/inn.md", strings.NewReader(inn)),
+		CompilerOutput(&bytes.Buffer{}),
+	)
+	diags, ok := err.(Diagnostics)
+	if !ok || len(diags) != 2 {
+		t.Fatalf("expected two diagnostics, got %v", err)
+	}
+	if diags[0].Code != LinkErrorCode || diags[0].Message != "extern give conflicts with its declaration in scripts/shop.md" || diags[0].Span.Start.File != "scripts/inn.md" {
+		t.Errorf("expected a conflicting extern, got %v", diags[0])
+	}
+	if diags[1].Code != UnknownNodeCode || diags[1].Message != "unknown node inn.entry in scripts/inn.md" || diags[1].Span.Start.File != "scripts/shop.md" || diags[1].Span.Start.Line != 9 {
+		t.Errorf("expected an unknown node, got %v", diags[1])
+	}
+}
+
+func TestCompileObjectAndLink(t *testing.T) {
+	shop := "extern give(string);\n" +
+		"var gold: number = 5;\n" +
+		"```\n" +
+		"# greeting\n" +
+		"\n" +
+		"```\n" +
+		"give(\"map\");\n" +
+		"```\n" +
+		"\n" +
+		"- [inn.entry](Go to the inn)\n" +
+		"- [bye](Leave)\n" +
+		"\n" +
+		"# bye\n" +
+		"\n" +
+		"Bye.\n" +
+		"\n"
+	inn := "extern give(string);\n" +
+		"var rooms = 3;\n" +
+		"```\n" +
+		"# entry\n" +
+		"\n" +
+		"```\n" +
+		"if rooms > 0 {\n" +
+		"  rooms = rooms - 1;\n" +
+		"}\n" +
+		"```\n" +
+		"\n" +
+		"[shop.bye](Back)\n" +
+		"\n"
+
+	compileObject := func(filename, input string, options ...CompileArg) *bytes.Buffer {
+		var b bytes.Buffer
+		err := Compile(append([]CompileArg{
+			CompileObject,
+			CompilerFilename(filename),
+			CompilerInput(strings.NewReader(input)),
+			CompilerOutput(&b),
+		}, options...)...)
+		if err != nil {
+			t.Fatalf("no error expected, got %v", err)
+		}
+		return &b
+	}
+	shopObj := compileObject("shop.md", shop)
+	innObj := compileObject("inn.md", inn, NoDeadCodeElimination)
+
+	var b bytes.Buffer
+	if err := Link(LinkInput(shopObj), LinkInput(innObj), LinkOutput(&b)); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	script, err := FromReader(ScriptInput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+
+	nodes := []string{}
+	var hf HandlerFunc = func(m Message) ExecutionType {
+		if m.Type == EnterNodeType {
+			nodes = append(nodes, m.EnterNode.NodeEntered)
+		}
+		return Continue
+	}
+	proc, err := script.New(hf, WithFunction("give", func(args ...interface{}) (interface{}, ExecutionType) {
+		return nil, Continue
+	}))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := proc.Start(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := proc.ChooseAndResume(0); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	expected := []string{"shop.greeting", "inn.entry", "shop.bye"}
+	if strings.Join(nodes, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %q got %q", expected, nodes)
+	}
+	if gold, _ := proc.GetVariableNumber("gold"); gold != 5 {
+		t.Errorf("expected gold 5 got %d", gold)
+	}
+	if rooms, _ := proc.GetVariableNumber("rooms"); rooms != 2 {
+		t.Errorf("expected rooms 2 got %d", rooms)
+	}
+
+	err = Link(LinkInput(compileObject("shop.md", shop)), LinkOutput(&bytes.Buffer{}))
+	if err == nil || err.Error() != "shop.md: node inn.entry is not in any object" {
+		t.Errorf("expected an unresolved import, got %v", err)
+	}
+
+	err = Compile(CompileObject, CompilerInput(strings.NewReader(inn)), CompilerOutput(&bytes.Buffer{}))
+	if err == nil {
+		t.Errorf("expected an error for an object without a filename")
+	}
+}
+
+func TestCompileEntryNodes(t *testing.T) {
+	input := "var gold = 5;\n" +
+		"```\n" +
+		"# start\n" +
+		"\n" +
+		"Hello.\n" +
+		"\n" +
+		"# shop @entry\n" +
+		"\n" +
+		"```\n" +
+		"gold = gold - 1;\n" +
+		"```\n" +
+		"\n" +
+		"# hidden\n" +
+		"\n" +
+		"Nobody comes here.\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&b),
+	)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	script, err := FromReader(ScriptInput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+
+	nodes := []string{}
+	var hf HandlerFunc = func(m Message) ExecutionType {
+		if m.Type == EnterNodeType {
+			nodes = append(nodes, m.EnterNode.NodeEntered)
+		}
+		return Continue
+	}
+	proc, err := script.New(hf)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := proc.StartAt("shop"); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if len(nodes) != 1 || nodes[0] != "shop" {
+		t.Errorf("expected to only enter shop, got %q", nodes)
+	}
+	if gold, _ := proc.GetVariableNumber("gold"); gold != 4 {
+		t.Errorf("expected gold 4 got %d", gold)
+	}
+
+	if err := proc.StartAt("hidden"); err == nil || err.Error() != "unknown node hidden" {
+		t.Errorf("expected the unreachable node to be pruned, got %v", err)
+	}
+}
+
+func TestCompileFormats(t *testing.T) {
+	input := "```\n" +
+		"# start\n" +
+		"\n" +
+		"Hello.\n" +
+		"\n"
+
+	for name, format := range map[string]Format{
+		"compressed": CompressedFormat,
+		"json":       JSONFormat,
+		"binary":     BinaryFormat,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			err := Compile(
+				CompilerFormat(format),
+				CompilerInput(strings.NewReader(input)),
+				CompilerOutput(&b),
+			)
+			if err != nil {
+				t.Fatalf("no error expected, got %v", err)
+			}
+			script, err := FromReader(ScriptInput(&b))
+			if err != nil {
+				t.Fatalf("no error expected, got %v", err)
+			}
+			lines := []string{}
+			var hf HandlerFunc = func(m Message) ExecutionType {
+				if m.Type == ShowLineType {
+					lines = append(lines, m.ShowLine.Line)
+				}
+				return Continue
+			}
+			proc, err := script.New(hf)
+			if err != nil {
+				t.Fatalf("no error expected, got %v", err)
+			}
+			if err := proc.Start(); err != nil {
+				t.Fatalf("no error expected, got %v", err)
+			}
+			if len(lines) != 1 || lines[0] != "Hello." {
+				t.Errorf("expected to show Hello. got %q", lines)
+			}
+		})
+	}
+
+	var obj, linked bytes.Buffer
+	err := Compile(
+		CompilerFormat(BinaryFormat),
+		CompileObject,
+		CompilerFilename("a.md"),
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&obj),
+	)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	objBytes := obj.Bytes()
+	if err := Link(LinkInput(bytes.NewReader(objBytes)), LinkOutput(&linked)); err != nil {
+		t.Fatalf("expected a binary object to link, got %v", err)
+	}
+	if _, err := FromReader(ScriptInput(&linked)); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+	for name, format := range map[string]Format{
+		"json":   JSONFormat,
+		"binary": BinaryFormat,
+	} {
+		t.Run("link "+name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := Link(LinkInput(bytes.NewReader(objBytes)), LinkOutput(&b), LinkFormat(format)); err != nil {
+				t.Fatalf("no error expected, got %v", err)
+			}
+			if isJSON := b.Bytes()[0] == '{'; isJSON != (format == JSONFormat) {
+				t.Errorf("expected the script in the %s format, got %q", name, b.Bytes())
+			}
+			if _, err := FromReader(ScriptInput(&b)); err != nil {
+				t.Errorf("no error expected, got %v", err)
+			}
+		})
+	}
+	err = Link(LinkInput(bytes.NewReader(objBytes)), LinkOutput(&bytes.Buffer{}), LinkFormat(Format(10)))
+	if err == nil || err.Error() != "unknown format 10" {
+		t.Errorf("expected an error for an unknown link format, got %v", err)
+	}
+
+	err = Compile(
+		CompilerFormat(Format(10)),
+		CompilerInput(strings.NewReader(input)),
+		CompilerOutput(&bytes.Buffer{}),
+	)
+	if err == nil || err.Error() != "unknown format 10" {
+		t.Errorf("expected an error for an unknown format, got %v", err)
+	}
+}
+
+func TestCompileComparisons(t *testing.T) {
+	input := "var gold = 5;\n" +
+		"```\n" +
+		"# start\n" +
+		"\n" +
+		"```\n" +
+		"rich = gold >= 5;\n" +
+		"poor = gold <= 4;\n" +
+		"```\n" +
+		"\n"
+
+	for name, options := range map[string][]CompileArg{
+		"compressed": {},
+		"json":       {NoDeadCodeElimination},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			err := Compile(append(options,
+				CompilerInput(strings.NewReader(input)),
+				CompilerOutput(&b),
+			)...)
+			if err != nil {
+				t.Fatalf("no error expected, got %v", err)
+			}
+			script, err := FromReader(ScriptInput(&b))
+			if err != nil {
+				t.Fatalf("no error expected, got %v", err)
+			}
+			var hf HandlerFunc = func(m Message) ExecutionType { return Continue }
+			proc, err := script.New(hf)
+			if err != nil {
+				t.Fatalf("no error expected, got %v", err)
+			}
+			if err := proc.Start(); err != nil {
+				t.Fatalf("no error expected, got %v", err)
+			}
+			if rich, _ := proc.GetVariableBoolean("rich"); !rich {
+				t.Errorf("expected rich to be true")
+			}
+			if poor, ok := proc.GetVariableBoolean("poor"); !ok || poor {
+				t.Errorf("expected poor to be false")
+			}
+		})
+	}
+}
+
+func TestCompileArithmetic(t *testing.T) {
+	input := "var gold = 10;\n" +
+		"var flag = true;\n" +
+		"```\n" +
+		"# start\n" +
+		"\n" +
+		"```\n" +
+		"a = 10 - 3 - 2;\n" +
+		"b = gold - 3 - 2;\n" +
+		"c = 24 / gold / 2;\n" +
+		"d = 17 % gold % 4;\n" +
+		"e = --gold * 2;\n" +
+		"f = - -gold + 1;\n" +
+		"g = !!flag;\n" +
+		"```\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(CompilerInput(strings.NewReader(input)), CompilerOutput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	script, err := FromReader(ScriptInput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	var hf HandlerFunc = func(m Message) ExecutionType { return Continue }
+	proc, err := script.New(hf)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := proc.Start(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	for name, expected := range map[string]int{"a": 5, "b": 5, "c": 1, "d": 3, "e": 18, "f": 11} {
+		if actual, ok := proc.GetVariableNumber(name); !ok || actual != expected {
+			t.Errorf("expected %s to be %d got %v", name, expected, actual)
+		}
+	}
+	if g, ok := proc.GetVariableBoolean("g"); !ok || !g {
+		t.Errorf("expected g to be true")
+	}
+}
+
+func TestCompileFractions(t *testing.T) {
+	input := "var price = 2.5;\n" +
+		"```\n" +
+		"# start\n" +
+		"\n" +
+		"```\n" +
+		"total = price * 3;\n" +
+		"half = 7 / 2;\n" +
+		"exact = 7.0 / 2;\n" +
+		"same = 2 == 2.0;\n" +
+		"```\n" +
+		"\n" +
+		"Total: `total`, each: `1 / 3.0`\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(CompilerInput(strings.NewReader(input)), CompilerOutput(&b), CompilerFormat(BinaryFormat))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	script, err := FromReader(ScriptInput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	lines := []string{}
+	var hf HandlerFunc = func(m Message) ExecutionType {
+		if m.Type == ShowLineType {
+			lines = append(lines, m.ShowLine.Line)
+		}
+		return Continue
+	}
+	proc, err := script.New(hf)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := proc.Start(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if total, ok := proc.GetVariableFloat("total"); !ok || total != 7.5 {
+		t.Errorf("expected total to be 7.5 got %v", total)
+	}
+	if half, ok := proc.GetVariableNumber("half"); !ok || half != 3 {
+		t.Errorf("expected half to be 3 got %v", half)
+	}
+	if exact, ok := proc.GetVariableFloat("exact"); !ok || exact != 3.5 {
+		t.Errorf("expected exact to be 3.5 got %v", exact)
+	}
+	if _, ok := proc.GetVariableNumber("exact"); ok {
+		t.Errorf("expected exact not to be a whole number")
+	}
+	if same, ok := proc.GetVariableBoolean("same"); !ok || !same {
+		t.Errorf("expected same to be true")
+	}
+	if len(lines) != 1 || lines[0] != "Total: 7.5, each: 0.333333" {
+		t.Errorf("expected the fractions to be formatted, got %q", lines)
+	}
+
+	for name, code := range map[string]string{
+		"literal":       "x = 1e999;\n",
+		"whole literal": "x = 99999999999999999999;\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			input := "```\n# start\n\n```\n" + code + "```\n\n"
+			err := Compile(CompilerInput(strings.NewReader(input)), CompilerOutput(&bytes.Buffer{}))
+			if err == nil || !strings.Contains(err.Error(), "is out of range") {
+				t.Errorf("expected an out of range error, got %v", err)
+			}
+		})
+	}
+
+	for name, code := range map[string]string{
+		"fraction":      "x = 1e300 * 1e300;\n",
+		"folded whole":  "x = 9223372036854775807 + 1;\n",
+		"whole":         "x = 9223372036854775807;\nx = x + 1;\n",
+		"whole negated": "x = -9223372036854775807 - 1;\nx = -x;\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			input := "```\n# start\n\n```\n" + code + "```\n\n"
+			var b bytes.Buffer
+			if err := Compile(CompilerInput(strings.NewReader(input)), CompilerOutput(&b)); err != nil {
+				t.Fatalf("expected an overflow to be left to runtime, got %v", err)
+			}
+			script, _ := FromReader(ScriptInput(&b))
+			proc, _ := script.New(hf)
+			if rerr, ok := proc.Start().(RuntimeError); !ok || rerr.Code != NumberOutOfRangeFault {
+				t.Errorf("expected a number out of range fault, got %v", rerr)
+			}
+		})
+	}
+}
+
+func TestCompileCollections(t *testing.T) {
+	input := "extern loot(): list;\n" +
+		"extern show(list);\n" +
+		"var inventory: list = [\"sword\"];\n" +
+		"var prices = {\"sword\": 10, \"shield\": 7};\n" +
+		"var item = 5;\n" +
+		"```\n" +
+		"# start\n" +
+		"\n" +
+		"```\n" +
+		"inventory[1] = \"shield\";\n" +
+		"total = 0;\n" +
+		"for item in inventory {\n" +
+		"    total = total + prices[item];\n" +
+		"}\n" +
+		"item = 100;\n" +
+		"list = 1;\n" +
+		"named = {\"a\": list};\n" +
+		"pair = [1, 2];\n" +
+		"pair[1] = 3;\n" +
+		"names = \"\";\n" +
+		"for name in prices {\n" +
+		"    if name == \"shield\" { continue; }\n" +
+		"    names = names . name;\n" +
+		"}\n" +
+		"prices[\"sword\"] = null;\n" +
+		"found = loot();\n" +
+		"show(found);\n" +
+		"```\n" +
+		"\n" +
+		"Items: `inventory`, count `count(inventory)`, first `inventory[0]`.\n" +
+		"\n"
+
+	var b bytes.Buffer
+	err := Compile(CompilerInput(strings.NewReader(input)), CompilerOutput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	script, err := FromReader(ScriptInput(&b))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	lines := []string{}
+	shown := []interface{}{}
+	var hf HandlerFunc = func(m Message) ExecutionType {
+		if m.Type == ShowLineType {
+			lines = append(lines, m.ShowLine.Line)
+		}
+		return Continue
+	}
+	proc, err := script.New(hf,
+		WithFunction("loot", func(args ...interface{}) (interface{}, ExecutionType) {
+			return []interface{}{"gem", 2.5}, Continue
+		}),
+		WithFunction("show", func(args ...interface{}) (interface{}, ExecutionType) {
+			shown = append(shown, args...)
+			return nil, Continue
+		}),
+	)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := proc.Start(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if total, ok := proc.GetVariableNumber("total"); !ok || total != 17 {
+		t.Errorf("expected total to be 17 got %v", total)
+	}
+	if item, ok := proc.GetVariableNumber("item"); !ok || item != 100 {
+		t.Errorf("expected the global item to be assigned after the loop, got %v", item)
+	}
+	if named, ok := proc.GetVariableMap("named"); !ok || named["a"] != 1 {
+		t.Errorf("expected a variable named list in a map literal, got %v", named)
+	}
+	if pair, ok := proc.GetVariableList("pair"); !ok || len(pair) != 2 || pair[1] != 3 {
+		t.Errorf("expected an undeclared list to be assigned by index, got %v", pair)
+	}
+	if names, ok := proc.GetVariableString("names"); !ok || names != "sword" {
+		t.Errorf("expected names to be sword got %q", names)
+	}
+	if prices, ok := proc.GetVariableMap("prices"); !ok || len(prices) != 1 || prices["shield"] != 7 {
+		t.Errorf("expected the sword's price to be removed, got %v", prices)
+	}
+	if found, ok := proc.GetVariableList("found"); !ok || len(found) != 2 || found[0] != "gem" || found[1] != 2.5 {
+		t.Errorf("expected the list returned by loot, got %v", found)
+	}
+	if len(shown) != 1 || len(shown[0].([]interface{})) != 2 {
+		t.Errorf("expected show to receive a list, got %v", shown)
+	}
+	if len(lines) != 1 || lines[0] != `Items: ["sword", "shield"], count 2, first sword.` {
+		t.Errorf("expected the inventory to be shown, got %q", lines)
+	}
+}
+
+func TestCompileCollectionErrors(t *testing.T) {
+	tests := map[string]struct {
+		code     string
+		expected string
+	}{
+		"string index of list": {
+			code:     "local x = [1, 2];\ny = x[\"a\"];\n",
+			expected: "list index is string",
+		},
+		"number key of map": {
+			code:     "local x = {\"a\": 1};\ny = x[0];\n",
+			expected: "map key is number",
+		},
+		"indexing a number": {
+			code:     "local x = 1;\ny = x[0];\n",
+			expected: "cannot index number",
+		},
+		"looping over a string": {
+			code:     "for c in \"abc\" { y = c; }\n",
+			expected: "cannot loop over string",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			input := "```\n# start\n\n```\n" + test.code + "```\n\n"
+			err := Compile(CompilerInput(strings.NewReader(input)), CompilerOutput(&bytes.Buffer{}))
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("expected error containing %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package dialogue
+
+import (
+	"github.com/mcvoid/dialogue/internal/types/diagnostic"
+	"github.com/mcvoid/dialogue/internal/types/lexeme"
+)
+
+type (
+	// Diagnostic describes a single problem with a script: how severe it
+	// is, a stable code identifying the kind of problem, a message, and
+	// where in the source it occurred.
+	Diagnostic = diagnostic.Diagnostic
+
+	// Diagnostics is the error Compile returns when a script has problems.
+	// It lists every problem found rather than stopping at the first.
+	Diagnostics = diagnostic.Diagnostics
+
+	Severity       = diagnostic.Severity
+	DiagnosticCode = diagnostic.Code
+
+	// Span is a stretch of source text, from Start up to but not including End.
+	Span = diagnostic.Span
+
+	// RelatedSpan is a secondary location which helps explain a Diagnostic.
+	RelatedSpan = diagnostic.Related
+
+	// Position is a file, line, column and byte offset in a script.
+	Position = lexeme.Position
+)
+
+const (
+	SeverityError   = diagnostic.Error
+	SeverityWarning = diagnostic.Warning
+)
+
+const (
+	LexErrorCode       = diagnostic.LexError
+	SyntaxErrorCode    = diagnostic.SyntaxError
+	UnknownNodeCode    = diagnostic.UnknownNode
+	DuplicateNodeCode  = diagnostic.DuplicateNode
+	TypeErrorCode      = diagnostic.TypeError
+	LoopControlCode    = diagnostic.LoopControl
+	LinkErrorCode      = diagnostic.LinkError
+	CodegenFailureCode = diagnostic.CodegenFailure
+)
@@ -0,0 +1,73 @@
+package dialogue
+
+import (
+	"fmt"
+
+	"github.com/mcvoid/dialogue/internal/vm"
+)
+
+type (
+	// RuntimeError is an instruction which failed while a Process ran:
+	// its address, its opcode, the node it was in and why it failed.
+	// Start, StartAt, Resume and ChooseAndResume return one when the
+	// script fails, including when the Handler panics.
+	RuntimeError = vm.RuntimeError
+
+	// FaultCode is the reason an instruction failed.
+	FaultCode = vm.FaultCode
+
+	// FaultAction is what a FaultHandler tells the Process to do about a
+	// RuntimeError.
+	FaultAction = vm.FaultAction
+
+	// FaultHandler decides what to do when an instruction fails.
+	FaultHandler func(err RuntimeError) FaultAction
+)
+
+const (
+	InvalidInstructionFault = vm.InvalidInstruction
+	StackUnderflowFault     = vm.StackUnderflow
+	TypeMismatchFault       = vm.TypeMismatch
+	DivideByZeroFault       = vm.DivideByZero
+	OutOfBoundsFault        = vm.OutOfBounds
+	UnknownFunctionFault    = vm.UnknownFunction
+	BuiltinFailedFault      = vm.BuiltinFailed
+	HandlerPanicFault       = vm.HandlerPanic
+	IndexOutOfRangeFault    = vm.IndexOutOfRange
+	NumberOutOfRangeFault   = vm.NumberOutOfRange
+)
+
+var (
+	// AbortOnFault stops the Process for good and returns the
+	// RuntimeError. It's what happens without a FaultHandler.
+	AbortOnFault = vm.AbortOnFault
+
+	// SkipInstruction carries on after the instruction which failed.
+	SkipInstruction = vm.SkipInstruction
+)
+
+// RecoverAt carries on at the start of the named node. If there's no
+// such node, the Process stops with the RuntimeError.
+func RecoverAt(node string) FaultAction {
+	return vm.RecoverAt(node)
+}
+
+// WithFaultHandler lets h decide what happens when an instruction fails
+// instead of stopping the Process.
+func WithFaultHandler(h FaultHandler) ProcessOption {
+	return ProcessOption{
+		apply: func(po *processOptions) {
+			po.handlesFaults = true
+			po.faultHandler = h
+		},
+	}
+}
+
+func faultHandlerOption(h FaultHandler) (vm.Option, error) {
+	if h == nil {
+		return nil, fmt.Errorf("cannot have nil fault handler")
+	}
+	return vm.HandleFault(func(v *vm.VM, err RuntimeError) FaultAction {
+		return h(err)
+	}), nil
+}
@@ -18,6 +18,23 @@ type CodegenContext struct {
 	Cursor             int
 	Code               []asm.Instruction
 	CurrentNode        ast.Symbol
+	Functions          map[string][]ast.Type
+	Returns            map[string]ast.Type
+	// Locals are the variables declared so far in the current node.
+	Locals map[ast.Symbol]bool
+	// Loops are the loops enclosing the current statement, innermost last.
+	Loops []LoopLabels
+	// ForLoops counts the for loops generated so far, to name the
+	// hidden locals each one keeps its place in.
+	ForLoops int
+	err      error
+}
+
+// LoopLabels are the jump targets of a loop being generated. Breaks are
+// the addresses of the jumps to patch once the loop's end is known.
+type LoopLabels struct {
+	Continue int
+	Breaks   []int
 }
 
 func (ctx *CodegenContext) AddInstruction(instr asm.Instruction) {
@@ -25,6 +42,17 @@ func (ctx *CodegenContext) AddInstruction(instr asm.Instruction) {
 	ctx.Cursor++
 }
 
+// returnsValue reports whether a call to the named extern or builtin
+// function leaves its result on the stack.
+func (ctx *CodegenContext) returnsValue(name string) bool {
+	if _, ok := ctx.Functions[name]; ok {
+		_, ok := ctx.Returns[name]
+		return ok
+	}
+	_, ok := asm.Builtins[name]
+	return ok
+}
+
 func (ctx *CodegenContext) AddSymbol(s ast.Symbol) {
 	ctx.SymbolTable[s] = ctx.Cursor
 	ctx.CurrentNode = s
@@ -35,43 +63,136 @@ func (ctx *CodegenContext) AddBackRef(s ast.Symbol) {
 }
 
 func generateScript(n ast.Script) (program.Program, error) {
-	ctx := CodegenContext{
+	ctx, p, err := generateCode(n)
+	if err != nil {
+		return program.Program{}, err
+	}
+
+	for i, sym := range ctx.BackreferenceTable {
+		dest, ok := ctx.SymbolTable[sym]
+		if !ok {
+			return program.Program{}, fmt.Errorf("unknown symbol %v", sym)
+		}
+		ctx.Code[i].Arg.Val = dest
+	}
+
+	return p, nil
+}
+
+// CodegenObject generates a relocatable object from a script. Nodes the
+// script refers to but doesn't have are left as imports, to be found
+// in another object when the objects are linked.
+func CodegenObject(n ast.Script, name string) (program.Object, error) {
+	ctx, p, err := generateCode(n)
+	if err != nil {
+		return program.Object{}, err
+	}
+
+	obj := program.Object{
+		Format:  program.ObjectFormat,
+		Version: program.ObjectVersion,
+		Name:    name,
+		Program: p,
+		Imports: map[int]string{},
+	}
+	for i, sym := range ctx.BackreferenceTable {
+		if dest, ok := ctx.SymbolTable[sym]; ok {
+			ctx.Code[i].Arg.Val = dest
+			continue
+		}
+		obj.Imports[i] = string(sym)
+	}
+	return obj, nil
+}
+
+// generateCode generates the script's code, leaving the jumps to other
+// nodes in the backreference table to be filled in.
+func generateCode(n ast.Script) (*CodegenContext, program.Program, error) {
+	ctx := &CodegenContext{
 		SymbolTable:        map[ast.Symbol]int{},
 		BackreferenceTable: map[int]ast.Symbol{},
 		Cursor:             0,
 		Code:               []asm.Instruction{},
+		Functions:          n.Functions,
+		Returns:            n.Returns,
+	}
+
+	funcs := generatePrototypes(n.Functions)
+	returns := map[string]asm.Type{}
+	for name, returnType := range n.Returns {
+		returns[name] = astTypeToAsmType[returnType]
 	}
 
+	entry := 0
 	if len(n.Nodes) == 0 {
 		ctx.AddInstruction(asm.Instruction{Opcode: asm.EndDialogue})
-		return program.Program{
-			Start: 0,
-			Code:  ctx.Code,
-			Funcs: map[string][]asm.Type{},
-		}, nil
+	} else {
+		generateGlobals(ctx, n.Variables)
+		entry = ctx.Cursor
+		for _, block := range n.Nodes {
+			generateBlock(ctx, block)
+		}
+		if ctx.err != nil {
+			return nil, program.Program{}, ctx.err
+		}
 	}
 
-	for _, block := range n.Nodes {
-		generateBlock(&ctx, block)
+	nodes := map[string]int{}
+	for sym, addr := range ctx.SymbolTable {
+		nodes[string(sym)] = addr
 	}
+	return ctx, program.Program{
+		Start:   0,
+		Code:    ctx.Code,
+		Entry:   entry,
+		Nodes:   nodes,
+		Funcs:   funcs,
+		Returns: returns,
+	}, nil
+}
 
-	for i, sym := range ctx.BackreferenceTable {
-		dest, ok := ctx.SymbolTable[sym]
-		if !ok {
-			return program.Program{}, fmt.Errorf("unknown symbol %v", sym)
+var astTypeToAsmType = map[ast.Type]asm.Type{
+	ast.BooleanType: asm.BooleanType,
+	ast.NumberType:  asm.NumberType,
+	ast.StringType:  asm.StringType,
+	ast.NullType:    asm.NullType,
+	ast.ListType:    asm.ListType,
+	ast.MapType:     asm.MapType,
+}
+
+// generatePrototypes translates the extern declarations into the
+// parameter types the VM checks Call arguments against.
+func generatePrototypes(functions map[string][]ast.Type) map[string][]asm.Type {
+	funcs := map[string][]asm.Type{}
+	for name, params := range functions {
+		proto := []asm.Type{}
+		for _, param := range params {
+			proto = append(proto, astTypeToAsmType[param])
 		}
-		ctx.Code[i].Arg.Val = dest
+		funcs[name] = proto
 	}
+	return funcs
+}
 
-	return program.Program{
-		Start: 0,
-		Code:  ctx.Code,
-		Funcs: map[string][]asm.Type{},
-	}, nil
+// generateGlobals gives the script's variables their initial values
+// before the first node runs. Variables which already have a value,
+// from an earlier run or from the game, keep it.
+func generateGlobals(ctx *CodegenContext, variables []ast.VariableDecl) {
+	for _, v := range variables {
+		if v.Scope != ast.GlobalScope {
+			continue
+		}
+		GenerateExpression(ctx, v.Val)
+		ctx.AddInstruction(asm.Instruction{
+			Opcode: asm.InitVariable,
+			Arg:    asm.Value{Type: asm.SymbolType, Val: string(v.Name)},
+		})
+	}
 }
 
 func generateBlock(ctx *CodegenContext, n ast.Node) {
 	ctx.AddSymbol(n.Name)
+	ctx.Locals = map[ast.Symbol]bool{}
 	var nodeString string = string(n.Name)
 	ctx.AddInstruction(asm.Instruction{
 		Opcode: asm.EnterNode,
@@ -104,18 +225,27 @@ func GenerateBlockElement(ctx *CodegenContext, n ast.BlockElement) {
 }
 
 func GenerateParagraph(ctx *CodegenContext, n ast.Paragraph) {
+	GenerateInlines(ctx, n)
+	ctx.AddInstruction(asm.Instruction{Opcode: asm.ShowLine})
+}
+
+// GenerateInlines pushes the inlines joined into a single string.
+func GenerateInlines(ctx *CodegenContext, n ast.Paragraph) {
+	if len(n) == 0 {
+		GenerateText(ctx, "")
+		return
+	}
 	for i, inline := range n {
 		GenerateInline(ctx, inline)
 		if i > 0 {
 			ctx.AddInstruction(asm.Instruction{Opcode: asm.Concat})
 		}
 	}
-	ctx.AddInstruction(asm.Instruction{Opcode: asm.ShowLine})
 }
 
 func GenerateLink(ctx *CodegenContext, n ast.Link) {
 	var nodeName string = string(ctx.CurrentNode)
-	GenerateInline(ctx, n.Text)
+	GenerateInlines(ctx, n.Text)
 	ctx.AddInstruction(asm.Instruction{Opcode: asm.ShowLine})
 	ctx.AddInstruction(asm.Instruction{
 		Opcode: asm.ExitNode,
@@ -131,12 +261,24 @@ func GenerateLink(ctx *CodegenContext, n ast.Link) {
 func GenerateOption(ctx *CodegenContext, n ast.Option) {
 	var nodeName string = string(ctx.CurrentNode)
 	for _, link := range n {
-		GenerateInline(ctx, link.Text)
+		guard := -1
+		if link.Guard != nil {
+			GenerateExpression(ctx, link.Guard)
+			guard = ctx.Cursor
+			ctx.AddInstruction(asm.Instruction{Opcode: asm.JumpIfFalse})
+		}
+		GenerateInlines(ctx, link.Text)
 		ctx.AddBackRef(link.Dest)
 		ctx.AddInstruction(asm.Instruction{
 			Opcode: asm.PushChoice,
 			Arg:    asm.Value{Type: asm.NumberType, Val: 0},
 		})
+		if guard >= 0 {
+			ctx.Code[guard] = asm.Instruction{
+				Opcode: asm.JumpIfFalse,
+				Arg:    asm.Value{Type: asm.NumberType, Val: ctx.Cursor},
+			}
+		}
 	}
 	ctx.AddInstruction(asm.Instruction{
 		Opcode: asm.ExitNode,
@@ -178,6 +320,10 @@ func GenerateStatement(ctx *CodegenContext, n ast.Statement) {
 		GenerateAssignment(ctx, n)
 	case ast.FunctionCall:
 		GenerateFunctionCall(ctx, n)
+		if ctx.returnsValue(string(n.Name)) {
+			// the result isn't used
+			ctx.AddInstruction(asm.Instruction{Opcode: asm.PopValue})
+		}
 	case ast.StatementBlock:
 		GenerateStatementBlock(ctx, n)
 	case ast.GotoNode:
@@ -188,18 +334,67 @@ func GenerateStatement(ctx *CodegenContext, n ast.Statement) {
 		GenerateLoop(ctx, n)
 	case ast.InfiniteLoop:
 		GenerateInfiniteLoop(ctx, n)
+	case ast.ForLoop:
+		GenerateForLoop(ctx, n)
+	case ast.IndexAssignment:
+		GenerateIndexAssignment(ctx, n)
+	case ast.Break:
+		GenerateBreak(ctx, n)
+	case ast.Continue:
+		GenerateContinue(ctx, n)
+	case ast.VariableDecl:
+		GenerateLocalDeclaration(ctx, n)
 	}
 }
 
 func GenerateAssignment(ctx *CodegenContext, n ast.Assignment) {
 	GenerateExpression(ctx, n.Val)
 	var varName string = string(n.Name)
+	opcode := asm.StoreVariable
+	if ctx.Locals[n.Name] {
+		opcode = asm.StoreLocal
+	}
+	ctx.AddInstruction(asm.Instruction{
+		Opcode: opcode,
+		Arg:    asm.Value{Type: asm.SymbolType, Val: varName},
+	})
+}
+
+// GenerateIndexAssignment stores a copy of the variable's list or map
+// with the item or entry at the key set.
+func GenerateIndexAssignment(ctx *CodegenContext, n ast.IndexAssignment) {
+	var varName string = string(n.Name)
+	load, store := asm.LoadVariable, asm.StoreVariable
+	if ctx.Locals[n.Name] {
+		load, store = asm.LoadLocal, asm.StoreLocal
+	}
+	ctx.AddInstruction(asm.Instruction{
+		Opcode: load,
+		Arg:    asm.Value{Type: asm.SymbolType, Val: varName},
+	})
+	GenerateExpression(ctx, n.Key)
+	GenerateExpression(ctx, n.Val)
+	ctx.AddInstruction(asm.Instruction{Opcode: asm.SetIndex})
 	ctx.AddInstruction(asm.Instruction{
-		Opcode: asm.StoreVariable,
+		Opcode: store,
 		Arg:    asm.Value{Type: asm.SymbolType, Val: varName},
 	})
 }
 
+// GenerateLocalDeclaration stores a local's initial value. From then
+// on, the name refers to the local until the node exits.
+func GenerateLocalDeclaration(ctx *CodegenContext, n ast.VariableDecl) {
+	GenerateExpression(ctx, n.Val)
+	if ctx.Locals == nil {
+		ctx.Locals = map[ast.Symbol]bool{}
+	}
+	ctx.Locals[n.Name] = true
+	ctx.AddInstruction(asm.Instruction{
+		Opcode: asm.StoreLocal,
+		Arg:    asm.Value{Type: asm.SymbolType, Val: string(n.Name)},
+	})
+}
+
 func GenerateStatementBlock(ctx *CodegenContext, n ast.StatementBlock) {
 	for _, statement := range n {
 		GenerateStatement(ctx, statement)
@@ -226,6 +421,29 @@ func GenerateExpression(ctx *CodegenContext, n ast.Expression) {
 		GenerateUnaryOp(ctx, n)
 	case ast.Literal:
 		GenerateLiteral(ctx, n)
+	case ast.FunctionCall:
+		GenerateFunctionCall(ctx, n)
+	case ast.ListLiteral:
+		for _, item := range n.Items {
+			GenerateExpression(ctx, item)
+		}
+		ctx.AddInstruction(asm.Instruction{
+			Opcode: asm.MakeList,
+			Arg:    asm.Value{Type: asm.NumberType, Val: len(n.Items)},
+		})
+	case ast.MapLiteral:
+		for _, entry := range n.Entries {
+			GenerateExpression(ctx, entry.Key)
+			GenerateExpression(ctx, entry.Val)
+		}
+		ctx.AddInstruction(asm.Instruction{
+			Opcode: asm.MakeMap,
+			Arg:    asm.Value{Type: asm.NumberType, Val: len(n.Entries)},
+		})
+	case ast.Index:
+		GenerateExpression(ctx, n.Container)
+		GenerateExpression(ctx, n.Key)
+		ctx.AddInstruction(asm.Instruction{Opcode: asm.Index})
 	}
 }
 
@@ -252,10 +470,19 @@ func GenerateLiteral(ctx *CodegenContext, n ast.Literal) {
 			Arg:    asm.Value{Type: asm.BooleanType, Val: n.Val},
 		})
 	case ast.SymbolType:
-		symNode := n.Val.(ast.Symbol)
-		var varName string = string(symNode)
+		var varName string
+		switch sym := n.Val.(type) {
+		case ast.Symbol:
+			varName = string(sym)
+		case string:
+			varName = sym
+		}
+		opcode := asm.LoadVariable
+		if ctx.Locals[ast.Symbol(varName)] {
+			opcode = asm.LoadLocal
+		}
 		ctx.AddInstruction(asm.Instruction{
-			Opcode: asm.LoadVariable,
+			Opcode: opcode,
 			Arg:    asm.Value{Type: asm.SymbolType, Val: varName},
 		})
 	}
@@ -312,6 +539,7 @@ func GenerateLoop(ctx *CodegenContext, n ast.Loop) {
 	GenerateExpression(ctx, n.Cond)
 	cond := ctx.Cursor
 	ctx.AddInstruction(asm.Instruction{Opcode: asm.JumpIfFalse})
+	ctx.Loops = append(ctx.Loops, LoopLabels{Continue: loopStart})
 	GenerateStatement(ctx, n.Consequent)
 	ctx.AddInstruction(asm.Instruction{
 		Opcode: asm.Jump,
@@ -321,15 +549,127 @@ func GenerateLoop(ctx *CodegenContext, n ast.Loop) {
 		Opcode: asm.JumpIfFalse,
 		Arg:    asm.Value{Type: asm.NumberType, Val: ctx.Cursor},
 	}
+	endLoop(ctx)
 }
 
 func GenerateInfiniteLoop(ctx *CodegenContext, n ast.InfiniteLoop) {
 	loopStart := ctx.Cursor
+	ctx.Loops = append(ctx.Loops, LoopLabels{Continue: loopStart})
 	GenerateStatement(ctx, n.Consequent)
 	ctx.AddInstruction(asm.Instruction{
 		Opcode: asm.Jump,
 		Arg:    asm.Value{Type: asm.NumberType, Val: loopStart},
 	})
+	endLoop(ctx)
+}
+
+// GenerateForLoop keeps the list being looped over and the index of
+// the current item in hidden locals, which can't clash with a
+// variable's name. The index is incremented at the top so that continue
+// has somewhere to go before the body is generated:
+//
+//	    list = Iterate(iterable); index = 0; jump check
+//	next:  index++
+//	check: if !(index < Length(list)) jump end
+//	    item = list[index]; body; jump next
+//	end:
+func GenerateForLoop(ctx *CodegenContext, n ast.ForLoop) {
+	list := fmt.Sprintf("for#%d.list", ctx.ForLoops)
+	index := fmt.Sprintf("for#%d.index", ctx.ForLoops)
+	ctx.ForLoops++
+	local := func(opcode asm.Opcode, name string) {
+		ctx.AddInstruction(asm.Instruction{
+			Opcode: opcode,
+			Arg:    asm.Value{Type: asm.SymbolType, Val: name},
+		})
+	}
+
+	GenerateExpression(ctx, n.Iterable)
+	ctx.AddInstruction(asm.Instruction{Opcode: asm.Iterate})
+	local(asm.StoreLocal, list)
+	ctx.AddInstruction(asm.Instruction{
+		Opcode: asm.PushNumber,
+		Arg:    asm.Value{Type: asm.NumberType, Val: 0},
+	})
+	local(asm.StoreLocal, index)
+	skip := ctx.Cursor
+	ctx.AddInstruction(asm.Instruction{Opcode: asm.Jump})
+
+	next := ctx.Cursor
+	local(asm.LoadLocal, index)
+	ctx.AddInstruction(asm.Instruction{Opcode: asm.Increment})
+	local(asm.StoreLocal, index)
+
+	ctx.Code[skip] = asm.Instruction{
+		Opcode: asm.Jump,
+		Arg:    asm.Value{Type: asm.NumberType, Val: ctx.Cursor},
+	}
+	local(asm.LoadLocal, index)
+	local(asm.LoadLocal, list)
+	ctx.AddInstruction(asm.Instruction{Opcode: asm.Length})
+	ctx.AddInstruction(asm.Instruction{Opcode: asm.Lessthan})
+	cond := ctx.Cursor
+	ctx.AddInstruction(asm.Instruction{Opcode: asm.JumpIfFalse})
+
+	local(asm.LoadLocal, list)
+	local(asm.LoadLocal, index)
+	ctx.AddInstruction(asm.Instruction{Opcode: asm.Index})
+	if ctx.Locals == nil {
+		ctx.Locals = map[ast.Symbol]bool{}
+	}
+	// the loop variable goes out of scope after the loop's body
+	outer := ctx.Locals[n.Name]
+	ctx.Locals[n.Name] = true
+	local(asm.StoreLocal, string(n.Name))
+
+	ctx.Loops = append(ctx.Loops, LoopLabels{Continue: next})
+	GenerateStatement(ctx, n.Consequent)
+	if !outer {
+		delete(ctx.Locals, n.Name)
+	}
+	ctx.AddInstruction(asm.Instruction{
+		Opcode: asm.Jump,
+		Arg:    asm.Value{Type: asm.NumberType, Val: next},
+	})
+	ctx.Code[cond] = asm.Instruction{
+		Opcode: asm.JumpIfFalse,
+		Arg:    asm.Value{Type: asm.NumberType, Val: ctx.Cursor},
+	}
+	endLoop(ctx)
+}
+
+// endLoop points the innermost loop's breaks at the cursor and leaves
+// the loop.
+func endLoop(ctx *CodegenContext) {
+	loop := ctx.Loops[len(ctx.Loops)-1]
+	ctx.Loops = ctx.Loops[:len(ctx.Loops)-1]
+	for _, i := range loop.Breaks {
+		ctx.Code[i] = asm.Instruction{
+			Opcode: asm.Jump,
+			Arg:    asm.Value{Type: asm.NumberType, Val: ctx.Cursor},
+		}
+	}
+}
+
+func GenerateBreak(ctx *CodegenContext, n ast.Break) {
+	if len(ctx.Loops) == 0 {
+		ctx.err = fmt.Errorf("break outside of a loop")
+		return
+	}
+	loop := &ctx.Loops[len(ctx.Loops)-1]
+	loop.Breaks = append(loop.Breaks, ctx.Cursor)
+	ctx.AddInstruction(asm.Instruction{Opcode: asm.Jump})
+}
+
+func GenerateContinue(ctx *CodegenContext, n ast.Continue) {
+	if len(ctx.Loops) == 0 {
+		ctx.err = fmt.Errorf("continue outside of a loop")
+		return
+	}
+	ctx.AddInstruction(asm.Instruction{
+		Opcode: asm.Jump,
+		Arg:    asm.Value{Type: asm.NumberType, Val: ctx.Loops[len(ctx.Loops)-1].Continue},
+	})
 }
 
 func GenerateUnaryOp(ctx *CodegenContext, n ast.UnaryOp) {
@@ -21,6 +21,20 @@ func compareProgram(a, b program.Program) bool {
 			return false
 		}
 	}
+	if len(a.Funcs) != len(b.Funcs) {
+		return false
+	}
+	for name, proto := range a.Funcs {
+		proto2, ok := b.Funcs[name]
+		if !ok || len(proto) != len(proto2) {
+			return false
+		}
+		for i := range proto {
+			if proto[i] != proto2[i] {
+				return false
+			}
+		}
+	}
 
 	return true
 }
@@ -67,7 +81,7 @@ func TestCodegen(t *testing.T) {
 				{
 					Name: "Node1",
 					Body: []ast.BlockElement{
-						ast.Link{Dest: "Node2", Text: ast.Text("Go to Node2")},
+						ast.Link{Dest: "Node2", Text: ast.Paragraph{ast.Text("Go to Node2")}},
 					},
 				},
 				{
@@ -97,7 +111,7 @@ func TestCodegen(t *testing.T) {
 				{
 					Name: "Node1",
 					Body: []ast.BlockElement{
-						ast.Link{Dest: "Node3", Text: ast.Text("Go to Node3")},
+						ast.Link{Dest: "Node3", Text: ast.Paragraph{ast.Text("Go to Node3")}},
 					},
 				},
 			},
@@ -170,8 +184,8 @@ func TestCodegen(t *testing.T) {
 					Name: "Node1",
 					Body: []ast.BlockElement{
 						ast.Option{
-							{Dest: "Node1", Text: ast.Text("Go to Node1")},
-							{Dest: "Node2", Text: ast.Text("Go to Node2")},
+							{Dest: "Node1", Text: ast.Paragraph{ast.Text("Go to Node1")}},
+							{Dest: "Node2", Text: ast.Paragraph{ast.Text("Go to Node2")}},
 						},
 					},
 				},
@@ -199,6 +213,74 @@ func TestCodegen(t *testing.T) {
 			},
 			hasError: false,
 		},
+		{
+			name: "guarded option",
+			ast: []ast.Node{
+				{
+					Name: "Node1",
+					Body: []ast.BlockElement{
+						ast.Option{
+							{Dest: "Node1", Text: ast.Paragraph{ast.Text("Open")}, Guard: ast.Literal{Type: ast.SymbolType, Val: ast.Symbol("has_key")}},
+							{Dest: "Node1", Text: ast.Paragraph{ast.Text("Leave")}},
+						},
+					},
+				},
+			},
+			expected: program.Program{
+				Start: 0,
+				Code: []asm.Instruction{
+					{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "Node1"}},
+					{Opcode: asm.LoadVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "has_key"}},
+					{Opcode: asm.JumpIfFalse, Arg: asm.Value{Type: asm.NumberType, Val: 5}},
+					{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "Open"}},
+					{Opcode: asm.PushChoice, Arg: asm.Value{Type: asm.NumberType, Val: 0}},
+					{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "Leave"}},
+					{Opcode: asm.PushChoice, Arg: asm.Value{Type: asm.NumberType, Val: 0}},
+					{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "Node1"}},
+					{Opcode: asm.ShowChoice},
+					{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "Node1"}},
+					{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+				},
+			},
+			hasError: false,
+		},
+		{
+			name: "option with inline code",
+			ast: []ast.Node{
+				{
+					Name: "Node1",
+					Body: []ast.BlockElement{
+						ast.Option{
+							{Dest: "Node1", Text: ast.Paragraph{
+								ast.Text("Buy for "),
+								ast.InlineCode{Expr: ast.Literal{Type: ast.SymbolType, Val: ast.Symbol("price")}},
+								ast.Text(" gold"),
+							}},
+							{Dest: "Node1", Text: ast.Paragraph{}},
+						},
+					},
+				},
+			},
+			expected: program.Program{
+				Start: 0,
+				Code: []asm.Instruction{
+					{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "Node1"}},
+					{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "Buy for "}},
+					{Opcode: asm.LoadVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "price"}},
+					{Opcode: asm.Concat},
+					{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: " gold"}},
+					{Opcode: asm.Concat},
+					{Opcode: asm.PushChoice, Arg: asm.Value{Type: asm.NumberType, Val: 0}},
+					{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: ""}},
+					{Opcode: asm.PushChoice, Arg: asm.Value{Type: asm.NumberType, Val: 0}},
+					{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "Node1"}},
+					{Opcode: asm.ShowChoice},
+					{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "Node1"}},
+					{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+				},
+			},
+			hasError: false,
+		},
 		{
 			name: "assignment",
 			ast: []ast.Node{
@@ -467,6 +549,72 @@ func TestCodegen(t *testing.T) {
 			},
 			hasError: false,
 		},
+		{
+			name: "break and continue",
+			ast: []ast.Node{
+				{
+					Name: "Node1",
+					Body: []ast.BlockElement{
+						ast.CodeBlock{
+							Code: []ast.Statement{ast.Loop{
+								Cond: ast.Literal{Type: ast.SymbolType, Val: "val1"},
+								Consequent: ast.StatementBlock{
+									ast.InfiniteLoop{Consequent: ast.Break{}},
+									ast.Conditional{
+										Cond:       ast.Literal{Type: ast.SymbolType, Val: "val2"},
+										Consequent: ast.Break{},
+										Alternate:  ast.StatementBlock{},
+									},
+									ast.Continue{},
+								},
+							}},
+						},
+					},
+				},
+			},
+			expected: program.Program{
+				Start: 0,
+				Code: []asm.Instruction{
+					{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "Node1"}},
+					{Opcode: asm.LoadVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "val1"}},
+					{Opcode: asm.JumpIfFalse, Arg: asm.Value{Type: asm.NumberType, Val: 10}},
+					{Opcode: asm.Jump, Arg: asm.Value{Type: asm.NumberType, Val: 5}},
+					{Opcode: asm.Jump, Arg: asm.Value{Type: asm.NumberType, Val: 3}},
+					{Opcode: asm.LoadVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "val2"}},
+					{Opcode: asm.JumpIfFalse, Arg: asm.Value{Type: asm.NumberType, Val: 8}},
+					{Opcode: asm.Jump, Arg: asm.Value{Type: asm.NumberType, Val: 10}},
+					{Opcode: asm.Jump, Arg: asm.Value{Type: asm.NumberType, Val: 1}},
+					{Opcode: asm.Jump, Arg: asm.Value{Type: asm.NumberType, Val: 1}},
+					{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "Node1"}},
+					{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+				},
+			},
+			hasError: false,
+		},
+		{
+			name: "break outside of a loop",
+			ast: []ast.Node{
+				{
+					Name: "Node1",
+					Body: []ast.BlockElement{
+						ast.CodeBlock{Code: []ast.Statement{ast.Break{}}},
+					},
+				},
+			},
+			hasError: true,
+		},
+		{
+			name: "continue outside of a loop",
+			ast: []ast.Node{
+				{
+					Name: "Node1",
+					Body: []ast.BlockElement{
+						ast.CodeBlock{Code: []ast.Statement{ast.Continue{}}},
+					},
+				},
+			},
+			hasError: true,
+		},
 		{
 			name: "add",
 			ast: []ast.Node{
@@ -1083,3 +1231,193 @@ func TestCodegen(t *testing.T) {
 		})
 	}
 }
+
+func TestCodegenPrototypes(t *testing.T) {
+	p, err := Codegen(ast.Script{
+		Functions: map[string][]ast.Type{
+			"func1": {ast.BooleanType, ast.NumberType, ast.StringType, ast.NullType},
+			"func2": {},
+		},
+		Nodes: []ast.Node{},
+	})
+	if err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	expected := program.Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+		},
+		Funcs: map[string][]asm.Type{
+			"func1": {asm.BooleanType, asm.NumberType, asm.StringType, asm.NullType},
+			"func2": {},
+		},
+	}
+	if !compareProgram(p, expected) {
+		t.Errorf("Expected %v got %v", expected, p)
+	}
+}
+
+func TestCodegenCallResults(t *testing.T) {
+	p, err := Codegen(ast.Script{
+		Functions: map[string][]ast.Type{
+			"has_item": {ast.StringType},
+		},
+		Returns: map[string]ast.Type{
+			"has_item": ast.BooleanType,
+		},
+		Nodes: []ast.Node{{Name: "abc", Body: []ast.BlockElement{ast.CodeBlock{Code: []ast.Statement{
+			ast.Assignment{Name: "x", Val: ast.FunctionCall{
+				Name:   "has_item",
+				Params: []ast.Expression{ast.Literal{Type: ast.StringType, Val: "key"}},
+			}},
+			ast.FunctionCall{
+				Name:   "has_item",
+				Params: []ast.Expression{ast.Literal{Type: ast.StringType, Val: "key"}},
+			},
+		}}}}},
+	})
+	if err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	expected := program.Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "abc"}},
+			{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "key"}},
+			{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "has_item"}},
+			{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "x"}},
+			{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "key"}},
+			{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "has_item"}},
+			{Opcode: asm.PopValue},
+			{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "abc"}},
+			{Opcode: asm.EndDialogue},
+		},
+		Funcs: map[string][]asm.Type{
+			"has_item": {asm.StringType},
+		},
+	}
+	if !compareProgram(p, expected) {
+		t.Errorf("Expected %v got %v", expected, p)
+	}
+	if p.Returns["has_item"] != asm.BooleanType {
+		t.Errorf("expected has_item to return %v got %v", asm.BooleanType, p.Returns)
+	}
+}
+
+func TestCodegenBuiltinCall(t *testing.T) {
+	p, err := Codegen(ast.Script{
+		Nodes: []ast.Node{{Name: "abc", Body: []ast.BlockElement{ast.CodeBlock{Code: []ast.Statement{
+			ast.FunctionCall{
+				Name:   "upper",
+				Params: []ast.Expression{ast.Literal{Type: ast.StringType, Val: "key"}},
+			},
+		}}}}},
+	})
+	if err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	expected := program.Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "abc"}},
+			{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "key"}},
+			{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "upper"}},
+			{Opcode: asm.PopValue},
+			{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "abc"}},
+			{Opcode: asm.EndDialogue},
+		},
+		Funcs: map[string][]asm.Type{},
+	}
+	if !compareProgram(p, expected) {
+		t.Errorf("Expected %v got %v", expected, p)
+	}
+}
+
+func TestCodegenVariables(t *testing.T) {
+	p, err := Codegen(ast.Script{
+		Variables: []ast.VariableDecl{
+			{Name: "gold", Type: ast.NumberType, Scope: ast.GlobalScope, Val: ast.Literal{Type: ast.NumberType, Val: 5}},
+			{Name: "name", Type: ast.StringType, Scope: ast.ExternScope},
+		},
+		Nodes: []ast.Node{{Name: "abc", Body: []ast.BlockElement{ast.CodeBlock{Code: []ast.Statement{
+			ast.Assignment{Name: "x", Val: ast.Literal{Type: ast.SymbolType, Val: "gold"}},
+			ast.VariableDecl{Name: "x", Scope: ast.LocalScope, Val: ast.Literal{Type: ast.NumberType, Val: 1}},
+			ast.Assignment{Name: "x", Val: ast.Literal{Type: ast.SymbolType, Val: "x"}},
+		}}}}},
+	})
+	if err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	expected := program.Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 5}},
+			{Opcode: asm.InitVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "gold"}},
+			{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "abc"}},
+			{Opcode: asm.LoadVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "gold"}},
+			{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "x"}},
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 1}},
+			{Opcode: asm.StoreLocal, Arg: asm.Value{Type: asm.SymbolType, Val: "x"}},
+			{Opcode: asm.LoadLocal, Arg: asm.Value{Type: asm.SymbolType, Val: "x"}},
+			{Opcode: asm.StoreLocal, Arg: asm.Value{Type: asm.SymbolType, Val: "x"}},
+			{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "abc"}},
+			{Opcode: asm.EndDialogue},
+		},
+		Funcs: map[string][]asm.Type{},
+	}
+	if !compareProgram(p, expected) {
+		t.Errorf("Expected %v got %v", expected, p)
+	}
+}
+
+func TestCodegenObject(t *testing.T) {
+	obj, err := CodegenObject(ast.Script{
+		Variables: []ast.VariableDecl{
+			{Name: "gold", Type: ast.NumberType, Scope: ast.GlobalScope, Val: ast.Literal{Type: ast.NumberType, Val: 5}},
+		},
+		Nodes: []ast.Node{
+			{Name: "a.start", Body: []ast.BlockElement{
+				ast.CodeBlock{Code: []ast.Statement{ast.GotoNode{Name: "b.end"}}},
+			}},
+			{Name: "a.next", Body: []ast.BlockElement{
+				ast.Link{Dest: "a.start", Text: ast.Paragraph{}},
+			}},
+		},
+	}, "a.md")
+	if err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	expected := program.Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 5}},
+			{Opcode: asm.InitVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "gold"}},
+			{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "a.start"}},
+			{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "a.start"}},
+			{Opcode: asm.Jump, Arg: asm.Value{Type: asm.NumberType, Val: 0}},
+			{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "a.start"}},
+			{Opcode: asm.EndDialogue},
+			{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "a.next"}},
+			{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: ""}},
+			{Opcode: asm.ShowLine},
+			{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "a.next"}},
+			{Opcode: asm.Jump, Arg: asm.Value{Type: asm.NumberType, Val: 2}},
+			{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "a.next"}},
+			{Opcode: asm.EndDialogue},
+		},
+		Funcs: map[string][]asm.Type{},
+	}
+	if !compareProgram(obj.Program, expected) {
+		t.Errorf("Expected %v got %v", expected, obj.Program)
+	}
+	if obj.Format != program.ObjectFormat || obj.Version != program.ObjectVersion || obj.Name != "a.md" || obj.Program.Entry != 2 {
+		t.Errorf("expected an object for a.md entered at 2, got %v", obj)
+	}
+	if len(obj.Program.Nodes) != 2 || obj.Program.Nodes["a.start"] != 2 || obj.Program.Nodes["a.next"] != 7 {
+		t.Errorf("expected both nodes to be exported, got %v", obj.Program.Nodes)
+	}
+	if len(obj.Imports) != 1 || obj.Imports[4] != "b.end" {
+		t.Errorf("expected b.end to be imported, got %v", obj.Imports)
+	}
+}
@@ -10,19 +10,37 @@ import (
 )
 
 type Lexer struct {
-	input string
-	start int
-	pos   int
-	width int
-	items []lexeme.Item
-	state State
+	filename string
+	input    string
+	start    int
+	pos      int
+	width    int
+	line     int
+	column   int
+	items    []lexeme.Item
+	state    State
+	// afterInlineCode is where lexing picks up after inline code
+	afterInlineCode State
+	// inGuard ends code at the end of the line for an option's guard
+	inGuard bool
+	// inInitializer returns to the front matter after a variable's
+	// initial value
+	inInitializer bool
 }
 
 func New(input string) *Lexer {
+	return NewFile("", input)
+}
+
+// NewFile creates a lexer whose tokens report positions in the named file.
+func NewFile(filename, input string) *Lexer {
 	l := Lexer{
-		input: input,
-		items: []lexeme.Item{},
-		state: LexFrontMatter,
+		filename: filename,
+		input:    input,
+		line:     1,
+		column:   1,
+		items:    []lexeme.Item{},
+		state:    LexFrontMatter,
 	}
 	return &l
 }
@@ -33,14 +51,42 @@ func run(l *Lexer) {
 	}
 }
 
+// position computes the source position of an offset at or after l.start.
+func position(l *Lexer, offset int) lexeme.Position {
+	line, column := l.line, l.column
+	for _, r := range l.input[l.start:offset] {
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return lexeme.Position{
+		File:   l.filename,
+		Offset: offset,
+		Line:   line,
+		Column: column,
+	}
+}
+
+// skipTo moves the start of the next token to l.pos, keeping the
+// line and column counters in step.
+func skipTo(l *Lexer) {
+	p := position(l, l.pos)
+	l.line, l.column = p.Line, p.Column
+	l.start = l.pos
+}
+
 func emit(l *Lexer, t lexeme.ItemType) {
 	substr := l.input[l.start:l.pos]
 	item := lexeme.Item{
 		Type: t,
 		Val:  substr,
+		Pos:  position(l, l.start),
 	}
 	l.items = append(l.items, item)
-	l.start = l.pos
+	skipTo(l)
 }
 
 func next(l *Lexer) (rune, error) {
@@ -56,7 +102,7 @@ func next(l *Lexer) (rune, error) {
 }
 
 func ignore(l *Lexer) {
-	l.start = l.pos
+	skipTo(l)
 }
 
 func backup(l *Lexer) {
@@ -102,6 +148,7 @@ func errorf(l *Lexer, format string, args ...interface{}) State {
 	l.items = append(l.items, lexeme.Item{
 		Type: lexeme.Error,
 		Val:  fmt.Sprintf(format, args...),
+		Pos:  position(l, l.pos),
 	})
 	return nil
 }
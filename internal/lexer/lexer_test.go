@@ -27,6 +27,16 @@ func TestLexText(t *testing.T) {
 				{Type: lexeme.Eof, Val: ""},
 			},
 		},
+		"entry header": {
+			input: "# abc @entry\n",
+			tokens: []lexeme.Item{
+				{Type: lexeme.Hash, Val: "#"},
+				{Type: lexeme.Symbol, Val: "abc"},
+				{Type: lexeme.EntryAnnotation, Val: "@entry"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Eof, Val: ""},
+			},
+		},
 		"text, link, list": {
 			input: `
 abc def ghi
@@ -56,6 +66,88 @@ abc`,
 				{Type: lexeme.Eof, Val: ""},
 			},
 		},
+		"link with inline code": {
+			input: "[shop](Buy for `price` gold)\n- [shop](`price`)\n",
+			tokens: []lexeme.Item{
+				{Type: lexeme.OpenSquareBrace, Val: "["},
+				{Type: lexeme.Symbol, Val: "shop"},
+				{Type: lexeme.CloseSquareBrace, Val: "]"},
+				{Type: lexeme.OpenParen, Val: "("},
+				{Type: lexeme.TextLiteral, Val: "Buy for "},
+				{Type: lexeme.OpenInlineCode, Val: "`"},
+				{Type: lexeme.Symbol, Val: "price"},
+				{Type: lexeme.CloseInlineCode, Val: "`"},
+				{Type: lexeme.TextLiteral, Val: " gold"},
+				{Type: lexeme.CloseParen, Val: ")"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.ListItemPrefix, Val: "-"},
+				{Type: lexeme.OpenSquareBrace, Val: "["},
+				{Type: lexeme.Symbol, Val: "shop"},
+				{Type: lexeme.CloseSquareBrace, Val: "]"},
+				{Type: lexeme.OpenParen, Val: "("},
+				{Type: lexeme.OpenInlineCode, Val: "`"},
+				{Type: lexeme.Symbol, Val: "price"},
+				{Type: lexeme.CloseInlineCode, Val: "`"},
+				{Type: lexeme.CloseParen, Val: ")"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Eof, Val: ""},
+			},
+		},
+		"qualified node names": {
+			input: "[shop.greeting](hi)\n```\ngoto inn.entry;x = a.b;\n```\n",
+			tokens: []lexeme.Item{
+				{Type: lexeme.OpenSquareBrace, Val: "["},
+				{Type: lexeme.Symbol, Val: "shop.greeting"},
+				{Type: lexeme.CloseSquareBrace, Val: "]"},
+				{Type: lexeme.OpenParen, Val: "("},
+				{Type: lexeme.TextLiteral, Val: "hi"},
+				{Type: lexeme.CloseParen, Val: ")"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.OpenCodeFence, Val: "```"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.GotoLiteral, Val: "goto"},
+				{Type: lexeme.Symbol, Val: "inn.entry"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.Symbol, Val: "x"},
+				{Type: lexeme.Eq, Val: "="},
+				{Type: lexeme.Symbol, Val: "a"},
+				{Type: lexeme.Dot, Val: "."},
+				{Type: lexeme.Symbol, Val: "b"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.CloseCodeFence, Val: "```"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Eof, Val: ""},
+			},
+		},
+		"option guard": {
+			input: "- [shop](buy) if gold >= 10\n- [shop](sell)  if !sold \n",
+			tokens: []lexeme.Item{
+				{Type: lexeme.ListItemPrefix, Val: "-"},
+				{Type: lexeme.OpenSquareBrace, Val: "["},
+				{Type: lexeme.Symbol, Val: "shop"},
+				{Type: lexeme.CloseSquareBrace, Val: "]"},
+				{Type: lexeme.OpenParen, Val: "("},
+				{Type: lexeme.TextLiteral, Val: "buy"},
+				{Type: lexeme.CloseParen, Val: ")"},
+				{Type: lexeme.IfLiteral, Val: "if"},
+				{Type: lexeme.Symbol, Val: "gold"},
+				{Type: lexeme.Gte, Val: ">="},
+				{Type: lexeme.Number, Val: "10"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.ListItemPrefix, Val: "-"},
+				{Type: lexeme.OpenSquareBrace, Val: "["},
+				{Type: lexeme.Symbol, Val: "shop"},
+				{Type: lexeme.CloseSquareBrace, Val: "]"},
+				{Type: lexeme.OpenParen, Val: "("},
+				{Type: lexeme.TextLiteral, Val: "sell"},
+				{Type: lexeme.CloseParen, Val: ")"},
+				{Type: lexeme.IfLiteral, Val: "if"},
+				{Type: lexeme.Not, Val: "!"},
+				{Type: lexeme.Symbol, Val: "sold"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Eof, Val: ""},
+			},
+		},
 		"link error 1": {
 			input: "[9abc](abc)\n",
 			tokens: []lexeme.Item{
@@ -160,6 +252,22 @@ abc`,
 				{Type: lexeme.Eof, Val: ""},
 			},
 		},
+		"inline fraction": {
+			input: "`0.5 12.25e3 1.5.x 2.x`",
+			tokens: []lexeme.Item{
+				{Type: lexeme.OpenInlineCode, Val: "`"},
+				{Type: lexeme.Number, Val: "0.5"},
+				{Type: lexeme.Number, Val: "12.25e3"},
+				{Type: lexeme.Number, Val: "1.5"},
+				{Type: lexeme.Dot, Val: "."},
+				{Type: lexeme.Symbol, Val: "x"},
+				{Type: lexeme.Number, Val: "2"},
+				{Type: lexeme.Dot, Val: "."},
+				{Type: lexeme.Symbol, Val: "x"},
+				{Type: lexeme.CloseInlineCode, Val: "`"},
+				{Type: lexeme.Eof, Val: ""},
+			},
+		},
 		"bad number 1": {
 			input: "`0123`",
 			tokens: []lexeme.Item{
@@ -220,6 +328,43 @@ abc`,
 				{Type: lexeme.Eof, Val: ""},
 			},
 		},
+		"collections": {
+			input: "`for x in [1] { m[x] }`",
+			tokens: []lexeme.Item{
+				{Type: lexeme.OpenInlineCode, Val: "`"},
+				{Type: lexeme.ForLiteral, Val: ForLiteral},
+				{Type: lexeme.Symbol, Val: "x"},
+				{Type: lexeme.InLiteral, Val: InLiteral},
+				{Type: lexeme.OpenSquareBrace, Val: OpenSquareBracket},
+				{Type: lexeme.Number, Val: "1"},
+				{Type: lexeme.CloseSquareBrace, Val: CloseSquareBracket},
+				{Type: lexeme.OpenCurlyBrace, Val: OpenCurlyBrace},
+				{Type: lexeme.Symbol, Val: "m"},
+				{Type: lexeme.OpenSquareBrace, Val: OpenSquareBracket},
+				{Type: lexeme.Symbol, Val: "x"},
+				{Type: lexeme.CloseSquareBrace, Val: CloseSquareBracket},
+				{Type: lexeme.CloseCurlyBrace, Val: CloseCurlyBrace},
+				{Type: lexeme.CloseInlineCode, Val: "`"},
+				{Type: lexeme.Eof, Val: ""},
+			},
+		},
+		"type names in a map literal": {
+			input: "`{\"a\": list, \"b\": map}`",
+			tokens: []lexeme.Item{
+				{Type: lexeme.OpenInlineCode, Val: "`"},
+				{Type: lexeme.OpenCurlyBrace, Val: OpenCurlyBrace},
+				{Type: lexeme.String, Val: "\"a\""},
+				{Type: lexeme.Colon, Val: ":"},
+				{Type: lexeme.Symbol, Val: "list"},
+				{Type: lexeme.Comma, Val: ","},
+				{Type: lexeme.String, Val: "\"b\""},
+				{Type: lexeme.Colon, Val: ":"},
+				{Type: lexeme.Symbol, Val: "map"},
+				{Type: lexeme.CloseCurlyBrace, Val: CloseCurlyBrace},
+				{Type: lexeme.CloseInlineCode, Val: "`"},
+				{Type: lexeme.Eof, Val: ""},
+			},
+		},
 		"bad operator": {
 			input: "`&`",
 			tokens: []lexeme.Item{
@@ -377,6 +522,106 @@ func TestLexer(t *testing.T) {
 				{Type: lexeme.Eof, Val: ""},
 			},
 		},
+		"frontmatter return type": {
+			input: "extern abc(): bool;\n```\n",
+			tokens: []lexeme.Item{
+				{Type: lexeme.ExternKeyword, Val: "extern"},
+				{Type: lexeme.Symbol, Val: "abc"},
+				{Type: lexeme.OpenParen, Val: "("},
+				{Type: lexeme.CloseParen, Val: ")"},
+				{Type: lexeme.Colon, Val: ":"},
+				{Type: lexeme.Type, Val: "bool"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.CloseCodeFence, Val: "```"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Eof, Val: ""},
+			},
+		},
+		"frontmatter collection types": {
+			input: "extern loot(list): map;\n```\n",
+			tokens: []lexeme.Item{
+				{Type: lexeme.ExternKeyword, Val: "extern"},
+				{Type: lexeme.Symbol, Val: "loot"},
+				{Type: lexeme.OpenParen, Val: "("},
+				{Type: lexeme.Type, Val: "list"},
+				{Type: lexeme.CloseParen, Val: ")"},
+				{Type: lexeme.Colon, Val: ":"},
+				{Type: lexeme.Type, Val: "map"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.CloseCodeFence, Val: "```"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Eof, Val: ""},
+			},
+		},
+		"frontmatter variables": {
+			input: "var gold: number = 1 + 2;\nextern var name: string;\n```\n",
+			tokens: []lexeme.Item{
+				{Type: lexeme.VarKeyword, Val: "var"},
+				{Type: lexeme.Symbol, Val: "gold"},
+				{Type: lexeme.Colon, Val: ":"},
+				{Type: lexeme.Type, Val: "number"},
+				{Type: lexeme.Eq, Val: "="},
+				{Type: lexeme.Number, Val: "1"},
+				{Type: lexeme.Plus, Val: "+"},
+				{Type: lexeme.Number, Val: "2"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.ExternKeyword, Val: "extern"},
+				{Type: lexeme.VarKeyword, Val: "var"},
+				{Type: lexeme.Symbol, Val: "name"},
+				{Type: lexeme.Colon, Val: ":"},
+				{Type: lexeme.Type, Val: "string"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.CloseCodeFence, Val: "```"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Eof, Val: ""},
+			},
+		},
+		"loop control": {
+			input: "```\n# abc\n```\nbreak;continue;\n```\n",
+			tokens: []lexeme.Item{
+				{Type: lexeme.CloseCodeFence, Val: "```"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Hash, Val: "#"},
+				{Type: lexeme.Symbol, Val: "abc"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.OpenCodeFence, Val: "```"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.BreakLiteral, Val: "break"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.ContinueLiteral, Val: "continue"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.CloseCodeFence, Val: "```"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Eof, Val: ""},
+			},
+		},
+		"local declarations": {
+			input: "```\n# abc\n```\nlocal a: number = number;\nlocal b = a;\n```\n",
+			tokens: []lexeme.Item{
+				{Type: lexeme.CloseCodeFence, Val: "```"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Hash, Val: "#"},
+				{Type: lexeme.Symbol, Val: "abc"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.OpenCodeFence, Val: "```"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.LocalKeyword, Val: "local"},
+				{Type: lexeme.Symbol, Val: "a"},
+				{Type: lexeme.Colon, Val: ":"},
+				{Type: lexeme.Type, Val: "number"},
+				{Type: lexeme.Eq, Val: "="},
+				{Type: lexeme.Symbol, Val: "number"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.LocalKeyword, Val: "local"},
+				{Type: lexeme.Symbol, Val: "b"},
+				{Type: lexeme.Eq, Val: "="},
+				{Type: lexeme.Symbol, Val: "a"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.CloseCodeFence, Val: "```"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Eof, Val: ""},
+			},
+		},
 		"no enline after frontmatter": {
 			input: "```",
 			tokens: []lexeme.Item{
@@ -420,3 +665,41 @@ func TestLexer(t *testing.T) {
 		})
 	}
 }
+
+func TestLexPositions(t *testing.T) {
+	input := "```\n# abc\n\nhé `x + 1`\n"
+	expected := []lexeme.Position{
+		{File: "f.md", Offset: 0, Line: 1, Column: 1},   // ```
+		{File: "f.md", Offset: 3, Line: 1, Column: 4},   // \n
+		{File: "f.md", Offset: 4, Line: 2, Column: 1},   // #
+		{File: "f.md", Offset: 6, Line: 2, Column: 3},   // abc
+		{File: "f.md", Offset: 9, Line: 2, Column: 6},   // \n
+		{File: "f.md", Offset: 10, Line: 3, Column: 1},  // \n
+		{File: "f.md", Offset: 11, Line: 4, Column: 1},  // hé
+		{File: "f.md", Offset: 15, Line: 4, Column: 4},  // `
+		{File: "f.md", Offset: 16, Line: 4, Column: 5},  // x
+		{File: "f.md", Offset: 18, Line: 4, Column: 7},  // +
+		{File: "f.md", Offset: 20, Line: 4, Column: 9},  // 1
+		{File: "f.md", Offset: 21, Line: 4, Column: 10}, // `
+		{File: "f.md", Offset: 22, Line: 4, Column: 11}, // \n
+		{File: "f.md", Offset: 23, Line: 5, Column: 1},  // eof
+	}
+	actual := NewFile("f.md", input).Lex()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d tokens got %v", len(expected), actual)
+	}
+	for i := range expected {
+		if actual[i].Pos != expected[i] {
+			t.Errorf("token %d (%q): expected %v got %+v", i, actual[i].Val, expected[i], actual[i].Pos)
+		}
+	}
+
+	actual = New("```\n# abc\n\n[abc](abc").Lex()
+	last := actual[len(actual)-1]
+	if last.Type != lexeme.Error {
+		t.Fatalf("expected error token got %v", last)
+	}
+	if last.Pos.Line != 4 || last.Pos.Column != 10 {
+		t.Errorf("expected error at 4:10 got %v", last.Pos)
+	}
+}
@@ -49,25 +49,35 @@ const (
 	GotoLiteral              = "goto"
 	Dot                      = "."
 	Semicolon                = ";"
+	Colon                    = ":"
 	And                      = "&&"
 	Or                       = "||"
-	Operators                = "!+-*/><=&|{}().,;%"
+	Operators                = "!+-*/><=&|{}[]().,;:%"
 	IfLiteral                = "if"
 	ElseLiteral              = "else"
 	WhileLiteral             = "while"
+	BreakLiteral             = "break"
+	ContinueLiteral          = "continue"
+	ForLiteral               = "for"
+	InLiteral                = "in"
+	EntryAnnotation          = "@entry"
 	Comment                  = "//"
 	BoolType                 = "bool"
 	NumberType               = "number"
 	StringType               = "string"
 	NullType                 = "null"
+	ListType                 = "list"
+	MapType                  = "map"
 	ExternKeyword            = "extern"
+	VarKeyword               = "var"
+	LocalKeyword             = "local"
 )
 
 const (
-	ErrorBadHeader         = "Header must only be of the form '# HeaderName\\n"
+	ErrorBadHeader         = "Header must only be of the form '# HeaderName [@entry]\\n"
 	ErrorBadLink           = "Link must only be of the form '[symbol] (text)\\n"
 	ErrorBadCode           = "Unrecognized code element"
-	ErrorBadNumber         = "Numbers must be in format -?0|([1-9][0-9]*(e[+-]?[0-9])?)"
+	ErrorBadNumber         = "Numbers must be in format (0|[1-9][0-9]*)(\\.[0-9]+)?([eE][+-]?[0-9]+)?"
 	ErrorBadOperator       = "Not a valid operator"
 	ErrorBadString         = "Not a valid string"
 	ErrorBadEscape         = "The only valid escapes are \\\\, \\/, \\b, \\f, \\n, \\r, \\t, \\uxxxx"
@@ -110,6 +120,16 @@ func LexFrontMatter(l *Lexer) State {
 		emit(l, lexeme.Semicolon)
 		return LexFrontMatter
 	}
+	if accept(l, Colon) {
+		emit(l, lexeme.Colon)
+		return LexFrontMatter
+	}
+	// a variable's initial value is code, which ends at the semicolon
+	if accept(l, Eq) {
+		emit(l, lexeme.Eq)
+		l.inInitializer = true
+		return LexCode
+	}
 	if accept(l, SymbolStart) {
 		acceptRun(l, SymbolTail)
 
@@ -120,10 +140,16 @@ func LexFrontMatter(l *Lexer) State {
 			fallthrough
 		case StringType:
 			fallthrough
+		case ListType:
+			fallthrough
+		case MapType:
+			fallthrough
 		case NullType:
 			emit(l, lexeme.Type)
 		case ExternKeyword:
 			emit(l, lexeme.ExternKeyword)
+		case VarKeyword:
+			emit(l, lexeme.VarKeyword)
 		default:
 			emit(l, lexeme.Symbol)
 		}
@@ -165,6 +191,7 @@ func LexLine(l *Lexer) State {
 			if l.pos > l.start {
 				emit(l, lexeme.TextLiteral)
 			}
+			l.afterInlineCode = LexLine
 			return LexOpenInlineCode
 		}
 		if strings.HasPrefix(l.input[l.pos:], LineEnd) {
@@ -201,6 +228,12 @@ func LexHeader(l *Lexer) State {
 		emit(l, lexeme.Symbol)
 		return LexHeader
 	}
+	// a node can be marked as a place a script can start
+	if strings.HasPrefix(l.input[l.pos:], EntryAnnotation) {
+		l.pos += len(EntryAnnotation)
+		emit(l, lexeme.EntryAnnotation)
+		return LexHeader
+	}
 	if accept(l, LineEnd) {
 		emit(l, lexeme.LineBreak)
 		return LexLine
@@ -218,8 +251,17 @@ func LexLink(l *Lexer) State {
 		emit(l, lexeme.OpenSquareBrace)
 		return LexLink
 	}
+
+	// a guard can follow the text, running as code to the end of the line
+	if strings.HasPrefix(l.input[l.pos:], IfLiteral) && l.items[len(l.items)-1].Type == lexeme.CloseParen {
+		l.pos += len(IfLiteral)
+		emit(l, lexeme.IfLiteral)
+		l.inGuard = true
+		return LexCode
+	}
 	if accept(l, SymbolStart) {
 		acceptRun(l, SymbolTail)
+		acceptQualifier(l)
 		emit(l, lexeme.Symbol)
 		return LexLink
 	}
@@ -232,24 +274,7 @@ func LexLink(l *Lexer) State {
 		emit(l, lexeme.OpenParen)
 		acceptRun(l, Whitespace)
 		ignore(l)
-
-		for {
-			r, err := peek(l)
-			if err != nil {
-				return errorf(l, ErrorBadLink)
-			}
-			if strings.ContainsRune(LineEnd, r) {
-				return errorf(l, ErrorBadLink)
-			}
-			if strings.ContainsRune(CloseParen, r) {
-				emit(l, lexeme.TextLiteral)
-				accept(l, CloseParen)
-				emit(l, lexeme.CloseParen)
-				break
-			}
-			next(l)
-		}
-		return LexLink
+		return LexLinkText
 	}
 
 	if accept(l, LineEnd) {
@@ -259,6 +284,37 @@ func LexLink(l *Lexer) State {
 	return errorf(l, ErrorBadLink)
 }
 
+// LexLinkText lexes a link's text up to its closing paren. Like a
+// paragraph line, the text can contain inline code.
+func LexLinkText(l *Lexer) State {
+	for {
+		if strings.HasPrefix(l.input[l.pos:], CodeDelimiter) {
+			if l.pos > l.start {
+				emit(l, lexeme.TextLiteral)
+			}
+			l.afterInlineCode = LexLinkText
+			return LexOpenInlineCode
+		}
+		r, err := peek(l)
+		if err != nil {
+			return errorf(l, ErrorBadLink)
+		}
+		if strings.ContainsRune(LineEnd, r) {
+			return errorf(l, ErrorBadLink)
+		}
+		if strings.ContainsRune(CloseParen, r) {
+			// text without any inline code is always given, even if empty
+			if l.pos > l.start || l.items[len(l.items)-1].Type == lexeme.OpenParen {
+				emit(l, lexeme.TextLiteral)
+			}
+			accept(l, CloseParen)
+			emit(l, lexeme.CloseParen)
+			return LexLink
+		}
+		next(l)
+	}
+}
+
 func LexUnorderedListItem(l *Lexer) State {
 	l.pos += len(UnorderedListPrefix)
 	emit(l, lexeme.ListItemPrefix)
@@ -278,6 +334,9 @@ func LexOpenInlineCode(l *Lexer) State {
 func LexCloseInlineCode(l *Lexer) State {
 	l.pos += len(CodeDelimiter)
 	emit(l, lexeme.CloseInlineCode)
+	if l.afterInlineCode != nil {
+		return l.afterInlineCode
+	}
 	return LexLine
 }
 
@@ -302,6 +361,18 @@ func LexCloseCodeFence(l *Lexer) State {
 }
 
 func LexCode(l *Lexer) State {
+	if l.inInitializer && l.items[len(l.items)-1].Type == lexeme.Semicolon {
+		l.inInitializer = false
+		return LexFrontMatter
+	}
+	if l.inGuard {
+		acceptRun(l, Whitespace)
+		ignore(l)
+		if l.pos == len(l.input) || strings.HasPrefix(l.input[l.pos:], LineEnd) {
+			l.inGuard = false
+			return LexLink
+		}
+	}
 	acceptRun(l, Whitespace+LineEnd)
 	ignore(l)
 
@@ -356,6 +427,9 @@ func LexCode(l *Lexer) State {
 func LexSymbol(l *Lexer) State {
 	accept(l, SymbolStart)
 	acceptRun(l, SymbolTail)
+	if len(l.items) > 0 && l.items[len(l.items)-1].Type == lexeme.GotoLiteral {
+		acceptQualifier(l)
+	}
 	switch l.input[l.start:l.pos] {
 	case TrueLiteral:
 		fallthrough
@@ -371,26 +445,68 @@ func LexSymbol(l *Lexer) State {
 		emit(l, lexeme.ElseLiteral)
 	case WhileLiteral:
 		emit(l, lexeme.WhileLiteral)
+	case BreakLiteral:
+		emit(l, lexeme.BreakLiteral)
+	case ContinueLiteral:
+		emit(l, lexeme.ContinueLiteral)
+	case ForLiteral:
+		emit(l, lexeme.ForLiteral)
+	case InLiteral:
+		emit(l, lexeme.InLiteral)
+	case LocalKeyword:
+		emit(l, lexeme.LocalKeyword)
 	default:
-		emit(l, lexeme.Symbol)
+		emit(l, symbolOrType(l))
 	}
 	return LexCode
 }
 
+// acceptQualifier accepts the rest of a node name which is qualified
+// by the script it's in, like shop.greeting.
+func acceptQualifier(l *Lexer) {
+	rest := l.input[l.pos:]
+	if len(rest) < 2 || rest[0] != '.' || !strings.ContainsRune(SymbolStart, rune(rest[1])) {
+		return
+	}
+	l.pos++
+	acceptRun(l, SymbolTail)
+}
+
+// symbolOrType tells a local's type annotation apart from a symbol, so
+// that type names can still be used as variable names elsewhere. Only
+// the colon in local name: starts a type; one in a map literal doesn't.
+func symbolOrType(l *Lexer) lexeme.ItemType {
+	n := len(l.items)
+	if n < 3 || l.items[n-1].Type != lexeme.Colon || l.items[n-2].Type != lexeme.Symbol || l.items[n-3].Type != lexeme.LocalKeyword {
+		return lexeme.Symbol
+	}
+	switch l.input[l.start:l.pos] {
+	case BoolType, NumberType, StringType, ListType, MapType:
+		return lexeme.Type
+	}
+	return lexeme.Symbol
+}
+
 func LexNumber(l *Lexer) State {
 	if accept(l, "0") {
-		if accept(l, "0123456789eE") {
+		if accept(l, NumberStart) {
 			return errorf(l, ErrorBadNumber)
 		}
-		emit(l, lexeme.Number)
-		return LexCode
+	} else {
+		accept(l, "123456789")
+		acceptRun(l, NumberStart)
+	}
+
+	// a point only starts a fraction when a digit follows it, so that
+	// a number can still be concatenated
+	if rest := l.input[l.pos:]; len(rest) > 1 && rest[0] == '.' && strings.ContainsRune(NumberStart, rune(rest[1])) {
+		l.pos++
+		acceptRun(l, NumberStart)
 	}
-	accept(l, "123456789")
-	acceptRun(l, "0123456789")
 
 	if accept(l, "eE") {
 		accept(l, "+-")
-		if !acceptRun(l, "0123456789") {
+		if !acceptRun(l, NumberStart) {
 			return errorf(l, ErrorBadNumber)
 		}
 	}
@@ -416,6 +532,8 @@ func LexOperator(l *Lexer) State {
 		{CloseParen, lexeme.CloseParen},
 		{OpenCurlyBrace, lexeme.OpenCurlyBrace},
 		{CloseCurlyBrace, lexeme.CloseCurlyBrace},
+		{OpenSquareBracket, lexeme.OpenSquareBrace},
+		{CloseSquareBracket, lexeme.CloseSquareBrace},
 		{Plus, lexeme.Plus},
 		{Minus, lexeme.Minus},
 		{Star, lexeme.Star},
@@ -427,6 +545,7 @@ func LexOperator(l *Lexer) State {
 		{Eq, lexeme.Eq},
 		{Comma, lexeme.Comma},
 		{Semicolon, lexeme.Semicolon},
+		{Colon, lexeme.Colon},
 		{Not, lexeme.Not},
 	}
 
@@ -13,22 +13,37 @@ type (
 		err    error
 	}
 
+	// Failure tracks the farthest token any terminal failed to match.
+	// When a parse fails, that token is where the script went wrong.
+	Failure struct {
+		Pos int
+	}
+
 	Context struct {
-		Tokens  []lexeme.Item
-		Pos     int
-		Grammar map[string]Parselet
-		Memo    map[memoKey]memoVal
-		Visited map[memoKey]bool
+		Tokens   []lexeme.Item
+		Pos      int
+		Grammar  map[string]Parselet
+		Memo     map[memoKey]memoVal
+		Visited  map[memoKey]bool
+		Farthest *Failure
 	}
 )
 
 func (ctx Context) Move(consumed int) Context {
 	return Context{
-		Tokens:  ctx.Tokens,
-		Pos:     ctx.Pos + consumed,
-		Grammar: ctx.Grammar,
-		Memo:    ctx.Memo,
-		Visited: ctx.Visited,
+		Tokens:   ctx.Tokens,
+		Pos:      ctx.Pos + consumed,
+		Grammar:  ctx.Grammar,
+		Memo:     ctx.Memo,
+		Visited:  ctx.Visited,
+		Farthest: ctx.Farthest,
+	}
+}
+
+// Fail records a terminal failing to match at the current position.
+func (ctx Context) Fail() {
+	if ctx.Farthest != nil && ctx.Pos > ctx.Farthest.Pos {
+		ctx.Farthest.Pos = ctx.Pos
 	}
 }
 
@@ -56,10 +71,11 @@ func (ctx Context) GetMemoizedResult(name string) (*Result, bool, error) {
 
 func NewContext(tokens []lexeme.Item, grammar map[string]Parselet) Context {
 	return Context{
-		Tokens:  tokens,
-		Pos:     0,
-		Grammar: grammar,
-		Memo:    map[memoKey]memoVal{},
-		Visited: map[memoKey]bool{},
+		Tokens:   tokens,
+		Pos:      0,
+		Grammar:  grammar,
+		Memo:     map[memoKey]memoVal{},
+		Visited:  map[memoKey]bool{},
+		Farthest: &Failure{Pos: -1},
 	}
 }
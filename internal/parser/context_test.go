@@ -44,4 +44,10 @@ func TestContext(t *testing.T) {
 	if err != nil {
 		t.Errorf("expected error")
 	}
+
+	ctx.Fail()
+	ctx.Move(-3).Fail()
+	if ctx.Farthest.Pos != 5 {
+		t.Errorf("ctx fail - expected farthest failure %d got %d", 5, ctx.Farthest.Pos)
+	}
 }
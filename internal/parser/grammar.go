@@ -10,13 +10,15 @@ var Grammar = map[string]Parselet{
 		return Val{Script: parsetree.Script{
 			FrontMatter: m[0].FrontMatter,
 			Nodes:       m[1].Nodes,
+			Skipped:     m[1].Skipped,
 			Eof:         m[2].Token,
 		}}
 	}),
 	"frontmatter": Or(
-		Seq(Nonterm("funcdecls"), Term(lexeme.CloseCodeFence), Term(lexeme.LineBreak))(func(m ...Val) Val {
+		Seq(Nonterm("decls"), Term(lexeme.CloseCodeFence), Term(lexeme.LineBreak))(func(m ...Val) Val {
 			return Val{FrontMatter: parsetree.FrontMatter{
 				FuncDecls: m[0].FuncDecls,
+				VarDecls:  m[0].VarDecls,
 				Delimiter: m[1].Token,
 				EndLine:   m[2].Token,
 			}}
@@ -24,24 +26,88 @@ var Grammar = map[string]Parselet{
 		Empty(func(m ...Val) Val {
 			return Val{FrontMatter: parsetree.FrontMatter{
 				FuncDecls: []parsetree.FuncDecl{},
+				VarDecls:  []parsetree.VarDecl{},
 			}}
 		}),
 	),
-	"funcdecls": ZeroOrMore(Nonterm("funcdecl"))(func(m ...Val) Val {
-		vals := []parsetree.FuncDecl{}
+	"decls": ZeroOrMore(Nonterm("decl"))(func(m ...Val) Val {
+		funcs := []parsetree.FuncDecl{}
+		vars := []parsetree.VarDecl{}
 		for _, v := range m {
-			vals = append(vals, v.FuncDecl)
+			if v.VarDecl.VarKeyword.Type == lexeme.VarKeyword {
+				vars = append(vars, v.VarDecl)
+				continue
+			}
+			funcs = append(funcs, v.FuncDecl)
 		}
-		return Val{FuncDecls: vals}
+		return Val{FuncDecls: funcs, VarDecls: vars}
 	}),
+	"decl": Or(
+		Nonterm("funcdecl"),
+		Nonterm("vardecl"),
+		Nonterm("externvardecl"),
+	),
+	"vardecl": Seq(
+		Term(lexeme.VarKeyword),
+		Term(lexeme.Symbol),
+		Nonterm("varType"),
+		Term(lexeme.Eq),
+		Nonterm("expression"),
+		Term(lexeme.Semicolon),
+		Nonterm("funcdeclEnd"),
+	)(func(m ...Val) Val {
+		return Val{VarDecl: parsetree.VarDecl{
+			VarKeyword: m[0].Token,
+			Symbol:     m[1].Token,
+			Colon:      m[2].VarDecl.Colon,
+			Type:       m[2].VarDecl.Type,
+			EqualSign:  m[3].Token,
+			Value:      m[4].Expression,
+			Semicolon:  m[5].Token,
+			EndLine:    m[6].Token,
+		}}
+	}),
+	"externvardecl": Seq(
+		Term(lexeme.ExternKeyword),
+		Term(lexeme.VarKeyword),
+		Term(lexeme.Symbol),
+		Term(lexeme.Colon),
+		Term(lexeme.Type),
+		Term(lexeme.Semicolon),
+		Nonterm("funcdeclEnd"),
+	)(func(m ...Val) Val {
+		return Val{VarDecl: parsetree.VarDecl{
+			ExternKeyword: m[0].Token,
+			VarKeyword:    m[1].Token,
+			Symbol:        m[2].Token,
+			Colon:         m[3].Token,
+			Type:          m[4].Token,
+			Semicolon:     m[5].Token,
+			EndLine:       m[6].Token,
+		}}
+	}),
+	// a variable's type can be left out to take the type of its
+	// initial value
+	"varType": Or(
+		Seq(Term(lexeme.Colon), Term(lexeme.Type))(func(m ...Val) Val {
+			return Val{VarDecl: parsetree.VarDecl{
+				Colon: m[0].Token,
+				Type:  m[1].Token,
+			}}
+		}),
+		Empty(func(m ...Val) Val {
+			return Val{}
+		}),
+	),
 	"funcdecl": Seq(
 		Term(lexeme.ExternKeyword),
 		Term(lexeme.Symbol),
 		Term(lexeme.OpenParen),
 		Nonterm("params"),
 		Term(lexeme.CloseParen),
+		Nonterm("returnType"),
 		Term(lexeme.Semicolon),
-		Term(lexeme.LineBreak),
+		Nonterm("funcdeclEnd"),
 	)(func(m ...Val) Val {
 		return Val{FuncDecl: parsetree.FuncDecl{
 			ExternKeyword: m[0].Token,
@@ -49,10 +115,33 @@ var Grammar = map[string]Parselet{
 			OpenParen:     m[2].Token,
 			Params:        m[3].Params,
 			CloseParen:    m[4].Token,
-			Semicolon:     m[5].Token,
-			EndLine:       m[6].Token,
+			Colon:         m[5].FuncDecl.Colon,
+			ReturnType:    m[5].FuncDecl.ReturnType,
+			Semicolon:     m[6].Token,
+			EndLine:       m[7].Token,
 		}}
 	}),
+	"returnType": Or(
+		Seq(Term(lexeme.Colon), Term(lexeme.Type))(func(m ...Val) Val {
+			return Val{FuncDecl: parsetree.FuncDecl{
+				Colon:      m[0].Token,
+				ReturnType: m[1].Token,
+			}}
+		}),
+		Empty(func(m ...Val) Val {
+			return Val{}
+		}),
+	),
+	// the lexer drops line breaks in the front matter like it does in
+	// code blocks, so the one after a declaration is optional
+	"funcdeclEnd": Or(
+		Seq(Term(lexeme.LineBreak))(func(m ...Val) Val {
+			return Val{Token: m[0].Token}
+		}),
+		Empty(func(m ...Val) Val {
+			return Val{}
+		}),
+	),
 	"params": Or(
 		Seq(Term(lexeme.Type), Nonterm("restparams"))(func(m ...Val) Val {
 			return Val{Params: append([]lexeme.Item{m[0].Token}, m[1].Params...)}
@@ -71,12 +160,20 @@ var Grammar = map[string]Parselet{
 	"restparam": Seq(Term(lexeme.Comma), Term(lexeme.Type))(func(m ...Val) Val {
 		return m[1]
 	}),
-	"nodes": OneOrMore(Nonterm("node"))(func(m ...Val) Val {
+	"nodes": OneOrMore(Nonterm("recoveringNode"))(func(m ...Val) Val {
 		vals := []parsetree.Node{}
+		skipped := []parsetree.Skipped{}
 		for _, v := range m {
+			if len(v.Skipped) > 0 {
+				skipped = append(skipped, v.Skipped...)
+				continue
+			}
 			vals = append(vals, v.Node)
 		}
-		return Val{Nodes: vals}
+		return Val{Nodes: vals, Skipped: skipped}
+	}),
+	"recoveringNode": Recover(Nonterm("node"), SyncNode)(func(m ...Val) Val {
+		return m[0]
 	}),
 	"node": Seq(Nonterm("header"), Term(lexeme.LineBreak), Nonterm("blocks"))(func(m ...Val) Val {
 		return Val{Node: parsetree.Node{
@@ -85,14 +182,27 @@ var Grammar = map[string]Parselet{
 			Blocks:  m[2].Blocks,
 		}}
 	}),
-	"header": Seq(Term(lexeme.Hash), Term(lexeme.Symbol), Term(lexeme.LineBreak))(func(m ...Val) Val {
-		return Val{Header: parsetree.Header{
-			Hash:    m[0].Token,
-			Name:    m[1].Token,
-			EndLine: m[2].Token,
-		}}
+	"header": Or(
+		Seq(Term(lexeme.Hash), Term(lexeme.Symbol), Term(lexeme.EntryAnnotation), Term(lexeme.LineBreak))(func(m ...Val) Val {
+			return Val{Header: parsetree.Header{
+				Hash:    m[0].Token,
+				Name:    m[1].Token,
+				Entry:   m[2].Token,
+				EndLine: m[3].Token,
+			}}
+		}),
+		Seq(Term(lexeme.Hash), Term(lexeme.Symbol), Term(lexeme.LineBreak))(func(m ...Val) Val {
+			return Val{Header: parsetree.Header{
+				Hash:    m[0].Token,
+				Name:    m[1].Token,
+				EndLine: m[2].Token,
+			}}
+		}),
+	),
+	"recoveringBlock": Recover(Nonterm("block"), SyncBlock)(func(m ...Val) Val {
+		return Val{Block: m[0].Skipped[0]}
 	}),
-	"blocks": OneOrMore(Nonterm("block"))(func(m ...Val) Val {
+	"blocks": OneOrMore(Nonterm("recoveringBlock"))(func(m ...Val) Val {
 		vals := []parsetree.Block{}
 		for _, v := range m {
 			vals = append(vals, v.Block)
@@ -160,32 +270,47 @@ var Grammar = map[string]Parselet{
 		}
 		return Val{ListItems: vals}
 	}),
-	"listItem": Seq(Term(lexeme.ListItemPrefix), Nonterm("link"))(func(m ...Val) Val {
+	"listItem": Seq(Term(lexeme.ListItemPrefix), Nonterm("linkText"), Nonterm("guard"), Term(lexeme.LineBreak))(func(m ...Val) Val {
+		link := m[1].Link
+		link.EndLine = m[3].Token
 		return Val{ListItem: parsetree.ListItem{
 			Prefix: m[0].Token,
-			Link:   m[1].Link,
+			Link:   link,
+			If:     m[2].ListItem.If,
+			Guard:  m[2].ListItem.Guard,
 		}}
 	}),
+	"guard": Or(
+		Seq(Term(lexeme.IfLiteral), Nonterm("expression"))(func(m ...Val) Val {
+			return Val{ListItem: parsetree.ListItem{If: m[0].Token, Guard: m[1].Expression}}
+		}),
+		Empty(func(m ...Val) Val {
+			return Val{}
+		}),
+	),
 	"linkBlock": Seq(Nonterm("link"), Term(lexeme.LineBreak))(func(m ...Val) Val {
 		return Val{Block: parsetree.LinkBlock{Link: m[0].Link, EndLine: m[1].Token}}
 	}),
-	"link": Seq(
+	"link": Seq(Nonterm("linkText"), Term(lexeme.LineBreak))(func(m ...Val) Val {
+		link := m[0].Link
+		link.EndLine = m[1].Token
+		return Val{Link: link}
+	}),
+	"linkText": Seq(
 		Term(lexeme.OpenSquareBrace),
 		Term(lexeme.Symbol),
 		Term(lexeme.CloseSquareBrace),
 		Term(lexeme.OpenParen),
-		Nonterm("text"),
+		Nonterm("inlines"),
 		Term(lexeme.CloseParen),
-		Term(lexeme.LineBreak),
 	)(func(m ...Val) Val {
 		return Val{Link: parsetree.Link{
 			OpenBrace:  m[0].Token,
 			Symbol:     m[1].Token,
 			CloseBrace: m[2].Token,
 			OpenParen:  m[3].Token,
-			Text:       m[4].Inline,
+			Text:       m[4].Inlines,
 			CloseParen: m[5].Token,
-			EndLine:    m[6].Token,
 		}}
 	}),
 	"codeBlock": Seq(
@@ -219,7 +344,12 @@ var Grammar = map[string]Parselet{
 		Nonterm("functionCall"),
 		Nonterm("goto"),
 		Nonterm("loop"),
+		Nonterm("break"),
+		Nonterm("continue"),
+		Nonterm("forLoop"),
+		Nonterm("indexAssignment"),
 		Nonterm("assignment"),
+		Nonterm("localDeclaration"),
 	),
 	"conditional": Seq(Term(lexeme.IfLiteral), Nonterm("expression"), Nonterm("statementBlock"))(func(m ...Val) Val {
 		return Val{Statement: parsetree.Conditional{
@@ -296,6 +426,18 @@ var Grammar = map[string]Parselet{
 			Semicolon:   m[2].Token,
 		}}
 	}),
+	"break": Seq(Term(lexeme.BreakLiteral), Term(lexeme.Semicolon))(func(m ...Val) Val {
+		return Val{Statement: parsetree.Break{
+			BreakLiteral: m[0].Token,
+			Semicolon:    m[1].Token,
+		}}
+	}),
+	"continue": Seq(Term(lexeme.ContinueLiteral), Term(lexeme.Semicolon))(func(m ...Val) Val {
+		return Val{Statement: parsetree.Continue{
+			ContinueLiteral: m[0].Token,
+			Semicolon:       m[1].Token,
+		}}
+	}),
 	"assignment": Seq(
 		Term(lexeme.Symbol),
 		Term(lexeme.Eq),
@@ -309,6 +451,43 @@ var Grammar = map[string]Parselet{
 			Semicolon: m[3].Token,
 		}}
 	}),
+	"indexAssignment": Seq(
+		Term(lexeme.Symbol),
+		Term(lexeme.OpenSquareBrace),
+		Nonterm("expression"),
+		Term(lexeme.CloseSquareBrace),
+		Term(lexeme.Eq),
+		Nonterm("expression"),
+		Term(lexeme.Semicolon),
+	)(func(m ...Val) Val {
+		return Val{Statement: parsetree.IndexAssignment{
+			Symbol:     m[0].Token,
+			OpenBrace:  m[1].Token,
+			Key:        m[2].Expression,
+			CloseBrace: m[3].Token,
+			EqualSign:  m[4].Token,
+			Value:      m[5].Expression,
+			Semicolon:  m[6].Token,
+		}}
+	}),
+	"localDeclaration": Seq(
+		Term(lexeme.LocalKeyword),
+		Term(lexeme.Symbol),
+		Nonterm("varType"),
+		Term(lexeme.Eq),
+		Nonterm("expression"),
+		Term(lexeme.Semicolon),
+	)(func(m ...Val) Val {
+		return Val{Statement: parsetree.LocalDeclaration{
+			LocalKeyword: m[0].Token,
+			Symbol:       m[1].Token,
+			Colon:        m[2].VarDecl.Colon,
+			Type:         m[2].VarDecl.Type,
+			EqualSign:    m[3].Token,
+			Value:        m[4].Expression,
+			Semicolon:    m[5].Token,
+		}}
+	}),
 	"loop": Seq(
 		Term(lexeme.WhileLiteral),
 		Nonterm("expression"),
@@ -320,153 +499,115 @@ var Grammar = map[string]Parselet{
 			Body:         m[2].Statement,
 		}}
 	}),
-	"expression": Or(
-		Seq(Nonterm("andComparator"), Term(lexeme.Or), Nonterm("expression"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.BinaryExpression{
-				LeftOperand:  m[0].Expression,
-				Operator:     m[1].Token,
-				RightOperand: m[2].Expression,
-			}}
-		}),
-		Nonterm("andComparator"),
-	),
-	"andComparator": Or(
-		Seq(Nonterm("eqComparator"), Term(lexeme.And), Nonterm("andComparator"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.BinaryExpression{
-				LeftOperand:  m[0].Expression,
-				Operator:     m[1].Token,
-				RightOperand: m[2].Expression,
-			}}
-		}),
-		Nonterm("eqComparator"),
-	),
-	"eqComparator": Or(
-		Seq(Nonterm("ineqComparator"), Term(lexeme.DoubleEq), Nonterm("eqComparator"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.BinaryExpression{
-				LeftOperand:  m[0].Expression,
-				Operator:     m[1].Token,
-				RightOperand: m[2].Expression,
-			}}
-		}),
-		Seq(Nonterm("ineqComparator"), Term(lexeme.Neq), Nonterm("eqComparator"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.BinaryExpression{
-				LeftOperand:  m[0].Expression,
-				Operator:     m[1].Token,
-				RightOperand: m[2].Expression,
-			}}
-		}),
-		Nonterm("ineqComparator"),
-	),
-	"ineqComparator": Or(
-		Seq(Nonterm("term"), Term(lexeme.Gt), Nonterm("ineqComparator"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.BinaryExpression{
-				LeftOperand:  m[0].Expression,
-				Operator:     m[1].Token,
-				RightOperand: m[2].Expression,
-			}}
-		}),
-		Seq(Nonterm("term"), Term(lexeme.Gte), Nonterm("ineqComparator"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.BinaryExpression{
-				LeftOperand:  m[0].Expression,
-				Operator:     m[1].Token,
-				RightOperand: m[2].Expression,
-			}}
-		}),
-		Seq(Nonterm("term"), Term(lexeme.Lt), Nonterm("ineqComparator"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.BinaryExpression{
-				LeftOperand:  m[0].Expression,
-				Operator:     m[1].Token,
-				RightOperand: m[2].Expression,
-			}}
-		}),
-		Seq(Nonterm("term"), Term(lexeme.Lte), Nonterm("ineqComparator"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.BinaryExpression{
-				LeftOperand:  m[0].Expression,
-				Operator:     m[1].Token,
-				RightOperand: m[2].Expression,
-			}}
-		}),
-		Nonterm("term"),
-	),
-	"term": Or(
-		Seq(Nonterm("factor"), Term(lexeme.Plus), Nonterm("term"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.BinaryExpression{
-				LeftOperand:  m[0].Expression,
-				Operator:     m[1].Token,
-				RightOperand: m[2].Expression,
-			}}
-		}),
-		Seq(Nonterm("factor"), Term(lexeme.Minus), Nonterm("term"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.BinaryExpression{
-				LeftOperand:  m[0].Expression,
-				Operator:     m[1].Token,
-				RightOperand: m[2].Expression,
-			}}
-		}),
-		Seq(Nonterm("factor"), Term(lexeme.Dot), Nonterm("term"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.BinaryExpression{
-				LeftOperand:  m[0].Expression,
-				Operator:     m[1].Token,
-				RightOperand: m[2].Expression,
-			}}
-		}),
-		Nonterm("factor"),
-	),
-	"factor": Or(
-		Seq(Nonterm("unary"), Term(lexeme.Star), Nonterm("factor"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.BinaryExpression{
-				LeftOperand:  m[0].Expression,
-				Operator:     m[1].Token,
-				RightOperand: m[2].Expression,
-			}}
-		}),
-		Seq(Nonterm("unary"), Term(lexeme.Slash), Nonterm("factor"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.BinaryExpression{
-				LeftOperand:  m[0].Expression,
-				Operator:     m[1].Token,
-				RightOperand: m[2].Expression,
-			}}
-		}),
-		Seq(Nonterm("unary"), Term(lexeme.Percent), Nonterm("factor"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.BinaryExpression{
-				LeftOperand:  m[0].Expression,
-				Operator:     m[1].Token,
-				RightOperand: m[2].Expression,
-			}}
-		}),
-		Nonterm("unary"),
-	),
-	"unary": Or(
-		Seq(Term(lexeme.Inc), Nonterm("value"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.UnaryExpression{
-				Operator: m[0].Token,
-				Operand:  m[1].Expression,
-			}}
-		}),
-		Seq(Term(lexeme.Dec), Nonterm("value"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.UnaryExpression{
-				Operator: m[0].Token,
-				Operand:  m[1].Expression,
-			}}
-		}),
-		Seq(Term(lexeme.Not), Nonterm("value"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.UnaryExpression{
-				Operator: m[0].Token,
-				Operand:  m[1].Expression,
-			}}
-		}),
-		Seq(Term(lexeme.Minus), Nonterm("value"))(func(m ...Val) Val {
-			return Val{Expression: parsetree.UnaryExpression{
-				Operator: m[0].Token,
-				Operand:  m[1].Expression,
-			}}
-		}),
-		Nonterm("value"),
-	),
+	"forLoop": Seq(
+		Term(lexeme.ForLiteral),
+		Term(lexeme.Symbol),
+		Term(lexeme.InLiteral),
+		Nonterm("expression"),
+		Nonterm("statementBlock"),
+	)(func(m ...Val) Val {
+		return Val{Statement: parsetree.ForLoop{
+			ForLiteral: m[0].Token,
+			Symbol:     m[1].Token,
+			InLiteral:  m[2].Token,
+			Iterable:   m[3].Expression,
+			Body:       m[4].Statement,
+		}}
+	}),
+	"expression": Pratt(Nonterm("indexed"), PrefixOperators, InfixOperators),
+	// indexing binds tighter than any operator, so a[0] + b[1] adds
+	// two items
+	"indexed": Seq(Nonterm("value"), Nonterm("indexes"))(func(m ...Val) Val {
+		expr := m[0].Expression
+		for _, index := range m[1].FuncArgsList {
+			index := index.(parsetree.IndexExpression)
+			index.Container = expr
+			expr = index
+		}
+		return Val{Expression: expr}
+	}),
+	"indexes": ZeroOrMore(Nonterm("index"))(func(m ...Val) Val {
+		vals := []parsetree.Expression{}
+		for _, v := range m {
+			vals = append(vals, v.Expression)
+		}
+		return Val{FuncArgsList: vals}
+	}),
+	// the container is filled in by "indexed"
+	"index": Seq(Term(lexeme.OpenSquareBrace), Nonterm("expression"), Term(lexeme.CloseSquareBrace))(func(m ...Val) Val {
+		return Val{Expression: parsetree.IndexExpression{
+			OpenBrace:  m[0].Token,
+			Key:        m[1].Expression,
+			CloseBrace: m[2].Token,
+		}}
+	}),
 	"value": Or(
 		Nonterm("nested"),
+		Nonterm("call"),
+		Nonterm("listLiteral"),
+		Nonterm("mapLiteral"),
 		Nonterm("literal"),
 	),
+	"listLiteral": Seq(
+		Term(lexeme.OpenSquareBrace),
+		Nonterm("funcArgList"),
+		Term(lexeme.CloseSquareBrace),
+	)(func(m ...Val) Val {
+		return Val{Expression: parsetree.ListLiteral{
+			OpenBrace:  m[0].Token,
+			Items:      m[1].FuncArgsList,
+			CloseBrace: m[2].Token,
+		}}
+	}),
+	"mapLiteral": Seq(
+		Term(lexeme.OpenCurlyBrace),
+		Nonterm("mapEntries"),
+		Term(lexeme.CloseCurlyBrace),
+	)(func(m ...Val) Val {
+		return Val{Expression: parsetree.MapLiteral{
+			OpenBrace:  m[0].Token,
+			Entries:    m[1].MapEntries,
+			CloseBrace: m[2].Token,
+		}}
+	}),
+	"mapEntries": Or(
+		Seq(Nonterm("mapEntry"), Nonterm("restEntries"))(func(m ...Val) Val {
+			return Val{MapEntries: append(m[0].MapEntries, m[1].MapEntries...)}
+		}),
+		Empty(func(m ...Val) Val {
+			return Val{MapEntries: []parsetree.MapEntry{}}
+		}),
+	),
+	"restEntries": ZeroOrMore(Nonterm("restEntry"))(func(m ...Val) Val {
+		vals := []parsetree.MapEntry{}
+		for _, v := range m {
+			vals = append(vals, v.MapEntries...)
+		}
+		return Val{MapEntries: vals}
+	}),
+	"restEntry": Seq(Term(lexeme.Comma), Nonterm("mapEntry"))(func(m ...Val) Val {
+		return m[1]
+	}),
+	"mapEntry": Seq(Nonterm("expression"), Term(lexeme.Colon), Nonterm("expression"))(func(m ...Val) Val {
+		return Val{MapEntries: []parsetree.MapEntry{{
+			Key:   m[0].Expression,
+			Colon: m[1].Token,
+			Value: m[2].Expression,
+		}}}
+	}),
+	"call": Seq(
+		Term(lexeme.Symbol),
+		Term(lexeme.OpenParen),
+		Nonterm("funcArgList"),
+		Term(lexeme.CloseParen),
+	)(func(m ...Val) Val {
+		return Val{Expression: parsetree.CallExpression{
+			Symbol:     m[0].Token,
+			OpenParen:  m[1].Token,
+			Args:       m[2].FuncArgsList,
+			CloseParen: m[3].Token,
+		}}
+	}),
 	"nested": Seq(Term(lexeme.OpenParen), Nonterm("expression"), Term(lexeme.CloseParen))(func(m ...Val) Val {
 		return Val{Expression: parsetree.NestedExpression{
 			OpenParen:  m[0].Token,
@@ -492,3 +633,51 @@ var Grammar = map[string]Parselet{
 		}),
 	),
 }
+
+func startsLine(tokens []lexeme.Item, pos int) bool {
+	return pos == 0 || tokens[pos-1].Type == lexeme.LineBreak
+}
+
+// SyncNode resumes parsing at the next node header.
+func SyncNode(tokens []lexeme.Item, pos int) int {
+	if pos < len(tokens) && tokens[pos].Type == lexeme.Eof {
+		return pos
+	}
+	for i := pos + 1; i < len(tokens); i++ {
+		if tokens[i].Type == lexeme.Eof {
+			return i
+		}
+		if tokens[i].Type == lexeme.Hash && startsLine(tokens, i) {
+			return i
+		}
+	}
+	return len(tokens)
+}
+
+// SyncBlock resumes parsing after the next blank line, or at the next
+// code fence or node header, whichever comes first.
+func SyncBlock(tokens []lexeme.Item, pos int) int {
+	if pos < len(tokens) && tokens[pos].Type == lexeme.LineBreak {
+		// a stray blank line between blocks
+		return pos + 1
+	}
+	for i := pos; i < len(tokens); i++ {
+		switch tokens[i].Type {
+		case lexeme.Eof:
+			return i
+		case lexeme.Hash:
+			if startsLine(tokens, i) {
+				return i
+			}
+		case lexeme.OpenCodeFence:
+			if i > pos && startsLine(tokens, i) {
+				return i
+			}
+		case lexeme.LineBreak:
+			if i+1 < len(tokens) && tokens[i+1].Type == lexeme.LineBreak {
+				return i + 2
+			}
+		}
+	}
+	return len(tokens)
+}
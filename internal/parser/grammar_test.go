@@ -34,6 +34,41 @@ func TestExpression(t *testing.T) {
 			consumed: 1,
 			err:      nil,
 		},
+		"call": {
+			input: []lexeme.Item{
+				{Type: lexeme.Symbol, Val: "has_item"},
+				{Type: lexeme.OpenParen, Val: "("},
+				{Type: lexeme.String, Val: "\"key\""},
+				{Type: lexeme.Comma, Val: ","},
+				{Type: lexeme.Number, Val: "1"},
+				{Type: lexeme.CloseParen, Val: ")"},
+				{Type: lexeme.And, Val: "&&"},
+				{Type: lexeme.Symbol, Val: "ready"},
+				{Type: lexeme.OpenParen, Val: "("},
+				{Type: lexeme.CloseParen, Val: ")"},
+			},
+			expected: parsetree.BinaryExpression{
+				LeftOperand: parsetree.CallExpression{
+					Symbol:    lexeme.Item{Type: lexeme.Symbol, Val: "has_item"},
+					OpenParen: lexeme.Item{Type: lexeme.OpenParen, Val: "("},
+					Args: []parsetree.Expression{
+						parsetree.Literal{Value: lexeme.Item{Type: lexeme.String, Val: "\"key\""}},
+						parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "1"}},
+					},
+					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
+				},
+				Operator: lexeme.Item{Type: lexeme.And, Val: "&&"},
+				RightOperand: parsetree.CallExpression{
+					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "ready"},
+					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
+					Args:       []parsetree.Expression{},
+					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
+				},
+			},
+			start:    "expression",
+			consumed: 10,
+			err:      nil,
+		},
 		"symbol": {
 			input: []lexeme.Item{
 				{Type: lexeme.Symbol, Val: "abc"},
@@ -61,6 +96,121 @@ func TestExpression(t *testing.T) {
 			consumed: 1,
 			err:      nil,
 		},
+		"list literal": {
+			input: []lexeme.Item{
+				{Type: lexeme.OpenSquareBrace, Val: "["},
+				{Type: lexeme.Number, Val: "1"},
+				{Type: lexeme.Comma, Val: ","},
+				{Type: lexeme.String, Val: "\"a\""},
+				{Type: lexeme.CloseSquareBrace, Val: "]"},
+			},
+			expected: parsetree.ListLiteral{
+				OpenBrace: lexeme.Item{Type: lexeme.OpenSquareBrace, Val: "["},
+				Items: []parsetree.Expression{
+					parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "1"}},
+					parsetree.Literal{Value: lexeme.Item{Type: lexeme.String, Val: "\"a\""}},
+				},
+				CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
+			},
+			start:    "value",
+			consumed: 5,
+			err:      nil,
+		},
+		"empty list literal": {
+			input: []lexeme.Item{
+				{Type: lexeme.OpenSquareBrace, Val: "["},
+				{Type: lexeme.CloseSquareBrace, Val: "]"},
+			},
+			expected: parsetree.ListLiteral{
+				OpenBrace:  lexeme.Item{Type: lexeme.OpenSquareBrace, Val: "["},
+				Items:      []parsetree.Expression{},
+				CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
+			},
+			start:    "value",
+			consumed: 2,
+			err:      nil,
+		},
+		"map literal": {
+			input: []lexeme.Item{
+				{Type: lexeme.OpenCurlyBrace, Val: "{"},
+				{Type: lexeme.String, Val: "\"gold\""},
+				{Type: lexeme.Colon, Val: ":"},
+				{Type: lexeme.Number, Val: "3"},
+				{Type: lexeme.Comma, Val: ","},
+				{Type: lexeme.String, Val: "\"gems\""},
+				{Type: lexeme.Colon, Val: ":"},
+				{Type: lexeme.Number, Val: "1"},
+				{Type: lexeme.CloseCurlyBrace, Val: "}"},
+			},
+			expected: parsetree.MapLiteral{
+				OpenBrace: lexeme.Item{Type: lexeme.OpenCurlyBrace, Val: "{"},
+				Entries: []parsetree.MapEntry{
+					{
+						Key:   parsetree.Literal{Value: lexeme.Item{Type: lexeme.String, Val: "\"gold\""}},
+						Colon: lexeme.Item{Type: lexeme.Colon, Val: ":"},
+						Value: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "3"}},
+					},
+					{
+						Key:   parsetree.Literal{Value: lexeme.Item{Type: lexeme.String, Val: "\"gems\""}},
+						Colon: lexeme.Item{Type: lexeme.Colon, Val: ":"},
+						Value: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "1"}},
+					},
+				},
+				CloseBrace: lexeme.Item{Type: lexeme.CloseCurlyBrace, Val: "}"},
+			},
+			start:    "value",
+			consumed: 9,
+			err:      nil,
+		},
+		"empty map literal": {
+			input: []lexeme.Item{
+				{Type: lexeme.OpenCurlyBrace, Val: "{"},
+				{Type: lexeme.CloseCurlyBrace, Val: "}"},
+			},
+			expected: parsetree.MapLiteral{
+				OpenBrace:  lexeme.Item{Type: lexeme.OpenCurlyBrace, Val: "{"},
+				Entries:    []parsetree.MapEntry{},
+				CloseBrace: lexeme.Item{Type: lexeme.CloseCurlyBrace, Val: "}"},
+			},
+			start:    "value",
+			consumed: 2,
+			err:      nil,
+		},
+		"index binds tighter than operators": {
+			input: []lexeme.Item{
+				{Type: lexeme.Minus, Val: "-"},
+				{Type: lexeme.Symbol, Val: "grid"},
+				{Type: lexeme.OpenSquareBrace, Val: "["},
+				{Type: lexeme.Number, Val: "0"},
+				{Type: lexeme.CloseSquareBrace, Val: "]"},
+				{Type: lexeme.OpenSquareBrace, Val: "["},
+				{Type: lexeme.Number, Val: "1"},
+				{Type: lexeme.CloseSquareBrace, Val: "]"},
+				{Type: lexeme.Plus, Val: "+"},
+				{Type: lexeme.Number, Val: "2"},
+			},
+			expected: parsetree.BinaryExpression{
+				LeftOperand: parsetree.UnaryExpression{
+					Operator: lexeme.Item{Type: lexeme.Minus, Val: "-"},
+					Operand: parsetree.IndexExpression{
+						Container: parsetree.IndexExpression{
+							Container:  parsetree.Literal{Value: lexeme.Item{Type: lexeme.Symbol, Val: "grid"}},
+							OpenBrace:  lexeme.Item{Type: lexeme.OpenSquareBrace, Val: "["},
+							Key:        parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "0"}},
+							CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
+						},
+						OpenBrace:  lexeme.Item{Type: lexeme.OpenSquareBrace, Val: "["},
+						Key:        parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "1"}},
+						CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
+					},
+				},
+				Operator:     lexeme.Item{Type: lexeme.Plus, Val: "+"},
+				RightOperand: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "2"}},
+			},
+			start:    "expression",
+			consumed: 10,
+			err:      nil,
+		},
 		"nested expression": {
 			input: []lexeme.Item{
 				{Type: lexeme.OpenParen, Val: "("},
@@ -85,7 +235,7 @@ func TestExpression(t *testing.T) {
 				Operator: lexeme.Item{Type: lexeme.Minus, Val: "-"},
 				Operand:  parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
 			},
-			start:    "unary",
+			start:    "expression",
 			consumed: 2,
 			err:      nil,
 		},
@@ -98,7 +248,7 @@ func TestExpression(t *testing.T) {
 				Operator: lexeme.Item{Type: lexeme.Inc, Val: "++"},
 				Operand:  parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
 			},
-			start:    "unary",
+			start:    "expression",
 			consumed: 2,
 			err:      nil,
 		},
@@ -111,7 +261,7 @@ func TestExpression(t *testing.T) {
 				Operator: lexeme.Item{Type: lexeme.Dec, Val: "--"},
 				Operand:  parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
 			},
-			start:    "unary",
+			start:    "expression",
 			consumed: 2,
 			err:      nil,
 		},
@@ -124,7 +274,7 @@ func TestExpression(t *testing.T) {
 				Operator: lexeme.Item{Type: lexeme.Not, Val: "!"},
 				Operand:  parsetree.Literal{Value: lexeme.Item{Type: lexeme.Boolean, Val: "true"}},
 			},
-			start:    "unary",
+			start:    "expression",
 			consumed: 2,
 			err:      nil,
 		},
@@ -133,7 +283,7 @@ func TestExpression(t *testing.T) {
 				{Type: lexeme.Number, Val: "5"},
 			},
 			expected: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
-			start:    "unary",
+			start:    "expression",
 			consumed: 1,
 			err:      nil,
 		},
@@ -152,7 +302,7 @@ func TestExpression(t *testing.T) {
 				Operator:     lexeme.Item{Type: lexeme.Star, Val: "*"},
 				RightOperand: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
 			},
-			start:    "factor",
+			start:    "expression",
 			consumed: 4,
 			err:      nil,
 		},
@@ -171,7 +321,7 @@ func TestExpression(t *testing.T) {
 					Operand:  parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
 				},
 			},
-			start:    "factor",
+			start:    "expression",
 			consumed: 4,
 			err:      nil,
 		},
@@ -190,7 +340,7 @@ func TestExpression(t *testing.T) {
 					Operand:  parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
 				},
 			},
-			start:    "factor",
+			start:    "expression",
 			consumed: 4,
 			err:      nil,
 		},
@@ -215,7 +365,7 @@ func TestExpression(t *testing.T) {
 				Operator:     lexeme.Item{Type: lexeme.Plus, Val: "+"},
 				RightOperand: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
 			},
-			start:    "term",
+			start:    "expression",
 			consumed: 6,
 			err:      nil,
 		},
@@ -240,7 +390,7 @@ func TestExpression(t *testing.T) {
 					RightOperand: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
 				},
 			},
-			start:    "term",
+			start:    "expression",
 			consumed: 6,
 			err:      nil,
 		},
@@ -255,7 +405,7 @@ func TestExpression(t *testing.T) {
 				Operator:     lexeme.Item{Type: lexeme.Dot, Val: "."},
 				RightOperand: parsetree.Literal{Value: lexeme.Item{Type: lexeme.String, Val: "\"abc\""}},
 			},
-			start:    "term",
+			start:    "expression",
 			consumed: 3,
 			err:      nil,
 		},
@@ -276,7 +426,7 @@ func TestExpression(t *testing.T) {
 				Operator:     lexeme.Item{Type: lexeme.Gt, Val: ">"},
 				RightOperand: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
 			},
-			start:    "ineqComparator",
+			start:    "expression",
 			consumed: 5,
 			err:      nil,
 		},
@@ -297,7 +447,7 @@ func TestExpression(t *testing.T) {
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 				},
 			},
-			start:    "ineqComparator",
+			start:    "expression",
 			consumed: 5,
 			err:      nil,
 		},
@@ -318,7 +468,7 @@ func TestExpression(t *testing.T) {
 				Operator:     lexeme.Item{Type: lexeme.Gte, Val: ">="},
 				RightOperand: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
 			},
-			start:    "ineqComparator",
+			start:    "expression",
 			consumed: 5,
 			err:      nil,
 		},
@@ -339,7 +489,7 @@ func TestExpression(t *testing.T) {
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 				},
 			},
-			start:    "ineqComparator",
+			start:    "expression",
 			consumed: 5,
 			err:      nil,
 		},
@@ -360,7 +510,7 @@ func TestExpression(t *testing.T) {
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 				},
 			},
-			start:    "eqComparator",
+			start:    "expression",
 			consumed: 5,
 			err:      nil,
 		},
@@ -381,7 +531,7 @@ func TestExpression(t *testing.T) {
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 				},
 			},
-			start:    "eqComparator",
+			start:    "expression",
 			consumed: 5,
 			err:      nil,
 		},
@@ -402,7 +552,7 @@ func TestExpression(t *testing.T) {
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 				},
 			},
-			start:    "andComparator",
+			start:    "expression",
 			consumed: 5,
 			err:      nil,
 		},
@@ -532,6 +682,99 @@ func TestExpression(t *testing.T) {
 			consumed: 9,
 			err:      nil,
 		},
+		"left associative": {
+			input: []lexeme.Item{
+				{Type: lexeme.Number, Val: "10"},
+				{Type: lexeme.Minus, Val: "-"},
+				{Type: lexeme.Number, Val: "3"},
+				{Type: lexeme.Minus, Val: "-"},
+				{Type: lexeme.Number, Val: "2"},
+			},
+			expected: parsetree.BinaryExpression{
+				LeftOperand: parsetree.BinaryExpression{
+					LeftOperand:  parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "10"}},
+					Operator:     lexeme.Item{Type: lexeme.Minus, Val: "-"},
+					RightOperand: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "3"}},
+				},
+				Operator:     lexeme.Item{Type: lexeme.Minus, Val: "-"},
+				RightOperand: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "2"}},
+			},
+			start:    "expression",
+			consumed: 5,
+			err:      nil,
+		},
+		"left associative factors": {
+			input: []lexeme.Item{
+				{Type: lexeme.Number, Val: "24"},
+				{Type: lexeme.Slash, Val: "/"},
+				{Type: lexeme.Number, Val: "4"},
+				{Type: lexeme.Percent, Val: "%"},
+				{Type: lexeme.Number, Val: "2"},
+			},
+			expected: parsetree.BinaryExpression{
+				LeftOperand: parsetree.BinaryExpression{
+					LeftOperand:  parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "24"}},
+					Operator:     lexeme.Item{Type: lexeme.Slash, Val: "/"},
+					RightOperand: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "4"}},
+				},
+				Operator:     lexeme.Item{Type: lexeme.Percent, Val: "%"},
+				RightOperand: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "2"}},
+			},
+			start:    "expression",
+			consumed: 5,
+			err:      nil,
+		},
+		"chained unary": {
+			input: []lexeme.Item{
+				{Type: lexeme.Minus, Val: "-"},
+				{Type: lexeme.Dec, Val: "--"},
+				{Type: lexeme.Symbol, Val: "x"},
+				{Type: lexeme.Star, Val: "*"},
+				{Type: lexeme.Number, Val: "2"},
+			},
+			expected: parsetree.BinaryExpression{
+				LeftOperand: parsetree.UnaryExpression{
+					Operator: lexeme.Item{Type: lexeme.Minus, Val: "-"},
+					Operand: parsetree.UnaryExpression{
+						Operator: lexeme.Item{Type: lexeme.Dec, Val: "--"},
+						Operand:  parsetree.Literal{Value: lexeme.Item{Type: lexeme.Symbol, Val: "x"}},
+					},
+				},
+				Operator:     lexeme.Item{Type: lexeme.Star, Val: "*"},
+				RightOperand: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "2"}},
+			},
+			start:    "expression",
+			consumed: 5,
+			err:      nil,
+		},
+		"double not": {
+			input: []lexeme.Item{
+				{Type: lexeme.Not, Val: "!"},
+				{Type: lexeme.Not, Val: "!"},
+				{Type: lexeme.Symbol, Val: "flag"},
+			},
+			expected: parsetree.UnaryExpression{
+				Operator: lexeme.Item{Type: lexeme.Not, Val: "!"},
+				Operand: parsetree.UnaryExpression{
+					Operator: lexeme.Item{Type: lexeme.Not, Val: "!"},
+					Operand:  parsetree.Literal{Value: lexeme.Item{Type: lexeme.Symbol, Val: "flag"}},
+				},
+			},
+			start:    "expression",
+			consumed: 3,
+			err:      nil,
+		},
+		"dangling operator": {
+			input: []lexeme.Item{
+				{Type: lexeme.Number, Val: "5"},
+				{Type: lexeme.Plus, Val: "+"},
+				{Type: lexeme.CloseParen, Val: ")"},
+			},
+			expected: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
+			start:    "expression",
+			consumed: 1,
+			err:      nil,
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			ctx := Context{
@@ -612,6 +855,74 @@ func TestStatement(t *testing.T) {
 			consumed: 7,
 			err:      nil,
 		},
+		"break": {
+			input: []lexeme.Item{
+				{Type: lexeme.BreakLiteral, Val: "break"},
+				{Type: lexeme.Semicolon, Val: ";"},
+			},
+			expected: parsetree.Break{
+				BreakLiteral: lexeme.Item{Type: lexeme.BreakLiteral, Val: "break"},
+				Semicolon:    lexeme.Item{Type: lexeme.Semicolon, Val: ";"},
+			},
+			start:    "statement",
+			consumed: 2,
+			err:      nil,
+		},
+		"continue": {
+			input: []lexeme.Item{
+				{Type: lexeme.ContinueLiteral, Val: "continue"},
+				{Type: lexeme.Semicolon, Val: ";"},
+			},
+			expected: parsetree.Continue{
+				ContinueLiteral: lexeme.Item{Type: lexeme.ContinueLiteral, Val: "continue"},
+				Semicolon:       lexeme.Item{Type: lexeme.Semicolon, Val: ";"},
+			},
+			start:    "statement",
+			consumed: 2,
+			err:      nil,
+		},
+		"local declaration": {
+			input: []lexeme.Item{
+				{Type: lexeme.LocalKeyword, Val: "local"},
+				{Type: lexeme.Symbol, Val: "abc"},
+				{Type: lexeme.Colon, Val: ":"},
+				{Type: lexeme.Type, Val: "number"},
+				{Type: lexeme.Eq, Val: "="},
+				{Type: lexeme.Number, Val: "5"},
+				{Type: lexeme.Semicolon, Val: ";"},
+			},
+			expected: parsetree.LocalDeclaration{
+				LocalKeyword: lexeme.Item{Type: lexeme.LocalKeyword, Val: "local"},
+				Symbol:       lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
+				Colon:        lexeme.Item{Type: lexeme.Colon, Val: ":"},
+				Type:         lexeme.Item{Type: lexeme.Type, Val: "number"},
+				EqualSign:    lexeme.Item{Type: lexeme.Eq, Val: "="},
+				Value:        parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
+				Semicolon:    lexeme.Item{Type: lexeme.Semicolon, Val: ";"},
+			},
+			start:    "statement",
+			consumed: 7,
+			err:      nil,
+		},
+		"untyped local declaration": {
+			input: []lexeme.Item{
+				{Type: lexeme.LocalKeyword, Val: "local"},
+				{Type: lexeme.Symbol, Val: "abc"},
+				{Type: lexeme.Eq, Val: "="},
+				{Type: lexeme.Symbol, Val: "def"},
+				{Type: lexeme.Semicolon, Val: ";"},
+			},
+			expected: parsetree.LocalDeclaration{
+				LocalKeyword: lexeme.Item{Type: lexeme.LocalKeyword, Val: "local"},
+				Symbol:       lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
+				EqualSign:    lexeme.Item{Type: lexeme.Eq, Val: "="},
+				Value:        parsetree.Literal{Value: lexeme.Item{Type: lexeme.Symbol, Val: "def"}},
+				Semicolon:    lexeme.Item{Type: lexeme.Semicolon, Val: ";"},
+			},
+			start:    "statement",
+			consumed: 5,
+			err:      nil,
+		},
 		"function call": {
 			input: []lexeme.Item{
 				{Type: lexeme.Symbol, Val: "abc"},
@@ -756,6 +1067,60 @@ func TestStatement(t *testing.T) {
 			consumed: 9,
 			err:      nil,
 		},
+		"for loop": {
+			input: []lexeme.Item{
+				{Type: lexeme.ForLiteral, Val: "for"},
+				{Type: lexeme.Symbol, Val: "item"},
+				{Type: lexeme.InLiteral, Val: "in"},
+				{Type: lexeme.Symbol, Val: "items"},
+				{Type: lexeme.OpenCurlyBrace, Val: "{"},
+				{Type: lexeme.BreakLiteral, Val: "break"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.CloseCurlyBrace, Val: "}"},
+			},
+			expected: parsetree.ForLoop{
+				ForLiteral: lexeme.Item{Type: lexeme.ForLiteral, Val: "for"},
+				Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "item"},
+				InLiteral:  lexeme.Item{Type: lexeme.InLiteral, Val: "in"},
+				Iterable:   parsetree.Literal{Value: lexeme.Item{Type: lexeme.Symbol, Val: "items"}},
+				Body: parsetree.StatementBlock{
+					OpenBrace: lexeme.Item{Type: lexeme.OpenCurlyBrace, Val: "{"},
+					Statements: []parsetree.Statement{
+						parsetree.Break{
+							BreakLiteral: lexeme.Item{Type: lexeme.BreakLiteral, Val: "break"},
+							Semicolon:    lexeme.Item{Type: lexeme.Semicolon, Val: ";"},
+						},
+					},
+					CloseBrace: lexeme.Item{Type: lexeme.CloseCurlyBrace, Val: "}"},
+				},
+			},
+			start:    "statement",
+			consumed: 8,
+			err:      nil,
+		},
+		"index assignment": {
+			input: []lexeme.Item{
+				{Type: lexeme.Symbol, Val: "items"},
+				{Type: lexeme.OpenSquareBrace, Val: "["},
+				{Type: lexeme.Number, Val: "0"},
+				{Type: lexeme.CloseSquareBrace, Val: "]"},
+				{Type: lexeme.Eq, Val: "="},
+				{Type: lexeme.String, Val: "\"sword\""},
+				{Type: lexeme.Semicolon, Val: ";"},
+			},
+			expected: parsetree.IndexAssignment{
+				Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "items"},
+				OpenBrace:  lexeme.Item{Type: lexeme.OpenSquareBrace, Val: "["},
+				Key:        parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "0"}},
+				CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
+				EqualSign:  lexeme.Item{Type: lexeme.Eq, Val: "="},
+				Value:      parsetree.Literal{Value: lexeme.Item{Type: lexeme.String, Val: "\"sword\""}},
+				Semicolon:  lexeme.Item{Type: lexeme.Semicolon, Val: ";"},
+			},
+			start:    "statement",
+			consumed: 7,
+			err:      nil,
+		},
 		"cond": {
 			input: []lexeme.Item{
 				{Type: lexeme.IfLiteral, Val: "if"},
@@ -959,7 +1324,7 @@ func TestBlock(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -969,6 +1334,43 @@ func TestBlock(t *testing.T) {
 			consumed: 8,
 			err:      nil,
 		},
+		"linkBlock with inline code": {
+			input: []lexeme.Item{
+				{Type: lexeme.OpenSquareBrace, Val: "["},
+				{Type: lexeme.Symbol, Val: "abc"},
+				{Type: lexeme.CloseSquareBrace, Val: "]"},
+				{Type: lexeme.OpenParen, Val: "("},
+				{Type: lexeme.TextLiteral, Val: "def "},
+				{Type: lexeme.OpenInlineCode, Val: "`"},
+				{Type: lexeme.Symbol, Val: "ghi"},
+				{Type: lexeme.CloseInlineCode, Val: "`"},
+				{Type: lexeme.CloseParen, Val: ")"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+			},
+			expected: parsetree.LinkBlock{
+				Link: parsetree.Link{
+					OpenBrace:  lexeme.Item{Type: lexeme.OpenSquareBrace, Val: "["},
+					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
+					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
+					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
+					Text: []parsetree.Inline{
+						parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def "}},
+						parsetree.InlineCode{
+							CodeStart: lexeme.Item{Type: lexeme.OpenInlineCode, Val: "`"},
+							Code:      parsetree.Literal{Value: lexeme.Item{Type: lexeme.Symbol, Val: "ghi"}},
+							CodeEnd:   lexeme.Item{Type: lexeme.CloseInlineCode, Val: "`"},
+						},
+					},
+					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
+					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+				},
+				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+			},
+			start:    "linkBlock",
+			consumed: 11,
+			err:      nil,
+		},
 		"list": {
 			input: []lexeme.Item{
 				{Type: lexeme.ListItemPrefix, Val: "-"},
@@ -1006,7 +1408,7 @@ func TestBlock(t *testing.T) {
 							Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 							CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 							OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-							Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 							CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 							EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 						},
@@ -1018,7 +1420,7 @@ func TestBlock(t *testing.T) {
 							Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 							CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 							OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-							Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 							CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 							EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 						},
@@ -1030,7 +1432,7 @@ func TestBlock(t *testing.T) {
 							Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 							CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 							OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-							Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 							CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 							EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 						},
@@ -1042,6 +1444,47 @@ func TestBlock(t *testing.T) {
 			consumed: 25,
 			err:      nil,
 		},
+		"guarded list": {
+			input: []lexeme.Item{
+				{Type: lexeme.ListItemPrefix, Val: "-"},
+				{Type: lexeme.OpenSquareBrace, Val: "["},
+				{Type: lexeme.Symbol, Val: "abc"},
+				{Type: lexeme.CloseSquareBrace, Val: "]"},
+				{Type: lexeme.OpenParen, Val: "("},
+				{Type: lexeme.TextLiteral, Val: "def"},
+				{Type: lexeme.CloseParen, Val: ")"},
+				{Type: lexeme.IfLiteral, Val: "if"},
+				{Type: lexeme.Not, Val: "!"},
+				{Type: lexeme.Symbol, Val: "ghi"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+			},
+			expected: parsetree.List{
+				Links: []parsetree.ListItem{
+					{
+						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
+						Link: parsetree.Link{
+							OpenBrace:  lexeme.Item{Type: lexeme.OpenSquareBrace, Val: "["},
+							Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
+							CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
+							OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
+							Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
+							CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
+							EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+						},
+						If: lexeme.Item{Type: lexeme.IfLiteral, Val: "if"},
+						Guard: parsetree.UnaryExpression{
+							Operator: lexeme.Item{Type: lexeme.Not, Val: "!"},
+							Operand:  parsetree.Literal{Value: lexeme.Item{Type: lexeme.Symbol, Val: "ghi"}},
+						},
+					},
+				},
+				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+			},
+			start:    "list",
+			consumed: 12,
+			err:      nil,
+		},
 		"paragraph": {
 			input: []lexeme.Item{
 				{Type: lexeme.TextLiteral, Val: "abc"},
@@ -1148,6 +1591,42 @@ func TestNode(t *testing.T) {
 		consumed int
 		err      error
 	}{
+		"entry node": {
+			input: []lexeme.Item{
+				{Type: lexeme.Hash, Val: "#"},
+				{Type: lexeme.Symbol, Val: "abc"},
+				{Type: lexeme.EntryAnnotation, Val: "@entry"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.TextLiteral, Val: "abc"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+			},
+			expected: parsetree.Node{
+				Header: parsetree.Header{
+					Hash:    lexeme.Item{Type: lexeme.Hash, Val: "#"},
+					Name:    lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
+					Entry:   lexeme.Item{Type: lexeme.EntryAnnotation, Val: "@entry"},
+					EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+				},
+				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+				Blocks: []parsetree.Block{
+					parsetree.Paragraph{
+						Lines: []parsetree.Line{
+							{
+								Items: []parsetree.Inline{
+									parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}},
+								},
+								EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+							},
+						},
+						EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+					},
+				},
+			},
+			consumed: 8,
+			err:      nil,
+		},
 		"node": {
 			input: []lexeme.Item{
 				{Type: lexeme.Hash, Val: "#"},
@@ -1286,7 +1765,7 @@ func TestNode(t *testing.T) {
 									Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 									CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 									OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-									Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+									Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 									CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 									EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 								},
@@ -1298,7 +1777,7 @@ func TestNode(t *testing.T) {
 									Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 									CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 									OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-									Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+									Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 									CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 									EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 								},
@@ -1310,7 +1789,7 @@ func TestNode(t *testing.T) {
 									Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 									CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 									OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-									Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+									Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 									CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 									EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 								},
@@ -1324,7 +1803,7 @@ func TestNode(t *testing.T) {
 							Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 							CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 							OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-							Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 							CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 							EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 						},
@@ -1613,7 +2092,7 @@ func TestScript(t *testing.T) {
 											Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 											CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 											OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-											Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+											Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 											CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 											EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 										},
@@ -1625,7 +2104,7 @@ func TestScript(t *testing.T) {
 											Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 											CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 											OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-											Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+											Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 											CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 											EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 										},
@@ -1637,7 +2116,7 @@ func TestScript(t *testing.T) {
 											Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 											CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 											OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-											Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+											Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 											CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 											EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 										},
@@ -1661,7 +2140,7 @@ func TestScript(t *testing.T) {
 									Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 									CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 									OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-									Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+									Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 									CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 									EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 								},
@@ -1718,6 +2197,177 @@ func TestScript(t *testing.T) {
 			consumed: 104,
 			err:      nil,
 		},
+		"frontmatter with return type": {
+			input: []lexeme.Item{
+				{Type: lexeme.ExternKeyword, Val: "extern"},
+				{Type: lexeme.Symbol, Val: "abc"},
+				{Type: lexeme.OpenParen, Val: "("},
+				{Type: lexeme.Type, Val: "bool"},
+				{Type: lexeme.CloseParen, Val: ")"},
+				{Type: lexeme.Colon, Val: ":"},
+				{Type: lexeme.Type, Val: "string"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.CloseCodeFence, Val: "```"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Hash, Val: "#"},
+				{Type: lexeme.Symbol, Val: "abc"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.TextLiteral, Val: "abc"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Eof, Val: ""},
+			},
+			expected: parsetree.Script{
+				FrontMatter: parsetree.FrontMatter{
+					FuncDecls: []parsetree.FuncDecl{
+						{
+							ExternKeyword: lexeme.Item{Type: lexeme.ExternKeyword, Val: "extern"},
+							Symbol:        lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
+							OpenParen:     lexeme.Item{Type: lexeme.OpenParen, Val: "("},
+							Params:        []lexeme.Item{{Type: lexeme.Type, Val: "bool"}},
+							CloseParen:    lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
+							Colon:         lexeme.Item{Type: lexeme.Colon, Val: ":"},
+							ReturnType:    lexeme.Item{Type: lexeme.Type, Val: "string"},
+							Semicolon:     lexeme.Item{Type: lexeme.Semicolon, Val: ";"},
+						},
+					},
+					Delimiter: lexeme.Item{Type: lexeme.CloseCodeFence, Val: "```"},
+					EndLine:   lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+				},
+				Nodes: []parsetree.Node{
+					{
+						Header: parsetree.Header{
+							Hash:    lexeme.Item{Type: lexeme.Hash, Val: "#"},
+							Name:    lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
+							EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+						},
+						EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+						Blocks: []parsetree.Block{
+							parsetree.Paragraph{
+								Lines: []parsetree.Line{
+									{
+										Items: []parsetree.Inline{
+											parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}},
+										},
+										EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+									},
+								},
+								EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+							},
+						},
+					},
+				},
+				Eof: lexeme.Item{Type: lexeme.Eof, Val: ""},
+			},
+			consumed: 18,
+			err:      nil,
+		},
+		"frontmatter with variables": {
+			input: []lexeme.Item{
+				{Type: lexeme.VarKeyword, Val: "var"},
+				{Type: lexeme.Symbol, Val: "gold"},
+				{Type: lexeme.Colon, Val: ":"},
+				{Type: lexeme.Type, Val: "number"},
+				{Type: lexeme.Eq, Val: "="},
+				{Type: lexeme.Number, Val: "0"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.ExternKeyword, Val: "extern"},
+				{Type: lexeme.Symbol, Val: "abc"},
+				{Type: lexeme.OpenParen, Val: "("},
+				{Type: lexeme.CloseParen, Val: ")"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.VarKeyword, Val: "var"},
+				{Type: lexeme.Symbol, Val: "met"},
+				{Type: lexeme.Eq, Val: "="},
+				{Type: lexeme.Boolean, Val: "false"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.ExternKeyword, Val: "extern"},
+				{Type: lexeme.VarKeyword, Val: "var"},
+				{Type: lexeme.Symbol, Val: "name"},
+				{Type: lexeme.Colon, Val: ":"},
+				{Type: lexeme.Type, Val: "string"},
+				{Type: lexeme.Semicolon, Val: ";"},
+				{Type: lexeme.CloseCodeFence, Val: "```"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Hash, Val: "#"},
+				{Type: lexeme.Symbol, Val: "abc"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.TextLiteral, Val: "abc"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.LineBreak, Val: "\n"},
+				{Type: lexeme.Eof, Val: ""},
+			},
+			expected: parsetree.Script{
+				FrontMatter: parsetree.FrontMatter{
+					FuncDecls: []parsetree.FuncDecl{
+						{
+							ExternKeyword: lexeme.Item{Type: lexeme.ExternKeyword, Val: "extern"},
+							Symbol:        lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
+							OpenParen:     lexeme.Item{Type: lexeme.OpenParen, Val: "("},
+							Params:        []lexeme.Item{},
+							CloseParen:    lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
+							Semicolon:     lexeme.Item{Type: lexeme.Semicolon, Val: ";"},
+						},
+					},
+					VarDecls: []parsetree.VarDecl{
+						{
+							VarKeyword: lexeme.Item{Type: lexeme.VarKeyword, Val: "var"},
+							Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "gold"},
+							Colon:      lexeme.Item{Type: lexeme.Colon, Val: ":"},
+							Type:       lexeme.Item{Type: lexeme.Type, Val: "number"},
+							EqualSign:  lexeme.Item{Type: lexeme.Eq, Val: "="},
+							Value:      parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "0"}},
+							Semicolon:  lexeme.Item{Type: lexeme.Semicolon, Val: ";"},
+						},
+						{
+							VarKeyword: lexeme.Item{Type: lexeme.VarKeyword, Val: "var"},
+							Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "met"},
+							EqualSign:  lexeme.Item{Type: lexeme.Eq, Val: "="},
+							Value:      parsetree.Literal{Value: lexeme.Item{Type: lexeme.Boolean, Val: "false"}},
+							Semicolon:  lexeme.Item{Type: lexeme.Semicolon, Val: ";"},
+						},
+						{
+							ExternKeyword: lexeme.Item{Type: lexeme.ExternKeyword, Val: "extern"},
+							VarKeyword:    lexeme.Item{Type: lexeme.VarKeyword, Val: "var"},
+							Symbol:        lexeme.Item{Type: lexeme.Symbol, Val: "name"},
+							Colon:         lexeme.Item{Type: lexeme.Colon, Val: ":"},
+							Type:          lexeme.Item{Type: lexeme.Type, Val: "string"},
+							Semicolon:     lexeme.Item{Type: lexeme.Semicolon, Val: ";"},
+						},
+					},
+					Delimiter: lexeme.Item{Type: lexeme.CloseCodeFence, Val: "```"},
+					EndLine:   lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+				},
+				Nodes: []parsetree.Node{
+					{
+						Header: parsetree.Header{
+							Hash:    lexeme.Item{Type: lexeme.Hash, Val: "#"},
+							Name:    lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
+							EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+						},
+						EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+						Blocks: []parsetree.Block{
+							parsetree.Paragraph{
+								Lines: []parsetree.Line{
+									{
+										Items: []parsetree.Inline{
+											parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}},
+										},
+										EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+									},
+								},
+								EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+							},
+						},
+					},
+				},
+				Eof: lexeme.Item{Type: lexeme.Eof, Val: ""},
+			},
+			consumed: 33,
+			err:      nil,
+		},
 		"no frontmatter": {
 			input: []lexeme.Item{
 				{Type: lexeme.Hash, Val: "#"},
@@ -1884,7 +2534,7 @@ func TestScript(t *testing.T) {
 											Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 											CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 											OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-											Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+											Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 											CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 											EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 										},
@@ -1896,7 +2546,7 @@ func TestScript(t *testing.T) {
 											Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 											CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 											OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-											Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+											Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 											CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 											EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 										},
@@ -1908,7 +2558,7 @@ func TestScript(t *testing.T) {
 											Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 											CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 											OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-											Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+											Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 											CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 											EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 										},
@@ -1932,7 +2582,7 @@ func TestScript(t *testing.T) {
 									Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 									CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 									OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-									Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+									Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 									CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 									EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 								},
@@ -2018,3 +2668,47 @@ func TestScript(t *testing.T) {
 		})
 	}
 }
+
+func TestSync(t *testing.T) {
+	tokens := []lexeme.Item{
+		{Type: lexeme.TextLiteral, Val: "abc"}, // 0
+		{Type: lexeme.LineBreak, Val: "\n"},
+		{Type: lexeme.TextLiteral, Val: "abc"},
+		{Type: lexeme.LineBreak, Val: "\n"},
+		{Type: lexeme.LineBreak, Val: "\n"},
+		{Type: lexeme.OpenSquareBrace, Val: "["}, // 5
+		{Type: lexeme.LineBreak, Val: "\n"},
+		{Type: lexeme.OpenCodeFence, Val: "```"},
+		{Type: lexeme.LineBreak, Val: "\n"},
+		{Type: lexeme.CloseCodeFence, Val: "```"},
+		{Type: lexeme.LineBreak, Val: "\n"}, // 10
+		{Type: lexeme.Hash, Val: "#"},
+		{Type: lexeme.Symbol, Val: "abc"},
+		{Type: lexeme.LineBreak, Val: "\n"},
+		{Type: lexeme.Eof, Val: ""},
+	}
+
+	for name, test := range map[string]struct {
+		sync     Sync
+		pos      int
+		expected int
+	}{
+		"block to blank line":    {SyncBlock, 0, 5},
+		"block to code fence":    {SyncBlock, 5, 7},
+		"block past code fence":  {SyncBlock, 7, 11},
+		"block at header":        {SyncBlock, 11, 11},
+		"block at stray newline": {SyncBlock, 13, 14},
+		"block at eof":           {SyncBlock, 14, 14},
+		"block off the end":      {SyncBlock, 15, 15},
+		"node to header":         {SyncNode, 0, 11},
+		"node from header":       {SyncNode, 11, 14},
+		"node at eof":            {SyncNode, 14, 14},
+		"node off the end":       {SyncNode, 15, 15},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if actual := test.sync(tokens, test.pos); actual != test.expected {
+				t.Errorf("expected %d got %d", test.expected, actual)
+			}
+		})
+	}
+}
@@ -9,11 +9,12 @@ import (
 )
 
 var (
-	ErrorNoMatches     = errors.New("no matches found")
-	ErrorBadRuleName   = errors.New("invalid production name")
-	ErrorTokenMismatch = errors.New("invalid token type")
-	ErrorLeftRecursion = errors.New("illegal left recursion")
-	ErrorUnexpectedEof = errors.New("unexpected EOF")
+	ErrorNoMatches       = errors.New("no matches found")
+	ErrorBadRuleName     = errors.New("invalid production name")
+	ErrorTokenMismatch   = errors.New("invalid token type")
+	ErrorLeftRecursion   = errors.New("illegal left recursion")
+	ErrorUnexpectedEof   = errors.New("unexpected EOF")
+	ErrorUnexpectedToken = errors.New("unexpected token")
 )
 
 type (
@@ -23,6 +24,8 @@ type (
 		FrontMatter  parsetree.FrontMatter
 		FuncDecls    []parsetree.FuncDecl
 		FuncDecl     parsetree.FuncDecl
+		VarDecls     []parsetree.VarDecl
+		VarDecl      parsetree.VarDecl
 		Params       []lexeme.Item
 		Nodes        []parsetree.Node
 		Node         parsetree.Node
@@ -40,6 +43,8 @@ type (
 		Statement    parsetree.Statement
 		Expression   parsetree.Expression
 		FuncArgsList []parsetree.Expression
+		MapEntries   []parsetree.MapEntry
+		Skipped      []parsetree.Skipped
 	}
 	Result struct {
 		Consumed int
@@ -48,6 +53,10 @@ type (
 	Action   func(m ...Val) Val
 	Rule     func(a Action) Parselet
 	Parselet func(ctx Context) (*Result, error)
+
+	// Sync finds where parsing can safely resume after a syntax error
+	// at pos. Returning pos means there is nothing that can be skipped.
+	Sync func(tokens []lexeme.Item, pos int) int
 )
 
 func Seq(p ...Parselet) Rule {
@@ -125,6 +134,42 @@ func ZeroOrMore(parse Parselet) Rule {
 	}
 }
 
+// Recover tries parse, and if it fails, skips ahead to the next safe point
+// found by sync instead of failing. The skipped tokens and the error are
+// handed to the action as a parsetree.Skipped so that the enclosing rule
+// can carry on. It still fails if there's nothing to skip.
+func Recover(parse Parselet, sync Sync) Rule {
+	return func(action Action) Parselet {
+		return func(ctx Context) (*Result, error) {
+			attempt := ctx.Move(0)
+			attempt.Farthest = &Failure{Pos: -1}
+			r, err := parse(attempt)
+			farthest := attempt.Farthest.Pos
+			if ctx.Farthest != nil && farthest > ctx.Farthest.Pos {
+				ctx.Farthest.Pos = farthest
+			}
+			if err == nil {
+				return r, nil
+			}
+
+			end := sync(ctx.Tokens, ctx.Pos)
+			if end <= ctx.Pos {
+				return nil, err
+			}
+			if farthest < ctx.Pos || farthest >= end {
+				farthest = ctx.Pos
+			}
+			return &Result{
+				Consumed: end - ctx.Pos,
+				Val: action(Val{Skipped: []parsetree.Skipped{{
+					Tokens: ctx.Tokens[ctx.Pos:end],
+					Err:    unexpected(ctx.Tokens[farthest]),
+				}}}),
+			}, nil
+		}
+	}
+}
+
 func Empty(action Action) Parselet {
 	return func(ctx Context) (*Result, error) {
 		return &Result{
@@ -171,11 +216,13 @@ func Nonterm(name string) Parselet {
 func Term(name lexeme.ItemType) Parselet {
 	return func(ctx Context) (*Result, error) {
 		if ctx.Pos >= len(ctx.Tokens) {
+			ctx.Fail()
 			return nil, ErrorUnexpectedEof
 		}
 		token := ctx.Tokens[ctx.Pos]
 		if token.Type != name {
-			return nil, fmt.Errorf("%w: token type %d expected %d", ErrorTokenMismatch, token.Type, name)
+			ctx.Fail()
+			return nil, fmt.Errorf("%v: %w: token type %d expected %d", token.Pos, ErrorTokenMismatch, token.Type, name)
 		}
 		return &Result{
 			Consumed: 1,
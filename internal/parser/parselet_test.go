@@ -437,3 +437,51 @@ func TestNonterm(t *testing.T) {
 		t.Errorf("expected error on left recursion")
 	}
 }
+
+func TestRecover(t *testing.T) {
+	v := Val{Token: lexeme.Item{Type: lexeme.Symbol, Val: "abc"}}
+	tokens := []lexeme.Item{
+		{Type: lexeme.Symbol, Val: "abc"},
+		{Type: lexeme.Number, Val: "5"},
+		{Type: lexeme.Symbol, Val: "def"},
+		{Type: lexeme.Eof, Val: ""},
+	}
+	skipTwo := func(tokens []lexeme.Item, pos int) int {
+		if pos+2 > len(tokens) {
+			return pos
+		}
+		return pos + 2
+	}
+	action := func(m ...Val) Val {
+		return m[0]
+	}
+
+	ctx := NewContext(tokens, Grammar)
+	r, err := Recover(val(v), skipTwo)(action)(ctx)
+	if err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	if r.Consumed != 1 || r.Val.Token != v.Token || len(r.Val.Skipped) != 0 {
+		t.Errorf("expected successful parse to pass through, got %v", r)
+	}
+
+	r, err = Recover(Seq(Term(lexeme.Symbol), Term(lexeme.Symbol))(action), skipTwo)(action)(ctx)
+	if err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	if r.Consumed != 2 || len(r.Val.Skipped) != 1 || len(r.Val.Skipped[0].Tokens) != 2 {
+		t.Fatalf("expected two tokens skipped, got %v", r)
+	}
+	var syntaxErr SyntaxError
+	if !errors.As(r.Val.Skipped[0].Err, &syntaxErr) || syntaxErr.Token != tokens[1] {
+		t.Errorf("expected error at the farthest failure, got %v", r.Val.Skipped[0].Err)
+	}
+	if ctx.Farthest.Pos != 1 {
+		t.Errorf("expected farthest failure to be recorded, got %d", ctx.Farthest.Pos)
+	}
+
+	_, err = Recover(fail(ErrorTokenMismatch), skipTwo)(action)(ctx.Move(3))
+	if !errors.Is(err, ErrorTokenMismatch) {
+		t.Errorf("expected failure when there is nothing to skip, got %v", err)
+	}
+}
@@ -1,6 +1,11 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/mcvoid/dialogue/internal/types/lexeme"
 	"github.com/mcvoid/dialogue/internal/types/parsetree"
 )
@@ -13,8 +18,41 @@ type (
 	Lexer interface {
 		Lex() []lexeme.Item
 	}
+
+	// SyntaxError is a lexing or parsing failure at a location in the script.
+	// Token is the token which couldn't be matched, or the lexer's Error token.
+	SyntaxError struct {
+		Pos   lexeme.Position
+		Token lexeme.Item
+		Err   error
+	}
+
+	// SyntaxErrors is every syntax error the parser recovered from,
+	// in the order they appear in the script.
+	SyntaxErrors []SyntaxError
 )
 
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Pos, e.Err)
+}
+
+func (e SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+func (e SyntaxErrors) Error() string {
+	lines := []string{}
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Lexical reports whether the error came from the lexer rather than the grammar.
+func (e SyntaxError) Lexical() bool {
+	return e.Token.Type == lexeme.Error
+}
+
 func New() *Parser {
 	return &Parser{
 		start:   "script",
@@ -22,14 +60,72 @@ func New() *Parser {
 	}
 }
 
+// Parse builds a parse tree from the lexer's tokens. When the parser is
+// able to recover from syntax errors, it returns the partial tree along
+// with SyntaxErrors listing what was skipped.
 func (p *Parser) Parse(l Lexer) (parsetree.Script, error) {
+	tokens := l.Lex()
+	if n := len(tokens); n > 0 && tokens[n-1].Type == lexeme.Error {
+		// the lexer stops at its first error, so it's always the last token
+		return parsetree.Script{}, SyntaxError{
+			Pos:   tokens[n-1].Pos,
+			Token: tokens[n-1],
+			Err:   errors.New(tokens[n-1].Val),
+		}
+	}
 	ctx := NewContext(
-		l.Lex(),
+		tokens,
 		Grammar,
 	)
 	r, err := p.grammar[p.start](ctx)
 	if err != nil {
-		return parsetree.Script{}, err
+		return parsetree.Script{}, syntaxError(ctx, err)
+	}
+	if errs := recoveredErrors(r.Val.Script); len(errs) > 0 {
+		return r.Val.Script, errs
 	}
 	return r.Val.Script, nil
 }
+
+func recoveredErrors(script parsetree.Script) SyntaxErrors {
+	skipped := append([]parsetree.Skipped{}, script.Skipped...)
+	for _, node := range script.Nodes {
+		for _, block := range node.Blocks {
+			if s, ok := block.(parsetree.Skipped); ok {
+				skipped = append(skipped, s)
+			}
+		}
+	}
+
+	errs := SyntaxErrors{}
+	for _, s := range skipped {
+		var syntaxErr SyntaxError
+		if errors.As(s.Err, &syntaxErr) {
+			errs = append(errs, syntaxErr)
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Pos.Offset < errs[j].Pos.Offset
+	})
+	return errs
+}
+
+// syntaxError locates a failed parse at the farthest token the grammar
+// was unable to match.
+func syntaxError(ctx Context, err error) error {
+	pos := ctx.Farthest.Pos
+	if pos < 0 || len(ctx.Tokens) == 0 {
+		return err
+	}
+	if pos >= len(ctx.Tokens) {
+		pos = len(ctx.Tokens) - 1
+	}
+	return unexpected(ctx.Tokens[pos])
+}
+
+func unexpected(token lexeme.Item) SyntaxError {
+	if token.Type == lexeme.Eof {
+		return SyntaxError{Pos: token.Pos, Token: token, Err: ErrorUnexpectedEof}
+	}
+	return SyntaxError{Pos: token.Pos, Token: token, Err: fmt.Errorf("%w %q", ErrorUnexpectedToken, token.Val)}
+}
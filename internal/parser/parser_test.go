@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/mcvoid/dialogue/internal/types/lexeme"
@@ -188,7 +189,7 @@ func TestParser(t *testing.T) {
 									Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 									CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 									OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-									Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+									Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 									CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 									EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 								},
@@ -200,7 +201,7 @@ func TestParser(t *testing.T) {
 									Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 									CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 									OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-									Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+									Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 									CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 									EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 								},
@@ -212,7 +213,7 @@ func TestParser(t *testing.T) {
 									Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 									CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 									OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-									Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+									Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 									CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 									EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 								},
@@ -236,7 +237,7 @@ func TestParser(t *testing.T) {
 							Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 							CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 							OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-							Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 							CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 							EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 						},
@@ -389,3 +390,137 @@ func TestParser(t *testing.T) {
 		t.Errorf("error expected")
 	}
 }
+
+func TestParserErrorPositions(t *testing.T) {
+	parser := New()
+	at := func(line, col int) lexeme.Position {
+		return lexeme.Position{Line: line, Column: col}
+	}
+	tokens := []lexeme.Item{
+		{Type: lexeme.CloseCodeFence, Val: "```", Pos: at(1, 1)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(1, 4)},
+		{Type: lexeme.Hash, Val: "#", Pos: at(2, 1)},
+		{Type: lexeme.Symbol, Val: "abc", Pos: at(2, 3)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(2, 6)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(3, 1)},
+		{Type: lexeme.OpenCodeFence, Val: "```", Pos: at(4, 1)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(4, 4)},
+		{Type: lexeme.GotoLiteral, Val: "goto", Pos: at(5, 1)},
+		{Type: lexeme.Symbol, Val: "abc", Pos: at(5, 6)},
+		{Type: lexeme.Symbol, Val: "def", Pos: at(5, 10)},
+		{Type: lexeme.CloseCodeFence, Val: "```", Pos: at(6, 1)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(6, 4)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(7, 1)},
+		{Type: lexeme.Eof, Val: "", Pos: at(8, 1)},
+	}
+	_, err := parser.Parse(mockLexer(tokens))
+	var recovered SyntaxErrors
+	if !errors.As(err, &recovered) || len(recovered) != 1 {
+		t.Fatalf("expected one syntax error got %v", err)
+	}
+	if recovered[0].Pos != at(5, 10) {
+		t.Errorf("expected error at 5:10 got %v", recovered[0].Pos)
+	}
+	if !errors.Is(recovered[0], ErrorUnexpectedToken) {
+		t.Errorf("expected unexpected token error got %v", err)
+	}
+
+	tokens = []lexeme.Item{
+		{Type: lexeme.CloseCodeFence, Val: "```", Pos: at(1, 1)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(1, 4)},
+		{Type: lexeme.Hash, Val: "#", Pos: at(2, 1)},
+		{Type: lexeme.Error, Val: "bad header", Pos: at(2, 3)},
+	}
+	_, err = parser.Parse(mockLexer(tokens))
+	var syntaxErr SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected syntax error got %v", err)
+	}
+	if syntaxErr.Pos != at(2, 3) || syntaxErr.Err.Error() != "bad header" {
+		t.Errorf("expected lexer error at 2:3 got %v", err)
+	}
+}
+
+func TestParserRecovery(t *testing.T) {
+	parser := New()
+	at := func(line int) lexeme.Position {
+		return lexeme.Position{Line: line, Column: 1, Offset: line * 100}
+	}
+	tokens := []lexeme.Item{
+		{Type: lexeme.CloseCodeFence, Val: "```", Pos: at(1)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(1)},
+		// a node missing the blank line after its header
+		{Type: lexeme.Hash, Val: "#", Pos: at(2)},
+		{Type: lexeme.Symbol, Val: "broken", Pos: at(2)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(2)},
+		{Type: lexeme.TextLiteral, Val: "abc", Pos: at(3)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(3)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(4)},
+		{Type: lexeme.Hash, Val: "#", Pos: at(5)},
+		{Type: lexeme.Symbol, Val: "good", Pos: at(5)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(5)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(6)},
+		// a bad code block
+		{Type: lexeme.OpenCodeFence, Val: "```", Pos: at(7)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(7)},
+		{Type: lexeme.GotoLiteral, Val: "goto", Pos: at(8)},
+		{Type: lexeme.Semicolon, Val: ";", Pos: at(8)},
+		{Type: lexeme.CloseCodeFence, Val: "```", Pos: at(9)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(9)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(10)},
+		// a good paragraph
+		{Type: lexeme.TextLiteral, Val: "def", Pos: at(11)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(11)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(12)},
+		// a bad link followed directly by a good code block
+		{Type: lexeme.OpenSquareBrace, Val: "[", Pos: at(13)},
+		{Type: lexeme.CloseSquareBrace, Val: "]", Pos: at(13)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(13)},
+		{Type: lexeme.OpenCodeFence, Val: "```", Pos: at(14)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(14)},
+		{Type: lexeme.GotoLiteral, Val: "goto", Pos: at(15)},
+		{Type: lexeme.Symbol, Val: "good", Pos: at(15)},
+		{Type: lexeme.Semicolon, Val: ";", Pos: at(15)},
+		{Type: lexeme.CloseCodeFence, Val: "```", Pos: at(16)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(16)},
+		{Type: lexeme.LineBreak, Val: "\n", Pos: at(17)},
+		{Type: lexeme.Eof, Val: "", Pos: at(18)},
+	}
+	script, err := parser.Parse(mockLexer(tokens))
+	var recovered SyntaxErrors
+	if !errors.As(err, &recovered) {
+		t.Fatalf("expected syntax errors got %v", err)
+	}
+	expectedLines := []int{3, 8, 13}
+	if len(recovered) != len(expectedLines) {
+		t.Fatalf("expected %d errors got %v", len(expectedLines), recovered)
+	}
+	for i, line := range expectedLines {
+		if recovered[i].Pos.Line != line {
+			t.Errorf("error %d: expected line %d got %v", i, line, recovered[i])
+		}
+	}
+
+	if len(script.Nodes) != 1 || script.Nodes[0].Header.Name.Val != "good" {
+		t.Fatalf("expected only the good node to be parsed, got %v", script.Nodes)
+	}
+	if len(script.Skipped) != 1 || len(script.Skipped[0].Tokens) != 6 {
+		t.Errorf("expected the broken node to be skipped, got %v", script.Skipped)
+	}
+	blocks := script.Nodes[0].Blocks
+	if len(blocks) != 4 {
+		t.Fatalf("expected 4 blocks got %v", blocks)
+	}
+	if _, ok := blocks[0].(parsetree.Skipped); !ok {
+		t.Errorf("expected bad code block to be skipped, got %v", blocks[0])
+	}
+	if _, ok := blocks[1].(parsetree.Paragraph); !ok {
+		t.Errorf("expected paragraph, got %v", blocks[1])
+	}
+	if _, ok := blocks[2].(parsetree.Skipped); !ok {
+		t.Errorf("expected bad link to be skipped, got %v", blocks[2])
+	}
+	if _, ok := blocks[3].(parsetree.CodeBlock); !ok {
+		t.Errorf("expected code block, got %v", blocks[3])
+	}
+}
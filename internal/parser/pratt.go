@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"github.com/mcvoid/dialogue/internal/types/lexeme"
+	"github.com/mcvoid/dialogue/internal/types/parsetree"
+)
+
+// Precedence is how tightly an operator binds. Operators with a higher
+// precedence are grouped first.
+type Precedence int
+
+// Precedence levels, from loosest to tightest. A new operator goes into
+// InfixOperators or PrefixOperators at the level it binds at.
+const (
+	LogicalOrPrecedence Precedence = iota + 1
+	LogicalAndPrecedence
+	EqualityPrecedence
+	ComparisonPrecedence
+	AdditivePrecedence
+	MultiplicativePrecedence
+	PrefixPrecedence
+)
+
+// Operator is how an operator token binds in an expression.
+type Operator struct {
+	Precedence Precedence
+	// RightAssoc groups a chain of operators at the same level from the
+	// right instead of from the left.
+	RightAssoc bool
+}
+
+// InfixOperators are the binary operators, which all group from the left,
+// so 10 - 3 - 2 is (10 - 3) - 2.
+var InfixOperators = map[lexeme.ItemType]Operator{
+	lexeme.Or:       {Precedence: LogicalOrPrecedence},
+	lexeme.And:      {Precedence: LogicalAndPrecedence},
+	lexeme.DoubleEq: {Precedence: EqualityPrecedence},
+	lexeme.Neq:      {Precedence: EqualityPrecedence},
+	lexeme.Gt:       {Precedence: ComparisonPrecedence},
+	lexeme.Gte:      {Precedence: ComparisonPrecedence},
+	lexeme.Lt:       {Precedence: ComparisonPrecedence},
+	lexeme.Lte:      {Precedence: ComparisonPrecedence},
+	lexeme.Plus:     {Precedence: AdditivePrecedence},
+	lexeme.Minus:    {Precedence: AdditivePrecedence},
+	lexeme.Dot:      {Precedence: AdditivePrecedence},
+	lexeme.Star:     {Precedence: MultiplicativePrecedence},
+	lexeme.Slash:    {Precedence: MultiplicativePrecedence},
+	lexeme.Percent:  {Precedence: MultiplicativePrecedence},
+}
+
+// PrefixOperators are the unary operators. They can be chained, as in
+// !!flag or - -x.
+var PrefixOperators = map[lexeme.ItemType]Operator{
+	lexeme.Inc:   {Precedence: PrefixPrecedence},
+	lexeme.Dec:   {Precedence: PrefixPrecedence},
+	lexeme.Not:   {Precedence: PrefixPrecedence},
+	lexeme.Minus: {Precedence: PrefixPrecedence},
+}
+
+// Pratt parses an expression of operands joined by operators, grouping
+// them by precedence climbing. An operator which isn't followed by an
+// operand is left unconsumed, the way an Or of Seqs would leave it.
+func Pratt(operand Parselet, prefix, infix map[lexeme.ItemType]Operator) Parselet {
+	var parse func(ctx Context, min Precedence) (*Result, error)
+
+	// unary parses an operand along with any prefix operators before it.
+	unary := func(ctx Context) (*Result, error) {
+		if ctx.Pos < len(ctx.Tokens) {
+			token := ctx.Tokens[ctx.Pos]
+			if op, ok := prefix[token.Type]; ok {
+				r, err := parse(ctx.Move(1), op.Precedence)
+				if err != nil {
+					return nil, err
+				}
+				return &Result{
+					Consumed: 1 + r.Consumed,
+					Val: Val{Expression: parsetree.UnaryExpression{
+						Operator: token,
+						Operand:  r.Val.Expression,
+					}},
+				}, nil
+			}
+		}
+		return operand(ctx)
+	}
+
+	parse = func(ctx Context, min Precedence) (*Result, error) {
+		left, err := unary(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			next := ctx.Move(left.Consumed)
+			if next.Pos >= len(next.Tokens) {
+				next.Fail()
+				return left, nil
+			}
+			token := next.Tokens[next.Pos]
+			op, ok := infix[token.Type]
+			if !ok {
+				next.Fail()
+				return left, nil
+			}
+			if op.Precedence < min {
+				return left, nil
+			}
+			rightMin := op.Precedence + 1
+			if op.RightAssoc {
+				rightMin = op.Precedence
+			}
+			right, err := parse(next.Move(1), rightMin)
+			if err != nil {
+				return left, nil
+			}
+			left = &Result{
+				Consumed: left.Consumed + 1 + right.Consumed,
+				Val: Val{Expression: parsetree.BinaryExpression{
+					LeftOperand:  left.Val.Expression,
+					Operator:     token,
+					RightOperand: right.Val.Expression,
+				}},
+			}
+		}
+	}
+
+	return func(ctx Context) (*Result, error) {
+		return parse(ctx, 0)
+	}
+}
@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/mcvoid/dialogue/internal/types/lexeme"
+	"github.com/mcvoid/dialogue/internal/types/parsetree"
+)
+
+func TestPrattRightAssoc(t *testing.T) {
+	parse := Pratt(Nonterm("literal"), PrefixOperators, map[lexeme.ItemType]Operator{
+		lexeme.Plus: {Precedence: AdditivePrecedence},
+		lexeme.Star: {Precedence: MultiplicativePrecedence, RightAssoc: true},
+	})
+	num := func(n string) parsetree.Literal {
+		return parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: n}}
+	}
+	star := lexeme.Item{Type: lexeme.Star, Val: "*"}
+	plus := lexeme.Item{Type: lexeme.Plus, Val: "+"}
+
+	ctx := NewContext([]lexeme.Item{
+		num("1").Value, star, num("2").Value, star, num("3").Value, plus, num("4").Value,
+	}, Grammar)
+	r, err := parse(ctx)
+	if err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	expected := parsetree.BinaryExpression{
+		LeftOperand: parsetree.BinaryExpression{
+			LeftOperand: num("1"),
+			Operator:    star,
+			RightOperand: parsetree.BinaryExpression{
+				LeftOperand:  num("2"),
+				Operator:     star,
+				RightOperand: num("3"),
+			},
+		},
+		Operator:     plus,
+		RightOperand: num("4"),
+	}
+	if r.Consumed != 7 {
+		t.Errorf("expected 7 consumed got %d", r.Consumed)
+	}
+	if !r.Val.Expression.CompareExpression(expected) {
+		t.Errorf("expected value %v got %v", expected, r.Val.Expression)
+	}
+
+	if _, err := parse(NewContext([]lexeme.Item{{Type: lexeme.Minus, Val: "-"}}, Grammar)); err == nil {
+		t.Errorf("expected error on an operator without an operand")
+	}
+}
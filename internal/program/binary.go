@@ -0,0 +1,567 @@
+package program
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mcvoid/dialogue/internal/types/asm"
+)
+
+const (
+	// BinaryMagic starts every program in the binary encoding.
+	BinaryMagic = "\x7fDLG"
+	// BinaryObjectMagic starts every object in the binary encoding.
+	BinaryObjectMagic = "\x7fDLO"
+	// BinaryVersion is the version of the binary encoding written by
+	// WriteBinaryTo. ReadBinaryFrom rejects any other version.
+	BinaryVersion = 1
+)
+
+// binaryTypes gives each type its byte in the binary encoding. The
+// zero byte is an instruction without an argument or a function
+// without a return type.
+var binaryTypes = []asm.Type{
+	"",
+	asm.BooleanType,
+	asm.NumberType,
+	asm.StringType,
+	asm.NullType,
+	asm.SymbolType,
+	asm.ListType,
+	asm.MapType,
+}
+
+// hasArg is set in an opcode's byte when an argument follows it.
+const hasArg = 0x80
+
+// fractionType is the type byte of a number with a fraction, which is
+// written as the eight bytes of a float64 instead of as a varint.
+const fractionType = 0x40
+
+func typeIndex(t asm.Type) (int, bool) {
+	for i, bt := range binaryTypes {
+		if bt == t {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// binaryWriter builds the body of a binary program. Strings and symbols
+// are written as indexes into a pool which is written before the body.
+type binaryWriter struct {
+	body    bytes.Buffer
+	pool    []string
+	indexes map[string]int
+}
+
+func (w *binaryWriter) uvarint(n int) {
+	var b [binary.MaxVarintLen64]byte
+	w.body.Write(b[:binary.PutUvarint(b[:], uint64(n))])
+}
+
+func (w *binaryWriter) varint(n int) {
+	var b [binary.MaxVarintLen64]byte
+	w.body.Write(b[:binary.PutVarint(b[:], int64(n))])
+}
+
+func (w *binaryWriter) str(s string) {
+	i, ok := w.indexes[s]
+	if !ok {
+		i = len(w.pool)
+		w.pool = append(w.pool, s)
+		w.indexes[s] = i
+	}
+	w.uvarint(i)
+}
+
+func (w *binaryWriter) typ(t asm.Type) error {
+	i, ok := typeIndex(t)
+	if !ok {
+		return fmt.Errorf("invalid type %v", t)
+	}
+	w.body.WriteByte(byte(i))
+	return nil
+}
+
+func (w *binaryWriter) value(v asm.Value) error {
+	if f, ok := v.Val.(float64); ok && v.Type == asm.NumberType {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(f))
+		w.body.WriteByte(fractionType)
+		w.body.Write(b[:])
+		return nil
+	}
+	if err := w.typ(v.Type); err != nil {
+		return err
+	}
+	switch v.Type {
+	case asm.BooleanType:
+		b, ok := v.Val.(bool)
+		if !ok {
+			return fmt.Errorf("type boolean must hold a bool")
+		}
+		if b {
+			w.body.WriteByte(1)
+		} else {
+			w.body.WriteByte(0)
+		}
+	case asm.NumberType:
+		n, ok := v.Val.(int)
+		if !ok {
+			return fmt.Errorf("type number must hold an int or float64")
+		}
+		w.varint(n)
+	case asm.StringType, asm.SymbolType:
+		s, ok := v.Val.(string)
+		if !ok {
+			return fmt.Errorf("type %v must hold a string", v.Type)
+		}
+		w.str(s)
+	case asm.ListType, asm.MapType:
+		return fmt.Errorf("type %v cannot be an argument", v.Type)
+	}
+	return nil
+}
+
+// WriteBinaryTo writes the program in the binary encoding: the magic and
+// version, a pool of the strings and symbols it uses, then the program
+// with one byte for each opcode and varints for numbers and indexes into
+// the pool, and last a CRC-32 of everything before it.
+func (p *Program) WriteBinaryTo(w io.Writer) (n int64, err error) {
+	bw := binaryWriter{indexes: map[string]int{}}
+	if err := bw.program(p); err != nil {
+		return 0, err
+	}
+	return bw.writeTo(w, BinaryMagic)
+}
+
+// WriteBinaryTo writes the object in the binary encoding: its program as
+// WriteBinaryTo writes it, followed by its name and imports, but with
+// its own magic so that it can't be mistaken for a program.
+func (o *Object) WriteBinaryTo(w io.Writer) (n int64, err error) {
+	bw := binaryWriter{indexes: map[string]int{}}
+	if err := bw.program(&o.Program); err != nil {
+		return 0, err
+	}
+	bw.str(o.Name)
+	addrs := []int{}
+	for addr := range o.Imports {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+	bw.uvarint(len(addrs))
+	for _, addr := range addrs {
+		bw.uvarint(addr)
+		bw.str(o.Imports[addr])
+	}
+	return bw.writeTo(w, BinaryObjectMagic)
+}
+
+func (bw *binaryWriter) program(p *Program) error {
+	bw.varint(p.Start)
+	bw.varint(p.Entry)
+
+	bw.uvarint(len(p.Code))
+	for _, instr := range p.Code {
+		op, ok := asm.OpcodeByte(instr.Opcode)
+		if !ok {
+			return fmt.Errorf("invalid opcode %v", instr.Opcode)
+		}
+		if instr.Arg.Type == "" {
+			bw.body.WriteByte(op)
+			continue
+		}
+		bw.body.WriteByte(op | hasArg)
+		if err := bw.value(instr.Arg); err != nil {
+			return err
+		}
+	}
+
+	nodes := []string{}
+	for name := range p.Nodes {
+		nodes = append(nodes, name)
+	}
+	sort.Strings(nodes)
+	bw.uvarint(len(nodes))
+	for _, name := range nodes {
+		bw.str(name)
+		bw.uvarint(p.Nodes[name])
+	}
+
+	funcs := []string{}
+	for name := range p.Funcs {
+		funcs = append(funcs, name)
+	}
+	sort.Strings(funcs)
+	bw.uvarint(len(funcs))
+	for _, name := range funcs {
+		bw.str(name)
+		bw.uvarint(len(p.Funcs[name]))
+		for _, t := range p.Funcs[name] {
+			if err := bw.typ(t); err != nil {
+				return err
+			}
+		}
+		if err := bw.typ(p.Returns[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTo writes the magic, the version, the pool, the body and the
+// checksum.
+func (bw *binaryWriter) writeTo(w io.Writer, magic string) (n int64, err error) {
+	var out bytes.Buffer
+	out.WriteString(magic)
+	header := binaryWriter{}
+	header.uvarint(BinaryVersion)
+	header.uvarint(len(bw.pool))
+	for _, s := range bw.pool {
+		header.uvarint(len(s))
+		header.body.WriteString(s)
+	}
+	out.Write(header.body.Bytes())
+	out.Write(bw.body.Bytes())
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(out.Bytes()))
+	out.Write(sum[:])
+
+	num, err := w.Write(out.Bytes())
+	return int64(num), err
+}
+
+// binaryReader reads the program written by a binaryWriter.
+type binaryReader struct {
+	r    *bytes.Reader
+	pool []string
+}
+
+func (r *binaryReader) uvarint() (int, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return 0, errTruncated(err)
+	}
+	if n > uint64(r.r.Size()) {
+		return 0, fmt.Errorf("binary program holds %d, which is too large", n)
+	}
+	return int(n), nil
+}
+
+func (r *binaryReader) varint() (int, error) {
+	n, err := binary.ReadVarint(r.r)
+	if err != nil {
+		return 0, errTruncated(err)
+	}
+	return int(n), nil
+}
+
+func (r *binaryReader) str() (string, error) {
+	i, err := r.uvarint()
+	if err != nil {
+		return "", err
+	}
+	if i >= len(r.pool) {
+		return "", fmt.Errorf("string %d is not in the constant pool", i)
+	}
+	return r.pool[i], nil
+}
+
+func (r *binaryReader) typ() (asm.Type, error) {
+	b, err := r.r.ReadByte()
+	if err != nil {
+		return "", errTruncated(err)
+	}
+	if int(b) >= len(binaryTypes) {
+		return "", fmt.Errorf("invalid type %d", b)
+	}
+	return binaryTypes[b], nil
+}
+
+func (r *binaryReader) value() (asm.Value, error) {
+	if b, err := r.r.ReadByte(); err == nil && b == fractionType {
+		var f [8]byte
+		if _, err := io.ReadFull(r.r, f[:]); err != nil {
+			return asm.Value{}, errTruncated(err)
+		}
+		return asm.Value{Type: asm.NumberType, Val: math.Float64frombits(binary.BigEndian.Uint64(f[:]))}, nil
+	} else if err == nil {
+		r.r.UnreadByte()
+	}
+	t, err := r.typ()
+	if err != nil {
+		return asm.Value{}, err
+	}
+	switch t {
+	case asm.BooleanType:
+		b, err := r.r.ReadByte()
+		if err != nil {
+			return asm.Value{}, errTruncated(err)
+		}
+		return asm.Value{Type: t, Val: b != 0}, nil
+	case asm.NumberType:
+		n, err := r.varint()
+		return asm.Value{Type: t, Val: n}, err
+	case asm.StringType, asm.SymbolType:
+		s, err := r.str()
+		return asm.Value{Type: t, Val: s}, err
+	case asm.NullType:
+		return asm.Null, nil
+	}
+	return asm.Value{}, fmt.Errorf("an argument must have a type")
+}
+
+func errTruncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("binary program is truncated")
+	}
+	return err
+}
+
+// ReadBinaryFrom reads a program written by WriteBinaryTo.
+func (p *Program) ReadBinaryFrom(r io.Reader) (n int64, err error) {
+	b, err := ioutil.ReadAll(r)
+	bytesRead := int64(len(b))
+	if err != nil {
+		return bytesRead, err
+	}
+	br, err := openBinary(b, BinaryMagic, "program")
+	if err != nil {
+		return bytesRead, err
+	}
+	if err := br.read(p); err != nil {
+		return bytesRead, err
+	}
+	if br.r.Len() != 0 {
+		return bytesRead, fmt.Errorf("binary program has %d bytes left over", br.r.Len())
+	}
+	return bytesRead, nil
+}
+
+// ReadBinaryFrom reads an object written by WriteBinaryTo.
+func (o *Object) ReadBinaryFrom(r io.Reader) (n int64, err error) {
+	b, err := ioutil.ReadAll(r)
+	bytesRead := int64(len(b))
+	if err != nil {
+		return bytesRead, err
+	}
+	br, err := openBinary(b, BinaryObjectMagic, "object")
+	if err != nil {
+		return bytesRead, err
+	}
+	*o = Object{Format: ObjectFormat, Version: ObjectVersion, Imports: map[int]string{}}
+	if err := br.read(&o.Program); err != nil {
+		return bytesRead, err
+	}
+	if o.Name, err = br.str(); err != nil {
+		return bytesRead, err
+	}
+	importsLen, err := br.uvarint()
+	if err != nil {
+		return bytesRead, err
+	}
+	for i := 0; i < importsLen; i++ {
+		addr, err := br.uvarint()
+		if err != nil {
+			return bytesRead, err
+		}
+		if o.Imports[addr], err = br.str(); err != nil {
+			return bytesRead, err
+		}
+	}
+	if br.r.Len() != 0 {
+		return bytesRead, fmt.Errorf("binary object has %d bytes left over", br.r.Len())
+	}
+	return bytesRead, nil
+}
+
+// openBinary checks the magic and checksum of a binary program or
+// object, and gives a reader of what's between them.
+func openBinary(b []byte, magic, what string) (*binaryReader, error) {
+	if len(b) < len(magic)+4 || string(b[:len(magic)]) != magic {
+		return nil, fmt.Errorf("not a binary %s", what)
+	}
+	body, sum := b[:len(b)-4], b[len(b)-4:]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(sum) {
+		return nil, fmt.Errorf("binary %s checksum does not match", what)
+	}
+	return &binaryReader{r: bytes.NewReader(body[len(magic):])}, nil
+}
+
+func (r *binaryReader) read(p *Program) error {
+	version, err := r.uvarint()
+	if err != nil {
+		return err
+	}
+	if version != BinaryVersion {
+		return fmt.Errorf("unsupported binary program version %d", version)
+	}
+	poolLen, err := r.uvarint()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < poolLen; i++ {
+		strLen, err := r.uvarint()
+		if err != nil {
+			return err
+		}
+		s := make([]byte, strLen)
+		if _, err := io.ReadFull(r.r, s); err != nil {
+			return errTruncated(err)
+		}
+		r.pool = append(r.pool, string(s))
+	}
+
+	*p = Program{
+		Code:    []asm.Instruction{},
+		Nodes:   map[string]int{},
+		Funcs:   map[string][]asm.Type{},
+		Returns: map[string]asm.Type{},
+	}
+	if p.Start, err = r.varint(); err != nil {
+		return err
+	}
+	if p.Entry, err = r.varint(); err != nil {
+		return err
+	}
+
+	codeLen, err := r.uvarint()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < codeLen; i++ {
+		op, err := r.r.ReadByte()
+		if err != nil {
+			return errTruncated(err)
+		}
+		if int(op&^hasArg) >= len(asm.Opcodes) {
+			return fmt.Errorf("invalid opcode %d at %d", op&^hasArg, i)
+		}
+		instr := asm.Instruction{Opcode: asm.Opcodes[op&^hasArg].Name}
+		if op&hasArg != 0 {
+			if instr.Arg, err = r.value(); err != nil {
+				return err
+			}
+		}
+		p.Code = append(p.Code, instr)
+	}
+
+	nodesLen, err := r.uvarint()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < nodesLen; i++ {
+		name, err := r.str()
+		if err != nil {
+			return err
+		}
+		if p.Nodes[name], err = r.uvarint(); err != nil {
+			return err
+		}
+	}
+
+	funcsLen, err := r.uvarint()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < funcsLen; i++ {
+		name, err := r.str()
+		if err != nil {
+			return err
+		}
+		paramsLen, err := r.uvarint()
+		if err != nil {
+			return err
+		}
+		params := []asm.Type{}
+		for j := 0; j < paramsLen; j++ {
+			t, err := r.typ()
+			if err != nil {
+				return err
+			}
+			params = append(params, t)
+		}
+		p.Funcs[name] = params
+		returnType, err := r.typ()
+		if err != nil {
+			return err
+		}
+		if returnType != "" {
+			p.Returns[name] = returnType
+		}
+	}
+	return nil
+}
+
+// Decode reads a program in any of its encodings: JSON, the binary
+// encoding, or either of those compressed with zlib.
+func Decode(r io.Reader) (Program, error) {
+	p := Program{}
+	err := decode(r, BinaryMagic, p.ReadBinaryFrom, p.ReadFrom)
+	return p, err
+}
+
+// DecodeObject reads an object in any of the encodings Decode reads.
+func DecodeObject(r io.Reader) (Object, error) {
+	o := Object{}
+	err := decode(r, BinaryObjectMagic, o.ReadBinaryFrom, o.ReadFrom)
+	return o, err
+}
+
+// decode finds out how r is encoded and reads it with readBinary or
+// readJSON. JSON can start with whitespace or a byte order mark, as it
+// might once it's been edited by hand.
+func decode(r io.Reader, magic string, readBinary, readJSON func(io.Reader) (int64, error)) error {
+	br := bufio.NewReader(r)
+	skipSpace(br)
+	first, err := br.Peek(len(magic))
+	if err != nil && len(first) == 0 {
+		return err
+	}
+	switch {
+	case string(first) == magic:
+		_, err = readBinary(br)
+		return err
+	case first[0] == '{':
+		_, err = readJSON(br)
+		return err
+	}
+	rc, err := zlib.NewReader(br)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	inner := bufio.NewReader(rc)
+	skipSpace(inner)
+	if first, _ := inner.Peek(len(magic)); string(first) == magic {
+		_, err = readBinary(inner)
+		return err
+	}
+	_, err = readJSON(inner)
+	return err
+}
+
+// skipSpace drops any whitespace and byte order mark at the start of br.
+func skipSpace(br *bufio.Reader) {
+	for {
+		if bom, _ := br.Peek(3); string(bom) == "\xef\xbb\xbf" {
+			br.Discard(3)
+			continue
+		}
+		b, err := br.Peek(1)
+		if err != nil || !strings.ContainsRune(" \t\r\n", rune(b[0])) {
+			return
+		}
+		br.Discard(1)
+	}
+}
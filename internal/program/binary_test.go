@@ -0,0 +1,149 @@
+package program
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/mcvoid/dialogue/internal/types/asm"
+)
+
+var binaryProgram = Program{
+	Start: 0,
+	Code: []asm.Instruction{
+		{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: -300}},
+		{Opcode: asm.InitVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "gold"}},
+		{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "start"}},
+		{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "start"}},
+		{Opcode: asm.PushBool, Arg: asm.True},
+		{Opcode: asm.PushNull},
+		{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 2.5}},
+		{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "f"}},
+		{Opcode: asm.GreaterThan},
+		{Opcode: asm.JumpIfFalse, Arg: asm.Value{Type: asm.NumberType, Val: 2}},
+		{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "start"}},
+		{Opcode: asm.EndDialogue},
+	},
+	Entry:   2,
+	Nodes:   map[string]int{"start": 2},
+	Funcs:   map[string][]asm.Type{"f": {asm.StringType, asm.BooleanType}, "g": {}, "h": {asm.ListType, asm.MapType}},
+	Returns: map[string]asm.Type{"f": asm.NumberType},
+}
+
+func TestBinaryReadWrite(t *testing.T) {
+	var b bytes.Buffer
+	if _, err := binaryProgram.WriteBinaryTo(&b); err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	encoded := b.Bytes()
+
+	read := Program{}
+	if _, err := read.ReadBinaryFrom(bytes.NewReader(encoded)); err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	if !compareProgram(read, binaryProgram) {
+		t.Errorf("expected %v got %v", binaryProgram, read)
+	}
+	if read.Entry != 2 || read.Nodes["start"] != 2 || read.Returns["f"] != asm.NumberType || len(read.Returns) != 1 {
+		t.Errorf("expected the entry, nodes and returns to be kept, got %v", read)
+	}
+
+	var j bytes.Buffer
+	binaryProgram.WriteTo(&j)
+	if len(encoded) >= j.Len() {
+		t.Errorf("expected the binary encoding to be smaller than %d bytes, got %d", j.Len(), len(encoded))
+	}
+
+	// resign fixes the checksum of a changed program so that it gets read
+	resign := func(b []byte) []byte {
+		b = append([]byte{}, b...)
+		binary.BigEndian.PutUint32(b[len(b)-4:], crc32.ChecksumIEEE(b[:len(b)-4]))
+		return b
+	}
+	corrupt := append([]byte{}, encoded...)
+	corrupt[len(corrupt)-6] ^= 0xff
+	badVersion := append([]byte{}, encoded...)
+	badVersion[len(BinaryMagic)] = 2
+	var end bytes.Buffer
+	(&Program{Code: []asm.Instruction{{Opcode: asm.EndDialogue}}}).WriteBinaryTo(&end)
+	// magic, version, pool, start, entry and code length come first
+	badOpcode := end.Bytes()
+	badOpcode[len(BinaryMagic)+5] = 0x7f
+	for name, test := range map[string]struct {
+		input    []byte
+		expected string
+	}{
+		"wrong magic":    {[]byte("{\"start\":0}"), "not a binary program"},
+		"too short":      {[]byte(BinaryMagic), "not a binary program"},
+		"bad checksum":   {corrupt, "binary program checksum does not match"},
+		"wrong version":  {resign(badVersion), "unsupported binary program version 2"},
+		"bad opcode":     {resign(badOpcode), "invalid opcode 127 at 0"},
+		"truncated":      {resign(encoded[:len(encoded)-8]), "binary program is truncated"},
+		"trailing bytes": {resign(append(append([]byte{}, encoded...), 0)), "binary program has 1 bytes left over"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := (&Program{}).ReadBinaryFrom(bytes.NewReader(test.input))
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("expected %q got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestBinaryCollectionArgument(t *testing.T) {
+	p := Program{Code: []asm.Instruction{
+		{Opcode: asm.PushNumber, Arg: asm.NewList([]asm.Value{})},
+		{Opcode: asm.EndDialogue},
+	}}
+	var b bytes.Buffer
+	if _, err := p.WriteBinaryTo(&b); err == nil || err.Error() != "type list cannot be an argument" {
+		t.Errorf("expected a list argument to be refused, got %v", err)
+	}
+}
+
+func compress(b []byte) []byte {
+	var c bytes.Buffer
+	w := zlib.NewWriter(&c)
+	w.Write(b)
+	w.Close()
+	return c.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	var jsonProgram, binProgram bytes.Buffer
+	binaryProgram.WriteTo(&jsonProgram)
+	binaryProgram.WriteBinaryTo(&binProgram)
+	edited := append([]byte("\xef\xbb\xbf\r\n  \t"), jsonProgram.Bytes()...)
+
+	for name, input := range map[string][]byte{
+		"json":              jsonProgram.Bytes(),
+		"hand edited json":  edited,
+		"compressed edited": compress(edited),
+		"binary":            binProgram.Bytes(),
+		"compressed json":   compress(jsonProgram.Bytes()),
+		"compressed binary": compress(binProgram.Bytes()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			p, err := Decode(bytes.NewReader(input))
+			if err != nil {
+				t.Fatalf("no error expected got %v", err)
+			}
+			if !compareProgram(p, binaryProgram) {
+				t.Errorf("expected %v got %v", binaryProgram, p)
+			}
+		})
+	}
+
+	for name, input := range map[string][]byte{
+		"empty":    {},
+		"nonsense": []byte("nonsense"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode(bytes.NewReader(input)); err == nil {
+				t.Errorf("error expected got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package program
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/mcvoid/dialogue/internal/types/asm"
+)
+
+// Disassemble writes a listing of the program with one instruction on
+// each line after its address. Each node's name is written above its
+// first instruction.
+func Disassemble(w io.Writer, p Program) error {
+	labels := map[int][]string{}
+	for name, addr := range p.Nodes {
+		labels[addr] = append(labels[addr], name)
+	}
+
+	for pc, instr := range p.Code {
+		names := labels[pc]
+		sort.Strings(names)
+		for _, name := range names {
+			if _, err := fmt.Fprintf(w, "%s:\n", name); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%6d  %s\n", pc, disassembleInstruction(instr)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func disassembleInstruction(instr asm.Instruction) string {
+	info, ok := asm.Lookup(instr.Opcode)
+	if !ok {
+		return fmt.Sprintf("?%v %v", instr.Opcode, instr.Arg.Val)
+	}
+	switch info.Kind {
+	case asm.NoOperand:
+		return string(instr.Opcode)
+	case asm.LiteralOperand:
+		if s, ok := instr.Arg.Val.(string); ok {
+			return fmt.Sprintf("%v %q", instr.Opcode, s)
+		}
+	case asm.AddressOperand:
+		return fmt.Sprintf("%v -> %v", instr.Opcode, instr.Arg.Val)
+	}
+	return fmt.Sprintf("%v %v", instr.Opcode, instr.Arg.Val)
+}
@@ -0,0 +1,41 @@
+package program
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mcvoid/dialogue/internal/types/asm"
+)
+
+func TestDisassemble(t *testing.T) {
+	p := Program{
+		Code: []asm.Instruction{
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 5}},
+			{Opcode: asm.InitVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "gold"}},
+			{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "start"}},
+			{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "hi\n"}},
+			{Opcode: asm.ShowLine},
+			{Opcode: asm.Jump, Arg: asm.Value{Type: asm.NumberType, Val: 2}},
+			{Opcode: "Bogus"},
+		},
+		Nodes: map[string]int{"start": 2, "alias": 2},
+	}
+	expected := "" +
+		"     0  PushNumber 5\n" +
+		"     1  InitVariable gold\n" +
+		"alias:\n" +
+		"start:\n" +
+		"     2  EnterNode start\n" +
+		"     3  PushString \"hi\\n\"\n" +
+		"     4  ShowLine\n" +
+		"     5  Jump -> 2\n" +
+		"     6  ?Bogus <nil>\n"
+
+	var b bytes.Buffer
+	if err := Disassemble(&b, p); err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	if b.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
@@ -0,0 +1,187 @@
+package program
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/mcvoid/dialogue/internal/types/asm"
+)
+
+const (
+	// ObjectFormat names the encoding of an Object.
+	ObjectFormat = "dialogue-object"
+	// ObjectVersion is the version of the Object layout written by this
+	// compiler. Version 1 objects kept their nodes apart from their
+	// program and can't be read any more.
+	ObjectVersion = 2
+)
+
+// Object is a compiled script which still has to be linked with others
+// before it can run. Its addresses start at zero as if it were the
+// only code in the program, and its program's Nodes are the nodes it
+// gives to the other objects.
+type Object struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	// Name is the file the object was compiled from.
+	Name    string  `json:"name,omitempty"`
+	Program Program `json:"program"`
+	// Imports is the node each unresolved Jump or PushChoice goes to,
+	// keyed by the instruction's address.
+	Imports map[int]string `json:"imports,omitempty"`
+}
+
+func (o *Object) ReadFrom(r io.Reader) (n int64, err error) {
+	b, err := ioutil.ReadAll(r)
+	bytesRead := int64(len(b))
+	if err != nil {
+		return bytesRead, err
+	}
+	if err := json.Unmarshal(b, o); err != nil {
+		return bytesRead, err
+	}
+	if o.Format != ObjectFormat {
+		return bytesRead, fmt.Errorf("not a dialogue object")
+	}
+	if o.Version > 0 && o.Version < ObjectVersion {
+		return bytesRead, fmt.Errorf("object was compiled by an older compiler and must be recompiled")
+	}
+	if o.Version != ObjectVersion {
+		return bytesRead, fmt.Errorf("unsupported object version %d", o.Version)
+	}
+	if o.Program.Funcs == nil {
+		o.Program.Funcs = map[string][]asm.Type{}
+	}
+	if o.Program.Returns == nil {
+		o.Program.Returns = map[string]asm.Type{}
+	}
+	if o.Program.Nodes == nil {
+		o.Program.Nodes = map[string]int{}
+	}
+	if o.Imports == nil {
+		o.Imports = map[int]string{}
+	}
+	return bytesRead, nil
+}
+
+func (o *Object) WriteTo(w io.Writer) (n int64, err error) {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return 0, err
+	}
+
+	num, err := w.Write(b)
+
+	return int64(num), err
+}
+
+// Link combines objects into a single program which starts at the first
+// node of the first object. The code giving each object's globals their
+// initial values is gathered at the start of the program, followed by
+// each object's nodes. Both have their addresses rebased, and each
+// import is pointed at the node in another object.
+func Link(objects ...Object) (Program, error) {
+	p := Program{
+		Start:   0,
+		Code:    []asm.Instruction{},
+		Nodes:   map[string]int{},
+		Funcs:   map[string][]asm.Type{},
+		Returns: map[string]asm.Type{},
+	}
+	declaredBy := map[string]string{}
+
+	for _, obj := range objects {
+		entry := obj.Program.Entry
+		if entry < 0 || entry > len(obj.Program.Code) {
+			return Program{}, fmt.Errorf("%s: entry %d is out of bounds", obj.Name, entry)
+		}
+		// the globals' code can only jump within itself, or to its end
+		base := len(p.Code)
+		for addr, instr := range obj.Program.Code[:entry] {
+			if info, _ := asm.Lookup(instr.Opcode); info.Kind == asm.AddressOperand {
+				dest, ok := instr.Arg.Val.(int)
+				if !ok || dest < 0 || dest > entry {
+					return Program{}, fmt.Errorf("%s: %s at %d goes out of bounds", obj.Name, instr.Opcode, addr)
+				}
+				instr.Arg = asm.Value{Type: asm.NumberType, Val: base + dest}
+			}
+			p.Code = append(p.Code, instr)
+		}
+
+		names := []string{}
+		for name := range obj.Program.Funcs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			params := obj.Program.Funcs[name]
+			returnType, returns := obj.Program.Returns[name]
+			if first, ok := declaredBy[name]; ok {
+				firstReturn, firstReturns := p.Returns[name]
+				if !sameTypes(params, p.Funcs[name]) || returns != firstReturns || returnType != firstReturn {
+					return Program{}, fmt.Errorf("%s: extern %s conflicts with its declaration in %s", obj.Name, name, first)
+				}
+				continue
+			}
+			declaredBy[name] = obj.Name
+			p.Funcs[name] = params
+			if returns {
+				p.Returns[name] = returnType
+			}
+		}
+	}
+
+	p.Entry = len(p.Code)
+	bases := []int{}
+	exportedBy := map[string]string{}
+	base := p.Entry
+	for _, obj := range objects {
+		bases = append(bases, base)
+		for name, addr := range obj.Program.Nodes {
+			if first, ok := exportedBy[name]; ok {
+				return Program{}, fmt.Errorf("%s: node %s is also in %s", obj.Name, name, first)
+			}
+			exportedBy[name] = obj.Name
+			p.Nodes[name] = base + addr - obj.Program.Entry
+		}
+		base += len(obj.Program.Code) - obj.Program.Entry
+	}
+
+	for i, obj := range objects {
+		entry := obj.Program.Entry
+		for addr, instr := range obj.Program.Code[entry:] {
+			addr += entry
+			if name, ok := obj.Imports[addr]; ok {
+				dest, ok := p.Nodes[name]
+				if !ok {
+					return Program{}, fmt.Errorf("%s: node %s is not in any object", obj.Name, name)
+				}
+				instr.Arg = asm.Value{Type: asm.NumberType, Val: dest}
+			} else if info, _ := asm.Lookup(instr.Opcode); info.Kind == asm.AddressOperand {
+				dest, ok := instr.Arg.Val.(int)
+				if !ok || dest < entry || dest >= len(obj.Program.Code) {
+					return Program{}, fmt.Errorf("%s: %s at %d goes out of bounds", obj.Name, instr.Opcode, addr)
+				}
+				instr.Arg = asm.Value{Type: asm.NumberType, Val: bases[i] + dest - entry}
+			}
+			p.Code = append(p.Code, instr)
+		}
+	}
+
+	return p, nil
+}
+
+func sameTypes(a, b []asm.Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
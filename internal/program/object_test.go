@@ -0,0 +1,230 @@
+package program
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mcvoid/dialogue/internal/types/asm"
+)
+
+func TestLink(t *testing.T) {
+	num := func(n int) asm.Value {
+		return asm.Value{Type: asm.NumberType, Val: n}
+	}
+	sym := func(s string) asm.Value {
+		return asm.Value{Type: asm.SymbolType, Val: s}
+	}
+	a := Object{
+		Format: ObjectFormat,
+		Name:   "a.md",
+		Program: Program{
+			Code: []asm.Instruction{
+				{Opcode: asm.PushNumber, Arg: num(1)},
+				{Opcode: asm.InitVariable, Arg: sym("x")},
+				{Opcode: asm.EnterNode, Arg: sym("a.start")},
+				{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "go"}},
+				{Opcode: asm.PushChoice, Arg: num(0)},
+				{Opcode: asm.ExitNode, Arg: sym("a.start")},
+				{Opcode: asm.ShowChoice},
+				{Opcode: asm.ExitNode, Arg: sym("a.start")},
+				{Opcode: asm.EndDialogue},
+			},
+			Entry: 2,
+			Nodes: map[string]int{"a.start": 2},
+			Funcs: map[string][]asm.Type{"f": {asm.NumberType}},
+		},
+		Imports: map[int]string{4: "b.end"},
+	}
+	b := Object{
+		Format: ObjectFormat,
+		Name:   "b.md",
+		Program: Program{
+			Code: []asm.Instruction{
+				{Opcode: asm.PushNumber, Arg: num(2)},
+				{Opcode: asm.InitVariable, Arg: sym("y")},
+				{Opcode: asm.EnterNode, Arg: sym("b.end")},
+				{Opcode: asm.PushBool, Arg: asm.True},
+				{Opcode: asm.JumpIfFalse, Arg: num(2)},
+				{Opcode: asm.ExitNode, Arg: sym("b.end")},
+				{Opcode: asm.Jump, Arg: num(0)},
+				{Opcode: asm.ExitNode, Arg: sym("b.end")},
+				{Opcode: asm.EndDialogue},
+			},
+			Entry:   2,
+			Nodes:   map[string]int{"b.end": 2},
+			Funcs:   map[string][]asm.Type{"f": {asm.NumberType}, "g": {}},
+			Returns: map[string]asm.Type{"g": asm.StringType},
+		},
+		Imports: map[int]string{6: "a.start"},
+	}
+
+	p, err := Link(a, b)
+	if err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	expected := Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.PushNumber, Arg: num(1)},
+			{Opcode: asm.InitVariable, Arg: sym("x")},
+			{Opcode: asm.PushNumber, Arg: num(2)},
+			{Opcode: asm.InitVariable, Arg: sym("y")},
+			{Opcode: asm.EnterNode, Arg: sym("a.start")},
+			{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "go"}},
+			{Opcode: asm.PushChoice, Arg: num(11)},
+			{Opcode: asm.ExitNode, Arg: sym("a.start")},
+			{Opcode: asm.ShowChoice},
+			{Opcode: asm.ExitNode, Arg: sym("a.start")},
+			{Opcode: asm.EndDialogue},
+			{Opcode: asm.EnterNode, Arg: sym("b.end")},
+			{Opcode: asm.PushBool, Arg: asm.True},
+			{Opcode: asm.JumpIfFalse, Arg: num(11)},
+			{Opcode: asm.ExitNode, Arg: sym("b.end")},
+			{Opcode: asm.Jump, Arg: num(4)},
+			{Opcode: asm.ExitNode, Arg: sym("b.end")},
+			{Opcode: asm.EndDialogue},
+		},
+		Funcs: map[string][]asm.Type{"f": {asm.NumberType}, "g": {}},
+	}
+	if !compareProgram(p, expected) {
+		t.Errorf("expected %v got %v", expected, p)
+	}
+	if p.Returns["g"] != asm.StringType {
+		t.Errorf("expected g to return string got %v", p.Returns)
+	}
+	if p.Entry != 4 || len(p.Nodes) != 2 || p.Nodes["a.start"] != 4 || p.Nodes["b.end"] != 11 {
+		t.Errorf("expected the nodes at 4 and 11 entered at 4, got %v at %d", p.Nodes, p.Entry)
+	}
+
+	c := Object{
+		Name: "c.md",
+		Program: Program{
+			Code: []asm.Instruction{
+				{Opcode: asm.PushBool, Arg: asm.True},
+				{Opcode: asm.JumpIfFalse, Arg: num(4)},
+				{Opcode: asm.PushNumber, Arg: num(3)},
+				{Opcode: asm.InitVariable, Arg: sym("z")},
+				{Opcode: asm.EnterNode, Arg: sym("c.mid")},
+				{Opcode: asm.ExitNode, Arg: sym("c.mid")},
+				{Opcode: asm.EndDialogue},
+			},
+			Entry: 4,
+			Nodes: map[string]int{"c.mid": 4},
+		},
+	}
+	d := c
+	d.Name = "d.md"
+	d.Program.Nodes = map[string]int{"d.mid": 4}
+	p, err = Link(c, d)
+	if err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	if p.Entry != 8 || p.Code[1].Arg != num(4) || p.Code[5].Arg != num(8) {
+		t.Errorf("expected the jumps in the globals to go to 4 and 8, got %v and %v", p.Code[1], p.Code[5])
+	}
+
+	conflicting := b
+	conflicting.Program.Funcs = map[string][]asm.Type{"f": {asm.StringType}}
+	duplicate := b
+	duplicate.Name = "c.md"
+	missing := a
+	missing.Imports = map[int]string{4: "c.nowhere"}
+	outOfBounds := a
+	outOfBounds.Imports = map[int]string{}
+	outOfBounds.Program.Code = append([]asm.Instruction{}, a.Program.Code...)
+	outOfBounds.Program.Code[4].Arg = num(20)
+	initOutOfBounds := c
+	initOutOfBounds.Program.Code = append([]asm.Instruction{}, c.Program.Code...)
+	initOutOfBounds.Program.Code[1].Arg = num(5)
+	for name, test := range map[string]struct {
+		objects  []Object
+		expected string
+	}{
+		"conflicting extern": {[]Object{a, conflicting}, "b.md: extern f conflicts with its declaration in a.md"},
+		"duplicate node":     {[]Object{b, duplicate}, "c.md: node b.end is also in b.md"},
+		"missing node":       {[]Object{missing}, "a.md: node c.nowhere is not in any object"},
+		"out of bounds":      {[]Object{outOfBounds}, "a.md: PushChoice at 4 goes out of bounds"},
+		"globals jump":       {[]Object{initOutOfBounds}, "c.md: JumpIfFalse at 1 goes out of bounds"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Link(test.objects...)
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("expected %q got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestObjectReadWrite(t *testing.T) {
+	obj := Object{
+		Format:  ObjectFormat,
+		Version: ObjectVersion,
+		Name:    "a.md",
+		Program: Program{
+			Code: []asm.Instruction{
+				{Opcode: asm.Jump, Arg: asm.Value{Type: asm.NumberType, Val: 0}},
+			},
+			Nodes: map[string]int{"a.start": 0},
+		},
+		Imports: map[int]string{0: "b.end"},
+	}
+	var b bytes.Buffer
+	if _, err := obj.WriteTo(&b); err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	read := Object{}
+	if _, err := read.ReadFrom(&b); err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	if read.Name != "a.md" || read.Program.Nodes["a.start"] != 0 || read.Imports[0] != "b.end" || !compareProgram(read.Program, obj.Program) {
+		t.Errorf("expected %v got %v", obj, read)
+	}
+
+	var bin, edited bytes.Buffer
+	edited.WriteString(" \n\xef\xbb\xbf")
+	obj.WriteTo(&edited)
+	if _, err := obj.WriteBinaryTo(&bin); err != nil {
+		t.Fatalf("no error expected got %v", err)
+	}
+	encoded := bin.Bytes()
+	for name, input := range map[string][]byte{
+		"binary":            encoded,
+		"hand edited json":  edited.Bytes(),
+		"compressed binary": compress(encoded),
+	} {
+		t.Run(name, func(t *testing.T) {
+			read, err := DecodeObject(bytes.NewReader(input))
+			if err != nil {
+				t.Fatalf("no error expected got %v", err)
+			}
+			if read.Format != ObjectFormat || read.Version != ObjectVersion || read.Name != "a.md" || len(read.Imports) != 1 || read.Imports[0] != "b.end" || !compareProgram(read.Program, obj.Program) {
+				t.Errorf("expected %v got %v", obj, read)
+			}
+		})
+	}
+	if _, err := (&Program{}).ReadBinaryFrom(bytes.NewReader(encoded)); err == nil || err.Error() != "not a binary program" {
+		t.Errorf("expected a binary object not to be read as a program, got %v", err)
+	}
+	if _, err := (&Object{}).ReadBinaryFrom(bytes.NewReader(append(append([]byte{}, encoded...), 0))); err == nil || err.Error() != "binary object checksum does not match" {
+		t.Errorf("expected a changed binary object to be refused, got %v", err)
+	}
+
+	for name, test := range map[string]struct {
+		input    string
+		expected string
+	}{
+		"not an object":  {`{"start": 0, "code": []}`, "not a dialogue object"},
+		"invalid JSON":   {`{"format": "dialogue-object"`, "unexpected end of JSON input"},
+		"no version":     {`{"format": "dialogue-object", "program": {}}`, "unsupported object version 0"},
+		"version 1":      {`{"format": "dialogue-object", "version": 1, "program": {}, "entry": 0, "exports": {}}`, "object was compiled by an older compiler and must be recompiled"},
+		"future version": {`{"format": "dialogue-object", "version": 3, "program": {}}`, "unsupported object version 3"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			read := Object{}
+			if _, err := read.ReadFrom(strings.NewReader(test.input)); err == nil || err.Error() != test.expected {
+				t.Errorf("expected %q got %v", test.expected, err)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package program
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"io/ioutil"
@@ -9,9 +11,15 @@ import (
 )
 
 type Program struct {
-	Start int                   `json:"start"`
-	Code  []asm.Instruction     `json:"code"`
-	Funcs map[string][]asm.Type `json:"funcs,omitempty"`
+	Start int               `json:"start"`
+	Code  []asm.Instruction `json:"code"`
+	// Entry is the address of the first node. The code before it gives
+	// the script's global variables their initial values.
+	Entry int `json:"entry,omitempty"`
+	// Nodes is the address of each node, so a run can start at any of them.
+	Nodes   map[string]int        `json:"nodes,omitempty"`
+	Funcs   map[string][]asm.Type `json:"funcs,omitempty"`
+	Returns map[string]asm.Type   `json:"returns,omitempty"`
 }
 
 func (p *Program) ReadFrom(r io.Reader) (n int64, err error) {
@@ -24,6 +32,12 @@ func (p *Program) ReadFrom(r io.Reader) (n int64, err error) {
 	if p.Funcs == nil {
 		p.Funcs = map[string][]asm.Type{}
 	}
+	if p.Returns == nil {
+		p.Returns = map[string]asm.Type{}
+	}
+	if p.Nodes == nil {
+		p.Nodes = map[string]int{}
+	}
 	return bytesRead, err
 }
 
@@ -37,3 +51,13 @@ func (p *Program) WriteTo(w io.Writer) (n int64, err error) {
 
 	return int64(num), err
 }
+
+// Hash identifies the program by the SHA-256 of its JSON encoding.
+func (p Program) Hash() (string, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -442,3 +442,30 @@ func TestWriteProgram(t *testing.T) {
 
 	}
 }
+
+func TestProgramHash(t *testing.T) {
+	p := Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.PushBool, Arg: asm.Value{Type: asm.BooleanType, Val: true}},
+			{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+		},
+	}
+	hash, err := p.Hash()
+	if err != nil || len(hash) != 64 {
+		t.Fatalf("expected a sha256 hash, got %q, %v", hash, err)
+	}
+	if again, _ := p.Hash(); again != hash {
+		t.Errorf("expected the same program to hash the same, got %v and %v", hash, again)
+	}
+
+	p.Start = 1
+	if other, _ := p.Hash(); other == hash {
+		t.Errorf("expected a different program to hash differently")
+	}
+
+	p.Code = append(p.Code, asm.Instruction{Opcode: "DoSomething"})
+	if _, err := p.Hash(); err == nil {
+		t.Errorf("expected error on bad opcode")
+	}
+}
@@ -0,0 +1,223 @@
+package program
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mcvoid/dialogue/internal/types/asm"
+)
+
+type (
+	// VerifyError is a problem with the instruction at PC.
+	VerifyError struct {
+		PC  int
+		Err error
+	}
+
+	// VerifyErrors is every problem Verify found, in address order.
+	VerifyErrors []VerifyError
+)
+
+func (e VerifyError) Error() string {
+	return fmt.Sprintf("%d: %v", e.PC, e.Err)
+}
+
+func (e VerifyError) Unwrap() error {
+	return e.Err
+}
+
+func (e VerifyErrors) Error() string {
+	lines := []string{}
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// verifier follows every path through a program, keeping the depth of
+// the stack at each address it reaches.
+type verifier struct {
+	p        Program
+	depths   map[int]int
+	work     []int
+	problems map[int]error
+}
+
+func (v *verifier) fail(pc int, format string, args ...interface{}) {
+	if _, ok := v.problems[pc]; !ok {
+		v.problems[pc] = fmt.Errorf(format, args...)
+	}
+}
+
+// reach records that pc can run with depth values on the stack.
+func (v *verifier) reach(from, pc, depth int) {
+	if pc < 0 || pc >= len(v.p.Code) {
+		v.fail(from, "goes to %d, which is out of bounds", pc)
+		return
+	}
+	seen, ok := v.depths[pc]
+	if !ok {
+		v.depths[pc] = depth
+		v.work = append(v.work, pc)
+		return
+	}
+	if seen != depth {
+		v.fail(pc, "stack has %d values on one path here and %d on another", seen, depth)
+	}
+}
+
+// checkArg reports whether the instruction's argument is the one its
+// opcode takes.
+func (v *verifier) checkArg(pc int, instr asm.Instruction) bool {
+	info, ok := asm.Lookup(instr.Opcode)
+	if !ok {
+		v.fail(pc, "invalid opcode %v", instr.Opcode)
+		return false
+	}
+	expected := info.Operand
+	if instr.Arg.Type != expected {
+		if expected == "" {
+			v.fail(pc, "%v takes no argument, got %v", instr.Opcode, instr.Arg.Type)
+		} else {
+			v.fail(pc, "%v takes a %v argument, got %v", instr.Opcode, expected, instr.Arg.Type)
+		}
+		return false
+	}
+	valid := true
+	switch expected {
+	case asm.NumberType:
+		// only a literal can have a fraction; addresses are whole
+		_, valid = instr.Arg.Val.(int)
+		if info.Kind == asm.LiteralOperand {
+			valid = asm.IsNumber(instr.Arg.Val)
+		}
+		if n, ok := instr.Arg.Val.(int); ok && info.Kind == asm.CountOperand && n < 0 {
+			v.fail(pc, "%v count %d is negative", instr.Opcode, n)
+			return false
+		}
+	case asm.BooleanType:
+		_, valid = instr.Arg.Val.(bool)
+	case asm.StringType, asm.SymbolType:
+		_, valid = instr.Arg.Val.(string)
+	}
+	if !valid {
+		v.fail(pc, "%v argument %v is not a %v", instr.Opcode, instr.Arg.Val, expected)
+	}
+	return valid
+}
+
+// stackEffect gives how many values an instruction takes off the stack
+// and how many it leaves.
+func (v *verifier) stackEffect(pc int, instr asm.Instruction) (needed, pushed int, ok bool) {
+	if instr.Opcode != asm.Call {
+		needed, pushed = asm.StackEffect(instr)
+		return needed, pushed, true
+	}
+	name := instr.Arg.Val.(string)
+	if params, ok := v.p.Funcs[name]; ok {
+		if _, returns := v.p.Returns[name]; returns {
+			pushed = 1
+		}
+		return len(params), pushed, true
+	}
+	if builtin, ok := asm.Builtins[name]; ok {
+		return len(builtin.Params), 1, true
+	}
+	v.fail(pc, "call to %s, which isn't declared", name)
+	return 0, 0, false
+}
+
+func (v *verifier) step(pc int) {
+	instr := v.p.Code[pc]
+	depth := v.depths[pc]
+	needed, pushed, ok := v.stackEffect(pc, instr)
+	if !ok {
+		return
+	}
+	if needed > depth {
+		v.fail(pc, "%v needs %d values on the stack, but there are %d", instr.Opcode, needed, depth)
+		return
+	}
+	depth += pushed - needed
+
+	next := func() {
+		if pc+1 == len(v.p.Code) {
+			v.fail(pc, "runs past the end of the code")
+			return
+		}
+		v.reach(pc, pc+1, depth)
+	}
+	switch instr.Opcode {
+	case asm.Jump:
+		v.reach(pc, instr.Arg.Val.(int), depth)
+	case asm.ShowChoice, asm.EndDialogue:
+	default:
+		next()
+		// a PushChoice's choice is taken from the ShowChoice after
+		// it, which leaves the stack as it is
+		if info, _ := asm.Lookup(instr.Opcode); info.Kind == asm.AddressOperand {
+			v.reach(pc, instr.Arg.Val.(int), depth)
+		}
+	}
+}
+
+// Verify checks a program before it runs: that every instruction has the
+// argument its opcode takes, that jumps and choices stay within the code,
+// that calls are to declared functions, and that the stack never
+// underflows and has the same depth on every path to an instruction.
+// Problems are returned as VerifyErrors.
+func Verify(p Program) error {
+	v := verifier{
+		p:        p,
+		depths:   map[int]int{},
+		problems: map[int]error{},
+	}
+
+	valid := map[int]bool{}
+	for pc, instr := range p.Code {
+		valid[pc] = v.checkArg(pc, instr)
+	}
+
+	if p.Start < 0 || p.Start >= len(p.Code) {
+		v.fail(p.Start, "start is out of bounds")
+	} else {
+		v.reach(p.Start, p.Start, 0)
+	}
+	if p.Entry < 0 || (p.Entry > 0 && p.Entry >= len(p.Code)) {
+		v.fail(p.Entry, "entry is out of bounds")
+	}
+	nodes := []string{}
+	for name := range p.Nodes {
+		nodes = append(nodes, name)
+	}
+	sort.Strings(nodes)
+	for _, name := range nodes {
+		addr := p.Nodes[name]
+		if addr < 0 || addr >= len(p.Code) {
+			v.fail(addr, "node %s is out of bounds", name)
+			continue
+		}
+		v.reach(addr, addr, 0)
+	}
+
+	for len(v.work) > 0 {
+		pc := v.work[0]
+		v.work = v.work[1:]
+		if valid[pc] {
+			v.step(pc)
+		}
+	}
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+	errs := VerifyErrors{}
+	for pc, err := range v.problems {
+		errs = append(errs, VerifyError{PC: pc, Err: err})
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].PC < errs[j].PC
+	})
+	return errs
+}
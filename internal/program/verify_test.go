@@ -0,0 +1,185 @@
+package program
+
+import (
+	"testing"
+
+	"github.com/mcvoid/dialogue/internal/types/asm"
+)
+
+func TestVerify(t *testing.T) {
+	num := func(n int) asm.Value {
+		return asm.Value{Type: asm.NumberType, Val: n}
+	}
+	sym := func(s string) asm.Value {
+		return asm.Value{Type: asm.SymbolType, Val: s}
+	}
+	str := asm.Value{Type: asm.StringType, Val: "abc"}
+
+	for name, test := range map[string]struct {
+		program  Program
+		expected string
+	}{
+		"valid": {
+			program: Program{
+				Code: []asm.Instruction{
+					{Opcode: asm.PushNumber, Arg: num(1)},
+					{Opcode: asm.InitVariable, Arg: sym("x")},
+					{Opcode: asm.EnterNode, Arg: sym("start")},
+					{Opcode: asm.LoadVariable, Arg: sym("x")},
+					{Opcode: asm.PushNumber, Arg: num(3)},
+					{Opcode: asm.GreaterThanOrEqual},
+					{Opcode: asm.JumpIfFalse, Arg: num(9)},
+					{Opcode: asm.PushString, Arg: str},
+					{Opcode: asm.PushChoice, Arg: num(13)},
+					{Opcode: asm.PushString, Arg: str},
+					{Opcode: asm.Call, Arg: sym("f")},
+					{Opcode: asm.Call, Arg: sym("length")},
+					{Opcode: asm.PopValue},
+					{Opcode: asm.ShowChoice},
+					{Opcode: asm.ExitNode, Arg: sym("start")},
+					{Opcode: asm.Jump, Arg: num(2)},
+				},
+				Entry:   2,
+				Nodes:   map[string]int{"start": 2},
+				Funcs:   map[string][]asm.Type{"f": {asm.StringType}},
+				Returns: map[string]asm.Type{"f": asm.StringType},
+			},
+			expected: "",
+		},
+		"invalid opcode": {
+			program: Program{
+				Code: []asm.Instruction{{Opcode: "Explode"}},
+			},
+			expected: "0: invalid opcode Explode",
+		},
+		"missing argument": {
+			program: Program{
+				Code: []asm.Instruction{{Opcode: asm.Jump}, {Opcode: asm.EndDialogue}},
+			},
+			expected: "0: Jump takes a number argument, got ",
+		},
+		"unexpected argument": {
+			program: Program{
+				Code: []asm.Instruction{{Opcode: asm.EndDialogue, Arg: num(1)}},
+			},
+			expected: "0: EndDialogue takes no argument, got number",
+		},
+		"wrong argument value": {
+			program: Program{
+				Code: []asm.Instruction{{Opcode: asm.Jump, Arg: asm.Value{Type: asm.NumberType, Val: "abc"}}},
+			},
+			expected: "0: Jump argument abc is not a number",
+		},
+		"jump out of bounds": {
+			program: Program{
+				Code: []asm.Instruction{{Opcode: asm.Jump, Arg: num(5)}},
+			},
+			expected: "0: goes to 5, which is out of bounds",
+		},
+		"stack underflow": {
+			program: Program{
+				Code: []asm.Instruction{
+					{Opcode: asm.PushNumber, Arg: num(1)},
+					{Opcode: asm.Modulo},
+					{Opcode: asm.EndDialogue},
+				},
+			},
+			expected: "1: Modulo needs 2 values on the stack, but there are 1",
+		},
+		"collection underflow": {
+			program: Program{
+				Code: []asm.Instruction{
+					{Opcode: asm.PushString, Arg: str},
+					{Opcode: asm.PushNull},
+					{Opcode: asm.MakeMap, Arg: num(2)},
+					{Opcode: asm.EndDialogue},
+				},
+			},
+			expected: "2: MakeMap needs 4 values on the stack, but there are 2",
+		},
+		"negative count": {
+			program: Program{
+				Code: []asm.Instruction{
+					{Opcode: asm.MakeList, Arg: num(-1)},
+					{Opcode: asm.EndDialogue},
+				},
+			},
+			expected: "0: MakeList count -1 is negative",
+		},
+		"call underflow": {
+			program: Program{
+				Code: []asm.Instruction{
+					{Opcode: asm.PushNumber, Arg: num(1)},
+					{Opcode: asm.Call, Arg: sym("clamp")},
+					{Opcode: asm.EndDialogue},
+				},
+			},
+			expected: "1: Call needs 3 values on the stack, but there are 1",
+		},
+		"undeclared function": {
+			program: Program{
+				Code: []asm.Instruction{
+					{Opcode: asm.Call, Arg: sym("g")},
+					{Opcode: asm.EndDialogue},
+				},
+			},
+			expected: "0: call to g, which isn't declared",
+		},
+		"unbalanced paths": {
+			program: Program{
+				Code: []asm.Instruction{
+					{Opcode: asm.PushBool, Arg: asm.True},
+					{Opcode: asm.JumpIfFalse, Arg: num(3)},
+					{Opcode: asm.PushNull},
+					{Opcode: asm.EndDialogue},
+				},
+			},
+			expected: "3: stack has 0 values on one path here and 1 on another",
+		},
+		"runs off the end": {
+			program: Program{
+				Code: []asm.Instruction{{Opcode: asm.PushNull}},
+			},
+			expected: "0: runs past the end of the code",
+		},
+		"node out of bounds": {
+			program: Program{
+				Code:  []asm.Instruction{{Opcode: asm.EndDialogue}},
+				Nodes: map[string]int{"start": 4},
+			},
+			expected: "4: node start is out of bounds",
+		},
+		"no code": {
+			program:  Program{},
+			expected: "0: start is out of bounds",
+		},
+		"several problems": {
+			program: Program{
+				Code: []asm.Instruction{
+					{Opcode: asm.ShowLine},
+					{Opcode: asm.EndDialogue},
+					{Opcode: asm.EnterNode, Arg: num(1)},
+				},
+				Nodes: map[string]int{"start": 0},
+			},
+			expected: "0: ShowLine needs 1 values on the stack, but there are 0\n" +
+				"2: EnterNode takes a symbol argument, got number",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := Verify(test.program)
+			if test.expected == "" {
+				if err != nil {
+					t.Errorf("no error expected got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("expected %q got %v", test.expected, err)
+			}
+			if _, ok := err.(VerifyErrors); !ok {
+				t.Errorf("expected VerifyErrors got %T", err)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package semantic_analysis
 
 import (
 	"encoding/json"
+	"strconv"
+	"strings"
 
 	"github.com/mcvoid/dialogue/internal/types/ast"
 	"github.com/mcvoid/dialogue/internal/types/lexeme"
@@ -10,8 +12,22 @@ import (
 
 func BuildScriptAst(src parsetree.Script) ast.Script {
 	dest := ast.Script{
-		Nodes:     []ast.Node{},
-		Functions: make(map[string][]ast.Type),
+		Nodes:       []ast.Node{},
+		Functions:   make(map[string][]ast.Type),
+		Returns:     make(map[string]ast.Type),
+		FunctionPos: make(map[string]lexeme.Position),
+	}
+
+	for _, decl := range src.FrontMatter.FuncDecls {
+		dest.Functions[decl.Symbol.Val] = BuildFunctionPrototype(decl)
+		dest.FunctionPos[decl.Symbol.Val] = decl.Symbol.Pos
+		if decl.ReturnType.Val != "" {
+			dest.Returns[decl.Symbol.Val] = ast.Type(decl.ReturnType.Val)
+		}
+	}
+
+	for _, decl := range src.FrontMatter.VarDecls {
+		dest.Variables = append(dest.Variables, BuildVariableDeclAst(decl))
 	}
 
 	for _, node := range src.Nodes {
@@ -21,12 +37,42 @@ func BuildScriptAst(src parsetree.Script) ast.Script {
 	return dest
 }
 
+func BuildFunctionPrototype(src parsetree.FuncDecl) []ast.Type {
+	dest := []ast.Type{}
+	for _, param := range src.Params {
+		dest = append(dest, ast.Type(param.Val))
+	}
+	return dest
+}
+
+func BuildVariableDeclAst(src parsetree.VarDecl) ast.VariableDecl {
+	dest := ast.VariableDecl{
+		Name:  ast.Symbol(src.Symbol.Val),
+		Type:  ast.Type(src.Type.Val),
+		Scope: ast.GlobalScope,
+		Pos:   src.Symbol.Pos,
+	}
+	if src.ExternKeyword.Type == lexeme.ExternKeyword {
+		dest.Scope = ast.ExternScope
+	}
+	if src.Value != nil {
+		dest.Val = BuildExpressionAst(src.Value)
+	}
+	return dest
+}
+
 func BuildNodeAst(src parsetree.Node) ast.Node {
 	dest := ast.Node{}
 
 	dest.Name = ast.Symbol(src.Header.Name.Val)
+	dest.Entry = src.Header.Entry.Type == lexeme.EntryAnnotation
+	dest.Pos = src.Header.Name.Pos
 	dest.Body = []ast.BlockElement{}
 	for _, block := range src.Blocks {
+		if _, ok := block.(parsetree.Skipped); ok {
+			// syntax errors have already been reported by the parser
+			continue
+		}
 		dest.Body = append(dest.Body, BuildBlockAst(block))
 	}
 
@@ -40,20 +86,7 @@ func BuildBlockAst(src parsetree.Block) ast.BlockElement {
 		{
 			dest := ast.Paragraph{}
 			for _, line := range src.Lines {
-				for _, inline := range line.Items {
-					switch inline := inline.(type) {
-					case parsetree.Text:
-						{
-							text := inline.Text.Val
-							dest = append(dest, ast.Text(text))
-						}
-					case parsetree.InlineCode:
-						{
-							expr := BuildExpressionAst(inline.Code)
-							dest = append(dest, ast.InlineCode{Expr: expr})
-						}
-					}
-				}
+				dest = append(dest, BuildInlinesAst(line.Items)...)
 				dest = append(dest, ast.Text("\n"))
 			}
 			return dest
@@ -72,7 +105,11 @@ func BuildBlockAst(src parsetree.Block) ast.BlockElement {
 		{
 			dest := ast.Option{}
 			for _, listItem := range src.Links {
-				dest = append(dest, BuildLinkAst(listItem.Link))
+				link := BuildLinkAst(listItem.Link)
+				if listItem.Guard != nil {
+					link.Guard = BuildExpressionAst(listItem.Guard)
+				}
+				dest = append(dest, link)
 			}
 			return dest
 		}
@@ -96,44 +133,90 @@ func BuildExpressionAst(src parsetree.Expression) ast.Expression {
 		return BuildUnaryOperationAst(src)
 	case parsetree.Literal:
 		return BuildLiteralAst(src)
+	case parsetree.CallExpression:
+		return BuildFunctionCallAst(src.Symbol, src.Args)
+	case parsetree.ListLiteral:
+		items := []ast.Expression{}
+		for _, item := range src.Items {
+			items = append(items, BuildExpressionAst(item))
+		}
+		return ast.ListLiteral{Items: items, Pos: src.OpenBrace.Pos}
+	case parsetree.MapLiteral:
+		entries := []ast.MapEntry{}
+		for _, entry := range src.Entries {
+			entries = append(entries, ast.MapEntry{
+				Key: BuildExpressionAst(entry.Key),
+				Val: BuildExpressionAst(entry.Value),
+			})
+		}
+		return ast.MapLiteral{Entries: entries, Pos: src.OpenBrace.Pos}
+	case parsetree.IndexExpression:
+		return ast.Index{
+			Container: BuildExpressionAst(src.Container),
+			Key:       BuildExpressionAst(src.Key),
+			Pos:       src.OpenBrace.Pos,
+		}
 	}
 	return nil
 }
 
+func BuildFunctionCallAst(name lexeme.Item, src []parsetree.Expression) ast.FunctionCall {
+	args := []ast.Expression{}
+	for _, expr := range src {
+		args = append(args, BuildExpressionAst(expr))
+	}
+	return ast.FunctionCall{
+		Name:   ast.Symbol(name.Val),
+		Params: args,
+		Pos:    name.Pos,
+	}
+}
+
 func BuildLiteralAst(src parsetree.Literal) ast.Literal {
 	switch src.Value.Type {
 	case lexeme.Null:
 		{
-			return ast.Literal{Type: ast.NullType, Val: nil}
+			return ast.Literal{Type: ast.NullType, Val: nil, Pos: src.Value.Pos}
 		}
 	case lexeme.Symbol:
 		{
-			return ast.Literal{Type: ast.SymbolType, Val: src.Value.Val}
+			return ast.Literal{Type: ast.SymbolType, Val: src.Value.Val, Pos: src.Value.Pos}
 		}
 	case lexeme.Number:
 		{
-			num := 0
-			json.Unmarshal([]byte(src.Value.Val), &num)
-			return ast.Literal{Type: ast.NumberType, Val: num}
+			// a number too large to hold keeps its text for the type
+			// checker to report
+			if strings.ContainsAny(src.Value.Val, ".eE") {
+				num, err := strconv.ParseFloat(src.Value.Val, 64)
+				if err != nil {
+					return ast.Literal{Type: ast.NumberType, Val: src.Value.Val, Pos: src.Value.Pos}
+				}
+				return ast.Literal{Type: ast.NumberType, Val: num, Pos: src.Value.Pos}
+			}
+			num, err := strconv.Atoi(src.Value.Val)
+			if err != nil {
+				return ast.Literal{Type: ast.NumberType, Val: src.Value.Val, Pos: src.Value.Pos}
+			}
+			return ast.Literal{Type: ast.NumberType, Val: num, Pos: src.Value.Pos}
 		}
 	case lexeme.String:
 		{
 			str := ""
 			json.Unmarshal([]byte(src.Value.Val), &str)
-			return ast.Literal{Type: ast.StringType, Val: str}
+			return ast.Literal{Type: ast.StringType, Val: str, Pos: src.Value.Pos}
 		}
 	case lexeme.Boolean:
 		{
 			b := false
 			json.Unmarshal([]byte(src.Value.Val), &b)
-			return ast.Literal{Type: ast.BooleanType, Val: b}
+			return ast.Literal{Type: ast.BooleanType, Val: b, Pos: src.Value.Pos}
 		}
 	}
-	return ast.Literal{}
+	return ast.Literal{Pos: src.Value.Pos}
 }
 
 func BuildUnaryOperationAst(src parsetree.UnaryExpression) ast.UnaryOp {
-	dest := ast.UnaryOp{}
+	dest := ast.UnaryOp{Pos: src.Operator.Pos}
 
 	dest.Arg = BuildExpressionAst(src.Operand)
 	dest.Operator = map[lexeme.ItemType]ast.UnaryOperator{
@@ -147,7 +230,7 @@ func BuildUnaryOperationAst(src parsetree.UnaryExpression) ast.UnaryOp {
 }
 
 func BuildBinaryOperationAst(src parsetree.BinaryExpression) ast.BinaryOp {
-	dest := ast.BinaryOp{}
+	dest := ast.BinaryOp{Pos: src.Operator.Pos}
 
 	dest.LeftArg = BuildExpressionAst(src.LeftOperand)
 	dest.RightArg = BuildExpressionAst(src.RightOperand)
@@ -178,12 +261,14 @@ func BuildStatementAst(src parsetree.Statement) ast.Statement {
 			Cond:       BuildExpressionAst(src.Cond),
 			Consequent: BuildStatementAst(src.Consequent),
 			Alternate:  ast.StatementBlock{},
+			Pos:        src.IfLiteral.Pos,
 		}
 	case parsetree.ConditionalWithElse:
 		return ast.Conditional{
 			Cond:       BuildExpressionAst(src.Cond),
 			Consequent: BuildStatementAst(src.Consequent),
 			Alternate:  BuildStatementAst(src.Alternate),
+			Pos:        src.IfLiteral.Pos,
 		}
 	case parsetree.StatementBlock:
 		{
@@ -194,30 +279,77 @@ func BuildStatementAst(src parsetree.Statement) ast.Statement {
 			return b
 		}
 	case parsetree.Assignment:
-		return ast.Assignment{Name: ast.Symbol(src.Symbol.Val), Val: BuildExpressionAst(src.Value)}
+		return ast.Assignment{
+			Name: ast.Symbol(src.Symbol.Val),
+			Val:  BuildExpressionAst(src.Value),
+			Pos:  src.Symbol.Pos,
+		}
+	case parsetree.IndexAssignment:
+		return ast.IndexAssignment{
+			Name: ast.Symbol(src.Symbol.Val),
+			Key:  BuildExpressionAst(src.Key),
+			Val:  BuildExpressionAst(src.Value),
+			Pos:  src.Symbol.Pos,
+		}
+	case parsetree.LocalDeclaration:
+		return ast.VariableDecl{
+			Name:  ast.Symbol(src.Symbol.Val),
+			Type:  ast.Type(src.Type.Val),
+			Scope: ast.LocalScope,
+			Val:   BuildExpressionAst(src.Value),
+			Pos:   src.Symbol.Pos,
+		}
 	case parsetree.Loop:
-		return ast.Loop{Cond: BuildExpressionAst(src.Cond), Consequent: BuildStatementAst(src.Body)}
+		return ast.Loop{
+			Cond:       BuildExpressionAst(src.Cond),
+			Consequent: BuildStatementAst(src.Body),
+			Pos:        src.WhileLiteral.Pos,
+		}
+	case parsetree.ForLoop:
+		return ast.ForLoop{
+			Name:       ast.Symbol(src.Symbol.Val),
+			Iterable:   BuildExpressionAst(src.Iterable),
+			Consequent: BuildStatementAst(src.Body),
+			Pos:        src.ForLiteral.Pos,
+		}
+	case parsetree.Break:
+		return ast.Break{Pos: src.BreakLiteral.Pos}
+	case parsetree.Continue:
+		return ast.Continue{Pos: src.ContinueLiteral.Pos}
 	case parsetree.Goto:
-		return ast.GotoNode{Name: ast.Symbol(src.Symbol.Val)}
+		return ast.GotoNode{Name: ast.Symbol(src.Symbol.Val), Pos: src.Symbol.Pos}
 	case parsetree.FunctionCall:
-		{
-			args := []ast.Expression{}
-			for _, expr := range src.Args {
-				args = append(args, BuildExpressionAst(expr))
+		return BuildFunctionCallAst(src.Symbol, src.Args)
+	default:
+		return nil
+	}
+}
+
+// BuildInlinesAst builds the text and inline code of a paragraph line
+// or link.
+func BuildInlinesAst(src []parsetree.Inline) ast.Paragraph {
+	dest := ast.Paragraph{}
+	for _, inline := range src {
+		switch inline := inline.(type) {
+		case parsetree.Text:
+			{
+				text := inline.Text.Val
+				dest = append(dest, ast.Text(text))
 			}
-			return ast.FunctionCall{
-				Name:   ast.Symbol(src.Symbol.Val),
-				Params: args,
+		case parsetree.InlineCode:
+			{
+				expr := BuildExpressionAst(inline.Code)
+				dest = append(dest, ast.InlineCode{Expr: expr})
 			}
 		}
-	default:
-		return nil
 	}
+	return dest
 }
 
 func BuildLinkAst(src parsetree.Link) ast.Link {
 	return ast.Link{
 		Dest: ast.Symbol(src.Symbol.Val),
-		Text: ast.Text(src.Text.(parsetree.Text).Text.Val),
+		Text: BuildInlinesAst(src.Text),
+		Pos:  src.Symbol.Pos,
 	}
 }
@@ -14,6 +14,10 @@ func (b bogusParseTree) CompareStatement(n2 parsetree.Statement) bool {
 	return false
 }
 
+func (b bogusParseTree) Pos() lexeme.Position {
+	return lexeme.Position{}
+}
+
 func TestBuildExpressionAst(t *testing.T) {
 	for name, test := range map[string]struct {
 		input    parsetree.Expression
@@ -35,6 +39,22 @@ func TestBuildExpressionAst(t *testing.T) {
 			parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
 			ast.Literal{Type: ast.NumberType, Val: 5},
 		},
+		"fractional number": {
+			parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "2.5"}},
+			ast.Literal{Type: ast.NumberType, Val: 2.5},
+		},
+		"exponent number": {
+			parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "1e3"}},
+			ast.Literal{Type: ast.NumberType, Val: 1000.0},
+		},
+		"number out of range": {
+			parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "1e999"}},
+			ast.Literal{Type: ast.NumberType, Val: "1e999"},
+		},
+		"whole number out of range": {
+			parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "99999999999999999999"}},
+			ast.Literal{Type: ast.NumberType, Val: "99999999999999999999"},
+		},
 		"boolean true": {
 			parsetree.Literal{Value: lexeme.Item{Type: lexeme.Boolean, Val: "true"}},
 			ast.Literal{Type: ast.BooleanType, Val: true},
@@ -257,6 +277,62 @@ func TestBuildExpressionAst(t *testing.T) {
 	}
 }
 
+func TestBuildCallExpressionAst(t *testing.T) {
+	input := parsetree.BinaryExpression{
+		Operator: lexeme.Item{Type: lexeme.And, Val: "&&"},
+		LeftOperand: parsetree.CallExpression{
+			Symbol: lexeme.Item{Type: lexeme.Symbol, Val: "has_item"},
+			Args: []parsetree.Expression{
+				parsetree.Literal{Value: lexeme.Item{Type: lexeme.String, Val: "\"key\""}},
+			},
+		},
+		RightOperand: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Boolean, Val: "true"}},
+	}
+	expected := ast.BinaryOp{
+		Operator: ast.AndOp,
+		LeftArg: ast.FunctionCall{
+			Name:   "has_item",
+			Params: []ast.Expression{ast.Literal{Type: ast.StringType, Val: "key"}},
+		},
+		RightArg: ast.Literal{Type: ast.BooleanType, Val: true},
+	}
+	actual := BuildExpressionAst(input)
+	if !expected.CompareExpression(actual) {
+		t.Errorf("expected %v got %v", expected, actual)
+	}
+}
+
+func TestBuildCollectionExpressionAst(t *testing.T) {
+	input := parsetree.IndexExpression{
+		Container: parsetree.ListLiteral{
+			Items: []parsetree.Expression{
+				parsetree.MapLiteral{
+					Entries: []parsetree.MapEntry{{
+						Key:   parsetree.Literal{Value: lexeme.Item{Type: lexeme.String, Val: "\"gold\""}},
+						Value: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "3"}},
+					}},
+				},
+			},
+		},
+		Key: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "0"}},
+	}
+	expected := ast.Index{
+		Container: ast.ListLiteral{
+			Items: []ast.Expression{
+				ast.MapLiteral{Entries: []ast.MapEntry{{
+					Key: ast.Literal{Type: ast.StringType, Val: "gold"},
+					Val: ast.Literal{Type: ast.NumberType, Val: 3},
+				}}},
+			},
+		},
+		Key: ast.Literal{Type: ast.NumberType, Val: 0},
+	}
+	actual := BuildExpressionAst(input)
+	if !expected.CompareExpression(actual) {
+		t.Errorf("expected %v got %v", expected, actual)
+	}
+}
+
 func TestBuildStatement(t *testing.T) {
 	for name, test := range map[string]struct {
 		input    parsetree.Statement
@@ -279,6 +355,30 @@ func TestBuildStatement(t *testing.T) {
 			},
 			ast.Assignment{Name: ast.Symbol("abc"), Val: ast.Literal{Type: ast.BooleanType, Val: false}},
 		},
+		"break": {
+			parsetree.Break{
+				BreakLiteral: lexeme.Item{Type: lexeme.BreakLiteral, Val: "break"},
+				Semicolon:    lexeme.Item{Type: lexeme.Semicolon, Val: ";"},
+			},
+			ast.Break{},
+		},
+		"continue": {
+			parsetree.Continue{
+				ContinueLiteral: lexeme.Item{Type: lexeme.ContinueLiteral, Val: "continue"},
+				Semicolon:       lexeme.Item{Type: lexeme.Semicolon, Val: ";"},
+			},
+			ast.Continue{},
+		},
+		"local declaration": {
+			parsetree.LocalDeclaration{
+				LocalKeyword: lexeme.Item{Type: lexeme.LocalKeyword, Val: "local"},
+				Symbol:       lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
+				EqualSign:    lexeme.Item{Type: lexeme.Eq, Val: "="},
+				Value:        parsetree.Literal{Value: lexeme.Item{Type: lexeme.Boolean, Val: "false"}},
+				Semicolon:    lexeme.Item{Type: lexeme.Semicolon, Val: ";"},
+			},
+			ast.VariableDecl{Name: "abc", Scope: ast.LocalScope, Val: ast.Literal{Type: ast.BooleanType, Val: false}},
+		},
 		"conditional": {
 			parsetree.Conditional{
 				IfLiteral: lexeme.Item{Type: lexeme.IfLiteral, Val: "if"},
@@ -398,6 +498,35 @@ func TestBuildStatement(t *testing.T) {
 				},
 			},
 		},
+		"index assignment": {
+			parsetree.IndexAssignment{
+				Symbol: lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
+				Key:    parsetree.Literal{Value: lexeme.Item{Type: lexeme.String, Val: "\"gold\""}},
+				Value:  parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "5"}},
+			},
+			ast.IndexAssignment{
+				Name: ast.Symbol("abc"),
+				Key:  ast.Literal{Type: ast.StringType, Val: "gold"},
+				Val:  ast.Literal{Type: ast.NumberType, Val: 5},
+			},
+		},
+		"for loop": {
+			parsetree.ForLoop{
+				ForLiteral: lexeme.Item{Type: lexeme.ForLiteral, Val: "for"},
+				Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "item"},
+				Iterable:   parsetree.Literal{Value: lexeme.Item{Type: lexeme.Symbol, Val: "items"}},
+				Body: parsetree.StatementBlock{
+					Statements: []parsetree.Statement{
+						parsetree.Break{BreakLiteral: lexeme.Item{Type: lexeme.BreakLiteral, Val: "break"}},
+					},
+				},
+			},
+			ast.ForLoop{
+				Name:       ast.Symbol("item"),
+				Iterable:   ast.Literal{Type: ast.SymbolType, Val: "items"},
+				Consequent: ast.StatementBlock{ast.Break{}},
+			},
+		},
 		"bogus node": {
 			bogusParseTree{},
 			nil,
@@ -437,7 +566,7 @@ func TestBlockElements(t *testing.T) {
 							Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 							CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 							OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-							Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}},
+							Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
 							CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 						},
 					},
@@ -448,7 +577,7 @@ func TestBlockElements(t *testing.T) {
 							Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "def"},
 							CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 							OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-							Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 							CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 						},
 					},
@@ -456,8 +585,8 @@ func TestBlockElements(t *testing.T) {
 				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 			},
 			expected: ast.Option{
-				ast.Link{Dest: "abc", Text: ast.Text("abc")},
-				ast.Link{Dest: "def", Text: ast.Text("def")},
+				ast.Link{Dest: "abc", Text: ast.Paragraph{ast.Text("abc")}},
+				ast.Link{Dest: "def", Text: ast.Paragraph{ast.Text("def")}},
 			},
 		},
 		"link": {
@@ -467,12 +596,57 @@ func TestBlockElements(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}},
+					Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 				},
 				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 			},
-			expected: ast.Link{Dest: "abc", Text: ast.Text("abc")},
+			expected: ast.Link{Dest: "abc", Text: ast.Paragraph{ast.Text("abc")}},
+		},
+		"guarded option": {
+			input: parsetree.List{
+				Links: []parsetree.ListItem{
+					{
+						Link: parsetree.Link{
+							Symbol: lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
+							Text:   []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						},
+						If:    lexeme.Item{Type: lexeme.IfLiteral, Val: "if"},
+						Guard: parsetree.Literal{Value: lexeme.Item{Type: lexeme.Symbol, Val: "open"}},
+					},
+					{
+						Link: parsetree.Link{
+							Symbol: lexeme.Item{Type: lexeme.Symbol, Val: "def"},
+							Text:   []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
+						},
+					},
+				},
+			},
+			expected: ast.Option{
+				ast.Link{Dest: "abc", Text: ast.Paragraph{ast.Text("abc")}, Guard: ast.Literal{Type: ast.SymbolType, Val: "open"}},
+				ast.Link{Dest: "def", Text: ast.Paragraph{ast.Text("def")}},
+			},
+		},
+		"link with inline code": {
+			input: parsetree.LinkBlock{
+				Link: parsetree.Link{
+					Symbol: lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
+					Text: []parsetree.Inline{
+						parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "Buy for "}},
+						parsetree.InlineCode{
+							CodeStart: lexeme.Item{Type: lexeme.OpenInlineCode},
+							Code:      parsetree.Literal{Value: lexeme.Item{Type: lexeme.Symbol, Val: "price"}},
+							CodeEnd:   lexeme.Item{Type: lexeme.CloseInlineCode},
+						},
+						parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: " gold"}},
+					},
+				},
+			},
+			expected: ast.Link{Dest: "abc", Text: ast.Paragraph{
+				ast.Text("Buy for "),
+				ast.InlineCode{Expr: ast.Literal{Type: ast.SymbolType, Val: "price"}},
+				ast.Text(" gold"),
+			}},
 		},
 		"Code block": {
 			input: parsetree.CodeBlock{
@@ -576,7 +750,7 @@ func TestBuildScript(t *testing.T) {
 									Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 									CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 									OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-									Text:       parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}},
+									Text:       []parsetree.Inline{parsetree.Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
 									CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 								},
 								EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
@@ -593,11 +767,97 @@ func TestBuildScript(t *testing.T) {
 					{
 						Name: ast.Symbol("abc"),
 						Body: []ast.BlockElement{
-							ast.Link{Dest: ast.Symbol("abc"), Text: ast.Text("abc")},
+							ast.Link{Dest: ast.Symbol("abc"), Text: ast.Paragraph{ast.Text("abc")}},
+						},
+					},
+				},
+			},
+		},
+		"entry node": {
+			input: parsetree.Script{
+				Nodes: []parsetree.Node{
+					{
+						Header: parsetree.Header{
+							Hash:    lexeme.Item{Type: lexeme.Hash, Val: "#"},
+							Name:    lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
+							Entry:   lexeme.Item{Type: lexeme.EntryAnnotation, Val: "@entry"},
+							EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+						},
+						Blocks:  []parsetree.Block{},
+						EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
+					},
+				},
+				Eof: lexeme.Item{Type: lexeme.Eof, Val: ""},
+			},
+			expected: ast.Script{
+				Functions: map[string][]ast.Type{},
+				Nodes: []ast.Node{
+					{Name: ast.Symbol("abc"), Entry: true, Body: []ast.BlockElement{}},
+				},
+			},
+		},
+		"front matter": {
+			input: parsetree.Script{
+				FrontMatter: parsetree.FrontMatter{
+					FuncDecls: []parsetree.FuncDecl{
+						{
+							Symbol: lexeme.Item{Type: lexeme.Symbol, Val: "func1"},
+							Params: []lexeme.Item{
+								{Type: lexeme.Type, Val: "bool"},
+								{Type: lexeme.Type, Val: "number"},
+								{Type: lexeme.Type, Val: "string"},
+								{Type: lexeme.Type, Val: "null"},
+							},
+						},
+						{
+							Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "func2"},
+							Params:     []lexeme.Item{},
+							Colon:      lexeme.Item{Type: lexeme.Colon, Val: ":"},
+							ReturnType: lexeme.Item{Type: lexeme.Type, Val: "string"},
+						},
+					},
+				},
+			},
+			expected: ast.Script{
+				Functions: map[string][]ast.Type{
+					"func1": {ast.BooleanType, ast.NumberType, ast.StringType, ast.NullType},
+					"func2": {},
+				},
+				Returns: map[string]ast.Type{
+					"func2": ast.StringType,
+				},
+				Nodes: []ast.Node{},
+			},
+		},
+		"front matter variables": {
+			input: parsetree.Script{
+				FrontMatter: parsetree.FrontMatter{
+					VarDecls: []parsetree.VarDecl{
+						{
+							VarKeyword: lexeme.Item{Type: lexeme.VarKeyword, Val: "var"},
+							Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "gold"},
+							Colon:      lexeme.Item{Type: lexeme.Colon, Val: ":"},
+							Type:       lexeme.Item{Type: lexeme.Type, Val: "number"},
+							EqualSign:  lexeme.Item{Type: lexeme.Eq, Val: "="},
+							Value:      parsetree.Literal{Value: lexeme.Item{Type: lexeme.Number, Val: "3"}},
+						},
+						{
+							ExternKeyword: lexeme.Item{Type: lexeme.ExternKeyword, Val: "extern"},
+							VarKeyword:    lexeme.Item{Type: lexeme.VarKeyword, Val: "var"},
+							Symbol:        lexeme.Item{Type: lexeme.Symbol, Val: "name"},
+							Colon:         lexeme.Item{Type: lexeme.Colon, Val: ":"},
+							Type:          lexeme.Item{Type: lexeme.Type, Val: "string"},
 						},
 					},
 				},
 			},
+			expected: ast.Script{
+				Variables: []ast.VariableDecl{
+					{Name: "gold", Type: ast.NumberType, Scope: ast.GlobalScope, Val: ast.Literal{Type: ast.NumberType, Val: 3}},
+					{Name: "name", Type: ast.StringType, Scope: ast.ExternScope},
+				},
+				Nodes: []ast.Node{},
+			},
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
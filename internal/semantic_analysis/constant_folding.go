@@ -1,19 +1,28 @@
 package semantic_analysis
 
 import (
-	"fmt"
 	"regexp"
 	"strings"
 
+	"github.com/mcvoid/dialogue/internal/types/asm"
 	"github.com/mcvoid/dialogue/internal/types/ast"
+	"github.com/mcvoid/dialogue/internal/types/lexeme"
 )
 
 var ws = regexp.MustCompile("[ \t\n]+")
 
 func ConstantFoldScript(node ast.Script) ast.Script {
 	foldedScript := ast.Script{
-		Functions: node.Functions,
-		Nodes:     []ast.Node{},
+		Functions:   node.Functions,
+		Returns:     node.Returns,
+		FunctionPos: node.FunctionPos,
+		Nodes:       []ast.Node{},
+	}
+	for _, v := range node.Variables {
+		if v.Val != nil {
+			v.Val, _ = ConstantFoldExpression(v.Val)
+		}
+		foldedScript.Variables = append(foldedScript.Variables, v)
 	}
 	for _, n := range node.Nodes {
 		foldedScript.Nodes = append(foldedScript.Nodes, ConstantFoldNode(n))
@@ -30,15 +39,49 @@ func ConstantFoldNode(node ast.Node) ast.Node {
 			foldedBlocks = append(foldedBlocks, ConstantFoldParagraph(block))
 		case ast.CodeBlock:
 			foldedBlocks = append(foldedBlocks, ConstantFoldCodeBlock(block))
+		case ast.Link:
+			foldedBlocks = append(foldedBlocks, ConstantFoldLink(block))
+		case ast.Option:
+			foldedBlocks = append(foldedBlocks, ConstantFoldOption(block))
 		default:
 			foldedBlocks = append(foldedBlocks, block)
 		}
 	}
 
 	return ast.Node{
-		Name: node.Name,
-		Body: foldedBlocks,
+		Name:  node.Name,
+		Body:  foldedBlocks,
+		Entry: node.Entry,
+		Pos:   node.Pos,
+	}
+}
+
+func ConstantFoldLink(node ast.Link) ast.Link {
+	return ast.Link{
+		Dest: node.Dest,
+		Text: ConstantFoldParagraph(node.Text),
+		Pos:  node.Pos,
+	}
+}
+
+// ConstantFoldOption drops the options whose guards never hold and
+// the guards which always do.
+func ConstantFoldOption(node ast.Option) ast.Option {
+	foldedOption := ast.Option{}
+	for _, link := range node {
+		foldedLink := ConstantFoldLink(link)
+		if link.Guard != nil {
+			guard, isConst := ConstantFoldExpression(link.Guard)
+			if isConst && guard.(ast.Literal).Val == false {
+				continue
+			}
+			if !isConst {
+				foldedLink.Guard = guard
+			}
+		}
+		foldedOption = append(foldedOption, foldedLink)
 	}
+	return foldedOption
 }
 
 func ConstantFoldCodeBlock(node ast.CodeBlock) ast.CodeBlock {
@@ -61,6 +104,7 @@ func ConstantFoldStatement(node ast.Statement) ast.Statement {
 			return ast.Assignment{
 				Name: node.Name,
 				Val:  expr,
+				Pos:  node.Pos,
 			}
 		}
 	case ast.StatementBlock:
@@ -78,17 +122,20 @@ func ConstantFoldStatement(node ast.Statement) ast.Statement {
 				Cond:       expr,
 				Consequent: ConstantFoldStatement(node.Consequent),
 				Alternate:  ConstantFoldStatement(node.Alternate),
+				Pos:        node.Pos,
 			}
 
 			if block, ok := folded.Consequent.(ast.StatementBlock); ok && len(block) == 0 {
 				expr, _ = ConstantFoldExpression(ast.UnaryOp{
 					Operator: ast.NotOp,
 					Arg:      expr,
+					Pos:      ast.PosOf(expr),
 				})
 				folded = ast.Conditional{
 					Cond:       expr,
 					Consequent: folded.Alternate,
 					Alternate:  ast.StatementBlock{},
+					Pos:        node.Pos,
 				}
 			}
 
@@ -101,24 +148,55 @@ func ConstantFoldStatement(node ast.Statement) ast.Statement {
 			return ast.Loop{
 				Cond:       expr,
 				Consequent: ConstantFoldStatement(node.Consequent),
+				Pos:        node.Pos,
 			}
 		}
-	case ast.FunctionCall:
+	case ast.IndexAssignment:
 		{
-			foldedArgs := []ast.Expression{}
-			for _, arg := range node.Params {
-				expr, _ := ConstantFoldExpression(arg)
-				foldedArgs = append(foldedArgs, expr)
+			key, _ := ConstantFoldExpression(node.Key)
+			val, _ := ConstantFoldExpression(node.Val)
+			return ast.IndexAssignment{
+				Name: node.Name,
+				Key:  key,
+				Val:  val,
+				Pos:  node.Pos,
 			}
-			return ast.FunctionCall{
-				Name:   node.Name,
-				Params: foldedArgs,
+		}
+	case ast.ForLoop:
+		{
+			expr, _ := ConstantFoldExpression(node.Iterable)
+			return ast.ForLoop{
+				Name:       node.Name,
+				Iterable:   expr,
+				Consequent: ConstantFoldStatement(node.Consequent),
+				Pos:        node.Pos,
 			}
 		}
+	case ast.FunctionCall:
+		return ConstantFoldFunctionCall(node)
+	case ast.VariableDecl:
+		{
+			expr, _ := ConstantFoldExpression(node.Val)
+			node.Val = expr
+			return node
+		}
 	}
 	return node
 }
 
+func ConstantFoldFunctionCall(node ast.FunctionCall) ast.FunctionCall {
+	foldedArgs := []ast.Expression{}
+	for _, arg := range node.Params {
+		expr, _ := ConstantFoldExpression(arg)
+		foldedArgs = append(foldedArgs, expr)
+	}
+	return ast.FunctionCall{
+		Name:   node.Name,
+		Params: foldedArgs,
+		Pos:    node.Pos,
+	}
+}
+
 func ConstantFoldParagraph(node ast.Paragraph) (foldedNode ast.Paragraph) {
 	lastFoldedIndex := 0
 	for i, inline := range node {
@@ -139,7 +217,7 @@ func ConstantFoldParagraph(node ast.Paragraph) (foldedNode ast.Paragraph) {
 				lastFoldedIndex++
 				continue
 			}
-			thisConst = ast.Text(fmt.Sprintf("%v", foldedExpr.(ast.Literal).Val))
+			thisConst = ast.Text(asm.Format(foldedExpr.(ast.Literal).Val))
 		case ast.Text:
 			thisConst = inline
 		}
@@ -174,13 +252,69 @@ func ConstantFoldParagraph(node ast.Paragraph) (foldedNode ast.Paragraph) {
 func ConstantFoldExpression(node ast.Expression) (foldedNode ast.Expression, isConstExpr bool) {
 	switch node := node.(type) {
 	case ast.BinaryOp:
-		return ConstantFoldBinaryOperation(node)
+		foldedNode, isConstExpr = ConstantFoldBinaryOperation(node)
 	case ast.UnaryOp:
-		return ConstantFoldUnaryOperation(node)
+		foldedNode, isConstExpr = ConstantFoldUnaryOperation(node)
 	case ast.Literal:
 		return ConstantFoldLiteral(node)
+	case ast.FunctionCall:
+		// the host decides what a call returns
+		return ConstantFoldFunctionCall(node), false
+	case ast.ListLiteral, ast.MapLiteral, ast.Index:
+		// lists and maps are made at runtime, though what's in them
+		// can still be folded
+		return ConstantFoldCollection(node), false
+	default:
+		return nil, false
 	}
-	return nil, false
+	return withPos(foldedNode, ast.PosOf(node)), isConstExpr
+}
+
+// ConstantFoldCollection folds the items of a list or map literal, or
+// the container and key of an index.
+func ConstantFoldCollection(node ast.Expression) ast.Expression {
+	switch node := node.(type) {
+	case ast.ListLiteral:
+		items := []ast.Expression{}
+		for _, item := range node.Items {
+			expr, _ := ConstantFoldExpression(item)
+			items = append(items, expr)
+		}
+		return ast.ListLiteral{Items: items, Pos: node.Pos}
+	case ast.MapLiteral:
+		entries := []ast.MapEntry{}
+		for _, entry := range node.Entries {
+			key, _ := ConstantFoldExpression(entry.Key)
+			val, _ := ConstantFoldExpression(entry.Val)
+			entries = append(entries, ast.MapEntry{Key: key, Val: val})
+		}
+		return ast.MapLiteral{Entries: entries, Pos: node.Pos}
+	case ast.Index:
+		container, _ := ConstantFoldExpression(node.Container)
+		key, _ := ConstantFoldExpression(node.Key)
+		return ast.Index{Container: container, Key: key, Pos: node.Pos}
+	}
+	return node
+}
+
+// withPos gives expressions created by folding the position of
+// the expression they replaced.
+func withPos(expr ast.Expression, pos lexeme.Position) ast.Expression {
+	if ast.PosOf(expr) != (lexeme.Position{}) {
+		return expr
+	}
+	switch expr := expr.(type) {
+	case ast.BinaryOp:
+		expr.Pos = pos
+		return expr
+	case ast.UnaryOp:
+		expr.Pos = pos
+		return expr
+	case ast.Literal:
+		expr.Pos = pos
+		return expr
+	}
+	return expr
 }
 
 func ConstantFoldLiteral(node ast.Literal) (foldedNode ast.Literal, isConstExpr bool) {
@@ -196,27 +330,34 @@ func ConstantFoldUnaryOperation(node ast.UnaryOp) (foldedNode ast.Expression, is
 	arg, argIsConst := ConstantFoldExpression(node.Arg)
 
 	if argIsConst {
+		// overflowing is left to fail at runtime
 		switch node.Operator {
 		case ast.IncOp:
-			return ast.Literal{
-				Type: ast.NumberType,
-				Val:  arg.(ast.Literal).Val.(int) + 1,
-			}, true
+			if val, err := asm.Arithmetic(asm.Add, arg.(ast.Literal).Val, 1); err == nil {
+				return ast.Literal{
+					Type: ast.NumberType,
+					Val:  val,
+				}, true
+			}
 		case ast.DecOp:
-			return ast.Literal{
-				Type: ast.NumberType,
-				Val:  arg.(ast.Literal).Val.(int) - 1,
-			}, true
+			if val, err := asm.Arithmetic(asm.Subtract, arg.(ast.Literal).Val, 1); err == nil {
+				return ast.Literal{
+					Type: ast.NumberType,
+					Val:  val,
+				}, true
+			}
 		case ast.NotOp:
 			return ast.Literal{
 				Type: ast.BooleanType,
 				Val:  !arg.(ast.Literal).Val.(bool),
 			}, true
 		case ast.NegOp:
-			return ast.Literal{
-				Type: ast.NumberType,
-				Val:  -(arg.(ast.Literal).Val.(int)),
-			}, true
+			if val, err := asm.Negate(arg.(ast.Literal).Val); err == nil {
+				return ast.Literal{
+					Type: ast.NumberType,
+					Val:  val,
+				}, true
+			}
 		}
 	}
 
@@ -280,66 +421,74 @@ func ConstantFoldUnaryOperation(node ast.UnaryOp) (foldedNode ast.Expression, is
 	}, false
 }
 
+// arithmeticOpcodes are the instructions whose promotion rules constant
+// folding follows, so that folding gives what the VM would.
+var arithmeticOpcodes = map[ast.BinaryOperator]asm.Opcode{
+	ast.AddOp: asm.Add,
+	ast.SubOp: asm.Subtract,
+	ast.MulOp: asm.Multiply,
+	ast.DivOp: asm.Divide,
+	ast.ModOp: asm.Modulo,
+}
+
+// literalsEqual compares two constants the way the VM would, so that
+// 2 == 2.0.
+func literalsEqual(a, b ast.Literal) bool {
+	if a.Type == ast.NumberType && b.Type == ast.NumberType {
+		return asm.CompareNumbers(a.Val, b.Val) == 0
+	}
+	return a.Type == b.Type && a.Val == b.Val
+}
+
 func ConstantFoldBinaryOperation(node ast.BinaryOp) (foldedNode ast.Expression, isConstExpr bool) {
 	left, leftIsConst := ConstantFoldExpression(node.LeftArg)
 	right, rightIsConst := ConstantFoldExpression(node.RightArg)
 
 	if leftIsConst && rightIsConst {
 		switch node.Operator {
-		case ast.AddOp:
-			return ast.Literal{
-				Type: ast.NumberType,
-				Val:  left.(ast.Literal).Val.(int) + right.(ast.Literal).Val.(int),
-			}, true
-		case ast.SubOp:
-			return ast.Literal{
-				Type: ast.NumberType,
-				Val:  left.(ast.Literal).Val.(int) - right.(ast.Literal).Val.(int),
-			}, true
-		case ast.MulOp:
-			return ast.Literal{
-				Type: ast.NumberType,
-				Val:  left.(ast.Literal).Val.(int) * right.(ast.Literal).Val.(int),
-			}, true
-		case ast.DivOp:
-			return ast.Literal{
-				Type: ast.NumberType,
-				Val:  left.(ast.Literal).Val.(int) / right.(ast.Literal).Val.(int),
-			}, true
-		case ast.ModOp:
+		case ast.AddOp, ast.SubOp, ast.MulOp, ast.DivOp, ast.ModOp:
+			val, err := asm.Arithmetic(arithmeticOpcodes[node.Operator], left.(ast.Literal).Val, right.(ast.Literal).Val)
+			if err != nil {
+				// dividing by zero or overflowing is left to fail at runtime
+				return ast.BinaryOp{
+					Operator: node.Operator,
+					LeftArg:  left,
+					RightArg: right,
+				}, false
+			}
 			return ast.Literal{
 				Type: ast.NumberType,
-				Val:  left.(ast.Literal).Val.(int) % right.(ast.Literal).Val.(int),
+				Val:  val,
 			}, true
 		case ast.GtOp:
 			return ast.Literal{
 				Type: ast.BooleanType,
-				Val:  left.(ast.Literal).Val.(int) > right.(ast.Literal).Val.(int),
+				Val:  asm.CompareNumbers(left.(ast.Literal).Val, right.(ast.Literal).Val) > 0,
 			}, true
 		case ast.GteOp:
 			return ast.Literal{
 				Type: ast.BooleanType,
-				Val:  left.(ast.Literal).Val.(int) >= right.(ast.Literal).Val.(int),
+				Val:  asm.CompareNumbers(left.(ast.Literal).Val, right.(ast.Literal).Val) >= 0,
 			}, true
 		case ast.LtOp:
 			return ast.Literal{
 				Type: ast.BooleanType,
-				Val:  left.(ast.Literal).Val.(int) < right.(ast.Literal).Val.(int),
+				Val:  asm.CompareNumbers(left.(ast.Literal).Val, right.(ast.Literal).Val) < 0,
 			}, true
 		case ast.LteOp:
 			return ast.Literal{
 				Type: ast.BooleanType,
-				Val:  left.(ast.Literal).Val.(int) <= right.(ast.Literal).Val.(int),
+				Val:  asm.CompareNumbers(left.(ast.Literal).Val, right.(ast.Literal).Val) <= 0,
 			}, true
 		case ast.EqOp:
 			return ast.Literal{
 				Type: ast.BooleanType,
-				Val:  left.(ast.Literal).Val.(int) == right.(ast.Literal).Val.(int),
+				Val:  literalsEqual(left.(ast.Literal), right.(ast.Literal)),
 			}, true
 		case ast.NeqOp:
 			return ast.Literal{
 				Type: ast.BooleanType,
-				Val:  left.(ast.Literal).Val.(int) != right.(ast.Literal).Val.(int),
+				Val:  !literalsEqual(left.(ast.Literal), right.(ast.Literal)),
 			}, true
 		case ast.AndOp:
 			return ast.Literal{
@@ -354,7 +503,7 @@ func ConstantFoldBinaryOperation(node ast.BinaryOp) (foldedNode ast.Expression,
 		case ast.ConcatOp:
 			return ast.Literal{
 				Type: ast.StringType,
-				Val:  fmt.Sprintf("%v%v", left.(ast.Literal).Val, right.(ast.Literal).Val),
+				Val:  asm.Format(left.(ast.Literal).Val) + asm.Format(right.(ast.Literal).Val),
 			}, true
 		}
 	}
@@ -1,6 +1,7 @@
 package semantic_analysis
 
 import (
+	"math"
 	"testing"
 
 	"github.com/mcvoid/dialogue/internal/types/ast"
@@ -71,21 +72,81 @@ func TestBlockElementFolding(t *testing.T) {
 			input: ast.Script{
 				Functions: map[string][]ast.Type{},
 				Nodes: []ast.Node{{Name: ast.Symbol("abc"), Body: []ast.BlockElement{
-					ast.Link{Dest: ast.Symbol("def"), Text: ast.Text("ghi")},
+					ast.Link{Dest: ast.Symbol("def"), Text: ast.Paragraph{ast.Text("ghi")}},
 					ast.Option{
-						ast.Link{Dest: ast.Symbol("abc"), Text: ast.Text("def")},
-						ast.Link{Dest: ast.Symbol("def"), Text: ast.Text("ghi")},
-						ast.Link{Dest: ast.Symbol("ghi"), Text: ast.Text("jkl")},
+						ast.Link{Dest: ast.Symbol("abc"), Text: ast.Paragraph{ast.Text("def")}},
+						ast.Link{Dest: ast.Symbol("def"), Text: ast.Paragraph{ast.Text("ghi")}},
+						ast.Link{Dest: ast.Symbol("ghi"), Text: ast.Paragraph{ast.Text("jkl")}},
 					},
 				}}}},
 			expected: ast.Script{
 				Functions: map[string][]ast.Type{},
 				Nodes: []ast.Node{{Name: ast.Symbol("abc"), Body: []ast.BlockElement{
-					ast.Link{Dest: ast.Symbol("def"), Text: ast.Text("ghi")},
+					ast.Link{Dest: ast.Symbol("def"), Text: ast.Paragraph{ast.Text("ghi")}},
 					ast.Option{
-						ast.Link{Dest: ast.Symbol("abc"), Text: ast.Text("def")},
-						ast.Link{Dest: ast.Symbol("def"), Text: ast.Text("ghi")},
-						ast.Link{Dest: ast.Symbol("ghi"), Text: ast.Text("jkl")},
+						ast.Link{Dest: ast.Symbol("abc"), Text: ast.Paragraph{ast.Text("def")}},
+						ast.Link{Dest: ast.Symbol("def"), Text: ast.Paragraph{ast.Text("ghi")}},
+						ast.Link{Dest: ast.Symbol("ghi"), Text: ast.Paragraph{ast.Text("jkl")}},
+					},
+				}}}},
+		},
+		"fold inline code in links": {
+			input: ast.Script{
+				Functions: map[string][]ast.Type{},
+				Nodes: []ast.Node{{Name: ast.Symbol("abc"), Body: []ast.BlockElement{
+					ast.Option{
+						ast.Link{Dest: ast.Symbol("abc"), Text: ast.Paragraph{
+							ast.Text("Buy for "),
+							ast.InlineCode{Expr: ast.BinaryOp{
+								Operator: ast.MulOp,
+								LeftArg:  ast.Literal{Type: ast.NumberType, Val: 2},
+								RightArg: ast.Literal{Type: ast.NumberType, Val: 5},
+							}},
+							ast.Text(" gold"),
+						}},
+						ast.Link{Dest: ast.Symbol("def"), Text: ast.Paragraph{
+							ast.Text("Sell for "),
+							ast.InlineCode{Expr: ast.Literal{Type: ast.SymbolType, Val: "price"}},
+						}},
+					},
+				}}}},
+			expected: ast.Script{
+				Functions: map[string][]ast.Type{},
+				Nodes: []ast.Node{{Name: ast.Symbol("abc"), Body: []ast.BlockElement{
+					ast.Option{
+						ast.Link{Dest: ast.Symbol("abc"), Text: ast.Paragraph{ast.Text("Buy for 10 gold")}},
+						ast.Link{Dest: ast.Symbol("def"), Text: ast.Paragraph{
+							ast.Text("Sell for "),
+							ast.InlineCode{Expr: ast.Literal{Type: ast.SymbolType, Val: "price"}},
+						}},
+					},
+				}}}},
+		},
+		"fold option guards": {
+			input: ast.Script{
+				Functions: map[string][]ast.Type{},
+				Nodes: []ast.Node{{Name: ast.Symbol("abc"), Body: []ast.BlockElement{
+					ast.Option{
+						ast.Link{Dest: ast.Symbol("abc"), Text: ast.Paragraph{ast.Text("always")}, Guard: ast.UnaryOp{
+							Operator: ast.NotOp,
+							Arg:      ast.Literal{Type: ast.BooleanType, Val: false},
+						}},
+						ast.Link{Dest: ast.Symbol("def"), Text: ast.Paragraph{ast.Text("never")}, Guard: ast.Literal{Type: ast.BooleanType, Val: false}},
+						ast.Link{Dest: ast.Symbol("ghi"), Text: ast.Paragraph{ast.Text("maybe")}, Guard: ast.UnaryOp{
+							Operator: ast.NotOp,
+							Arg:      ast.Literal{Type: ast.SymbolType, Val: "asked"},
+						}},
+					},
+				}}}},
+			expected: ast.Script{
+				Functions: map[string][]ast.Type{},
+				Nodes: []ast.Node{{Name: ast.Symbol("abc"), Body: []ast.BlockElement{
+					ast.Option{
+						ast.Link{Dest: ast.Symbol("abc"), Text: ast.Paragraph{ast.Text("always")}},
+						ast.Link{Dest: ast.Symbol("ghi"), Text: ast.Paragraph{ast.Text("maybe")}, Guard: ast.UnaryOp{
+							Operator: ast.NotOp,
+							Arg:      ast.Literal{Type: ast.SymbolType, Val: "asked"},
+						}},
 					},
 				}}}},
 		},
@@ -176,6 +237,90 @@ func TestFoldExpressions(t *testing.T) {
 			},
 			expected: ast.Literal{Type: ast.NumberType, Val: 8},
 		},
+		"add fractions": {
+			input: ast.BinaryOp{
+				Operator: ast.AddOp,
+				LeftArg:  ast.Literal{Type: ast.NumberType, Val: 1.5},
+				RightArg: ast.Literal{Type: ast.NumberType, Val: 1},
+			},
+			expected: ast.Literal{Type: ast.NumberType, Val: 2.5},
+		},
+		"whole division": {
+			input: ast.BinaryOp{
+				Operator: ast.DivOp,
+				LeftArg:  ast.Literal{Type: ast.NumberType, Val: 7},
+				RightArg: ast.Literal{Type: ast.NumberType, Val: 2},
+			},
+			expected: ast.Literal{Type: ast.NumberType, Val: 3},
+		},
+		"fractional division": {
+			input: ast.BinaryOp{
+				Operator: ast.DivOp,
+				LeftArg:  ast.Literal{Type: ast.NumberType, Val: 7.0},
+				RightArg: ast.Literal{Type: ast.NumberType, Val: 2},
+			},
+			expected: ast.Literal{Type: ast.NumberType, Val: 3.5},
+		},
+		"division by zero": {
+			input: ast.BinaryOp{
+				Operator: ast.DivOp,
+				LeftArg:  ast.Literal{Type: ast.NumberType, Val: 1},
+				RightArg: ast.Literal{Type: ast.NumberType, Val: 0},
+			},
+			expected: ast.BinaryOp{
+				Operator: ast.DivOp,
+				LeftArg:  ast.Literal{Type: ast.NumberType, Val: 1},
+				RightArg: ast.Literal{Type: ast.NumberType, Val: 0},
+			},
+		},
+		"overflow": {
+			input: ast.BinaryOp{
+				Operator: ast.MulOp,
+				LeftArg:  ast.Literal{Type: ast.NumberType, Val: 1e300},
+				RightArg: ast.Literal{Type: ast.NumberType, Val: 1e300},
+			},
+			expected: ast.BinaryOp{
+				Operator: ast.MulOp,
+				LeftArg:  ast.Literal{Type: ast.NumberType, Val: 1e300},
+				RightArg: ast.Literal{Type: ast.NumberType, Val: 1e300},
+			},
+		},
+		"whole overflow": {
+			input: ast.BinaryOp{
+				Operator: ast.AddOp,
+				LeftArg:  ast.Literal{Type: ast.NumberType, Val: math.MaxInt},
+				RightArg: ast.Literal{Type: ast.NumberType, Val: 2},
+			},
+			expected: ast.BinaryOp{
+				Operator: ast.AddOp,
+				LeftArg:  ast.Literal{Type: ast.NumberType, Val: math.MaxInt},
+				RightArg: ast.Literal{Type: ast.NumberType, Val: 2},
+			},
+		},
+		"whole equals fraction": {
+			input: ast.BinaryOp{
+				Operator: ast.EqOp,
+				LeftArg:  ast.Literal{Type: ast.NumberType, Val: 2},
+				RightArg: ast.Literal{Type: ast.NumberType, Val: 2.0},
+			},
+			expected: ast.Literal{Type: ast.BooleanType, Val: true},
+		},
+		"fraction comparison": {
+			input: ast.BinaryOp{
+				Operator: ast.LtOp,
+				LeftArg:  ast.Literal{Type: ast.NumberType, Val: 2},
+				RightArg: ast.Literal{Type: ast.NumberType, Val: 2.5},
+			},
+			expected: ast.Literal{Type: ast.BooleanType, Val: true},
+		},
+		"concat fraction": {
+			input: ast.BinaryOp{
+				Operator: ast.ConcatOp,
+				LeftArg:  ast.Literal{Type: ast.StringType, Val: "x"},
+				RightArg: ast.Literal{Type: ast.NumberType, Val: 1 / 3.0},
+			},
+			expected: ast.Literal{Type: ast.StringType, Val: "x0.333333"},
+		},
 		"x + 0 = x": {
 			input: ast.BinaryOp{
 				Operator: ast.AddOp,
@@ -501,6 +646,26 @@ func TestFoldExpressions(t *testing.T) {
 			},
 			expected: ast.Literal{Type: ast.NumberType, Val: 4},
 		},
+		"inc overflow": {
+			input: ast.UnaryOp{
+				Operator: ast.IncOp,
+				Arg:      ast.Literal{Type: ast.NumberType, Val: math.MaxInt},
+			},
+			expected: ast.UnaryOp{
+				Operator: ast.IncOp,
+				Arg:      ast.Literal{Type: ast.NumberType, Val: math.MaxInt},
+			},
+		},
+		"neg overflow": {
+			input: ast.UnaryOp{
+				Operator: ast.NegOp,
+				Arg:      ast.Literal{Type: ast.NumberType, Val: math.MinInt},
+			},
+			expected: ast.UnaryOp{
+				Operator: ast.NegOp,
+				Arg:      ast.Literal{Type: ast.NumberType, Val: math.MinInt},
+			},
+		},
 		"not": {
 			input: ast.UnaryOp{
 				Operator: ast.NotOp,
@@ -925,3 +1090,90 @@ func TestConstantFoldStatement(t *testing.T) {
 		})
 	}
 }
+
+func TestFoldCollections(t *testing.T) {
+	six := ast.BinaryOp{
+		Operator: ast.MulOp,
+		LeftArg:  ast.Literal{Type: ast.NumberType, Val: 2},
+		RightArg: ast.Literal{Type: ast.NumberType, Val: 3},
+	}
+	input := ast.Index{
+		Container: ast.ListLiteral{Items: []ast.Expression{
+			six,
+			ast.MapLiteral{Entries: []ast.MapEntry{{
+				Key: ast.BinaryOp{
+					Operator: ast.ConcatOp,
+					LeftArg:  ast.Literal{Type: ast.StringType, Val: "a"},
+					RightArg: ast.Literal{Type: ast.StringType, Val: "b"},
+				},
+				Val: six,
+			}}},
+		}},
+		Key: ast.UnaryOp{Operator: ast.IncOp, Arg: ast.Literal{Type: ast.NumberType, Val: 0}},
+	}
+	expected := ast.Index{
+		Container: ast.ListLiteral{Items: []ast.Expression{
+			ast.Literal{Type: ast.NumberType, Val: 6},
+			ast.MapLiteral{Entries: []ast.MapEntry{{
+				Key: ast.Literal{Type: ast.StringType, Val: "ab"},
+				Val: ast.Literal{Type: ast.NumberType, Val: 6},
+			}}},
+		}},
+		Key: ast.Literal{Type: ast.NumberType, Val: 1},
+	}
+	actual, isConst := ConstantFoldExpression(input)
+	if isConst {
+		t.Errorf("lists and maps are never constant")
+	}
+	if !expected.CompareExpression(actual) {
+		t.Errorf("expected %v got %v", expected, actual)
+	}
+
+	stmt := ConstantFoldStatement(ast.ForLoop{
+		Name:     "item",
+		Iterable: ast.ListLiteral{Items: []ast.Expression{six}},
+		Consequent: ast.IndexAssignment{
+			Name: "items",
+			Key:  six,
+			Val:  six,
+		},
+	})
+	expectedStmt := ast.ForLoop{
+		Name:     "item",
+		Iterable: ast.ListLiteral{Items: []ast.Expression{ast.Literal{Type: ast.NumberType, Val: 6}}},
+		Consequent: ast.IndexAssignment{
+			Name: "items",
+			Key:  ast.Literal{Type: ast.NumberType, Val: 6},
+			Val:  ast.Literal{Type: ast.NumberType, Val: 6},
+		},
+	}
+	if !expectedStmt.CompareStatement(stmt) {
+		t.Errorf("expected %v got %v", expectedStmt, stmt)
+	}
+}
+
+func TestFoldCallExpression(t *testing.T) {
+	input := ast.BinaryOp{
+		Operator: ast.AddOp,
+		LeftArg: ast.FunctionCall{
+			Name: "count",
+			Params: []ast.Expression{ast.BinaryOp{
+				Operator: ast.MulOp,
+				LeftArg:  ast.Literal{Type: ast.NumberType, Val: 2},
+				RightArg: ast.Literal{Type: ast.NumberType, Val: 3},
+			}},
+		},
+		RightArg: ast.Literal{Type: ast.NumberType, Val: 0},
+	}
+	expected := ast.FunctionCall{
+		Name:   "count",
+		Params: []ast.Expression{ast.Literal{Type: ast.NumberType, Val: 6}},
+	}
+	actual, isConst := ConstantFoldExpression(input)
+	if isConst {
+		t.Errorf("calls are never constant")
+	}
+	if !expected.CompareExpression(actual) {
+		t.Errorf("expected %v got %v", expected, actual)
+	}
+}
@@ -3,26 +3,42 @@ package semantic_analysis
 import "github.com/mcvoid/dialogue/internal/types/ast"
 
 func PruneScript(script ast.Script) ast.Script {
+	prunedScript := PruneCode(script)
+	prunedScript.Nodes = PruneUnreachableNodes(prunedScript.Nodes)
+	return prunedScript
+}
+
+// PruneCode removes the unreachable code within each node, but keeps
+// every node, even those which can't be reached from the first.
+func PruneCode(script ast.Script) ast.Script {
 	prunedScript := ast.Script{
-		Functions: map[string][]ast.Type{},
-		Nodes:     []ast.Node{},
+		Functions:   script.Functions,
+		Returns:     script.Returns,
+		FunctionPos: script.FunctionPos,
+		Variables:   script.Variables,
+		Nodes:       []ast.Node{},
 	}
 
 	for _, node := range script.Nodes {
 		prunedScript.Nodes = append(prunedScript.Nodes, PruneNode(node))
 	}
 
-	prunedScript.Nodes = PruneUnreachableNodes(prunedScript.Nodes)
-
 	return prunedScript
 }
 
+// PruneUnreachableNodes removes the nodes which can't be reached from
+// the first node or from a node marked as an entry.
 func PruneUnreachableNodes(nodes []ast.Node) []ast.Node {
 	if len(nodes) == 0 {
 		return nodes
 	}
 
 	minScript := []ast.Node{nodes[0]}
+	for _, node := range nodes[1:] {
+		if node.Entry {
+			minScript = append(minScript, node)
+		}
+	}
 	lastLen := 0
 	for len(minScript) > lastLen {
 		lastLen = len(minScript)
@@ -83,6 +99,10 @@ func FindNodeNamesinStatement(s ast.Statement) []ast.Symbol {
 		names = append(names, FindNodeNamesinStatement(s.Alternate)...)
 	case ast.Loop:
 		names = append(names, FindNodeNamesinStatement(s.Consequent)...)
+	case ast.InfiniteLoop:
+		names = append(names, FindNodeNamesinStatement(s.Consequent)...)
+	case ast.ForLoop:
+		names = append(names, FindNodeNamesinStatement(s.Consequent)...)
 	}
 	return names
 }
@@ -116,21 +136,37 @@ loop:
 	}
 
 	return ast.Node{
-		Name: node.Name,
-		Body: prunedBlocks,
+		Name:  node.Name,
+		Body:  prunedBlocks,
+		Entry: node.Entry,
+		Pos:   node.Pos,
 	}
 }
 
+// flow is where control goes after a statement runs.
+type flow int
+
+const (
+	// fallsThrough goes on to the next statement.
+	fallsThrough flow = iota
+	// leavesBlock jumps with break or continue, so the rest of the
+	// enclosing block is unreachable but the node goes on.
+	leavesBlock
+	// leavesNode goes to another node, so the rest of the node is
+	// unreachable.
+	leavesNode
+)
+
 func PruneCodeBlock(block ast.CodeBlock) (prunedBlock ast.CodeBlock, endsNode bool) {
 	prunedStatements := []ast.Statement{}
 
 	for _, stmt := range block.Code {
-		prunedStatement, endsNode := PruneStatement(stmt)
+		prunedStatement, f := pruneStatement(stmt)
 		prunedStatements = append(prunedStatements, prunedStatement)
-		if endsNode {
+		if f != fallsThrough {
 			return ast.CodeBlock{
 				Code: prunedStatements,
-			}, true
+			}, f == leavesNode
 		}
 	}
 
@@ -140,57 +176,118 @@ func PruneCodeBlock(block ast.CodeBlock) (prunedBlock ast.CodeBlock, endsNode bo
 }
 
 func PruneStatement(stmt ast.Statement) (prunedStatement ast.Statement, endsNode bool) {
+	prunedStatement, f := pruneStatement(stmt)
+	return prunedStatement, f == leavesNode
+}
+
+func pruneStatement(stmt ast.Statement) (ast.Statement, flow) {
 	switch stmt := stmt.(type) {
 	case ast.GotoNode:
-		return stmt, true
+		return stmt, leavesNode
+	case ast.Break, ast.Continue:
+		return stmt, leavesBlock
 	case ast.Conditional:
 		{
-			cons, consEndsNode := PruneStatement(stmt.Consequent)
-			alt, altEndsNode := PruneStatement(stmt.Alternate)
+			cons, consFlow := pruneStatement(stmt.Consequent)
+			alt, altFlow := pruneStatement(stmt.Alternate)
 
+			// the rest is only unreachable if it is unreachable whichever way you take
+			f := consFlow
+			if altFlow < f {
+				f = altFlow
+			}
 			return ast.Conditional{
 				Cond:       stmt.Cond,
 				Consequent: cons,
 				Alternate:  alt,
-				// if the node ends no matter which way you take, the rest is unreachable
-			}, consEndsNode && altEndsNode
+				Pos:        stmt.Pos,
+			}, f
 		}
 	case ast.Loop:
 		{
 			if stmt.Cond.CompareExpression(ast.Literal{Type: ast.BooleanType, Val: true}) {
-				cons, endsNode := PruneStatement(stmt.Consequent)
+				cons, f := pruneLoopBody(stmt.Consequent)
 				return ast.InfiniteLoop{
 					Consequent: cons,
-				}, endsNode
+					Pos:        stmt.Pos,
+				}, f
 			}
 
 			if stmt.Cond.CompareExpression(ast.Literal{Type: ast.BooleanType, Val: false}) {
-				return ast.StatementBlock{}, false
+				return ast.StatementBlock{}, fallsThrough
 			}
 
-			cons, endsNode := PruneStatement(stmt.Consequent)
+			cons, f := pruneLoopBody(stmt.Consequent)
 			return ast.Loop{
 				Cond:       stmt.Cond,
 				Consequent: cons,
-			}, endsNode
+				Pos:        stmt.Pos,
+			}, f
 
 		}
+	case ast.InfiniteLoop:
+		{
+			cons, f := pruneLoopBody(stmt.Consequent)
+			return ast.InfiniteLoop{
+				Consequent: cons,
+				Pos:        stmt.Pos,
+			}, f
+		}
+	case ast.ForLoop:
+		{
+			// the list can be empty, so the body might never run and
+			// the rest is always reachable
+			cons, _ := pruneStatement(stmt.Consequent)
+			return ast.ForLoop{
+				Name:       stmt.Name,
+				Iterable:   stmt.Iterable,
+				Consequent: cons,
+				Pos:        stmt.Pos,
+			}, fallsThrough
+		}
 	case ast.StatementBlock:
 		{
 			stmts := ast.StatementBlock{}
 
 			for _, s := range stmt {
-				prunedStatement, endsNode := PruneStatement(s)
+				prunedStatement, f := pruneStatement(s)
 				stmts = append(stmts, prunedStatement)
-				if endsNode {
+				if f != fallsThrough {
 					// stop adding statements here - the rest is unreachable
-					return stmts, true
+					return stmts, f
 				}
 			}
-			return stmts, false
+			return stmts, fallsThrough
 		}
 	default:
-		return stmt, false
+		return stmt, fallsThrough
+	}
+}
+
+// pruneLoopBody prunes the body of a loop. A loop only ends the node
+// if its body does and nothing in it breaks out of the loop.
+func pruneLoopBody(body ast.Statement) (ast.Statement, flow) {
+	cons, f := pruneStatement(body)
+	if f != leavesNode || breaksLoop(cons) {
+		return cons, fallsThrough
+	}
+	return cons, leavesNode
+}
 
+// breaksLoop reports whether a loop body has a break which leaves that
+// loop, rather than one nested inside it.
+func breaksLoop(s ast.Statement) bool {
+	switch s := s.(type) {
+	case ast.Break:
+		return true
+	case ast.StatementBlock:
+		for _, stmt := range s {
+			if breaksLoop(stmt) {
+				return true
+			}
+		}
+	case ast.Conditional:
+		return breaksLoop(s.Consequent) || breaksLoop(s.Alternate)
 	}
+	return false
 }
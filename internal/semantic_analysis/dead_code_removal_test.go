@@ -33,17 +33,34 @@ func TestDeadCodeElimination(t *testing.T) {
 				{Name: "abc", Body: []ast.BlockElement{}},
 			},
 		},
+		"keep entry nodes": {
+			input: []ast.Node{
+				{Name: "abc", Body: []ast.BlockElement{}},
+				{Name: "def", Body: []ast.BlockElement{}},
+				{Name: "ghi", Entry: true, Body: []ast.BlockElement{
+					ast.Link{Dest: "jkl", Text: ast.Paragraph{ast.Text("")}},
+				}},
+				{Name: "jkl", Body: []ast.BlockElement{}},
+			},
+			expected: []ast.Node{
+				{Name: "abc", Body: []ast.BlockElement{}},
+				{Name: "ghi", Entry: true, Body: []ast.BlockElement{
+					ast.Link{Dest: "jkl", Text: ast.Paragraph{ast.Text("")}},
+				}},
+				{Name: "jkl", Body: []ast.BlockElement{}},
+			},
+		},
 		"keep linked nodes": {
 			input: []ast.Node{
 				{Name: "abc", Body: []ast.BlockElement{
-					ast.Link{Dest: "def", Text: ast.Text("")},
+					ast.Link{Dest: "def", Text: ast.Paragraph{ast.Text("")}},
 				}},
 				{Name: "def", Body: []ast.BlockElement{}},
 				{Name: "ghi", Body: []ast.BlockElement{}},
 			},
 			expected: []ast.Node{
 				{Name: "abc", Body: []ast.BlockElement{
-					ast.Link{Dest: "def", Text: ast.Text("")},
+					ast.Link{Dest: "def", Text: ast.Paragraph{ast.Text("")}},
 				}},
 				{Name: "def", Body: []ast.BlockElement{}},
 			},
@@ -52,9 +69,9 @@ func TestDeadCodeElimination(t *testing.T) {
 			input: []ast.Node{
 				{Name: "abc", Body: []ast.BlockElement{
 					ast.Option{
-						ast.Link{Dest: "def", Text: ast.Text("")},
-						ast.Link{Dest: "ghi", Text: ast.Text("")},
-						ast.Link{Dest: "jkl", Text: ast.Text("")},
+						ast.Link{Dest: "def", Text: ast.Paragraph{ast.Text("")}},
+						ast.Link{Dest: "ghi", Text: ast.Paragraph{ast.Text("")}},
+						ast.Link{Dest: "jkl", Text: ast.Paragraph{ast.Text("")}},
 					},
 				}},
 				{Name: "def", Body: []ast.BlockElement{}},
@@ -65,9 +82,9 @@ func TestDeadCodeElimination(t *testing.T) {
 			expected: []ast.Node{
 				{Name: "abc", Body: []ast.BlockElement{
 					ast.Option{
-						ast.Link{Dest: "def", Text: ast.Text("")},
-						ast.Link{Dest: "ghi", Text: ast.Text("")},
-						ast.Link{Dest: "jkl", Text: ast.Text("")},
+						ast.Link{Dest: "def", Text: ast.Paragraph{ast.Text("")}},
+						ast.Link{Dest: "ghi", Text: ast.Paragraph{ast.Text("")}},
+						ast.Link{Dest: "jkl", Text: ast.Paragraph{ast.Text("")}},
 					},
 				}},
 				{Name: "def", Body: []ast.BlockElement{}},
@@ -78,27 +95,27 @@ func TestDeadCodeElimination(t *testing.T) {
 		"transitive nodes": {
 			input: []ast.Node{
 				{Name: "abc", Body: []ast.BlockElement{
-					ast.Link{Dest: "ghi", Text: ast.Text("")},
+					ast.Link{Dest: "ghi", Text: ast.Paragraph{ast.Text("")}},
 				}},
 				{Name: "def", Body: []ast.BlockElement{
-					ast.Link{Dest: "mno", Text: ast.Text("")},
+					ast.Link{Dest: "mno", Text: ast.Paragraph{ast.Text("")}},
 				}},
 				{Name: "ghi", Body: []ast.BlockElement{
-					ast.Link{Dest: "def", Text: ast.Text("")},
+					ast.Link{Dest: "def", Text: ast.Paragraph{ast.Text("")}},
 				}},
 				{Name: "jkl", Body: []ast.BlockElement{}},
 				{Name: "mno", Body: []ast.BlockElement{}},
 			},
 			expected: []ast.Node{
 				{Name: "abc", Body: []ast.BlockElement{
-					ast.Link{Dest: "ghi", Text: ast.Text("")},
+					ast.Link{Dest: "ghi", Text: ast.Paragraph{ast.Text("")}},
 				}},
 				{Name: "def", Body: []ast.BlockElement{
 
-					ast.Link{Dest: "mno", Text: ast.Text("")},
+					ast.Link{Dest: "mno", Text: ast.Paragraph{ast.Text("")}},
 				}},
 				{Name: "ghi", Body: []ast.BlockElement{
-					ast.Link{Dest: "def", Text: ast.Text("")},
+					ast.Link{Dest: "def", Text: ast.Paragraph{ast.Text("")}},
 				}},
 				{Name: "mno", Body: []ast.BlockElement{}},
 			},
@@ -108,14 +125,14 @@ func TestDeadCodeElimination(t *testing.T) {
 				{Name: "abc", Body: []ast.BlockElement{
 					ast.Paragraph{ast.Text("abc")},
 					ast.Paragraph{ast.Text("abc")},
-					ast.Link{Dest: "def", Text: ast.Text("abc")},
+					ast.Link{Dest: "def", Text: ast.Paragraph{ast.Text("abc")}},
 					ast.Paragraph{ast.Text("abc")},
 					ast.Paragraph{ast.Text("abc")},
 				}},
 				{Name: "def", Body: []ast.BlockElement{
 					ast.Paragraph{ast.Text("abc")},
 					ast.Paragraph{ast.Text("abc")},
-					ast.Link{Dest: "abc", Text: ast.Text("abc")},
+					ast.Link{Dest: "abc", Text: ast.Paragraph{ast.Text("abc")}},
 					ast.Paragraph{ast.Text("abc")},
 					ast.Paragraph{ast.Text("abc")},
 				}},
@@ -124,12 +141,12 @@ func TestDeadCodeElimination(t *testing.T) {
 				{Name: "abc", Body: []ast.BlockElement{
 					ast.Paragraph{ast.Text("abc")},
 					ast.Paragraph{ast.Text("abc")},
-					ast.Link{Dest: "def", Text: ast.Text("abc")},
+					ast.Link{Dest: "def", Text: ast.Paragraph{ast.Text("abc")}},
 				}},
 				{Name: "def", Body: []ast.BlockElement{
 					ast.Paragraph{ast.Text("abc")},
 					ast.Paragraph{ast.Text("abc")},
-					ast.Link{Dest: "abc", Text: ast.Text("abc")},
+					ast.Link{Dest: "abc", Text: ast.Paragraph{ast.Text("abc")}},
 				}},
 			},
 		},
@@ -501,6 +518,116 @@ func TestDeadCodeElimination(t *testing.T) {
 				ast.Assignment{Name: "abc", Val: ast.Literal{Type: ast.BooleanType, Val: true}},
 			}}}}},
 		},
+		"unreachable code after break": {
+			input: []ast.Node{{Name: "abc", Body: []ast.BlockElement{ast.CodeBlock{Code: []ast.Statement{
+				ast.Loop{
+					Cond: ast.Literal{Type: ast.SymbolType, Val: "var1"},
+					Consequent: ast.StatementBlock{
+						ast.Conditional{
+							Cond:       ast.Literal{Type: ast.SymbolType, Val: "var2"},
+							Consequent: ast.StatementBlock{ast.Continue{}},
+							Alternate:  ast.StatementBlock{ast.GotoNode{Name: "abc"}},
+						},
+						ast.Assignment{Name: "abc", Val: ast.Literal{Type: ast.BooleanType, Val: true}},
+					},
+				},
+				ast.InfiniteLoop{Consequent: ast.StatementBlock{
+					ast.Break{},
+					ast.Assignment{Name: "abc", Val: ast.Literal{Type: ast.BooleanType, Val: true}},
+				}},
+				ast.Assignment{Name: "abc", Val: ast.Literal{Type: ast.BooleanType, Val: true}},
+			}}}}},
+			expected: []ast.Node{{Name: "abc", Body: []ast.BlockElement{ast.CodeBlock{Code: []ast.Statement{
+				ast.Loop{
+					Cond: ast.Literal{Type: ast.SymbolType, Val: "var1"},
+					Consequent: ast.StatementBlock{
+						ast.Conditional{
+							Cond:       ast.Literal{Type: ast.SymbolType, Val: "var2"},
+							Consequent: ast.StatementBlock{ast.Continue{}},
+							Alternate:  ast.StatementBlock{ast.GotoNode{Name: "abc"}},
+						},
+					},
+				},
+				ast.InfiniteLoop{Consequent: ast.StatementBlock{
+					ast.Break{},
+				}},
+				ast.Assignment{Name: "abc", Val: ast.Literal{Type: ast.BooleanType, Val: true}},
+			}}}}},
+		},
+		"for loop body might not run": {
+			input: []ast.Node{
+				{Name: "abc", Body: []ast.BlockElement{
+					ast.CodeBlock{Code: []ast.Statement{
+						ast.ForLoop{
+							Name:     "item",
+							Iterable: ast.Literal{Type: ast.SymbolType, Val: "items"},
+							Consequent: ast.StatementBlock{
+								ast.GotoNode{Name: "def"},
+								ast.Break{},
+							},
+						},
+					}},
+					ast.Link{Dest: "ghi", Text: ast.Paragraph{ast.Text("")}},
+				}},
+				{Name: "def", Body: []ast.BlockElement{}},
+				{Name: "ghi", Body: []ast.BlockElement{}},
+			},
+			expected: []ast.Node{
+				{Name: "abc", Body: []ast.BlockElement{
+					ast.CodeBlock{Code: []ast.Statement{
+						ast.ForLoop{
+							Name:       "item",
+							Iterable:   ast.Literal{Type: ast.SymbolType, Val: "items"},
+							Consequent: ast.StatementBlock{ast.GotoNode{Name: "def"}},
+						},
+					}},
+					ast.Link{Dest: "ghi", Text: ast.Paragraph{ast.Text("")}},
+				}},
+				{Name: "def", Body: []ast.BlockElement{}},
+				{Name: "ghi", Body: []ast.BlockElement{}},
+			},
+		},
+		"break keeps code after infinite loop": {
+			input: []ast.Node{
+				{Name: "abc", Body: []ast.BlockElement{
+					ast.CodeBlock{Code: []ast.Statement{
+						ast.Loop{
+							Cond: ast.Literal{Type: ast.BooleanType, Val: true},
+							Consequent: ast.StatementBlock{
+								ast.Conditional{
+									Cond:       ast.Literal{Type: ast.SymbolType, Val: "var1"},
+									Consequent: ast.StatementBlock{ast.Break{}},
+									Alternate:  ast.StatementBlock{},
+								},
+								ast.GotoNode{Name: "def"},
+							},
+						},
+					}},
+					ast.Link{Dest: "ghi", Text: ast.Paragraph{ast.Text("")}},
+				}},
+				{Name: "def", Body: []ast.BlockElement{}},
+				{Name: "ghi", Body: []ast.BlockElement{}},
+			},
+			expected: []ast.Node{
+				{Name: "abc", Body: []ast.BlockElement{
+					ast.CodeBlock{Code: []ast.Statement{
+						ast.InfiniteLoop{
+							Consequent: ast.StatementBlock{
+								ast.Conditional{
+									Cond:       ast.Literal{Type: ast.SymbolType, Val: "var1"},
+									Consequent: ast.StatementBlock{ast.Break{}},
+									Alternate:  ast.StatementBlock{},
+								},
+								ast.GotoNode{Name: "def"},
+							},
+						},
+					}},
+					ast.Link{Dest: "ghi", Text: ast.Paragraph{ast.Text("")}},
+				}},
+				{Name: "def", Body: []ast.BlockElement{}},
+				{Name: "ghi", Body: []ast.BlockElement{}},
+			},
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			actual := PruneScript(ast.Script{
@@ -518,3 +645,14 @@ func TestDeadCodeElimination(t *testing.T) {
 		})
 	}
 }
+
+func TestPruneScriptKeepsFunctions(t *testing.T) {
+	functions := map[string][]ast.Type{
+		"func1": {ast.BooleanType, ast.NumberType},
+	}
+	actual := PruneScript(ast.Script{Functions: functions, Nodes: []ast.Node{}})
+	expected := ast.Script{Functions: functions, Nodes: []ast.Node{}}
+	if !expected.CompareScript(actual) {
+		t.Errorf("expected %v got %v", expected, actual)
+	}
+}
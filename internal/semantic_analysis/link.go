@@ -0,0 +1,234 @@
+package semantic_analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mcvoid/dialogue/internal/lexer"
+	"github.com/mcvoid/dialogue/internal/types/ast"
+	"github.com/mcvoid/dialogue/internal/types/diagnostic"
+	"github.com/mcvoid/dialogue/internal/types/lexeme"
+)
+
+// Source is one of several scripts to be linked together. Its nodes
+// are put in Namespace, which is usually named after File.
+type Source struct {
+	Namespace string
+	File      string
+	Script    ast.Script
+}
+
+// LinkScripts merges scripts from several files into one. Each file's
+// nodes are put in its namespace, so node greeting in shop becomes
+// shop.greeting, and a reference without a namespace is to a node in
+// the same file. Extern declarations are merged, and must agree when
+// more than one file declares the same function. The merged script
+// starts at the first node of the first source.
+func LinkScripts(sources []Source) (ast.Script, diagnostic.Diagnostics) {
+	return linkScripts(sources, false)
+}
+
+// LinkScriptsWithImports is LinkScripts for a script which will be
+// compiled into an object. References to nodes in scripts which aren't
+// among the sources are left to be found when the objects are linked.
+func LinkScriptsWithImports(sources []Source) (ast.Script, diagnostic.Diagnostics) {
+	return linkScripts(sources, true)
+}
+
+func linkScripts(sources []Source, imports bool) (ast.Script, diagnostic.Diagnostics) {
+	diags := diagnostic.Diagnostics{}
+	linked := ast.Script{
+		Nodes:       []ast.Node{},
+		Functions:   map[string][]ast.Type{},
+		Returns:     map[string]ast.Type{},
+		FunctionPos: map[string]lexeme.Position{},
+	}
+	files := map[string]string{}
+	functionFiles := map[string]string{}
+
+	for _, src := range sources {
+		if file, ok := files[src.Namespace]; ok {
+			diags = append(diags, diagnostic.Diagnostic{
+				Severity: diagnostic.Error,
+				Code:     diagnostic.LinkError,
+				Message:  fmt.Sprintf("%s and %s are both named %s", file, src.File, src.Namespace),
+				Span:     diagnostic.At(lexeme.Position{File: src.File}),
+			})
+			continue
+		}
+		files[src.Namespace] = src.File
+		if !isSymbol(src.Namespace) {
+			diags = append(diags, diagnostic.Diagnostic{
+				Severity: diagnostic.Error,
+				Code:     diagnostic.LinkError,
+				Message:  fmt.Sprintf("%s can't be referred to as %q; name the file like a node", src.File, src.Namespace),
+				Span:     diagnostic.At(lexeme.Position{File: src.File}),
+			})
+		}
+
+		names := []string{}
+		for name := range src.Script.Functions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			params := src.Script.Functions[name]
+			returnType, returns := src.Script.Returns[name]
+			pos := src.Script.FunctionPos[name]
+			if file, ok := functionFiles[name]; ok {
+				firstReturn, firstReturns := linked.Returns[name]
+				if !sameTypes(params, linked.Functions[name]) || returns != firstReturns || returnType != firstReturn {
+					diags = append(diags, diagnostic.Diagnostic{
+						Severity: diagnostic.Error,
+						Code:     diagnostic.LinkError,
+						Message:  fmt.Sprintf("extern %s conflicts with its declaration in %s", name, file),
+						Span:     nameSpan(ast.Symbol(name), pos),
+						Related: []diagnostic.Related{
+							{Message: "first declared here", Span: nameSpan(ast.Symbol(name), linked.FunctionPos[name])},
+						},
+					})
+				}
+				continue
+			}
+			functionFiles[name] = src.File
+			linked.Functions[name] = params
+			linked.FunctionPos[name] = pos
+			if returns {
+				linked.Returns[name] = returnType
+			}
+		}
+
+		for _, v := range src.Script.Variables {
+			if !declaredExtern(linked.Variables, v) {
+				linked.Variables = append(linked.Variables, v)
+			}
+		}
+
+		for _, node := range src.Script.Nodes {
+			linked.Nodes = append(linked.Nodes, qualifyNode(src.Namespace, node))
+		}
+	}
+
+	diags = append(diags, checkNodeReferences(linked, func(name ast.Symbol) (string, bool) {
+		namespace := strings.SplitN(string(name), ".", 2)[0]
+		if file, ok := files[namespace]; ok {
+			return fmt.Sprintf("unknown node %s in %s", name, file), true
+		}
+		return fmt.Sprintf("unknown node %s: there is no script named %s", name, namespace), !imports
+	})...)
+
+	return linked, diags
+}
+
+func sameTypes(a, b []ast.Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// declaredExtern reports whether an extern variable has already been
+// declared the same way by another file. Every file using an extern can
+// declare it.
+func declaredExtern(variables []ast.VariableDecl, v ast.VariableDecl) bool {
+	if v.Scope != ast.ExternScope {
+		return false
+	}
+	for _, declared := range variables {
+		if declared.Scope == ast.ExternScope && declared.Name == v.Name && declared.Type == v.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// qualify puts a node name in a namespace unless it is already in one.
+func qualify(namespace string, name ast.Symbol) ast.Symbol {
+	if strings.Contains(string(name), ".") {
+		return name
+	}
+	return ast.Symbol(namespace + "." + string(name))
+}
+
+func qualifyNode(namespace string, node ast.Node) ast.Node {
+	body := []ast.BlockElement{}
+	for _, block := range node.Body {
+		body = append(body, qualifyBlock(namespace, block))
+	}
+	return ast.Node{
+		Name:  qualify(namespace, node.Name),
+		Body:  body,
+		Entry: node.Entry,
+		Pos:   node.Pos,
+	}
+}
+
+func qualifyBlock(namespace string, b ast.BlockElement) ast.BlockElement {
+	switch b := b.(type) {
+	case ast.Link:
+		b.Dest = qualify(namespace, b.Dest)
+		return b
+	case ast.Option:
+		option := ast.Option{}
+		for _, link := range b {
+			link.Dest = qualify(namespace, link.Dest)
+			option = append(option, link)
+		}
+		return option
+	case ast.CodeBlock:
+		code := []ast.Statement{}
+		for _, stmt := range b.Code {
+			code = append(code, qualifyStatement(namespace, stmt))
+		}
+		return ast.CodeBlock{Code: code}
+	}
+	return b
+}
+
+func qualifyStatement(namespace string, s ast.Statement) ast.Statement {
+	switch s := s.(type) {
+	case ast.StatementBlock:
+		block := ast.StatementBlock{}
+		for _, stmt := range s {
+			block = append(block, qualifyStatement(namespace, stmt))
+		}
+		return block
+	case ast.GotoNode:
+		s.Name = qualify(namespace, s.Name)
+		return s
+	case ast.Conditional:
+		s.Consequent = qualifyStatement(namespace, s.Consequent)
+		s.Alternate = qualifyStatement(namespace, s.Alternate)
+		return s
+	case ast.Loop:
+		s.Consequent = qualifyStatement(namespace, s.Consequent)
+		return s
+	case ast.InfiniteLoop:
+		s.Consequent = qualifyStatement(namespace, s.Consequent)
+		return s
+	case ast.ForLoop:
+		s.Consequent = qualifyStatement(namespace, s.Consequent)
+		return s
+	}
+	return s
+}
+
+// isSymbol reports whether name lexes as a single symbol, which a
+// namespace must so that other files can refer to its nodes.
+func isSymbol(name string) bool {
+	if name == "" || !strings.ContainsRune(lexer.SymbolStart, rune(name[0])) {
+		return false
+	}
+	for _, r := range name {
+		if !strings.ContainsRune(lexer.SymbolTail, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package semantic_analysis
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mcvoid/dialogue/internal/types/ast"
+	"github.com/mcvoid/dialogue/internal/types/diagnostic"
+	"github.com/mcvoid/dialogue/internal/types/lexeme"
+)
+
+func TestLinkScripts(t *testing.T) {
+	at := func(file string, line int) lexeme.Position {
+		return lexeme.Position{File: file, Line: line, Column: 3}
+	}
+	shop := ast.Script{
+		Functions:   map[string][]ast.Type{"give": {ast.StringType}, "pay": {ast.NumberType}},
+		Returns:     map[string]ast.Type{},
+		FunctionPos: map[string]lexeme.Position{"give": at("shop.md", 1), "pay": at("shop.md", 2)},
+		Variables: []ast.VariableDecl{
+			{Name: "player", Type: ast.StringType, Scope: ast.ExternScope},
+		},
+		Nodes: []ast.Node{
+			{Name: "greeting", Body: []ast.BlockElement{
+				ast.CodeBlock{Code: []ast.Statement{
+					ast.Conditional{
+						Cond:       ast.Literal{Type: ast.SymbolType, Val: "x"},
+						Consequent: ast.StatementBlock{ast.GotoNode{Name: "inn.entry", Pos: at("shop.md", 3)}},
+						Alternate:  ast.StatementBlock{ast.GotoNode{Name: "inn.exit", Pos: at("shop.md", 4)}},
+					},
+				}},
+				ast.Option{
+					{Dest: "greeting", Text: ast.Paragraph{ast.Text("again")}, Pos: at("shop.md", 5)},
+					{Dest: "bank.vault", Text: ast.Paragraph{ast.Text("bank")}, Pos: at("shop.md", 6)},
+				},
+			}},
+		},
+	}
+	inn := ast.Script{
+		Functions:   map[string][]ast.Type{"give": {ast.StringType}, "pay": {ast.StringType}},
+		Returns:     map[string]ast.Type{},
+		FunctionPos: map[string]lexeme.Position{"give": at("inn.md", 1), "pay": at("inn.md", 2)},
+		Variables: []ast.VariableDecl{
+			{Name: "player", Type: ast.StringType, Scope: ast.ExternScope},
+		},
+		Nodes: []ast.Node{
+			{Name: "entry", Body: []ast.BlockElement{
+				ast.Link{Dest: "shop.greeting", Text: ast.Paragraph{ast.Text("back")}, Pos: at("inn.md", 3)},
+			}},
+		},
+	}
+
+	linked, diags := LinkScripts([]Source{
+		{Namespace: "shop", File: "shop.md", Script: shop},
+		{Namespace: "inn", File: "inn.md", Script: inn},
+	})
+
+	expected := ast.Script{
+		Functions: map[string][]ast.Type{"give": {ast.StringType}, "pay": {ast.NumberType}},
+		Returns:   map[string]ast.Type{},
+		Variables: []ast.VariableDecl{
+			{Name: "player", Type: ast.StringType, Scope: ast.ExternScope},
+		},
+		Nodes: []ast.Node{
+			{Name: "shop.greeting", Body: []ast.BlockElement{
+				ast.CodeBlock{Code: []ast.Statement{
+					ast.Conditional{
+						Cond:       ast.Literal{Type: ast.SymbolType, Val: "x"},
+						Consequent: ast.StatementBlock{ast.GotoNode{Name: "inn.entry"}},
+						Alternate:  ast.StatementBlock{ast.GotoNode{Name: "inn.exit"}},
+					},
+				}},
+				ast.Option{
+					{Dest: "shop.greeting", Text: ast.Paragraph{ast.Text("again")}},
+					{Dest: "bank.vault", Text: ast.Paragraph{ast.Text("bank")}},
+				},
+			}},
+			{Name: "inn.entry", Body: []ast.BlockElement{
+				ast.Link{Dest: "shop.greeting", Text: ast.Paragraph{ast.Text("back")}},
+			}},
+		},
+	}
+	if !expected.CompareScript(linked) {
+		t.Errorf("expected %v got %v", expected, linked)
+	}
+
+	expectedDiags := []struct {
+		code    diagnostic.Code
+		message string
+		file    string
+		line    int
+	}{
+		{diagnostic.LinkError, "extern pay conflicts with its declaration in shop.md", "inn.md", 2},
+		{diagnostic.UnknownNode, "unknown node inn.exit in inn.md", "shop.md", 4},
+		{diagnostic.UnknownNode, "unknown node bank.vault: there is no script named bank", "shop.md", 6},
+	}
+	if len(diags) != len(expectedDiags) {
+		t.Fatalf("expected %d diagnostics got %v", len(expectedDiags), diags)
+	}
+	for i, e := range expectedDiags {
+		d := diags[i]
+		if d.Code != e.code || d.Message != e.message || d.Span.Start.File != e.file || d.Span.Start.Line != e.line {
+			t.Errorf("diagnostic %d: expected %q at %s:%d got %v", i, e.message, e.file, e.line, d)
+		}
+	}
+	if len(diags[0].Related) != 1 || diags[0].Related[0].Span.Start.File != "shop.md" {
+		t.Errorf("expected the conflict to point at the first declaration, got %v", diags[0].Related)
+	}
+
+	_, diags = LinkScriptsWithImports([]Source{
+		{Namespace: "inn", File: "inn.md", Script: inn},
+		{Namespace: "bank", File: "bank.md", Script: ast.Script{Nodes: []ast.Node{
+			{Name: "vault", Body: []ast.BlockElement{
+				ast.Link{Dest: "teller", Text: ast.Paragraph{ast.Text("back")}, Pos: at("bank.md", 1)},
+			}},
+		}}},
+	})
+	if len(diags) != 1 || diags[0].Message != "unknown node bank.teller in bank.md" {
+		t.Errorf("expected only the missing node in a linked script to be reported, got %v", diags)
+	}
+
+	_, diags = LinkScripts([]Source{
+		{Namespace: "shop", File: "shop.md", Script: ast.Script{}},
+		{Namespace: "shop", File: "other/shop.md", Script: ast.Script{}},
+	})
+	if len(diags) != 1 || diags[0].Message != "shop.md and other/shop.md are both named shop" {
+		t.Errorf("expected a clash between the namespaces, got %v", diags)
+	}
+
+	for _, name := range []string{"my-shop", "1shop", ""} {
+		_, diags = LinkScripts([]Source{{Namespace: name, File: name + ".md", Script: ast.Script{}}})
+		if len(diags) != 1 || diags[0].Code != diagnostic.LinkError || diags[0].Message != fmt.Sprintf("%s.md can't be referred to as %q; name the file like a node", name, name) {
+			t.Errorf("expected %q to be refused as a namespace, got %v", name, diags)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package semantic_analysis
+
+import (
+	"github.com/mcvoid/dialogue/internal/types/ast"
+	"github.com/mcvoid/dialogue/internal/types/diagnostic"
+	"github.com/mcvoid/dialogue/internal/types/lexeme"
+)
+
+// CheckLoopControl reports break and continue statements which aren't
+// inside a loop.
+func CheckLoopControl(script ast.Script) diagnostic.Diagnostics {
+	diags := diagnostic.Diagnostics{}
+	for _, node := range script.Nodes {
+		for _, block := range node.Body {
+			if block, ok := block.(ast.CodeBlock); ok {
+				for _, stmt := range block.Code {
+					diags = append(diags, checkLoopControlInStatement(stmt, false)...)
+				}
+			}
+		}
+	}
+	return diags
+}
+
+func checkLoopControlInStatement(s ast.Statement, inLoop bool) diagnostic.Diagnostics {
+	diags := diagnostic.Diagnostics{}
+	switch s := s.(type) {
+	case ast.StatementBlock:
+		for _, stmt := range s {
+			diags = append(diags, checkLoopControlInStatement(stmt, inLoop)...)
+		}
+	case ast.Conditional:
+		diags = append(diags, checkLoopControlInStatement(s.Consequent, inLoop)...)
+		diags = append(diags, checkLoopControlInStatement(s.Alternate, inLoop)...)
+	case ast.Loop:
+		diags = append(diags, checkLoopControlInStatement(s.Consequent, true)...)
+	case ast.InfiniteLoop:
+		diags = append(diags, checkLoopControlInStatement(s.Consequent, true)...)
+	case ast.ForLoop:
+		diags = append(diags, checkLoopControlInStatement(s.Consequent, true)...)
+	case ast.Break:
+		if !inLoop {
+			diags = append(diags, loopControlError("break", s.Pos))
+		}
+	case ast.Continue:
+		if !inLoop {
+			diags = append(diags, loopControlError("continue", s.Pos))
+		}
+	}
+	return diags
+}
+
+func loopControlError(keyword string, pos lexeme.Position) diagnostic.Diagnostic {
+	return diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Code:     diagnostic.LoopControl,
+		Message:  keyword + " is not inside a loop",
+		Span:     diagnostic.Token(lexeme.Item{Val: keyword, Pos: pos}),
+	}
+}
@@ -0,0 +1,63 @@
+package semantic_analysis
+
+import (
+	"testing"
+
+	"github.com/mcvoid/dialogue/internal/types/ast"
+	"github.com/mcvoid/dialogue/internal/types/diagnostic"
+	"github.com/mcvoid/dialogue/internal/types/lexeme"
+)
+
+func TestCheckLoopControl(t *testing.T) {
+	at := func(line int) lexeme.Position {
+		return lexeme.Position{Line: line, Column: 3}
+	}
+	script := ast.Script{
+		Nodes: []ast.Node{
+			{Name: "a", Body: []ast.BlockElement{
+				ast.CodeBlock{Code: []ast.Statement{
+					ast.Loop{
+						Cond: ast.Literal{Type: ast.SymbolType, Val: "x"},
+						Consequent: ast.StatementBlock{
+							ast.Conditional{
+								Cond:       ast.Literal{Type: ast.SymbolType, Val: "y"},
+								Consequent: ast.StatementBlock{ast.Break{Pos: at(1)}},
+								Alternate:  ast.StatementBlock{ast.Continue{Pos: at(2)}},
+							},
+						},
+					},
+					ast.InfiniteLoop{Consequent: ast.StatementBlock{ast.Break{Pos: at(3)}}},
+					ast.ForLoop{
+						Name:       "item",
+						Iterable:   ast.Literal{Type: ast.SymbolType, Val: "items"},
+						Consequent: ast.StatementBlock{ast.Continue{Pos: at(6)}},
+					},
+					ast.Break{Pos: at(4)},
+					ast.Conditional{
+						Cond:       ast.Literal{Type: ast.SymbolType, Val: "y"},
+						Consequent: ast.StatementBlock{ast.Continue{Pos: at(5)}},
+						Alternate:  ast.StatementBlock{},
+					},
+				}},
+				ast.Link{Dest: "a", Text: ast.Paragraph{ast.Text("a")}},
+			}},
+		},
+	}
+
+	diags := CheckLoopControl(script)
+	expected := []struct {
+		message string
+		line    int
+	}{
+		{"break is not inside a loop", 4},
+		{"continue is not inside a loop", 5},
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d diagnostics got %v", len(expected), diags)
+	}
+	for i, e := range expected {
+		if diags[i].Code != diagnostic.LoopControl || diags[i].Message != e.message || diags[i].Span.Start.Line != e.line {
+			t.Errorf("diagnostic %d: expected %q on line %d got %v", i, e.message, e.line, diags[i])
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package semantic_analysis
+
+import (
+	"fmt"
+
+	"github.com/mcvoid/dialogue/internal/types/ast"
+	"github.com/mcvoid/dialogue/internal/types/diagnostic"
+	"github.com/mcvoid/dialogue/internal/types/lexeme"
+)
+
+type nodeReference struct {
+	name ast.Symbol
+	pos  lexeme.Position
+}
+
+// CheckNodeReferences reports nodes which are declared more than once
+// and links, options and gotos which lead to nodes that don't exist.
+func CheckNodeReferences(script ast.Script) diagnostic.Diagnostics {
+	return checkNodeReferences(script, func(name ast.Symbol) (string, bool) {
+		return fmt.Sprintf("unknown node %s", name), true
+	})
+}
+
+// checkNodeReferences is CheckNodeReferences with unknown giving the
+// message for a reference to an unknown node, and whether to report it.
+func checkNodeReferences(script ast.Script, unknown func(name ast.Symbol) (message string, report bool)) diagnostic.Diagnostics {
+	diags := diagnostic.Diagnostics{}
+	declared := map[ast.Symbol]ast.Node{}
+
+	for _, node := range script.Nodes {
+		if first, ok := declared[node.Name]; ok {
+			diags = append(diags, diagnostic.Diagnostic{
+				Severity: diagnostic.Error,
+				Code:     diagnostic.DuplicateNode,
+				Message:  fmt.Sprintf("node %s is declared more than once", node.Name),
+				Span:     nameSpan(node.Name, node.Pos),
+				Related: []diagnostic.Related{
+					{Message: "first declared here", Span: nameSpan(first.Name, first.Pos)},
+				},
+			})
+			continue
+		}
+		declared[node.Name] = node
+	}
+
+	for _, node := range script.Nodes {
+		for _, block := range node.Body {
+			for _, ref := range findNodeReferencesInBlock(block) {
+				if _, ok := declared[ref.name]; ok {
+					continue
+				}
+				message, report := unknown(ref.name)
+				if !report {
+					continue
+				}
+				diags = append(diags, diagnostic.Diagnostic{
+					Severity: diagnostic.Error,
+					Code:     diagnostic.UnknownNode,
+					Message:  message,
+					Span:     nameSpan(ref.name, ref.pos),
+				})
+			}
+		}
+	}
+
+	return diags
+}
+
+func nameSpan(name ast.Symbol, pos lexeme.Position) diagnostic.Span {
+	return diagnostic.Token(lexeme.Item{Val: string(name), Pos: pos})
+}
+
+func findNodeReferencesInBlock(b ast.BlockElement) []nodeReference {
+	refs := []nodeReference{}
+	switch b := b.(type) {
+	case ast.Link:
+		refs = append(refs, nodeReference{b.Dest, b.Pos})
+	case ast.Option:
+		for _, link := range b {
+			refs = append(refs, nodeReference{link.Dest, link.Pos})
+		}
+	case ast.CodeBlock:
+		for _, stmt := range b.Code {
+			refs = append(refs, findNodeReferencesInStatement(stmt)...)
+		}
+	}
+	return refs
+}
+
+func findNodeReferencesInStatement(s ast.Statement) []nodeReference {
+	refs := []nodeReference{}
+	switch s := s.(type) {
+	case ast.StatementBlock:
+		for _, stmt := range s {
+			refs = append(refs, findNodeReferencesInStatement(stmt)...)
+		}
+	case ast.GotoNode:
+		refs = append(refs, nodeReference{s.Name, s.Pos})
+	case ast.Conditional:
+		refs = append(refs, findNodeReferencesInStatement(s.Consequent)...)
+		refs = append(refs, findNodeReferencesInStatement(s.Alternate)...)
+	case ast.Loop:
+		refs = append(refs, findNodeReferencesInStatement(s.Consequent)...)
+	case ast.InfiniteLoop:
+		refs = append(refs, findNodeReferencesInStatement(s.Consequent)...)
+	case ast.ForLoop:
+		refs = append(refs, findNodeReferencesInStatement(s.Consequent)...)
+	}
+	return refs
+}
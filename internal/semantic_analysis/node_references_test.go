@@ -0,0 +1,64 @@
+package semantic_analysis
+
+import (
+	"testing"
+
+	"github.com/mcvoid/dialogue/internal/types/ast"
+	"github.com/mcvoid/dialogue/internal/types/diagnostic"
+	"github.com/mcvoid/dialogue/internal/types/lexeme"
+)
+
+func TestCheckNodeReferences(t *testing.T) {
+	at := func(line int) lexeme.Position {
+		return lexeme.Position{Line: line, Column: 3}
+	}
+	script := ast.Script{
+		Nodes: []ast.Node{
+			{Name: "a", Pos: at(1), Body: []ast.BlockElement{
+				ast.CodeBlock{Code: []ast.Statement{
+					ast.Conditional{
+						Cond:       ast.Literal{Type: ast.SymbolType, Val: "x"},
+						Consequent: ast.StatementBlock{ast.GotoNode{Name: "b", Pos: at(2)}},
+						Alternate:  ast.StatementBlock{ast.GotoNode{Name: "nowhere", Pos: at(3)}},
+					},
+					ast.InfiniteLoop{Consequent: ast.GotoNode{Name: "void", Pos: at(4)}},
+				}},
+				ast.Option{
+					{Dest: "a", Text: ast.Paragraph{ast.Text("a")}, Pos: at(5)},
+					{Dest: "missing", Text: ast.Paragraph{ast.Text("m")}, Pos: at(6)},
+				},
+			}},
+			{Name: "b", Pos: at(7), Body: []ast.BlockElement{
+				ast.Link{Dest: "gone", Text: ast.Paragraph{ast.Text("g")}, Pos: at(8)},
+			}},
+			{Name: "a", Pos: at(9), Body: []ast.BlockElement{}},
+		},
+	}
+
+	diags := CheckNodeReferences(script)
+	expected := []struct {
+		code diagnostic.Code
+		line int
+	}{
+		{diagnostic.DuplicateNode, 9},
+		{diagnostic.UnknownNode, 3},
+		{diagnostic.UnknownNode, 4},
+		{diagnostic.UnknownNode, 6},
+		{diagnostic.UnknownNode, 8},
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d diagnostics got %v", len(expected), diags)
+	}
+	for i, e := range expected {
+		if diags[i].Code != e.code || diags[i].Span.Start.Line != e.line {
+			t.Errorf("diagnostic %d: expected %v on line %d got %v", i, e.code, e.line, diags[i])
+		}
+	}
+	if len(diags[0].Related) != 1 || diags[0].Related[0].Span.Start.Line != 1 {
+		t.Errorf("expected duplicate node to point at the first declaration, got %v", diags[0].Related)
+	}
+
+	if diags := CheckNodeReferences(ast.Script{Nodes: script.Nodes[1:2]}); len(diags) != 1 {
+		t.Errorf("expected 1 diagnostic got %v", diags)
+	}
+}
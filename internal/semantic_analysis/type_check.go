@@ -1,6 +1,13 @@
 package semantic_analysis
 
-import "github.com/mcvoid/dialogue/internal/types/ast"
+import (
+	"fmt"
+
+	"github.com/mcvoid/dialogue/internal/types/asm"
+	"github.com/mcvoid/dialogue/internal/types/ast"
+	"github.com/mcvoid/dialogue/internal/types/diagnostic"
+	"github.com/mcvoid/dialogue/internal/types/lexeme"
+)
 
 type EffectiveType int
 
@@ -12,6 +19,8 @@ const (
 	String
 	Null
 	Void
+	List
+	Map
 )
 
 var astTypeToEffectiveType = map[ast.Type]EffectiveType{
@@ -20,121 +29,473 @@ var astTypeToEffectiveType = map[ast.Type]EffectiveType{
 	ast.BooleanType: Boolean,
 	ast.NullType:    Null,
 	ast.SymbolType:  Variant,
+	ast.ListType:    List,
+	ast.MapType:     Map,
+}
+
+var asmTypeToEffectiveType = map[asm.Type]EffectiveType{
+	asm.StringType:  String,
+	asm.NumberType:  Number,
+	asm.BooleanType: Boolean,
+	asm.NullType:    Null,
+	asm.ListType:    List,
+	asm.MapType:     Map,
+}
+
+var binaryOperatorNames = map[ast.BinaryOperator]string{
+	ast.AddOp:    "+",
+	ast.SubOp:    "-",
+	ast.MulOp:    "*",
+	ast.DivOp:    "/",
+	ast.ModOp:    "%",
+	ast.GtOp:     ">",
+	ast.GteOp:    ">=",
+	ast.LtOp:     "<",
+	ast.LteOp:    "<=",
+	ast.EqOp:     "==",
+	ast.NeqOp:    "!=",
+	ast.AndOp:    "&&",
+	ast.OrOp:     "||",
+	ast.ConcatOp: ".",
+}
+
+var unaryOperatorNames = map[ast.UnaryOperator]string{
+	ast.IncOp: "++",
+	ast.DecOp: "--",
+	ast.NotOp: "!",
+	ast.NegOp: "-",
+}
+
+func (t EffectiveType) String() string {
+	switch t {
+	case Error:
+		return "error"
+	case Variant:
+		return "variant"
+	case Number:
+		return "number"
+	case Boolean:
+		return "bool"
+	case String:
+		return "string"
+	case Null:
+		return "null"
+	case Void:
+		return "void"
+	case List:
+		return "list"
+	case Map:
+		return "map"
+	}
+	return fmt.Sprintf("type(%d)", int(t))
 }
 
-func TypeCheckScript(script ast.Script) EffectiveType {
+// TypeCheckContext collects the problems found while type checking
+// a script so that every error can be reported, not just the first.
+type TypeCheckContext struct {
+	Root        ast.Script
+	Diagnostics diagnostic.Diagnostics
+	// Globals and Locals hold the types of the variables declared so
+	// far. Locals are forgotten at the end of each node.
+	Globals map[ast.Symbol]EffectiveType
+	Locals  map[ast.Symbol]EffectiveType
+}
+
+func (ctx *TypeCheckContext) errorf(pos lexeme.Position, format string, args ...interface{}) {
+	ctx.Diagnostics = append(ctx.Diagnostics, diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Code:     diagnostic.TypeError,
+		Message:  fmt.Sprintf(format, args...),
+		Span:     diagnostic.At(pos),
+	})
+}
+
+// expect reports an error if t can't be used where want is required.
+// Errors have already been reported where they occurred so they are
+// let through rather than reported again.
+func (ctx *TypeCheckContext) expect(pos lexeme.Position, t, want EffectiveType, format string, args ...interface{}) {
+	if t == want || t == Variant || t == Error {
+		return
+	}
+	ctx.errorf(pos, format, append(args, t)...)
+}
+
+// TypeCheckScript checks every node in the script, returning Void if the
+// script is well typed and Error along with the reasons if it isn't.
+func TypeCheckScript(script ast.Script) (EffectiveType, diagnostic.Diagnostics) {
+	ctx := &TypeCheckContext{
+		Root:        script,
+		Diagnostics: diagnostic.Diagnostics{},
+		Globals:     map[ast.Symbol]EffectiveType{},
+	}
+	for _, decl := range script.Variables {
+		typeCheckDeclaration(ctx, decl, ctx.Globals)
+	}
 	for _, node := range script.Nodes {
+		ctx.Locals = map[ast.Symbol]EffectiveType{}
 		for _, block := range node.Body {
 			switch block := block.(type) {
 			case ast.Paragraph:
-				for _, inline := range block {
-					if inlineCode, ok := inline.(ast.InlineCode); ok {
-						if t := TypeCheckExpression(inlineCode.Expr); t == Error {
-							return Error
-						}
-					}
-				}
+				typeCheckInlines(ctx, block)
 			case ast.CodeBlock:
 				for _, stmt := range block.Code {
-					if t := TypeCheckStatement(stmt, script); t == Error {
-						return Error
-					}
+					TypeCheckStatement(ctx, stmt)
 				}
 			case ast.Link:
-				// continue
+				typeCheckInlines(ctx, block.Text)
 			case ast.Option:
-				// continue
+				for _, link := range block {
+					typeCheckInlines(ctx, link.Text)
+					if link.Guard != nil {
+						pos := ast.PosOf(link.Guard)
+						if pos == (lexeme.Position{}) {
+							pos = link.Pos
+						}
+						ctx.expect(pos, TypeCheckExpression(ctx, link.Guard), Boolean, "option guard is %s")
+					}
+				}
 			default:
-				return Error
+				ctx.errorf(node.Pos, "unknown block element in node %s", node.Name)
 			}
 		}
 	}
-	return Void
+	if len(ctx.Diagnostics) > 0 {
+		return Error, ctx.Diagnostics
+	}
+	return Void, ctx.Diagnostics
+}
+
+// typeCheckInlines checks the inline code in a paragraph or link's text.
+func typeCheckInlines(ctx *TypeCheckContext, inlines ast.Paragraph) {
+	for _, inline := range inlines {
+		if inlineCode, ok := inline.(ast.InlineCode); ok {
+			TypeCheckExpression(ctx, inlineCode.Expr)
+		}
+	}
+}
+
+// variableType finds the type of a declared variable, looking at the
+// node's locals before the globals.
+func (ctx *TypeCheckContext) variableType(name ast.Symbol) (EffectiveType, bool) {
+	if t, ok := ctx.Locals[name]; ok {
+		return t, true
+	}
+	t, ok := ctx.Globals[name]
+	return t, ok
+}
+
+// typeCheckDeclaration checks a variable's initial value against its
+// declared type and adds the variable to scope. A variable declared
+// without a type takes the type of its initial value.
+func typeCheckDeclaration(ctx *TypeCheckContext, decl ast.VariableDecl, scope map[ast.Symbol]EffectiveType) EffectiveType {
+	if _, ok := scope[decl.Name]; ok {
+		ctx.errorf(decl.Pos, "variable %s is already declared", decl.Name)
+		return Error
+	}
+	result := Void
+	declared := astTypeToEffectiveType[decl.Type]
+	if decl.Val != nil {
+		t := TypeCheckExpression(ctx, decl.Val)
+		if decl.Type == "" {
+			declared = t
+		}
+		ctx.expect(decl.Pos, t, declared, "%s is declared %s, got %s", decl.Name, declared)
+		if t == Error || (t != declared && t != Variant) {
+			result = Error
+		}
+	}
+	if declared == Error {
+		// the error has been reported, so don't report every use too
+		declared = Variant
+	}
+	scope[decl.Name] = declared
+	return result
 }
 
-func TypeCheckStatement(stmt ast.Statement, root ast.Script) EffectiveType {
+func TypeCheckStatement(ctx *TypeCheckContext, stmt ast.Statement) EffectiveType {
 	switch stmt := stmt.(type) {
 	case ast.Assignment:
 		{
-			if TypeCheckExpression(stmt.Val) == Error {
+			t := TypeCheckExpression(ctx, stmt.Val)
+			if t == Error {
 				return Error
 			}
+			if want, ok := ctx.variableType(stmt.Name); ok && want != Variant {
+				ctx.expect(stmt.Pos, t, want, "%s is %s, cannot assign %s", stmt.Name, want)
+				if t != want && t != Variant {
+					return Error
+				}
+			}
 			return Void
 		}
+	case ast.VariableDecl:
+		if ctx.Locals == nil {
+			ctx.Locals = map[ast.Symbol]EffectiveType{}
+		}
+		return typeCheckDeclaration(ctx, stmt, ctx.Locals)
 	case ast.StatementBlock:
 		{
+			result := Void
 			for _, s := range stmt {
-				if t := TypeCheckStatement(s, root); t == Error {
-					return Error
+				if t := TypeCheckStatement(ctx, s); t == Error {
+					result = Error
 				}
 			}
-			return Void
+			return result
 		}
 	case ast.Conditional:
 		{
-			t := TypeCheckExpression(stmt.Cond)
+			result := Void
+			t := TypeCheckExpression(ctx, stmt.Cond)
+			ctx.expect(stmt.Pos, t, Boolean, "if condition is %s")
 			if t != Boolean && t != Variant {
-				return Error
+				result = Error
 			}
-			t = TypeCheckStatement(stmt.Consequent, root)
-			if t != Void {
-				return Error
+			if TypeCheckStatement(ctx, stmt.Consequent) != Void {
+				result = Error
 			}
-			t = TypeCheckStatement(stmt.Alternate, root)
-			if t != Void {
-				return Error
+			if TypeCheckStatement(ctx, stmt.Alternate) != Void {
+				result = Error
 			}
-			return Void
+			return result
 		}
 	case ast.Loop:
 		{
-			t := TypeCheckExpression(stmt.Cond)
+			result := Void
+			t := TypeCheckExpression(ctx, stmt.Cond)
+			ctx.expect(stmt.Pos, t, Boolean, "while condition is %s")
 			if t != Boolean && t != Variant {
-				return Error
+				result = Error
 			}
-			t = TypeCheckStatement(stmt.Consequent, root)
-			if t != Void {
-				return Error
+			if TypeCheckStatement(ctx, stmt.Consequent) != Void {
+				result = Error
 			}
-			return Void
+			return result
 		}
-	case ast.FunctionCall:
+	case ast.InfiniteLoop:
+		return TypeCheckStatement(ctx, stmt.Consequent)
+	case ast.IndexAssignment:
 		{
-			prototype := root.Functions[string(stmt.Name)]
-			if len(prototype) != len(stmt.Params) {
-				return Error
+			// an undeclared variable can hold anything, as it can when
+			// it's assigned or loaded
+			container, ok := ctx.variableType(stmt.Name)
+			if !ok {
+				container = Variant
 			}
-			for i, param := range stmt.Params {
-				t := TypeCheckExpression(param)
-				if t == Error {
-					return Error
-				}
-				expectedType := astTypeToEffectiveType[prototype[i]]
-				if t != Variant && expectedType != t {
-					return Error
-				}
+			result := Void
+			if !typeCheckIndex(ctx, stmt.Pos, container, stmt.Key) {
+				result = Error
 			}
-			return Void
+			if TypeCheckExpression(ctx, stmt.Val) == Error {
+				result = Error
+			}
+			return result
 		}
-	case ast.GotoNode:
+	case ast.ForLoop:
+		{
+			result := Void
+			switch t := TypeCheckExpression(ctx, stmt.Iterable); t {
+			case List, Map, Variant:
+			case Error:
+				result = Error
+			default:
+				ctx.errorf(stmt.Pos, "cannot loop over %s", t)
+				result = Error
+			}
+			if ctx.Locals == nil {
+				ctx.Locals = map[ast.Symbol]EffectiveType{}
+			}
+			// the loop variable is a local holding any item, so it can
+			// be reused by another for loop but not by a typed local.
+			// It's only in scope in the loop's body.
+			outer, declared := ctx.Locals[stmt.Name]
+			if declared && outer != Variant {
+				ctx.errorf(stmt.Pos, "variable %s is already declared", stmt.Name)
+				result = Error
+			}
+			ctx.Locals[stmt.Name] = Variant
+			if TypeCheckStatement(ctx, stmt.Consequent) != Void {
+				result = Error
+			}
+			if declared {
+				ctx.Locals[stmt.Name] = outer
+			} else {
+				delete(ctx.Locals, stmt.Name)
+			}
+			return result
+		}
+	case ast.FunctionCall:
+		if !typeCheckCall(ctx, stmt) {
+			return Error
+		}
+		// any value returned is thrown away
+		return Void
+	case ast.GotoNode, ast.Break, ast.Continue:
 		return Void
 	}
+	ctx.errorf(lexeme.Position{}, "unknown statement %T", stmt)
 	return Error
 }
 
-func TypeCheckExpression(expr ast.Expression) EffectiveType {
+func TypeCheckExpression(ctx *TypeCheckContext, expr ast.Expression) EffectiveType {
 	switch expr := expr.(type) {
 	case ast.BinaryOp:
-		return TypeCheckBinary(expr)
+		return TypeCheckBinary(ctx, expr)
 	case ast.UnaryOp:
-		return TypeCheckUnary(expr)
+		return TypeCheckUnary(ctx, expr)
 	case ast.Literal:
-		return TypeCheckLiteral(expr)
+		return TypeCheckLiteral(ctx, expr)
+	case ast.FunctionCall:
+		return TypeCheckFunctionCall(ctx, expr)
+	case ast.ListLiteral:
+		return TypeCheckList(ctx, expr)
+	case ast.MapLiteral:
+		return TypeCheckMap(ctx, expr)
+	case ast.Index:
+		if !typeCheckIndex(ctx, expr.Pos, TypeCheckExpression(ctx, expr.Container), expr.Key) {
+			return Error
+		}
+		// the items of a list or map can be of any type
+		return Variant
 	}
+	ctx.errorf(lexeme.Position{}, "unknown expression %T", expr)
 	return Error
 }
 
-func TypeCheckBinary(op ast.BinaryOp) EffectiveType {
+// TypeCheckList checks each item of a list literal. Items can be of
+// any type.
+func TypeCheckList(ctx *TypeCheckContext, list ast.ListLiteral) EffectiveType {
+	result := List
+	for _, item := range list.Items {
+		if TypeCheckExpression(ctx, item) == Error {
+			result = Error
+		}
+	}
+	return result
+}
+
+// TypeCheckMap checks each entry of a map literal. Keys are strings and
+// values can be of any type.
+func TypeCheckMap(ctx *TypeCheckContext, m ast.MapLiteral) EffectiveType {
+	result := Map
+	for _, entry := range m.Entries {
+		pos := ast.PosOf(entry.Key)
+		if pos == (lexeme.Position{}) {
+			pos = m.Pos
+		}
+		key := TypeCheckExpression(ctx, entry.Key)
+		ctx.expect(pos, key, String, "map key is %s")
+		if key != String && key != Variant {
+			result = Error
+		}
+		if TypeCheckExpression(ctx, entry.Val) == Error {
+			result = Error
+		}
+	}
+	return result
+}
+
+// typeCheckIndex checks that a value of type container can be indexed
+// by key, reporting whether it can. Lists take number indexes and maps
+// string keys.
+func typeCheckIndex(ctx *TypeCheckContext, pos lexeme.Position, container EffectiveType, key ast.Expression) bool {
+	t := TypeCheckExpression(ctx, key)
+	switch container {
+	case List:
+		ctx.expect(pos, t, Number, "list index is %s")
+		return t == Number || t == Variant
+	case Map:
+		ctx.expect(pos, t, String, "map key is %s")
+		return t == String || t == Variant
+	case Variant:
+		return t != Error
+	case Error:
+		return false
+	}
+	ctx.errorf(pos, "cannot index %s", container)
+	return false
+}
+
+// TypeCheckFunctionCall checks a call used as a value, which is only
+// possible if the function was declared with a return type.
+func TypeCheckFunctionCall(ctx *TypeCheckContext, call ast.FunctionCall) EffectiveType {
+	if !typeCheckCall(ctx, call) {
+		return Error
+	}
+	_, returnType, _ := ctx.signature(string(call.Name))
+	if returnType == Void {
+		ctx.errorf(call.Pos, "%s does not return a value", call.Name)
+		return Error
+	}
+	return returnType
+}
+
+// signature looks up the parameter and return types of a function,
+// preferring an extern declaration over a builtin of the same name.
+// Functions without a return type return Void.
+func (ctx *TypeCheckContext) signature(name string) (params []EffectiveType, returnType EffectiveType, ok bool) {
+	if prototype, ok := ctx.Root.Functions[name]; ok {
+		for _, param := range prototype {
+			params = append(params, astTypeToEffectiveType[param])
+		}
+		returnType = Void
+		if t, ok := ctx.Root.Returns[name]; ok {
+			returnType = astTypeToEffectiveType[t]
+		}
+		return params, returnType, true
+	}
+	if builtin, ok := asm.Builtins[name]; ok {
+		for _, param := range builtin.Params {
+			params = append(params, asmTypeToEffectiveType[param])
+		}
+		return params, asmTypeToEffectiveType[builtin.Returns], true
+	}
+	return nil, Error, false
+}
+
+// typeCheckCall checks a call's arguments against the function's
+// declaration, reporting whether they all match.
+func typeCheckCall(ctx *TypeCheckContext, call ast.FunctionCall) bool {
+	prototype, _, ok := ctx.signature(string(call.Name))
+	if !ok {
+		ctx.errorf(call.Pos, "function %s is not declared", call.Name)
+		return false
+	}
+	if len(prototype) != len(call.Params) {
+		ctx.errorf(call.Pos, "%s takes %d arguments, got %d", call.Name, len(prototype), len(call.Params))
+		return false
+	}
+	result := true
+	for i, param := range call.Params {
+		t := TypeCheckExpression(ctx, param)
+		expectedType := prototype[i]
+		pos := ast.PosOf(param)
+		if pos == (lexeme.Position{}) {
+			pos = call.Pos
+		}
+		ctx.expect(pos, t, expectedType, "argument %d of %s expects %s, got %s", i+1, call.Name, expectedType)
+		if t != expectedType && t != Variant {
+			result = false
+		}
+	}
+	return result
+}
+
+// typeCheckOperands checks both sides of a binary operator against the
+// type it requires, reporting whether both are acceptable.
+func typeCheckOperands(ctx *TypeCheckContext, op ast.BinaryOp, want EffectiveType) bool {
+	name := binaryOperatorNames[op.Operator]
+	left := TypeCheckExpression(ctx, op.LeftArg)
+	ctx.expect(op.Pos, left, want, "left operand of %s expects %s, got %s", name, want)
+	right := TypeCheckExpression(ctx, op.RightArg)
+	ctx.expect(op.Pos, right, want, "right operand of %s expects %s, got %s", name, want)
+	return (left == want || left == Variant) && (right == want || right == Variant)
+}
+
+// TypeCheckBinary checks an operator's operands. Whole and fractional
+// numbers are both number, so they can be mixed freely; a whole number
+// is promoted when the other operand has a fraction.
+func TypeCheckBinary(ctx *TypeCheckContext, op ast.BinaryOp) EffectiveType {
 	switch op.Operator {
 	case ast.AddOp:
 		fallthrough
@@ -145,17 +506,10 @@ func TypeCheckBinary(op ast.BinaryOp) EffectiveType {
 	case ast.DivOp:
 		fallthrough
 	case ast.ModOp:
-		{
-			t := TypeCheckExpression(op.LeftArg)
-			if t != Number && t != Variant {
-				return Error
-			}
-			t = TypeCheckExpression(op.RightArg)
-			if t != Number && t != Variant {
-				return Error
-			}
-			return Number
+		if !typeCheckOperands(ctx, op, Number) {
+			return Error
 		}
+		return Number
 	case ast.GtOp:
 		fallthrough
 	case ast.LtOp:
@@ -163,104 +517,90 @@ func TypeCheckBinary(op ast.BinaryOp) EffectiveType {
 	case ast.GteOp:
 		fallthrough
 	case ast.LteOp:
-		{
-			t := TypeCheckExpression(op.LeftArg)
-			if t != Number && t != Variant {
-				return Error
-			}
-			t = TypeCheckExpression(op.RightArg)
-			if t != Number && t != Variant {
-				return Error
-			}
-			return Boolean
+		if !typeCheckOperands(ctx, op, Number) {
+			return Error
 		}
+		return Boolean
 	case ast.AndOp:
 		fallthrough
 	case ast.OrOp:
-		{
-			t := TypeCheckExpression(op.LeftArg)
-			if t != Boolean && t != Variant {
-				return Error
-			}
-			t = TypeCheckExpression(op.RightArg)
-			if t != Boolean && t != Variant {
-				return Error
-			}
-			return Boolean
+		if !typeCheckOperands(ctx, op, Boolean) {
+			return Error
 		}
+		return Boolean
 	case ast.EqOp:
 		fallthrough
 	case ast.NeqOp:
 		// eq can take any two types for arguments and always produces boolean
+		if TypeCheckExpression(ctx, op.LeftArg) == Error || TypeCheckExpression(ctx, op.RightArg) == Error {
+			return Error
+		}
 		return Boolean
 	case ast.ConcatOp:
 		// concat can take any two types for arguments and always produces string
+		if TypeCheckExpression(ctx, op.LeftArg) == Error || TypeCheckExpression(ctx, op.RightArg) == Error {
+			return Error
+		}
 		return String
 	}
 
+	ctx.errorf(op.Pos, "unknown operator %s", op.Operator)
 	return Error
 }
 
-func TypeCheckUnary(op ast.UnaryOp) EffectiveType {
+func TypeCheckUnary(ctx *TypeCheckContext, op ast.UnaryOp) EffectiveType {
+	var want EffectiveType
 	switch op.Operator {
 	case ast.IncOp:
 		fallthrough
 	case ast.DecOp:
-		{
-			t := TypeCheckExpression(op.Arg)
-			if t == Number || t == Variant {
-				return Number
-			}
-			return Error
-		}
-	case ast.NotOp:
-		{
-			t := TypeCheckExpression(op.Arg)
-			if t == Boolean || t == Variant {
-				return Boolean
-			}
-			return Error
-		}
+		fallthrough
 	case ast.NegOp:
-		{
-			t := TypeCheckExpression(op.Arg)
-			if t == Number || t == Variant {
-				return Number
-			}
-			return Error
-		}
+		want = Number
+	case ast.NotOp:
+		want = Boolean
+	default:
+		ctx.errorf(op.Pos, "unknown operator %s", op.Operator)
+		return Error
 	}
-	return Error
+	t := TypeCheckExpression(ctx, op.Arg)
+	ctx.expect(op.Pos, t, want, "operand of %s expects %s, got %s", unaryOperatorNames[op.Operator], want)
+	if t != want && t != Variant {
+		return Error
+	}
+	return want
 }
 
-func TypeCheckLiteral(lit ast.Literal) EffectiveType {
+func TypeCheckLiteral(ctx *TypeCheckContext, lit ast.Literal) EffectiveType {
+	ok := false
+	t := astTypeToEffectiveType[lit.Type]
 	switch lit.Type {
 	case ast.StringType:
-		if _, ok := lit.Val.(string); !ok {
-			return Error
-		}
-		return String
+		_, ok = lit.Val.(string)
 	case ast.NullType:
-		if lit.Val != nil {
-			return Error
-		}
-		return Null
+		ok = lit.Val == nil
 	case ast.NumberType:
-		if _, ok := lit.Val.(int); !ok {
+		if text, isText := lit.Val.(string); isText {
+			ctx.errorf(lit.Pos, "number %s is out of range", text)
 			return Error
 		}
-		return Number
+		ok = asm.IsNumber(lit.Val)
 	case ast.BooleanType:
-		if _, ok := lit.Val.(bool); !ok {
-			return Error
-		}
-		return Boolean
+		_, ok = lit.Val.(bool)
 	case ast.SymbolType:
-		if _, ok := lit.Val.(string); !ok {
-			return Error
+		// only declared variables have a type known before runtime
+		var name string
+		name, ok = lit.Val.(string)
+		if declared, isDeclared := ctx.variableType(ast.Symbol(name)); ok && isDeclared {
+			t = declared
 		}
-		// we don't know the values of variables until runtime
-		return Variant
+	default:
+		ctx.errorf(lit.Pos, "unknown literal type %s", lit.Type)
+		return Error
 	}
-	return Error
+	if !ok {
+		ctx.errorf(lit.Pos, "malformed %s literal", lit.Type)
+		return Error
+	}
+	return t
 }
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/mcvoid/dialogue/internal/types/ast"
+	"github.com/mcvoid/dialogue/internal/types/diagnostic"
+	"github.com/mcvoid/dialogue/internal/types/lexeme"
 )
 
 type bogusAstNode struct{}
@@ -452,15 +454,44 @@ func TestTypeChecking(t *testing.T) {
 		"link": {
 			input: []ast.Node{{Name: "abc", Body: []ast.BlockElement{ast.Link{
 				Dest: "abc",
-				Text: ast.Text("def"),
+				Text: ast.Paragraph{ast.Text("def")},
 			}}}},
 			expected: Void,
 		},
+		"link with bad inline code": {
+			input: []ast.Node{{Name: "abc", Body: []ast.BlockElement{ast.Link{
+				Dest: "abc",
+				Text: ast.Paragraph{ast.Text("def"), ast.InlineCode{Expr: bogusAstNode{}}},
+			}}}},
+			expected: Error,
+		},
+		"option with bad inline code": {
+			input: []ast.Node{{Name: "abc", Body: []ast.BlockElement{ast.Option{
+				ast.Link{Dest: "abc", Text: ast.Paragraph{ast.InlineCode{Expr: ast.UnaryOp{
+					Operator: ast.NotOp,
+					Arg:      ast.Literal{Type: ast.NumberType, Val: 1},
+				}}}},
+			}}}},
+			expected: Error,
+		},
+		"option with guard": {
+			input: []ast.Node{{Name: "abc", Body: []ast.BlockElement{ast.Option{
+				ast.Link{Dest: "abc", Text: ast.Paragraph{ast.Text("def")}, Guard: ast.Literal{Type: ast.BooleanType, Val: true}},
+				ast.Link{Dest: "abc", Text: ast.Paragraph{ast.Text("def")}, Guard: ast.Literal{Type: ast.SymbolType, Val: "ghi"}},
+			}}}},
+			expected: Void,
+		},
+		"option with bad guard": {
+			input: []ast.Node{{Name: "abc", Body: []ast.BlockElement{ast.Option{
+				ast.Link{Dest: "abc", Text: ast.Paragraph{ast.Text("def")}, Guard: ast.Literal{Type: ast.StringType, Val: "ghi"}},
+			}}}},
+			expected: Error,
+		},
 		"option": {
 			input: []ast.Node{{Name: "abc", Body: []ast.BlockElement{ast.Option{
-				ast.Link{Dest: "abc", Text: ast.Text("def")},
-				ast.Link{Dest: "def", Text: ast.Text("ghi")},
-				ast.Link{Dest: "ghi", Text: ast.Text("jkl")},
+				ast.Link{Dest: "abc", Text: ast.Paragraph{ast.Text("def")}},
+				ast.Link{Dest: "def", Text: ast.Paragraph{ast.Text("ghi")}},
+				ast.Link{Dest: "ghi", Text: ast.Paragraph{ast.Text("jkl")}},
 			}}}},
 			expected: Void,
 		},
@@ -628,7 +659,7 @@ func TestTypeChecking(t *testing.T) {
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
-			actual := TypeCheckScript(ast.Script{
+			actual, diags := TypeCheckScript(ast.Script{
 				Functions: map[string][]ast.Type{
 					"abc": {ast.BooleanType},
 				},
@@ -637,6 +668,427 @@ func TestTypeChecking(t *testing.T) {
 			if test.expected != actual {
 				t.Errorf("expected %v got %v", test.expected, actual)
 			}
+			if (actual == Error) != (len(diags) > 0) {
+				t.Errorf("expected diagnostics to explain an error, got %v", diags)
+			}
+		})
+	}
+}
+
+func TestTypeCheckMessages(t *testing.T) {
+	at := func(line, col int) lexeme.Position { return lexeme.Position{Line: line, Column: col} }
+	for name, test := range map[string]struct {
+		variables []ast.VariableDecl
+		input     []ast.Statement
+		expected  diagnostic.Diagnostics
+	}{
+		"argument type": {
+			input: []ast.Statement{ast.FunctionCall{
+				Name: "func1",
+				Params: []ast.Expression{
+					ast.Literal{Type: ast.NumberType, Val: 1, Pos: at(2, 7)},
+					ast.Literal{Type: ast.StringType, Val: "a", Pos: at(2, 10)},
+				},
+				Pos: at(2, 1),
+			}},
+			expected: diagnostic.Diagnostics{
+				{Message: "argument 2 of func1 expects number, got string", Span: diagnostic.At(at(2, 10))},
+			},
+		},
+		"argument count": {
+			input: []ast.Statement{ast.FunctionCall{Name: "func1", Pos: at(3, 1)}},
+			expected: diagnostic.Diagnostics{
+				{Message: "func1 takes 2 arguments, got 0", Span: diagnostic.At(at(3, 1))},
+			},
+		},
+		"undeclared function": {
+			input: []ast.Statement{ast.FunctionCall{Name: "func2", Pos: at(3, 1)}},
+			expected: diagnostic.Diagnostics{
+				{Message: "function func2 is not declared", Span: diagnostic.At(at(3, 1))},
+			},
+		},
+		"call result": {
+			input: []ast.Statement{ast.Loop{
+				Cond: ast.FunctionCall{
+					Name:   "name",
+					Params: []ast.Expression{},
+					Pos:    at(4, 7),
+				},
+				Consequent: ast.StatementBlock{},
+				Pos:        at(4, 1),
+			}},
+			expected: diagnostic.Diagnostics{
+				{Message: "while condition is string", Span: diagnostic.At(at(4, 1))},
+			},
+		},
+		"call without result": {
+			input: []ast.Statement{ast.Assignment{
+				Name: "x",
+				Val: ast.FunctionCall{
+					Name: "func1",
+					Params: []ast.Expression{
+						ast.Literal{Type: ast.NumberType, Val: 1},
+						ast.Literal{Type: ast.NumberType, Val: 2},
+					},
+					Pos: at(4, 5),
+				},
+			}},
+			expected: diagnostic.Diagnostics{
+				{Message: "func1 does not return a value", Span: diagnostic.At(at(4, 5))},
+			},
+		},
+		"call result argument": {
+			input: []ast.Statement{ast.FunctionCall{
+				Name: "func1",
+				Params: []ast.Expression{
+					ast.Literal{Type: ast.NumberType, Val: 1},
+					ast.FunctionCall{Name: "name", Params: []ast.Expression{}, Pos: at(4, 10)},
+				},
+				Pos: at(4, 1),
+			}},
+			expected: diagnostic.Diagnostics{
+				{Message: "argument 2 of func1 expects number, got string", Span: diagnostic.At(at(4, 10))},
+			},
+		},
+		"statement call discards result": {
+			input:    []ast.Statement{ast.FunctionCall{Name: "name", Params: []ast.Expression{}}},
+			expected: diagnostic.Diagnostics{},
+		},
+		"builtin call": {
+			input: []ast.Statement{ast.Assignment{Name: "x", Val: ast.BinaryOp{
+				Operator: ast.AddOp,
+				LeftArg: ast.FunctionCall{
+					Name:   "length",
+					Params: []ast.Expression{ast.Literal{Type: ast.StringType, Val: "abc"}},
+				},
+				RightArg: ast.FunctionCall{
+					Name: "clamp",
+					Params: []ast.Expression{
+						ast.Literal{Type: ast.NumberType, Val: 1},
+						ast.Literal{Type: ast.NumberType, Val: 2},
+						ast.Literal{Type: ast.NumberType, Val: 3},
+					},
+				},
+			}}},
+			expected: diagnostic.Diagnostics{},
+		},
+		"builtin argument type": {
+			input: []ast.Statement{ast.FunctionCall{
+				Name:   "upper",
+				Params: []ast.Expression{ast.Literal{Type: ast.NumberType, Val: 1, Pos: at(4, 7)}},
+				Pos:    at(4, 1),
+			}},
+			expected: diagnostic.Diagnostics{
+				{Message: "argument 1 of upper expects string, got number", Span: diagnostic.At(at(4, 7))},
+			},
+		},
+		"builtin result type": {
+			input: []ast.Statement{ast.Conditional{
+				Cond: ast.FunctionCall{
+					Name:   "to_string",
+					Params: []ast.Expression{ast.Literal{Type: ast.NumberType, Val: 1}},
+				},
+				Consequent: ast.StatementBlock{},
+				Alternate:  ast.StatementBlock{},
+				Pos:        at(4, 1),
+			}},
+			expected: diagnostic.Diagnostics{
+				{Message: "if condition is string", Span: diagnostic.At(at(4, 1))},
+			},
+		},
+		"extern shadows builtin": {
+			input: []ast.Statement{ast.Assignment{Name: "x", Val: ast.FunctionCall{
+				Name:   "abs",
+				Params: []ast.Expression{},
+				Pos:    at(4, 5),
+			}}},
+			expected: diagnostic.Diagnostics{
+				{Message: "abs does not return a value", Span: diagnostic.At(at(4, 5))},
+			},
+		},
+		"while condition": {
+			input: []ast.Statement{ast.Loop{
+				Cond:       ast.Literal{Type: ast.StringType, Val: "a"},
+				Consequent: ast.StatementBlock{},
+				Pos:        at(4, 1),
+			}},
+			expected: diagnostic.Diagnostics{
+				{Message: "while condition is string", Span: diagnostic.At(at(4, 1))},
+			},
+		},
+		"if condition": {
+			input: []ast.Statement{ast.Conditional{
+				Cond:       ast.Literal{Type: ast.NumberType, Val: 1},
+				Consequent: ast.StatementBlock{},
+				Alternate:  ast.StatementBlock{},
+				Pos:        at(5, 1),
+			}},
+			expected: diagnostic.Diagnostics{
+				{Message: "if condition is number", Span: diagnostic.At(at(5, 1))},
+			},
+		},
+		"no cascade": {
+			input: []ast.Statement{ast.Assignment{Name: "x", Val: ast.UnaryOp{
+				Operator: ast.NegOp,
+				Arg: ast.BinaryOp{
+					Operator: ast.AddOp,
+					LeftArg:  ast.Literal{Type: ast.BooleanType, Val: true},
+					RightArg: ast.Literal{Type: ast.NumberType, Val: 1},
+					Pos:      at(6, 8),
+				},
+				Pos: at(6, 5),
+			}}},
+			expected: diagnostic.Diagnostics{
+				{Message: "left operand of + expects number, got bool", Span: diagnostic.At(at(6, 8))},
+			},
+		},
+		"every error": {
+			input: []ast.Statement{
+				ast.Assignment{Name: "x", Val: ast.UnaryOp{
+					Operator: ast.NotOp,
+					Arg:      ast.Literal{Type: ast.NumberType, Val: 1},
+					Pos:      at(7, 5),
+				}},
+				ast.Assignment{Name: "y", Val: ast.BinaryOp{
+					Operator: ast.OrOp,
+					LeftArg:  ast.Literal{Type: ast.BooleanType, Val: true},
+					RightArg: ast.Literal{Type: ast.NullType},
+					Pos:      at(8, 10),
+				}},
+			},
+			expected: diagnostic.Diagnostics{
+				{Message: "operand of ! expects bool, got number", Span: diagnostic.At(at(7, 5))},
+				{Message: "right operand of || expects bool, got null", Span: diagnostic.At(at(8, 10))},
+			},
+		},
+		"declared variable assignment": {
+			input: []ast.Statement{ast.Assignment{Name: "gold", Val: ast.Literal{Type: ast.StringType, Val: "a"}, Pos: at(9, 1)}},
+			expected: diagnostic.Diagnostics{
+				{Message: "gold is number, cannot assign string", Span: diagnostic.At(at(9, 1))},
+			},
+		},
+		"extern variable type": {
+			input: []ast.Statement{ast.Conditional{
+				Cond:       ast.Literal{Type: ast.SymbolType, Val: "player"},
+				Consequent: ast.StatementBlock{},
+				Alternate:  ast.StatementBlock{},
+				Pos:        at(9, 1),
+			}},
+			expected: diagnostic.Diagnostics{
+				{Message: "if condition is string", Span: diagnostic.At(at(9, 1))},
+			},
+		},
+		"undeclared variables are variant": {
+			input: []ast.Statement{
+				ast.Assignment{Name: "x", Val: ast.Literal{Type: ast.NumberType, Val: 1}},
+				ast.Assignment{Name: "x", Val: ast.Literal{Type: ast.StringType, Val: "a"}},
+				ast.Assignment{Name: "y", Val: ast.UnaryOp{Operator: ast.NotOp, Arg: ast.Literal{Type: ast.SymbolType, Val: "x"}}},
+			},
+			expected: diagnostic.Diagnostics{},
+		},
+		"global initializer": {
+			variables: []ast.VariableDecl{
+				{Name: "met", Type: ast.BooleanType, Scope: ast.GlobalScope, Val: ast.Literal{Type: ast.NumberType, Val: 1}, Pos: at(1, 5)},
+			},
+			expected: diagnostic.Diagnostics{
+				{Message: "met is declared bool, got number", Span: diagnostic.At(at(1, 5))},
+			},
+		},
+		"global redeclared": {
+			variables: []ast.VariableDecl{
+				{Name: "gold", Scope: ast.GlobalScope, Val: ast.Literal{Type: ast.NumberType, Val: 1}, Pos: at(3, 5)},
+			},
+			expected: diagnostic.Diagnostics{
+				{Message: "variable gold is already declared", Span: diagnostic.At(at(3, 5))},
+			},
+		},
+		"local declaration": {
+			input: []ast.Statement{ast.VariableDecl{
+				Name:  "count",
+				Type:  ast.NumberType,
+				Scope: ast.LocalScope,
+				Val:   ast.Literal{Type: ast.StringType, Val: "a"},
+				Pos:   at(9, 7),
+			}},
+			expected: diagnostic.Diagnostics{
+				{Message: "count is declared number, got string", Span: diagnostic.At(at(9, 7))},
+			},
+		},
+		"local takes its initial value's type": {
+			input: []ast.Statement{
+				ast.VariableDecl{Name: "flag", Scope: ast.LocalScope, Val: ast.Literal{Type: ast.BooleanType, Val: true}},
+				ast.Assignment{Name: "x", Val: ast.UnaryOp{
+					Operator: ast.NegOp,
+					Arg:      ast.Literal{Type: ast.SymbolType, Val: "flag"},
+					Pos:      at(10, 5),
+				}},
+			},
+			expected: diagnostic.Diagnostics{
+				{Message: "operand of - expects number, got bool", Span: diagnostic.At(at(10, 5))},
+			},
+		},
+		"local redeclared": {
+			input: []ast.Statement{
+				ast.VariableDecl{Name: "x", Scope: ast.LocalScope, Val: ast.Literal{Type: ast.NumberType, Val: 1}},
+				ast.VariableDecl{Name: "x", Scope: ast.LocalScope, Val: ast.Literal{Type: ast.NumberType, Val: 1}, Pos: at(11, 7)},
+			},
+			expected: diagnostic.Diagnostics{
+				{Message: "variable x is already declared", Span: diagnostic.At(at(11, 7))},
+			},
+		},
+		"list index": {
+			input: []ast.Statement{
+				ast.VariableDecl{Name: "items", Scope: ast.LocalScope, Val: ast.ListLiteral{Items: []ast.Expression{
+					ast.Literal{Type: ast.NumberType, Val: 1},
+				}}},
+				ast.Assignment{Name: "x", Val: ast.Index{
+					Container: ast.Literal{Type: ast.SymbolType, Val: "items"},
+					Key:       ast.Literal{Type: ast.StringType, Val: "a"},
+					Pos:       at(12, 10),
+				}},
+			},
+			expected: diagnostic.Diagnostics{
+				{Message: "list index is string", Span: diagnostic.At(at(12, 10))},
+			},
+		},
+		"map key": {
+			input: []ast.Statement{
+				ast.Assignment{Name: "x", Val: ast.MapLiteral{
+					Entries: []ast.MapEntry{{
+						Key: ast.Literal{Type: ast.NumberType, Val: 1, Pos: at(13, 6)},
+						Val: ast.Literal{Type: ast.NumberType, Val: 1},
+					}},
+					Pos: at(13, 5),
+				}},
+			},
+			expected: diagnostic.Diagnostics{
+				{Message: "map key is number", Span: diagnostic.At(at(13, 6))},
+			},
+		},
+		"index a number": {
+			input: []ast.Statement{
+				ast.IndexAssignment{
+					Name: "gold",
+					Key:  ast.Literal{Type: ast.NumberType, Val: 0},
+					Val:  ast.Literal{Type: ast.NumberType, Val: 1},
+					Pos:  at(14, 1),
+				},
+			},
+			expected: diagnostic.Diagnostics{
+				{Message: "cannot index number", Span: diagnostic.At(at(14, 1))},
+			},
+		},
+		"index an undeclared variable": {
+			input: []ast.Statement{
+				ast.Assignment{Name: "bag", Val: ast.ListLiteral{}},
+				ast.IndexAssignment{
+					Name: "bag",
+					Key:  ast.Literal{Type: ast.NumberType, Val: 0},
+					Val:  ast.Literal{Type: ast.NumberType, Val: 1},
+					Pos:  at(14, 1),
+				},
+			},
+			expected: diagnostic.Diagnostics{},
+		},
+		"items are variant": {
+			input: []ast.Statement{
+				ast.VariableDecl{Name: "prices", Scope: ast.LocalScope, Val: ast.MapLiteral{}},
+				ast.IndexAssignment{
+					Name: "prices",
+					Key:  ast.Literal{Type: ast.StringType, Val: "sword"},
+					Val:  ast.Literal{Type: ast.NumberType, Val: 10},
+				},
+				ast.Assignment{Name: "gold", Val: ast.Index{
+					Container: ast.Literal{Type: ast.SymbolType, Val: "prices"},
+					Key:       ast.Literal{Type: ast.StringType, Val: "sword"},
+				}},
+			},
+			expected: diagnostic.Diagnostics{},
+		},
+		"loop over a number": {
+			input: []ast.Statement{
+				ast.ForLoop{
+					Name:       "item",
+					Iterable:   ast.Literal{Type: ast.SymbolType, Val: "gold"},
+					Consequent: ast.StatementBlock{},
+					Pos:        at(15, 1),
+				},
+			},
+			expected: diagnostic.Diagnostics{
+				{Message: "cannot loop over number", Span: diagnostic.At(at(15, 1))},
+			},
+		},
+		"loop variable reused": {
+			input: []ast.Statement{
+				ast.ForLoop{Name: "item", Iterable: ast.ListLiteral{}, Consequent: ast.StatementBlock{}},
+				ast.ForLoop{Name: "item", Iterable: ast.MapLiteral{}, Consequent: ast.StatementBlock{
+					ast.Assignment{Name: "gold", Val: ast.Literal{Type: ast.SymbolType, Val: "item"}},
+				}},
+			},
+			expected: diagnostic.Diagnostics{},
+		},
+		"loop variable out of scope after the loop": {
+			input: []ast.Statement{
+				ast.ForLoop{Name: "gold", Iterable: ast.ListLiteral{}, Consequent: ast.StatementBlock{
+					ast.Assignment{Name: "gold", Val: ast.Literal{Type: ast.StringType, Val: "a"}},
+				}},
+				ast.Assignment{Name: "gold", Val: ast.Literal{Type: ast.StringType, Val: "b"}, Pos: at(17, 1)},
+			},
+			expected: diagnostic.Diagnostics{
+				{Message: "gold is number, cannot assign string", Span: diagnostic.At(at(17, 1))},
+			},
+		},
+		"loop variable is a typed local": {
+			input: []ast.Statement{
+				ast.VariableDecl{Name: "item", Scope: ast.LocalScope, Val: ast.Literal{Type: ast.NumberType, Val: 1}},
+				ast.ForLoop{Name: "item", Iterable: ast.ListLiteral{}, Consequent: ast.StatementBlock{}, Pos: at(16, 1)},
+			},
+			expected: diagnostic.Diagnostics{
+				{Message: "variable item is already declared", Span: diagnostic.At(at(16, 1))},
+			},
+		},
+		"number out of range": {
+			input: []ast.Statement{
+				ast.Assignment{Name: "gold", Val: ast.Literal{Type: ast.NumberType, Val: "1e999", Pos: at(18, 8)}},
+			},
+			expected: diagnostic.Diagnostics{
+				{Message: "number 1e999 is out of range", Span: diagnostic.At(at(18, 8))},
+			},
+		},
+		"local shadows global": {
+			input: []ast.Statement{
+				ast.VariableDecl{Name: "gold", Scope: ast.LocalScope, Val: ast.Literal{Type: ast.StringType, Val: "a"}},
+				ast.Assignment{Name: "gold", Val: ast.Literal{Type: ast.StringType, Val: "b"}},
+			},
+			expected: diagnostic.Diagnostics{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, actual := TypeCheckScript(ast.Script{
+				Variables: append([]ast.VariableDecl{
+					{Name: "gold", Type: ast.NumberType, Scope: ast.GlobalScope, Val: ast.Literal{Type: ast.NumberType, Val: 0}},
+					{Name: "player", Type: ast.StringType, Scope: ast.ExternScope},
+				}, test.variables...),
+				Functions: map[string][]ast.Type{
+					"func1": {ast.NumberType, ast.NumberType},
+					"name":  {},
+					"abs":   {},
+				},
+				Returns: map[string]ast.Type{
+					"name": ast.StringType,
+				},
+				Nodes: []ast.Node{{Name: "abc", Body: []ast.BlockElement{ast.CodeBlock{Code: test.input}}}},
+			})
+			if len(actual) != len(test.expected) {
+				t.Fatalf("expected %v got %v", test.expected, actual)
+			}
+			for i := range actual {
+				if actual[i].Code != diagnostic.TypeError ||
+					actual[i].Message != test.expected[i].Message ||
+					actual[i].Span != test.expected[i].Span {
+					t.Errorf("expected %v got %v", test.expected[i], actual[i])
+				}
+			}
 		})
 	}
 }
@@ -0,0 +1,23 @@
+package asm
+
+// Builtin is the signature of a function the VM provides itself.
+// Builtins can be called without an extern declaration.
+type Builtin struct {
+	Params  []Type
+	Returns Type
+}
+
+// Builtins is the standard library of functions every VM implements.
+var Builtins = map[string]Builtin{
+	"length":    {[]Type{StringType}, NumberType},
+	"upper":     {[]Type{StringType}, StringType},
+	"lower":     {[]Type{StringType}, StringType},
+	"min":       {[]Type{NumberType, NumberType}, NumberType},
+	"max":       {[]Type{NumberType, NumberType}, NumberType},
+	"abs":       {[]Type{NumberType}, NumberType},
+	"clamp":     {[]Type{NumberType, NumberType, NumberType}, NumberType},
+	"random":    {[]Type{NumberType, NumberType}, NumberType},
+	"to_string": {[]Type{NumberType}, StringType},
+	"count":     {[]Type{ListType}, NumberType},
+	"keys":      {[]Type{MapType}, ListType},
+}
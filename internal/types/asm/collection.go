@@ -0,0 +1,204 @@
+package asm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lists and maps are values like numbers and strings. Assigning by index
+// makes a new list or map rather than changing the old one, so a change
+// made through one variable is never seen through another.
+type (
+	// List is the value of a ListType.
+	List struct {
+		Items []Value
+	}
+	// Map is the value of a MapType. Its keys are strings.
+	Map struct {
+		Entries map[string]Value
+	}
+)
+
+// ErrIndexOutOfRange is a list index past either end of the list.
+var ErrIndexOutOfRange = errors.New("index out of range")
+
+// NewList makes a list value holding items.
+func NewList(items []Value) Value {
+	return Value{Type: ListType, Val: &List{Items: items}}
+}
+
+// NewMap makes a map value holding entries.
+func NewMap(entries map[string]Value) Value {
+	return Value{Type: MapType, Val: &Map{Entries: entries}}
+}
+
+// listIndex checks that key can index a list of n items.
+func listIndex(key Value, n int) (int, error) {
+	i, ok := Whole(key.Val)
+	if key.Type != NumberType || !ok {
+		return 0, fmt.Errorf("list index %s is not a whole number", Format(key.Val))
+	}
+	if i < 0 || i >= n {
+		return 0, fmt.Errorf("list index %d with length %d: %w", i, n, ErrIndexOutOfRange)
+	}
+	return i, nil
+}
+
+// mapKey checks that key can index a map.
+func mapKey(key Value) (string, error) {
+	k, ok := key.Val.(string)
+	if key.Type != StringType || !ok {
+		return "", fmt.Errorf("map key %s is not a string", Format(key.Val))
+	}
+	return k, nil
+}
+
+// GetIndex gives the item of a list or the entry of a map at key. A map
+// gives null for a key it doesn't have.
+func GetIndex(container, key Value) (Value, error) {
+	switch c := container.Val.(type) {
+	case *List:
+		i, err := listIndex(key, len(c.Items))
+		if err != nil {
+			return Null, err
+		}
+		return c.Items[i], nil
+	case *Map:
+		k, err := mapKey(key)
+		if err != nil {
+			return Null, err
+		}
+		if val, ok := c.Entries[k]; ok {
+			return val, nil
+		}
+		return Null, nil
+	}
+	return Null, fmt.Errorf("cannot index %v", container.Type)
+}
+
+// PutIndex gives a copy of a list or map with the item or entry at key
+// set to val. Setting the index just past the end of a list appends to
+// it, and setting a map's entry to null removes it.
+func PutIndex(container, key, val Value) (Value, error) {
+	switch c := container.Val.(type) {
+	case *List:
+		items := append([]Value{}, c.Items...)
+		if i, ok := Whole(key.Val); ok && key.Type == NumberType && i == len(items) {
+			return NewList(append(items, val)), nil
+		}
+		i, err := listIndex(key, len(items))
+		if err != nil {
+			return Null, err
+		}
+		items[i] = val
+		return NewList(items), nil
+	case *Map:
+		k, err := mapKey(key)
+		if err != nil {
+			return Null, err
+		}
+		entries := map[string]Value{}
+		for name, entry := range c.Entries {
+			entries[name] = entry
+		}
+		if val.Type == NullType {
+			delete(entries, k)
+		} else {
+			entries[k] = val
+		}
+		return NewMap(entries), nil
+	}
+	return Null, fmt.Errorf("cannot index %v", container.Type)
+}
+
+// Keys gives a map's keys in order.
+func (m *Map) Keys() []string {
+	keys := []string{}
+	for k := range m.Entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// LoopItems gives the list a for loop goes through: a list's own items, or
+// a map's keys in order.
+func LoopItems(container Value) (Value, error) {
+	switch c := container.Val.(type) {
+	case *List:
+		return container, nil
+	case *Map:
+		keys := []Value{}
+		for _, k := range c.Keys() {
+			keys = append(keys, Value{Type: StringType, Val: k})
+		}
+		return NewList(keys), nil
+	}
+	return Null, fmt.Errorf("cannot iterate over %v", container.Type)
+}
+
+// Size gives the number of items in a list or entries in a map.
+func Size(container Value) (int, error) {
+	switch c := container.Val.(type) {
+	case *List:
+		return len(c.Items), nil
+	case *Map:
+		return len(c.Entries), nil
+	}
+	return 0, fmt.Errorf("%v has no length", container.Type)
+}
+
+// listsEqual compares lists item by item.
+func listsEqual(a, b *List) bool {
+	if len(a.Items) != len(b.Items) {
+		return false
+	}
+	for i := range a.Items {
+		if !ValuesEqual(a.Items[i], b.Items[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// mapsEqual compares maps entry by entry.
+func mapsEqual(a, b *Map) bool {
+	if len(a.Entries) != len(b.Entries) {
+		return false
+	}
+	for k, val := range a.Entries {
+		other, ok := b.Entries[k]
+		if !ok || !ValuesEqual(val, other) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatItem is how a value inside a list or map is shown. Strings are
+// quoted so that they can be told apart from the punctuation.
+func formatItem(val Value) string {
+	if s, ok := val.Val.(string); ok && val.Type == StringType {
+		return strconv.Quote(s)
+	}
+	return Format(val.Val)
+}
+
+func formatList(l *List) string {
+	items := []string{}
+	for _, item := range l.Items {
+		items = append(items, formatItem(item))
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+func formatMap(m *Map) string {
+	entries := []string{}
+	for _, k := range m.Keys() {
+		entries = append(entries, strconv.Quote(k)+": "+formatItem(m.Entries[k]))
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
@@ -0,0 +1,143 @@
+package asm
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestGetIndex(t *testing.T) {
+	list := NewList([]Value{{StringType, "a"}, {StringType, "b"}})
+	m := NewMap(map[string]Value{"gold": {NumberType, 3}})
+	for name, test := range map[string]struct {
+		container, key Value
+		expected       Value
+		errExpected    bool
+	}{
+		"list item":         {list, Value{NumberType, 1}, Value{StringType, "b"}, false},
+		"past end":          {list, Value{NumberType, 2}, Null, true},
+		"negative":          {list, Value{NumberType, -1}, Null, true},
+		"fractional index":  {list, Value{NumberType, 0.5}, Null, true},
+		"whole fraction":    {list, Value{NumberType, 1.0}, Value{StringType, "b"}, false},
+		"string index":      {list, Value{StringType, "0"}, Null, true},
+		"map entry":         {m, Value{StringType, "gold"}, Value{NumberType, 3}, false},
+		"missing map entry": {m, Value{StringType, "silver"}, Null, false},
+		"number key":        {m, Value{NumberType, 0}, Null, true},
+		"not a container":   {Value{StringType, "abc"}, Value{NumberType, 0}, Null, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			actual, err := GetIndex(test.container, test.key)
+			if test.errExpected != (err != nil) {
+				t.Fatalf("unexpected err result: %v", err)
+			}
+			if !ValuesEqual(actual, test.expected) {
+				t.Errorf("expected %v got %v", test.expected, actual)
+			}
+		})
+	}
+
+	if _, err := GetIndex(list, Value{NumberType, 5}); !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("expected an index out of range, got %v", err)
+	}
+}
+
+func TestPutIndex(t *testing.T) {
+	list := NewList([]Value{{StringType, "a"}})
+	m := NewMap(map[string]Value{"gold": {NumberType, 3}})
+	for name, test := range map[string]struct {
+		container, key, val Value
+		expected            Value
+		errExpected         bool
+	}{
+		"replace item": {list, Value{NumberType, 0}, Value{StringType, "b"}, NewList([]Value{{StringType, "b"}}), false},
+		"append item":  {list, Value{NumberType, 1}, Value{StringType, "b"}, NewList([]Value{{StringType, "a"}, {StringType, "b"}}), false},
+		"whole append": {list, Value{NumberType, 1.0}, Value{StringType, "b"}, NewList([]Value{{StringType, "a"}, {StringType, "b"}}), false},
+		"past end":     {list, Value{NumberType, 2}, Value{StringType, "b"}, Null, true},
+		"add entry":    {m, Value{StringType, "silver"}, Value{NumberType, 1}, NewMap(map[string]Value{"gold": {NumberType, 3}, "silver": {NumberType, 1}}), false},
+		"remove entry": {m, Value{StringType, "gold"}, Null, NewMap(map[string]Value{}), false},
+		"number key":   {m, Value{NumberType, 0}, Null, Null, true},
+		"not a list":   {True, Value{NumberType, 0}, Null, Null, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			before := Format(test.container.Val)
+			actual, err := PutIndex(test.container, test.key, test.val)
+			if test.errExpected != (err != nil) {
+				t.Fatalf("unexpected err result: %v", err)
+			}
+			if !ValuesEqual(actual, test.expected) {
+				t.Errorf("expected %v got %v", test.expected, actual)
+			}
+			if after := Format(test.container.Val); after != before {
+				t.Errorf("expected the original to be unchanged, got %s", after)
+			}
+		})
+	}
+}
+
+func TestLoopItems(t *testing.T) {
+	list := NewList([]Value{{NumberType, 2}, {NumberType, 1}})
+	if items, err := LoopItems(list); err != nil || !ValuesEqual(items, list) {
+		t.Errorf("expected a list to loop over its items, got %v %v", items, err)
+	}
+	m := NewMap(map[string]Value{"b": True, "a": False})
+	keys := NewList([]Value{{StringType, "a"}, {StringType, "b"}})
+	if items, err := LoopItems(m); err != nil || !ValuesEqual(items, keys) {
+		t.Errorf("expected a map to loop over its keys in order, got %v %v", items, err)
+	}
+	if _, err := LoopItems(Value{NumberType, 1}); err == nil {
+		t.Errorf("expected an error looping over a number")
+	}
+
+	if n, err := Size(list); err != nil || n != 2 {
+		t.Errorf("expected size 2 got %v %v", n, err)
+	}
+	if n, err := Size(m); err != nil || n != 2 {
+		t.Errorf("expected size 2 got %v %v", n, err)
+	}
+	if _, err := Size(Null); err == nil {
+		t.Errorf("expected an error for the size of null")
+	}
+}
+
+func TestCollectionJSON(t *testing.T) {
+	for name, test := range map[string]struct {
+		val  Value
+		json string
+	}{
+		"list":        {NewList([]Value{{NumberType, 1}, {StringType, "a"}}), `["list",[["number",1],["string","a"]]]`},
+		"empty list":  {NewList([]Value{}), `["list",[]]`},
+		"map":         {NewMap(map[string]Value{"a": {NumberType, 2.5}}), `["map",{"a":["number",2.5]}]`},
+		"nested list": {NewList([]Value{NewMap(map[string]Value{"a": Null})}), `["list",[["map",{"a":["null",null]}]]]`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			b, err := json.Marshal(test.val)
+			if err != nil {
+				t.Fatalf("no error expected got %v", err)
+			}
+			if string(b) != test.json {
+				t.Errorf("expected %s got %s", test.json, b)
+			}
+			var read Value
+			if err := json.Unmarshal(b, &read); err != nil {
+				t.Fatalf("no error expected got %v", err)
+			}
+			if !ValuesEqual(read, test.val) {
+				t.Errorf("expected %v got %v", test.val, read)
+			}
+		})
+	}
+
+	for name, input := range map[string]string{
+		"list of object": `["list",{}]`,
+		"map of array":   `["map",[]]`,
+		"bad item":       `["list",[["number","1"]]]`,
+		"bad entry":      `["map",{"a":["bogus",1]}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var read Value
+			if err := json.Unmarshal([]byte(input), &read); err == nil {
+				t.Errorf("expected error decoding %s", input)
+			}
+		})
+	}
+}
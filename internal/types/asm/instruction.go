@@ -1,8 +1,12 @@
 package asm
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 )
 
 type (
@@ -44,6 +48,9 @@ const (
 	Decrement          Opcode = "Decrement"
 	LoadVariable       Opcode = "LoadVariable"
 	StoreVariable      Opcode = "StoreVariable"
+	InitVariable       Opcode = "InitVariable"
+	LoadLocal          Opcode = "LoadLocal"
+	StoreLocal         Opcode = "StoreLocal"
 	ShowLine           Opcode = "ShowLine"
 	Jump               Opcode = "Jump"
 	JumpIfFalse        Opcode = "JumpIfFalse"
@@ -53,6 +60,12 @@ const (
 	ExitNode           Opcode = "ExitNode"
 	EndDialogue        Opcode = "EndDialogue"
 	Call               Opcode = "Call"
+	MakeList           Opcode = "MakeList"
+	MakeMap            Opcode = "MakeMap"
+	Index              Opcode = "Index"
+	SetIndex           Opcode = "SetIndex"
+	Iterate            Opcode = "Iterate"
+	Length             Opcode = "Length"
 )
 
 const (
@@ -61,6 +74,8 @@ const (
 	StringType  Type = "string"
 	NullType    Type = "null"
 	SymbolType  Type = "symbol"
+	ListType    Type = "list"
+	MapType     Type = "map"
 )
 
 var (
@@ -69,54 +84,59 @@ var (
 	False = Value{BooleanType, false}
 )
 
-var (
-	unaryOpcodes = map[Opcode]bool{
-		PushString:    true,
-		PushNumber:    true,
-		PushBool:      true,
-		Jump:          true,
-		JumpIfFalse:   true,
-		LoadVariable:  true,
-		StoreVariable: true,
-		PushChoice:    true,
-		EnterNode:     true,
-		ExitNode:      true,
-		Call:          true,
+func (v Value) MarshalJSON() ([]byte, error) {
+	if f, ok := v.Val.(float64); ok && v.Type == NumberType {
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("number %v cannot be encoded", f)
+		}
+		// a fraction is always written with a point or an exponent so
+		// that it isn't read back as a whole number
+		num := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(num, ".eE") {
+			num += ".0"
+		}
+		return json.Marshal([]interface{}{v.Type, json.RawMessage(num)})
 	}
-	nullaryOpcodes = map[Opcode]bool{
-		PushNull:    true,
-		PopValue:    true,
-		Concat:      true,
-		And:         true,
-		Or:          true,
-		Not:         true,
-		Equal:       true,
-		NotEqual:    true,
-		GreaterThan: true,
-		Lessthan:    true,
-		Negative:    true,
-		Add:         true,
-		Subtract:    true,
-		Multiply:    true,
-		Divide:      true,
-		Modulo:      true,
-		Increment:   true,
-		Decrement:   true,
-		ShowLine:    true,
-		ShowChoice:  true,
-		EndDialogue: true,
+	switch val := v.Val.(type) {
+	case *List:
+		return json.Marshal([]interface{}{v.Type, val.Items})
+	case *Map:
+		return json.Marshal([]interface{}{v.Type, val.Entries})
 	}
-)
-
-func (v Value) MarshalJSON() ([]byte, error) {
 	return json.Marshal([]interface{}{v.Type, v.Val})
 }
 
 func (v *Value) UnmarshalJSON(b []byte) error {
-	template := []interface{}{&v.Type, &v.Val}
+	var raw json.RawMessage
+	template := []interface{}{&v.Type, &raw}
 	if err := json.Unmarshal(b, &template); err != nil {
 		return err
 	}
+	// a list's items and a map's entries are values themselves
+	switch v.Type {
+	case ListType:
+		items := []Value{}
+		if err := json.Unmarshal(raw, &items); err != nil || items == nil {
+			return fmt.Errorf("type list must be encoded in JSON array value")
+		}
+		*v = NewList(items)
+		return nil
+	case MapType:
+		entries := map[string]Value{}
+		if err := json.Unmarshal(raw, &entries); err != nil || entries == nil {
+			return fmt.Errorf("type map must be encoded in JSON object value")
+		}
+		*v = NewMap(entries)
+		return nil
+	}
+	v.Val = nil
+	if len(raw) > 0 {
+		d := json.NewDecoder(bytes.NewReader(raw))
+		d.UseNumber()
+		if err := d.Decode(&v.Val); err != nil {
+			return err
+		}
+	}
 	switch v.Type {
 	case BooleanType:
 		if _, ok := v.Val.(bool); !ok {
@@ -131,11 +151,27 @@ func (v *Value) UnmarshalJSON(b []byte) error {
 			return fmt.Errorf("type symbol must be encoded in JSON string value")
 		}
 	case NumberType:
-		num, ok := v.Val.(float64)
+		num, ok := v.Val.(json.Number)
 		if !ok {
 			return fmt.Errorf("type number must be encoded in JSON number value")
 		}
-		v.Val = int(num)
+		if strings.ContainsAny(string(num), ".eE") {
+			f, err := num.Float64()
+			if err != nil {
+				return err
+			}
+			v.Val = f
+		} else {
+			n, err := strconv.Atoi(string(num))
+			if err != nil {
+				return err
+			}
+			v.Val = n
+		}
+	case NullType:
+		if v.Val != nil {
+			return fmt.Errorf("type null must be encoded in JSON null value")
+		}
 	default:
 		return fmt.Errorf("invalid type %v", v.Type)
 	}
@@ -143,13 +179,14 @@ func (v *Value) UnmarshalJSON(b []byte) error {
 }
 
 func (i Instruction) MarshalJSON() ([]byte, error) {
-	if nullaryOpcodes[i.Opcode] {
-		return json.Marshal([]interface{}{i.Opcode})
+	info, ok := Lookup(i.Opcode)
+	if !ok {
+		return nil, fmt.Errorf("invalid opcode")
 	}
-	if unaryOpcodes[i.Opcode] {
-		return json.Marshal([]interface{}{i.Opcode, i.Arg})
+	if info.Kind == NoOperand {
+		return json.Marshal([]interface{}{i.Opcode})
 	}
-	return nil, fmt.Errorf("invalid opcode")
+	return json.Marshal([]interface{}{i.Opcode, i.Arg})
 }
 
 func (i *Instruction) UnmarshalJSON(b []byte) error {
@@ -158,19 +195,20 @@ func (i *Instruction) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	if nullaryOpcodes[i.Opcode] {
-		return nil
+	info, ok := Lookup(i.Opcode)
+	if !ok {
+		return fmt.Errorf("invalid opcode")
 	}
-	if unaryOpcodes[i.Opcode] {
-		template = []interface{}{&i.Opcode, &i.Arg}
-		err := json.Unmarshal(b, &template)
-		if err != nil {
-			return err
-		}
-		if len(template) < 2 {
-			return fmt.Errorf("wrong arity")
-		}
+	if info.Kind == NoOperand {
 		return nil
 	}
-	return fmt.Errorf("invalid opcode")
+	template = []interface{}{&i.Opcode, &i.Arg}
+	err := json.Unmarshal(b, &template)
+	if err != nil {
+		return err
+	}
+	if len(template) < 2 {
+		return fmt.Errorf("wrong arity")
+	}
+	return nil
 }
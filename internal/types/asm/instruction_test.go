@@ -3,6 +3,7 @@ package asm
 import (
 	"bytes"
 	"encoding/json"
+	"math"
 	"testing"
 )
 
@@ -22,6 +23,16 @@ func TestUnmarshal(t *testing.T) {
 			expected:    Instruction{Opcode: PushNumber, Arg: Value{Type: NumberType, Val: 5}},
 			errExpected: false,
 		},
+		"unary (fractional number val)": {
+			input:       `["PushNumber", ["number", 2.5e1]]`,
+			expected:    Instruction{Opcode: PushNumber, Arg: Value{Type: NumberType, Val: 25.0}},
+			errExpected: false,
+		},
+		"unary (whole fractional number val)": {
+			input:       `["PushNumber", ["number", 2.0]]`,
+			expected:    Instruction{Opcode: PushNumber, Arg: Value{Type: NumberType, Val: 2.0}},
+			errExpected: false,
+		},
 		"unary (bool val)": {
 			input:       `["PushBool", ["boolean", true]]`,
 			expected:    Instruction{Opcode: PushBool, Arg: Value{Type: BooleanType, Val: true}},
@@ -99,6 +110,16 @@ func TestUnmarshal(t *testing.T) {
 	if err == nil {
 		t.Fatalf("unexpected err result: %v", err)
 	}
+
+	val = Value{}
+	err = val.UnmarshalJSON([]byte(`["null", null]`))
+	if err != nil || val != Null {
+		t.Errorf("expected %v got %v, %v", Null, val, err)
+	}
+	err = val.UnmarshalJSON([]byte(`["null", 5]`))
+	if err == nil {
+		t.Errorf("expected error for non-null null value")
+	}
 }
 
 func TestMarshal(t *testing.T) {
@@ -117,6 +138,21 @@ func TestMarshal(t *testing.T) {
 			expected:    `["PushNumber", ["number", 5]]`,
 			errExpected: false,
 		},
+		"fractional number": {
+			input:       Instruction{PushNumber, Value{NumberType, 0.25}},
+			expected:    `["PushNumber", ["number", 0.25]]`,
+			errExpected: false,
+		},
+		"whole fractional number": {
+			input:       Instruction{PushNumber, Value{NumberType, 2.0}},
+			expected:    `["PushNumber", ["number", 2.0]]`,
+			errExpected: false,
+		},
+		"infinite number": {
+			input:       Instruction{PushNumber, Value{NumberType, math.Inf(1)}},
+			expected:    "",
+			errExpected: true,
+		},
 		"bogus opcode": {
 			input:       Instruction{Opcode: Opcode("blah")},
 			expected:    "",
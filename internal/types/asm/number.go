@@ -0,0 +1,259 @@
+package asm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A number is held as an int while it's whole, or as a float64 once it
+// has a fraction. Arithmetic on two ints gives an int, so 7 / 2 is 3.
+// If either side is a float64 the other is promoted, and the result is
+// a float64 even if it's whole, so 7.0 / 2 is 3.5.
+
+// FractionDigits is the most decimal places a fractional number is shown
+// with. Trailing zeros are dropped, so 2.50 is shown as 2.5 and 1/3.0 as
+// 0.333333.
+const FractionDigits = 6
+
+// ErrDivideByZero is a division or modulo by zero.
+var ErrDivideByZero = errors.New("division by zero")
+
+// ErrNumberOutOfRange is a result too large to hold, as a whole number
+// or a fraction.
+var ErrNumberOutOfRange = errors.New("number out of range")
+
+// IsNumber reports whether val is an int or float64.
+func IsNumber(val interface{}) bool {
+	switch val.(type) {
+	case int, float64:
+		return true
+	}
+	return false
+}
+
+// Float gives a number as a float64.
+func Float(val interface{}) float64 {
+	switch n := val.(type) {
+	case int:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return math.NaN()
+}
+
+// Whole gives a number as an int if it has no fraction, so that 1.0
+// can be used wherever 1 can.
+func Whole(val interface{}) (int, bool) {
+	switch n := val.(type) {
+	case int:
+		return n, true
+	case float64:
+		if n == math.Trunc(n) && n >= math.MinInt && n < -math.MinInt {
+			return int(n), true
+		}
+	}
+	return 0, false
+}
+
+// Arithmetic applies Add, Subtract, Multiply, Divide or Modulo to two
+// numbers. It fails on a division or modulo by zero, and on a result
+// which overflows.
+func Arithmetic(op Opcode, a, b interface{}) (interface{}, error) {
+	x, xWhole := a.(int)
+	y, yWhole := b.(int)
+	if xWhole && yWhole {
+		switch op {
+		case Add:
+			r := x + y
+			if (x^r)&(y^r) < 0 {
+				return nil, ErrNumberOutOfRange
+			}
+			return r, nil
+		case Subtract:
+			r := x - y
+			if (x^y)&(x^r) < 0 {
+				return nil, ErrNumberOutOfRange
+			}
+			return r, nil
+		case Multiply:
+			r := x * y
+			if x != 0 && (r/x != y || (x == -1 && y == math.MinInt)) {
+				return nil, ErrNumberOutOfRange
+			}
+			return r, nil
+		case Divide:
+			if y == 0 {
+				return nil, ErrDivideByZero
+			}
+			if x == math.MinInt && y == -1 {
+				return nil, ErrNumberOutOfRange
+			}
+			return x / y, nil
+		case Modulo:
+			if y == 0 {
+				return nil, ErrDivideByZero
+			}
+			return x % y, nil
+		}
+		return nil, fmt.Errorf("%v is not arithmetic", op)
+	}
+
+	f, g := Float(a), Float(b)
+	switch op {
+	case Add:
+		return finite(f + g)
+	case Subtract:
+		return finite(f - g)
+	case Multiply:
+		return finite(f * g)
+	case Divide:
+		if g == 0 {
+			return nil, ErrDivideByZero
+		}
+		return finite(f / g)
+	case Modulo:
+		if g == 0 {
+			return nil, ErrDivideByZero
+		}
+		return finite(math.Mod(f, g))
+	}
+	return nil, fmt.Errorf("%v is not arithmetic", op)
+}
+
+// finite refuses an infinite or NaN result, which no number can be
+// written as.
+func finite(f float64) (interface{}, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, ErrNumberOutOfRange
+	}
+	return f, nil
+}
+
+// Negate gives -val. The smallest int has no negative which is an int.
+func Negate(val interface{}) (interface{}, error) {
+	if n, ok := val.(int); ok {
+		if n == math.MinInt {
+			return nil, ErrNumberOutOfRange
+		}
+		return -n, nil
+	}
+	return -Float(val), nil
+}
+
+// CompareNumbers gives -1, 0 or 1 as a is less than, equal to or
+// greater than b.
+func CompareNumbers(a, b interface{}) int {
+	x, xWhole := a.(int)
+	y, yWhole := b.(int)
+	if xWhole && yWhole {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	f, g := Float(a), Float(b)
+	switch {
+	case f < g:
+		return -1
+	case f > g:
+		return 1
+	}
+	return 0
+}
+
+// ValuesEqual reports whether two values are the same. Numbers are compared
+// by value, so 2 and 2.0 are equal, and lists and maps by their contents.
+func ValuesEqual(a, b Value) bool {
+	if a.Type == NumberType && b.Type == NumberType && IsNumber(a.Val) && IsNumber(b.Val) {
+		return CompareNumbers(a.Val, b.Val) == 0
+	}
+	switch x := a.Val.(type) {
+	case *List:
+		y, ok := b.Val.(*List)
+		return ok && a.Type == b.Type && listsEqual(x, y)
+	case *Map:
+		y, ok := b.Val.(*Map)
+		return ok && a.Type == b.Type && mapsEqual(x, y)
+	}
+	// values from the host can be of types which can't be compared
+	if !IsValue(a) || !IsValue(b) {
+		return false
+	}
+	return a == b
+}
+
+// IsValue reports whether v is a value the VM can hold: its Go value
+// matches its type, and a list's items and a map's entries are values too.
+func IsValue(v Value) bool {
+	switch val := v.Val.(type) {
+	case nil:
+		return v.Type == NullType
+	case bool:
+		return v.Type == BooleanType
+	case int, float64:
+		return v.Type == NumberType
+	case string:
+		return v.Type == StringType || v.Type == SymbolType
+	case *List:
+		if v.Type != ListType {
+			return false
+		}
+		for _, item := range val.Items {
+			if !IsValue(item) {
+				return false
+			}
+		}
+		return true
+	case *Map:
+		if v.Type != MapType {
+			return false
+		}
+		for _, entry := range val.Entries {
+			if !IsValue(entry) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// FormatNumber is how a number is shown in text. An int is shown in
+// full and a float64 with at most FractionDigits decimal places.
+func FormatNumber(val interface{}) string {
+	if n, ok := val.(int); ok {
+		return strconv.Itoa(n)
+	}
+	f := Float(val)
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	s := strconv.FormatFloat(f, 'f', FractionDigits, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// Format is how a value is shown in text, such as in inline code.
+func Format(val interface{}) string {
+	switch val := val.(type) {
+	case nil:
+		return "null"
+	case int, float64:
+		return FormatNumber(val)
+	case *List:
+		return formatList(val)
+	case *Map:
+		return formatMap(val)
+	}
+	return fmt.Sprintf("%v", val)
+}
@@ -0,0 +1,183 @@
+package asm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestArithmetic(t *testing.T) {
+	for name, test := range map[string]struct {
+		op       Opcode
+		a, b     interface{}
+		expected interface{}
+		err      error
+	}{
+		"whole add":            {Add, 2, 3, 5, nil},
+		"whole divide":         {Divide, 7, 2, 3, nil},
+		"whole modulo":         {Modulo, 7, 4, 3, nil},
+		"fraction add":         {Add, 0.5, 0.25, 0.75, nil},
+		"promoted left":        {Multiply, 3, 0.5, 1.5, nil},
+		"promoted right":       {Subtract, 2.5, 1, 1.5, nil},
+		"promoted divide":      {Divide, 7.0, 2, 3.5, nil},
+		"whole result":         {Add, 1.5, 0.5, 2.0, nil},
+		"fraction modulo":      {Modulo, 5.5, 2, 1.5, nil},
+		"whole divide zero":    {Divide, 1, 0, nil, ErrDivideByZero},
+		"fraction divide zero": {Divide, 1.5, 0, nil, ErrDivideByZero},
+		"modulo zero":          {Modulo, 1, 0.0, nil, ErrDivideByZero},
+		"overflow":             {Multiply, 1e300, 1e300, nil, ErrNumberOutOfRange},
+		"overflow add":         {Add, math.MaxFloat64, math.MaxFloat64, nil, ErrNumberOutOfRange},
+		"overflow divide":      {Divide, 1e300, 1e-300, nil, ErrNumberOutOfRange},
+		"whole overflow add":   {Add, math.MaxInt, 1, nil, ErrNumberOutOfRange},
+		"whole underflow add":  {Add, math.MinInt, -1, nil, ErrNumberOutOfRange},
+		"whole overflow sub":   {Subtract, math.MinInt, 1, nil, ErrNumberOutOfRange},
+		"whole overflow mul":   {Multiply, math.MaxInt / 2, 3, nil, ErrNumberOutOfRange},
+		"whole overflow -1":    {Multiply, -1, math.MinInt, nil, ErrNumberOutOfRange},
+		"whole overflow div":   {Divide, math.MinInt, -1, nil, ErrNumberOutOfRange},
+		"whole largest":        {Subtract, math.MaxInt - 1, -1, math.MaxInt, nil},
+		"whole negative mul":   {Multiply, -4, 5, -20, nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			actual, err := Arithmetic(test.op, test.a, test.b)
+			if err != test.err {
+				t.Errorf("expected error %v got %v", test.err, err)
+			}
+			if actual != test.expected {
+				t.Errorf("expected %#v got %#v", test.expected, actual)
+			}
+		})
+	}
+
+	if _, err := Arithmetic(Concat, 1, 2); err == nil {
+		t.Errorf("expected error on a non-arithmetic opcode")
+	}
+}
+
+func TestWhole(t *testing.T) {
+	for name, test := range map[string]struct {
+		val      interface{}
+		expected int
+		ok       bool
+	}{
+		"int":          {3, 3, true},
+		"whole float":  {3.0, 3, true},
+		"negative":     {-2.0, -2, true},
+		"fraction":     {2.5, 0, false},
+		"too large":    {1e19, 0, false},
+		"infinity":     {math.Inf(1), 0, false},
+		"not a number": {"3", 0, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if actual, ok := Whole(test.val); actual != test.expected || ok != test.ok {
+				t.Errorf("expected %d, %v got %d, %v", test.expected, test.ok, actual, ok)
+			}
+		})
+	}
+}
+
+func TestNegate(t *testing.T) {
+	if n, err := Negate(5); err != nil || n != -5 {
+		t.Errorf("expected -5 got %v, %v", n, err)
+	}
+	if n, err := Negate(2.5); err != nil || n != -2.5 {
+		t.Errorf("expected -2.5 got %v, %v", n, err)
+	}
+	if _, err := Negate(math.MinInt); err != ErrNumberOutOfRange {
+		t.Errorf("expected the smallest int to be out of range, got %v", err)
+	}
+}
+
+func TestCompareNumbers(t *testing.T) {
+	for name, test := range map[string]struct {
+		a, b     interface{}
+		expected int
+	}{
+		"whole less":       {1, 2, -1},
+		"whole equal":      {2, 2, 0},
+		"fraction greater": {2.5, 2, 1},
+		"promoted equal":   {2, 2.0, 0},
+		"fraction less":    {-0.5, 0.25, -1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if actual := CompareNumbers(test.a, test.b); actual != test.expected {
+				t.Errorf("expected %d got %d", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValuesEqual(t *testing.T) {
+	for name, test := range map[string]struct {
+		a, b     Value
+		expected bool
+	}{
+		"promoted numbers":  {Value{NumberType, 2}, Value{NumberType, 2.0}, true},
+		"different numbers": {Value{NumberType, 2}, Value{NumberType, 2.5}, false},
+		"strings":           {Value{StringType, "a"}, Value{StringType, "a"}, true},
+		"different types":   {Value{StringType, "2"}, Value{NumberType, 2}, false},
+		"nulls":             {Null, Null, true},
+		"lists":             {NewList([]Value{{NumberType, 1}}), NewList([]Value{{NumberType, 1.0}}), true},
+		"different lists":   {NewList([]Value{{NumberType, 1}}), NewList([]Value{}), false},
+		"maps":              {NewMap(map[string]Value{"a": True}), NewMap(map[string]Value{"a": True}), true},
+		"different maps":    {NewMap(map[string]Value{"a": True}), NewMap(map[string]Value{"b": True}), false},
+		"list and map":      {NewList([]Value{}), NewMap(map[string]Value{}), false},
+		"host values":       {Value{"[]string", []string{"a"}}, Value{"[]string", []string{"a"}}, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if actual := ValuesEqual(test.a, test.b); actual != test.expected {
+				t.Errorf("expected %v got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestIsValue(t *testing.T) {
+	for name, test := range map[string]struct {
+		val      Value
+		expected bool
+	}{
+		"null":            {Null, true},
+		"number":          {Value{NumberType, 2.5}, true},
+		"symbol":          {Value{SymbolType, "a"}, true},
+		"list":            {NewList([]Value{True, {StringType, "a"}}), true},
+		"map":             {NewMap(map[string]Value{"a": Null}), true},
+		"mismatched type": {Value{StringType, 2}, false},
+		"host value":      {Value{"[]string", []string{"a"}}, false},
+		"host item":       {NewList([]Value{{"int64", int64(1)}}), false},
+		"host entry":      {NewMap(map[string]Value{"a": {NumberType, "1"}}), false},
+		"untagged list":   {Value{NumberType, NewList([]Value{}).Val}, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if actual := IsValue(test.val); actual != test.expected {
+				t.Errorf("expected %v got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for name, test := range map[string]struct {
+		val      interface{}
+		expected string
+	}{
+		"whole":          {42, "42"},
+		"fraction":       {2.5, "2.5"},
+		"whole fraction": {2.0, "2"},
+		"repeating":      {1 / 3.0, "0.333333"},
+		"rounded":        {0.1 + 0.2, "0.3"},
+		"negative":       {-0.125, "-0.125"},
+		"negative zero":  {-0.0000001, "0"},
+		"large":          {1e21, "1000000000000000000000"},
+		"null":           {nil, "null"},
+		"boolean":        {true, "true"},
+		"string":         {"abc", "abc"},
+		"list":           {NewList([]Value{{NumberType, 1}, {StringType, "a"}}).Val, `[1, "a"]`},
+		"empty list":     {NewList([]Value{}).Val, "[]"},
+		"map":            {NewMap(map[string]Value{"b": Null, "a": {NumberType, 2.5}}).Val, `{"a": 2.5, "b": null}`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if actual := Format(test.val); actual != test.expected {
+				t.Errorf("expected %q got %q", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package asm
+
+// OperandKind is what an instruction's argument means.
+type OperandKind int
+
+const (
+	// NoOperand is an opcode without an argument.
+	NoOperand OperandKind = iota
+	// LiteralOperand is a value the instruction pushes.
+	LiteralOperand
+	// NameOperand is the name of a variable, node or function.
+	NameOperand
+	// AddressOperand is an address in the code the instruction can go to.
+	AddressOperand
+	// CountOperand is how many items the instruction collects off the
+	// stack.
+	CountOperand
+)
+
+// OpcodeInfo is everything the compiler, VM and tools know about an opcode.
+type OpcodeInfo struct {
+	Name Opcode
+	Kind OperandKind
+	// Operand is the type of the argument, or empty if there isn't one.
+	Operand Type
+	// Pops is how many values the opcode takes off the stack. A Call
+	// takes its function's arguments instead, and an opcode with a
+	// CountOperand takes Pops values for each item it counts.
+	Pops int
+	// Pushes is how many values the opcode leaves on the stack. A Call
+	// leaves one if its function returns a value.
+	Pushes int
+}
+
+// Opcodes is every opcode. An opcode's index is its byte in the binary
+// encoding, so new opcodes go at the end.
+var Opcodes = []OpcodeInfo{
+	{PushString, LiteralOperand, StringType, 0, 1},
+	{PushNumber, LiteralOperand, NumberType, 0, 1},
+	{PushBool, LiteralOperand, BooleanType, 0, 1},
+	{PushNull, NoOperand, "", 0, 1},
+	{PopValue, NoOperand, "", 1, 0},
+	{Concat, NoOperand, "", 2, 1},
+	{And, NoOperand, "", 2, 1},
+	{Or, NoOperand, "", 2, 1},
+	{Not, NoOperand, "", 1, 1},
+	{Equal, NoOperand, "", 2, 1},
+	{NotEqual, NoOperand, "", 2, 1},
+	{GreaterThan, NoOperand, "", 2, 1},
+	{Lessthan, NoOperand, "", 2, 1},
+	{GreaterThanOrEqual, NoOperand, "", 2, 1},
+	{LessthanOrEqual, NoOperand, "", 2, 1},
+	{Negative, NoOperand, "", 1, 1},
+	{Add, NoOperand, "", 2, 1},
+	{Subtract, NoOperand, "", 2, 1},
+	{Multiply, NoOperand, "", 2, 1},
+	{Divide, NoOperand, "", 2, 1},
+	{Modulo, NoOperand, "", 2, 1},
+	{Increment, NoOperand, "", 1, 1},
+	{Decrement, NoOperand, "", 1, 1},
+	{LoadVariable, NameOperand, SymbolType, 0, 1},
+	{StoreVariable, NameOperand, SymbolType, 1, 0},
+	{InitVariable, NameOperand, SymbolType, 1, 0},
+	{LoadLocal, NameOperand, SymbolType, 0, 1},
+	{StoreLocal, NameOperand, SymbolType, 1, 0},
+	{ShowLine, NoOperand, "", 1, 0},
+	{Jump, AddressOperand, NumberType, 0, 0},
+	{JumpIfFalse, AddressOperand, NumberType, 1, 0},
+	{PushChoice, AddressOperand, NumberType, 1, 0},
+	{ShowChoice, NoOperand, "", 0, 0},
+	{EnterNode, NameOperand, SymbolType, 0, 0},
+	{ExitNode, NameOperand, SymbolType, 0, 0},
+	{EndDialogue, NoOperand, "", 0, 0},
+	{Call, NameOperand, SymbolType, 0, 0},
+	{MakeList, CountOperand, NumberType, 1, 1},
+	{MakeMap, CountOperand, NumberType, 2, 1},
+	{Index, NoOperand, "", 2, 1},
+	{SetIndex, NoOperand, "", 3, 1},
+	{Iterate, NoOperand, "", 1, 1},
+	{Length, NoOperand, "", 1, 1},
+}
+
+var opcodeIndex = map[Opcode]int{}
+
+func init() {
+	for i, info := range Opcodes {
+		opcodeIndex[info.Name] = i
+	}
+}
+
+// StackEffect gives how many values an instruction other than a Call
+// takes off the stack and how many it leaves.
+func StackEffect(instr Instruction) (pops, pushes int) {
+	info, _ := Lookup(instr.Opcode)
+	if info.Kind == CountOperand {
+		n, _ := instr.Arg.Val.(int)
+		return info.Pops * n, info.Pushes
+	}
+	return info.Pops, info.Pushes
+}
+
+// Lookup gives what's known about an opcode, or false if it isn't one.
+func Lookup(op Opcode) (OpcodeInfo, bool) {
+	i, ok := opcodeIndex[op]
+	if !ok {
+		return OpcodeInfo{}, false
+	}
+	return Opcodes[i], true
+}
+
+// OpcodeByte gives an opcode's byte in the binary encoding.
+func OpcodeByte(op Opcode) (byte, bool) {
+	i, ok := opcodeIndex[op]
+	return byte(i), ok
+}
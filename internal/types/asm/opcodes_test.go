@@ -0,0 +1,52 @@
+package asm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOpcodes(t *testing.T) {
+	for i, info := range Opcodes {
+		t.Run(string(info.Name), func(t *testing.T) {
+			if found, ok := Lookup(info.Name); !ok || found != info {
+				t.Errorf("expected to look up %v got %v", info, found)
+			}
+			if b, ok := OpcodeByte(info.Name); !ok || int(b) != i {
+				t.Errorf("expected byte %d got %d", i, b)
+			}
+			if (info.Kind == NoOperand) != (info.Operand == "") {
+				t.Errorf("expected an operand type only for opcodes with an operand, got %v", info)
+			}
+
+			instr := Instruction{Opcode: info.Name}
+			switch info.Operand {
+			case StringType:
+				instr.Arg = Value{Type: StringType, Val: "abc"}
+			case NumberType:
+				instr.Arg = Value{Type: NumberType, Val: 5}
+			case BooleanType:
+				instr.Arg = True
+			case SymbolType:
+				instr.Arg = Value{Type: SymbolType, Val: "abc"}
+			}
+			b, err := json.Marshal(instr)
+			if err != nil {
+				t.Fatalf("no error expected got %v", err)
+			}
+			var read Instruction
+			if err := json.Unmarshal(b, &read); err != nil {
+				t.Fatalf("no error expected got %v", err)
+			}
+			if read != instr {
+				t.Errorf("expected %v got %v", instr, read)
+			}
+		})
+	}
+
+	if _, ok := Lookup("Bogus"); ok {
+		t.Errorf("expected Bogus not to be an opcode")
+	}
+	if _, ok := OpcodeByte("Bogus"); ok {
+		t.Errorf("expected Bogus not to have a byte")
+	}
+}
@@ -1,15 +1,31 @@
 package ast
 
+import "github.com/mcvoid/dialogue/internal/types/lexeme"
+
+// Elements with a Pos field record where they came from in the source
+// so that later passes can report problems. The Compare methods ignore it.
+
 // top level elements
 type (
 	Script struct {
 		Nodes     []Node
 		Functions map[string][]Type
+		// Returns holds the return type of each function which has one.
+		Returns map[string]Type
+		// FunctionPos is where each function was declared.
+		FunctionPos map[string]lexeme.Position
+		// Variables are the global and extern variables declared in
+		// the front matter, in the order they were declared.
+		Variables []VariableDecl
 	}
 	Symbol string
-	Node   struct {
-		Name Symbol
-		Body []BlockElement
+	// Node is a named part of the dialogue. An Entry node is a place the
+	// script can be started from besides the first node.
+	Node struct {
+		Name  Symbol
+		Body  []BlockElement
+		Entry bool
+		Pos   lexeme.Position
 	}
 )
 
@@ -19,9 +35,13 @@ type (
 		CompareBlock(b BlockElement) bool
 	}
 	Paragraph []Inline
-	Link      struct {
-		Dest Symbol
-		Text Inline
+	// Link goes to Dest. As part of an Option, it is only offered
+	// if Guard is nil or holds.
+	Link struct {
+		Dest  Symbol
+		Text  Paragraph
+		Guard Expression
+		Pos   lexeme.Position
 	}
 	Option    []Link
 	CodeBlock struct {
@@ -49,26 +69,66 @@ type (
 	Assignment     struct {
 		Name Symbol
 		Val  Expression
+		Pos  lexeme.Position
 	}
 	FunctionCall struct {
 		Name   Symbol
 		Params []Expression
+		Pos    lexeme.Position
 	}
 	GotoNode struct {
 		Name Symbol
+		Pos  lexeme.Position
 	}
 	Conditional struct {
 		Cond       Expression
 		Consequent Statement
 		Alternate  Statement
+		Pos        lexeme.Position
 	}
 	Loop struct {
 		Cond       Expression
 		Consequent Statement
+		Pos        lexeme.Position
 	}
 	InfiniteLoop struct {
 		Consequent Statement
+		Pos        lexeme.Position
+	}
+	Break struct {
+		Pos lexeme.Position
+	}
+	Continue struct {
+		Pos lexeme.Position
+	}
+	// IndexAssignment sets the item or entry at Key of the list or map
+	// in the variable Name.
+	IndexAssignment struct {
+		Name Symbol
+		Key  Expression
+		Val  Expression
+		Pos  lexeme.Position
 	}
+	// ForLoop runs Consequent with Name set to each item of a list or
+	// key of a map in turn.
+	ForLoop struct {
+		Name       Symbol
+		Iterable   Expression
+		Consequent Statement
+		Pos        lexeme.Position
+	}
+	// VariableDecl declares a variable in a scope. Type is empty if
+	// the variable takes the type of Val, and Val is nil for externs.
+	// An extern's Type is trusted while type checking; nothing checks
+	// the value the game sets, which is null until it sets one.
+	VariableDecl struct {
+		Name  Symbol
+		Type  Type
+		Scope Scope
+		Val   Expression
+		Pos   lexeme.Position
+	}
+	Scope string
 )
 
 // expressions
@@ -81,16 +141,37 @@ type (
 		Operator BinaryOperator
 		LeftArg  Expression
 		RightArg Expression
+		Pos      lexeme.Position
 	}
 	UnaryOperator string
 	UnaryOp       struct {
 		Operator UnaryOperator
 		Arg      Expression
+		Pos      lexeme.Position
 	}
 	Type    string
 	Literal struct {
 		Type Type
 		Val  interface{}
+		Pos  lexeme.Position
+	}
+	ListLiteral struct {
+		Items []Expression
+		Pos   lexeme.Position
+	}
+	MapEntry struct {
+		Key Expression
+		Val Expression
+	}
+	MapLiteral struct {
+		Entries []MapEntry
+		Pos     lexeme.Position
+	}
+	// Index is the item or entry at Key of a list or map.
+	Index struct {
+		Container Expression
+		Key       Expression
+		Pos       lexeme.Position
 	}
 )
 
@@ -101,6 +182,15 @@ const (
 	NumberType  = "number"
 	SymbolType  = "symbol"
 	NullType    = "null"
+	ListType    = "list"
+	MapType     = "map"
+)
+
+// variable scopes
+const (
+	GlobalScope Scope = "global"
+	LocalScope  Scope = "local"
+	ExternScope Scope = "extern"
 )
 
 // unary operators
@@ -152,6 +242,22 @@ func (s Script) CompareScript(s2 Script) bool {
 			}
 		}
 	}
+	if len(s.Returns) != len(s2.Returns) {
+		return false
+	}
+	for funcName, t := range s.Returns {
+		if t2, ok := s2.Returns[funcName]; !ok || t != t2 {
+			return false
+		}
+	}
+	if len(s.Variables) != len(s2.Variables) {
+		return false
+	}
+	for i := range s.Variables {
+		if !s.Variables[i].CompareStatement(s2.Variables[i]) {
+			return false
+		}
+	}
 	return true
 }
 
@@ -164,7 +270,7 @@ func (n Node) CompareNode(n2 Node) bool {
 			return false
 		}
 	}
-	return n.Name == n2.Name
+	return n.Name == n2.Name && n.Entry == n2.Entry
 }
 
 func (n Paragraph) CompareBlock(b BlockElement) bool {
@@ -191,7 +297,13 @@ func (n Link) CompareBlock(b BlockElement) bool {
 	if n.Dest != s.Dest {
 		return false
 	}
-	return n.Text.CompareInline(s.Text)
+	if !n.Text.CompareBlock(s.Text) {
+		return false
+	}
+	if n.Guard == nil || s.Guard == nil {
+		return n.Guard == nil && s.Guard == nil
+	}
+	return n.Guard.CompareExpression(s.Guard)
 }
 
 func (n Option) CompareBlock(b BlockElement) bool {
@@ -270,6 +382,19 @@ func (n FunctionCall) CompareStatement(b Statement) bool {
 	if !ok {
 		return false
 	}
+	return n.compare(s)
+}
+
+// FunctionCall is also an expression when the function returns a value.
+func (n FunctionCall) CompareExpression(b Expression) bool {
+	s, ok := b.(FunctionCall)
+	if !ok {
+		return false
+	}
+	return n.compare(s)
+}
+
+func (n FunctionCall) compare(s FunctionCall) bool {
 	if len(n.Params) != len(s.Params) {
 		return false
 	}
@@ -282,7 +407,11 @@ func (n FunctionCall) CompareStatement(b Statement) bool {
 }
 
 func (n GotoNode) CompareStatement(b Statement) bool {
-	return n == b
+	s, ok := b.(GotoNode)
+	if !ok {
+		return false
+	}
+	return n.Name == s.Name
 }
 
 func (n Conditional) CompareStatement(b Statement) bool {
@@ -319,14 +448,158 @@ func (n InfiniteLoop) CompareStatement(b Statement) bool {
 	return n.Consequent.CompareStatement(s.Consequent)
 }
 
+func (n Break) CompareStatement(b Statement) bool {
+	_, ok := b.(Break)
+	return ok
+}
+
+func (n Continue) CompareStatement(b Statement) bool {
+	_, ok := b.(Continue)
+	return ok
+}
+
+func (n IndexAssignment) CompareStatement(b Statement) bool {
+	s, ok := b.(IndexAssignment)
+	if !ok {
+		return false
+	}
+	if !n.Key.CompareExpression(s.Key) {
+		return false
+	}
+	if !n.Val.CompareExpression(s.Val) {
+		return false
+	}
+	return n.Name == s.Name
+}
+
+func (n ForLoop) CompareStatement(b Statement) bool {
+	s, ok := b.(ForLoop)
+	if !ok {
+		return false
+	}
+	if !n.Iterable.CompareExpression(s.Iterable) {
+		return false
+	}
+	if !n.Consequent.CompareStatement(s.Consequent) {
+		return false
+	}
+	return n.Name == s.Name
+}
+
+func (n VariableDecl) CompareStatement(b Statement) bool {
+	s, ok := b.(VariableDecl)
+	if !ok {
+		return false
+	}
+	if n.Name != s.Name || n.Type != s.Type || n.Scope != s.Scope {
+		return false
+	}
+	return compareExpressions(n.Val, s.Val)
+}
+
+func compareExpressions(a, b Expression) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.CompareExpression(b)
+}
+
 func (a BinaryOp) CompareExpression(b Expression) bool {
-	return a == b
+	s, ok := b.(BinaryOp)
+	if !ok {
+		return false
+	}
+	if a.Operator != s.Operator {
+		return false
+	}
+	if !compareExpressions(a.LeftArg, s.LeftArg) {
+		return false
+	}
+	return compareExpressions(a.RightArg, s.RightArg)
 }
 
 func (a UnaryOp) CompareExpression(b Expression) bool {
-	return a == b
+	s, ok := b.(UnaryOp)
+	if !ok {
+		return false
+	}
+	if a.Operator != s.Operator {
+		return false
+	}
+	return compareExpressions(a.Arg, s.Arg)
 }
 
 func (a Literal) CompareExpression(b Expression) bool {
-	return a == b
+	s, ok := b.(Literal)
+	if !ok {
+		return false
+	}
+	return a.Type == s.Type && a.Val == s.Val
+}
+
+func (a ListLiteral) CompareExpression(b Expression) bool {
+	s, ok := b.(ListLiteral)
+	if !ok {
+		return false
+	}
+	if len(a.Items) != len(s.Items) {
+		return false
+	}
+	for i := range a.Items {
+		if !compareExpressions(a.Items[i], s.Items[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a MapLiteral) CompareExpression(b Expression) bool {
+	s, ok := b.(MapLiteral)
+	if !ok {
+		return false
+	}
+	if len(a.Entries) != len(s.Entries) {
+		return false
+	}
+	for i := range a.Entries {
+		if !compareExpressions(a.Entries[i].Key, s.Entries[i].Key) {
+			return false
+		}
+		if !compareExpressions(a.Entries[i].Val, s.Entries[i].Val) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a Index) CompareExpression(b Expression) bool {
+	s, ok := b.(Index)
+	if !ok {
+		return false
+	}
+	if !compareExpressions(a.Container, s.Container) {
+		return false
+	}
+	return compareExpressions(a.Key, s.Key)
+}
+
+// PosOf finds where an expression appears in the source.
+func PosOf(e Expression) lexeme.Position {
+	switch e := e.(type) {
+	case BinaryOp:
+		return e.Pos
+	case UnaryOp:
+		return e.Pos
+	case Literal:
+		return e.Pos
+	case FunctionCall:
+		return e.Pos
+	case ListLiteral:
+		return e.Pos
+	case MapLiteral:
+		return e.Pos
+	case Index:
+		return e.Pos
+	}
+	return lexeme.Position{}
 }
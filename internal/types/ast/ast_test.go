@@ -535,44 +535,69 @@ func TestBlocks(t *testing.T) {
 		{
 			a: Link{
 				Dest: "abc",
-				Text: Text("def"),
+				Text: Paragraph{Text("def")},
 			},
 			b: Link{
 				Dest: "abc",
-				Text: Text("def"),
+				Text: Paragraph{Text("def")},
 			},
 			expected: true,
 		},
 		{
 			a: Link{
 				Dest: "abc",
-				Text: Text("def"),
+				Text: Paragraph{Text("def")},
 			},
 			b: Link{
 				Dest: "ab",
-				Text: Text("def"),
+				Text: Paragraph{Text("def")},
 			},
 			expected: false,
 		},
 		{
 			a: Link{
 				Dest: "abc",
-				Text: Text("def"),
+				Text: Paragraph{Text("def")},
 			},
 			b: Link{
 				Dest: "abc",
-				Text: Text("df"),
+				Text: Paragraph{Text("df")},
 			},
 			expected: false,
 		},
 		{
 			a: Link{
 				Dest: "abc",
-				Text: Text("def"),
+				Text: Paragraph{Text("def")},
 			},
 			b:        CodeBlock{},
 			expected: false,
 		},
+		{
+			a: Link{
+				Dest:  "abc",
+				Text:  Paragraph{Text("def")},
+				Guard: Literal{Type: SymbolType, Val: "ghi"},
+			},
+			b: Link{
+				Dest:  "abc",
+				Text:  Paragraph{Text("def")},
+				Guard: Literal{Type: SymbolType, Val: "ghi"},
+			},
+			expected: true,
+		},
+		{
+			a: Link{
+				Dest:  "abc",
+				Text:  Paragraph{Text("def")},
+				Guard: Literal{Type: SymbolType, Val: "ghi"},
+			},
+			b: Link{
+				Dest: "abc",
+				Text: Paragraph{Text("def")},
+			},
+			expected: false,
+		},
 		{
 			a: CodeBlock{
 				Code: []Statement{
@@ -629,42 +654,42 @@ func TestBlocks(t *testing.T) {
 		},
 		{
 			a: Option{
-				Link{Dest: "abc", Text: Text("def")},
-				Link{Dest: "def", Text: Text("ghi")},
+				Link{Dest: "abc", Text: Paragraph{Text("def")}},
+				Link{Dest: "def", Text: Paragraph{Text("ghi")}},
 			},
 			b: Option{
-				Link{Dest: "abc", Text: Text("def")},
-				Link{Dest: "def", Text: Text("ghi")},
+				Link{Dest: "abc", Text: Paragraph{Text("def")}},
+				Link{Dest: "def", Text: Paragraph{Text("ghi")}},
 			},
 			expected: true,
 		},
 		{
 			a: Option{
-				Link{Dest: "abc", Text: Text("def")},
-				Link{Dest: "def", Text: Text("ghi")},
+				Link{Dest: "abc", Text: Paragraph{Text("def")}},
+				Link{Dest: "def", Text: Paragraph{Text("ghi")}},
 			},
 			b: Option{
-				Link{Dest: "abc", Text: Text("def")},
+				Link{Dest: "abc", Text: Paragraph{Text("def")}},
 			},
 			expected: false,
 		},
 		{
 			a: Option{
-				Link{Dest: "abc", Text: Text("def")},
-				Link{Dest: "def", Text: Text("ghi")},
+				Link{Dest: "abc", Text: Paragraph{Text("def")}},
+				Link{Dest: "def", Text: Paragraph{Text("ghi")}},
 			},
 			b: Option{
-				Link{Dest: "abc", Text: Text("def")},
-				Link{Dest: "def", Text: Text("jjj")},
+				Link{Dest: "abc", Text: Paragraph{Text("def")}},
+				Link{Dest: "def", Text: Paragraph{Text("jjj")}},
 			},
 			expected: false,
 		},
 		{
 			a: Option{
-				Link{Dest: "abc", Text: Text("def")},
-				Link{Dest: "def", Text: Text("ghi")},
+				Link{Dest: "abc", Text: Paragraph{Text("def")}},
+				Link{Dest: "def", Text: Paragraph{Text("ghi")}},
 			},
-			b:        Link{Dest: "def", Text: Text("ghi")},
+			b:        Link{Dest: "def", Text: Paragraph{Text("ghi")}},
 			expected: false,
 		},
 		{
@@ -727,15 +752,15 @@ func TestNode(t *testing.T) {
 			a: Node{
 				Name: "abc",
 				Body: []BlockElement{
-					Link{Dest: "abc", Text: Text("def")},
-					Link{Dest: "def", Text: Text("ghi")},
+					Link{Dest: "abc", Text: Paragraph{Text("def")}},
+					Link{Dest: "def", Text: Paragraph{Text("ghi")}},
 				},
 			},
 			b: Node{
 				Name: "abc",
 				Body: []BlockElement{
-					Link{Dest: "abc", Text: Text("def")},
-					Link{Dest: "def", Text: Text("ghi")},
+					Link{Dest: "abc", Text: Paragraph{Text("def")}},
+					Link{Dest: "def", Text: Paragraph{Text("ghi")}},
 				},
 			},
 			expected: true,
@@ -744,15 +769,15 @@ func TestNode(t *testing.T) {
 			a: Node{
 				Name: "abc",
 				Body: []BlockElement{
-					Link{Dest: "abc", Text: Text("def")},
-					Link{Dest: "def", Text: Text("ghi")},
+					Link{Dest: "abc", Text: Paragraph{Text("def")}},
+					Link{Dest: "def", Text: Paragraph{Text("ghi")}},
 				},
 			},
 			b: Node{
 				Name: "bc",
 				Body: []BlockElement{
-					Link{Dest: "abc", Text: Text("def")},
-					Link{Dest: "def", Text: Text("ghi")},
+					Link{Dest: "abc", Text: Paragraph{Text("def")}},
+					Link{Dest: "def", Text: Paragraph{Text("ghi")}},
 				},
 			},
 			expected: false,
@@ -761,14 +786,14 @@ func TestNode(t *testing.T) {
 			a: Node{
 				Name: "abc",
 				Body: []BlockElement{
-					Link{Dest: "abc", Text: Text("def")},
-					Link{Dest: "def", Text: Text("ghi")},
+					Link{Dest: "abc", Text: Paragraph{Text("def")}},
+					Link{Dest: "def", Text: Paragraph{Text("ghi")}},
 				},
 			},
 			b: Node{
 				Name: "abc",
 				Body: []BlockElement{
-					Link{Dest: "def", Text: Text("ghi")},
+					Link{Dest: "def", Text: Paragraph{Text("ghi")}},
 				},
 			},
 			expected: false,
@@ -777,15 +802,15 @@ func TestNode(t *testing.T) {
 			a: Node{
 				Name: "abc",
 				Body: []BlockElement{
-					Link{Dest: "abc", Text: Text("def")},
-					Link{Dest: "def", Text: Text("ghi")},
+					Link{Dest: "abc", Text: Paragraph{Text("def")}},
+					Link{Dest: "def", Text: Paragraph{Text("ghi")}},
 				},
 			},
 			b: Node{
 				Name: "abc",
 				Body: []BlockElement{
-					Link{Dest: "abc", Text: Text("def")},
-					Link{Dest: "def", Text: Text("gi")},
+					Link{Dest: "abc", Text: Paragraph{Text("def")}},
+					Link{Dest: "def", Text: Paragraph{Text("gi")}},
 				},
 			},
 			expected: false,
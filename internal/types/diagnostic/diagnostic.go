@@ -0,0 +1,99 @@
+package diagnostic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mcvoid/dialogue/internal/types/lexeme"
+)
+
+type (
+	Severity int
+	Code     string
+
+	// Span is the stretch of source text a diagnostic refers to.
+	// End is exclusive.
+	Span struct {
+		Start lexeme.Position
+		End   lexeme.Position
+	}
+
+	// Related is a secondary location which helps explain a diagnostic,
+	// such as where a duplicated name was first declared.
+	Related struct {
+		Message string
+		Span    Span
+	}
+
+	// Diagnostic is a single problem found while compiling a script.
+	Diagnostic struct {
+		Severity Severity
+		Code     Code
+		Message  string
+		Span     Span
+		Related  []Related
+	}
+
+	// Diagnostics is every problem found while compiling a script.
+	// It is returned as an error when any of them are errors.
+	Diagnostics []Diagnostic
+)
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+const (
+	LexError       Code = "lex-error"
+	SyntaxError    Code = "syntax-error"
+	UnknownNode    Code = "unknown-node"
+	DuplicateNode  Code = "duplicate-node"
+	TypeError      Code = "type-error"
+	LoopControl    Code = "loop-control"
+	LinkError      Code = "link-error"
+	CodegenFailure Code = "codegen-failure"
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// At makes a span at a single point in the source.
+func At(pos lexeme.Position) Span {
+	return Span{Start: pos, End: pos}
+}
+
+// Token makes a span covering a single token.
+func Token(item lexeme.Item) Span {
+	return Span{Start: item.Pos, End: item.End()}
+}
+
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%v: %v: %s", d.Span.Start, d.Severity, d.Message)
+}
+
+func (d Diagnostics) Error() string {
+	lines := []string{}
+	for _, diag := range d {
+		lines = append(lines, diag.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HasErrors reports whether any of the diagnostics are errors rather
+// than warnings.
+func (d Diagnostics) HasErrors() bool {
+	for _, diag := range d {
+		if diag.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
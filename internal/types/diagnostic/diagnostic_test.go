@@ -0,0 +1,37 @@
+package diagnostic
+
+import (
+	"testing"
+
+	"github.com/mcvoid/dialogue/internal/types/lexeme"
+)
+
+func TestDiagnostics(t *testing.T) {
+	tok := lexeme.Item{Type: lexeme.Symbol, Val: "abc", Pos: lexeme.Position{File: "a.md", Offset: 10, Line: 2, Column: 5}}
+	span := Token(tok)
+	if span.End != (lexeme.Position{File: "a.md", Offset: 13, Line: 2, Column: 8}) {
+		t.Errorf("wrong span end %+v", span.End)
+	}
+	if At(tok.Pos).Start != At(tok.Pos).End {
+		t.Errorf("point span should start where it ends")
+	}
+
+	diags := Diagnostics{
+		{Severity: Warning, Code: UnknownNode, Message: "first", Span: span},
+	}
+	if diags.HasErrors() {
+		t.Errorf("warnings are not errors")
+	}
+	diags = append(diags, Diagnostic{Severity: Error, Code: UnknownNode, Message: "second", Span: span})
+	if !diags.HasErrors() {
+		t.Errorf("expected errors")
+	}
+
+	expected := "a.md:2:5: warning: first\na.md:2:5: error: second"
+	if diags.Error() != expected {
+		t.Errorf("expected\n%v\ngot\n%v", expected, diags.Error())
+	}
+	if Severity(7).String() != "severity(7)" {
+		t.Errorf("unexpected severity string %v", Severity(7))
+	}
+}
@@ -1,5 +1,7 @@
 package lexeme
 
+import "fmt"
+
 type ItemType int
 
 const (
@@ -50,13 +52,56 @@ const (
 	Not
 	Type
 	ExternKeyword
+	Colon
+	VarKeyword
+	LocalKeyword
+	BreakLiteral
+	ContinueLiteral
+	EntryAnnotation
+	ForLiteral
+	InLiteral
 )
 
+// Position is a location in a script's source text. Lines and columns
+// start at 1, columns are counted in runes, and Offset is the byte offset
+// from the start of the input.
+type Position struct {
+	File   string
+	Offset int
+	Line   int
+	Column int
+}
+
 type Item struct {
 	Type ItemType
 	Val  string
+	Pos  Position
+}
+
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// End is the position just past the last character of the item.
+func (i Item) End() Position {
+	end := i.Pos
+	for _, r := range i.Val {
+		if r == '\n' {
+			end.Line++
+			end.Column = 1
+		} else {
+			end.Column++
+		}
+	}
+	end.Offset += len(i.Val)
+	return end
 }
 
+// CompareItem reports whether two items have the same type and value.
+// Source positions are not compared.
 func (i Item) CompareItem(b Item) bool {
 	return i.Type == b.Type && i.Val == b.Val
 }
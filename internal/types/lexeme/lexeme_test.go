@@ -20,3 +20,20 @@ func TestCompareLexeme(t *testing.T) {
 		t.Errorf("expected CompareItem to be false, got true")
 	}
 }
+
+func TestPositionString(t *testing.T) {
+	p := Position{Line: 3, Column: 7, Offset: 20}
+	if p.String() != "3:7" {
+		t.Errorf("expected 3:7 got %v", p)
+	}
+	p.File = "intro.md"
+	if p.String() != "intro.md:3:7" {
+		t.Errorf("expected intro.md:3:7 got %v", p)
+	}
+
+	a := Item{Type: Number, Val: "5", Pos: Position{Line: 1, Column: 1}}
+	b := Item{Type: Number, Val: "5", Pos: Position{Line: 9, Column: 4}}
+	if !a.CompareItem(b) {
+		t.Errorf("expected CompareItem to ignore positions")
+	}
+}
@@ -4,6 +4,7 @@ import "github.com/mcvoid/dialogue/internal/types/lexeme"
 
 type Expression interface {
 	CompareExpression(n2 Expression) bool
+	Pos() lexeme.Position
 }
 
 type BinaryExpression struct {
@@ -74,3 +75,156 @@ func (n NestedExpression) CompareExpression(n2 Expression) bool {
 	}
 	return n.CloseParen.CompareItem(b.CloseParen)
 }
+
+type CallExpression struct {
+	Symbol     lexeme.Item
+	OpenParen  lexeme.Item
+	Args       []Expression
+	CloseParen lexeme.Item
+}
+
+func (n CallExpression) CompareExpression(n2 Expression) bool {
+	b, ok := n2.(CallExpression)
+	if !ok {
+		return false
+	}
+	if !n.Symbol.CompareItem(b.Symbol) {
+		return false
+	}
+	if !n.OpenParen.CompareItem(b.OpenParen) {
+		return false
+	}
+	if len(n.Args) != len(b.Args) {
+		return false
+	}
+	for i := range n.Args {
+		if !n.Args[i].CompareExpression(b.Args[i]) {
+			return false
+		}
+	}
+	return n.CloseParen.CompareItem(b.CloseParen)
+}
+
+// ListLiteral is a list of items between square brackets. The commas
+// between the items are dropped.
+type ListLiteral struct {
+	OpenBrace  lexeme.Item
+	Items      []Expression
+	CloseBrace lexeme.Item
+}
+
+func (n ListLiteral) CompareExpression(n2 Expression) bool {
+	b, ok := n2.(ListLiteral)
+	if !ok {
+		return false
+	}
+	if !n.OpenBrace.CompareItem(b.OpenBrace) {
+		return false
+	}
+	if len(n.Items) != len(b.Items) {
+		return false
+	}
+	for i := range n.Items {
+		if !n.Items[i].CompareExpression(b.Items[i]) {
+			return false
+		}
+	}
+	return n.CloseBrace.CompareItem(b.CloseBrace)
+}
+
+// MapEntry is a key and value in a map literal.
+type MapEntry struct {
+	Key   Expression
+	Colon lexeme.Item
+	Value Expression
+}
+
+// MapLiteral is a map's entries between curly braces. The commas
+// between the entries are dropped.
+type MapLiteral struct {
+	OpenBrace  lexeme.Item
+	Entries    []MapEntry
+	CloseBrace lexeme.Item
+}
+
+func (n MapLiteral) CompareExpression(n2 Expression) bool {
+	b, ok := n2.(MapLiteral)
+	if !ok {
+		return false
+	}
+	if !n.OpenBrace.CompareItem(b.OpenBrace) {
+		return false
+	}
+	if len(n.Entries) != len(b.Entries) {
+		return false
+	}
+	for i := range n.Entries {
+		if !n.Entries[i].Key.CompareExpression(b.Entries[i].Key) {
+			return false
+		}
+		if !n.Entries[i].Colon.CompareItem(b.Entries[i].Colon) {
+			return false
+		}
+		if !n.Entries[i].Value.CompareExpression(b.Entries[i].Value) {
+			return false
+		}
+	}
+	return n.CloseBrace.CompareItem(b.CloseBrace)
+}
+
+// IndexExpression is an item of a list or an entry of a map.
+type IndexExpression struct {
+	Container  Expression
+	OpenBrace  lexeme.Item
+	Key        Expression
+	CloseBrace lexeme.Item
+}
+
+func (n IndexExpression) CompareExpression(n2 Expression) bool {
+	b, ok := n2.(IndexExpression)
+	if !ok {
+		return false
+	}
+	if !n.Container.CompareExpression(b.Container) {
+		return false
+	}
+	if !n.OpenBrace.CompareItem(b.OpenBrace) {
+		return false
+	}
+	if !n.Key.CompareExpression(b.Key) {
+		return false
+	}
+	return n.CloseBrace.CompareItem(b.CloseBrace)
+}
+
+func (n BinaryExpression) Pos() lexeme.Position {
+	return n.LeftOperand.Pos()
+}
+
+func (n UnaryExpression) Pos() lexeme.Position {
+	return n.Operator.Pos
+}
+
+func (n Literal) Pos() lexeme.Position {
+	return n.Value.Pos
+}
+
+func (n NestedExpression) Pos() lexeme.Position {
+	return n.OpenParen.Pos
+}
+
+func (n CallExpression) Pos() lexeme.Position {
+	return n.Symbol.Pos
+}
+
+func (n ListLiteral) Pos() lexeme.Position {
+	return n.OpenBrace.Pos
+}
+
+func (n MapLiteral) Pos() lexeme.Position {
+	return n.OpenBrace.Pos
+}
+
+func (n IndexExpression) Pos() lexeme.Position {
+	return n.Container.Pos()
+}
@@ -23,6 +23,44 @@ func TestExpressions(t *testing.T) {
 			b:        Literal{lexeme.Item{Type: lexeme.Symbol, Val: "b"}},
 			expected: false,
 		},
+		{
+			a: CallExpression{
+				Symbol: lexeme.Item{Type: lexeme.Symbol, Val: "f"},
+				Args:   []Expression{Literal{lexeme.Item{Type: lexeme.Number, Val: "1"}}},
+			},
+			b: CallExpression{
+				Symbol: lexeme.Item{Type: lexeme.Symbol, Val: "f"},
+				Args:   []Expression{Literal{lexeme.Item{Type: lexeme.Number, Val: "1"}}},
+			},
+			expected: true,
+		},
+		{
+			a: CallExpression{
+				Symbol: lexeme.Item{Type: lexeme.Symbol, Val: "f"},
+				Args:   []Expression{Literal{lexeme.Item{Type: lexeme.Number, Val: "1"}}},
+			},
+			b: CallExpression{
+				Symbol: lexeme.Item{Type: lexeme.Symbol, Val: "f"},
+				Args:   []Expression{Literal{lexeme.Item{Type: lexeme.Number, Val: "2"}}},
+			},
+			expected: false,
+		},
+		{
+			a: CallExpression{
+				Symbol: lexeme.Item{Type: lexeme.Symbol, Val: "f"},
+				Args:   []Expression{},
+			},
+			b: CallExpression{
+				Symbol: lexeme.Item{Type: lexeme.Symbol, Val: "g"},
+				Args:   []Expression{},
+			},
+			expected: false,
+		},
+		{
+			a:        CallExpression{Symbol: lexeme.Item{Type: lexeme.Symbol, Val: "f"}},
+			b:        Literal{lexeme.Item{Type: lexeme.Symbol, Val: "f"}},
+			expected: false,
+		},
 		{
 			a:        Literal{lexeme.Item{Type: lexeme.Symbol, Val: "a"}},
 			b:        Literal{lexeme.Item{Type: lexeme.Number, Val: "a"}},
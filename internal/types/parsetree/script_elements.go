@@ -6,10 +6,12 @@ type (
 	Script struct {
 		FrontMatter FrontMatter
 		Nodes       []Node
+		Skipped     []Skipped
 		Eof         lexeme.Item
 	}
 	FrontMatter struct {
 		FuncDecls []FuncDecl
+		VarDecls  []VarDecl
 		Delimiter lexeme.Item
 		EndLine   lexeme.Item
 	}
@@ -19,6 +21,21 @@ type (
 		OpenParen     lexeme.Item
 		Params        []lexeme.Item
 		CloseParen    lexeme.Item
+		Colon         lexeme.Item
+		ReturnType    lexeme.Item
+		Semicolon     lexeme.Item
+		EndLine       lexeme.Item
+	}
+	// VarDecl declares a script-global variable, or with ExternKeyword,
+	// one the game provides. Only script variables have a Value.
+	VarDecl struct {
+		ExternKeyword lexeme.Item
+		VarKeyword    lexeme.Item
+		Symbol        lexeme.Item
+		Colon         lexeme.Item
+		Type          lexeme.Item
+		EqualSign     lexeme.Item
+		Value         Expression
 		Semicolon     lexeme.Item
 		EndLine       lexeme.Item
 	}
@@ -27,9 +44,11 @@ type (
 		EndLine lexeme.Item
 		Blocks  []Block
 	}
+	// Header names a node. Entry is the @entry annotation, if given.
 	Header struct {
 		Hash    lexeme.Item
 		Name    lexeme.Item
+		Entry   lexeme.Item
 		EndLine lexeme.Item
 	}
 )
@@ -37,6 +56,7 @@ type (
 type (
 	Block interface {
 		CompareBlock(n2 Block) bool
+		Pos() lexeme.Position
 	}
 	Paragraph struct {
 		Lines   []Line
@@ -51,7 +71,7 @@ type (
 		Symbol     lexeme.Item
 		CloseBrace lexeme.Item
 		OpenParen  lexeme.Item
-		Text       Inline
+		Text       []Inline
 		CloseParen lexeme.Item
 		EndLine    lexeme.Item
 	}
@@ -67,19 +87,30 @@ type (
 		Links   []ListItem
 		EndLine lexeme.Item
 	}
+	// ListItem is one option in a list. If it has a Guard, the option
+	// is only shown when the guard holds.
 	ListItem struct {
 		Prefix lexeme.Item
 		Link   Link
+		If     lexeme.Item
+		Guard  Expression
 	}
 	Line struct {
 		Items   []Inline
 		EndLine lexeme.Item
 	}
+	// Skipped is a run of tokens the parser discarded to recover from a
+	// syntax error. Err describes the error which caused the skip.
+	Skipped struct {
+		Tokens []lexeme.Item
+		Err    error
+	}
 )
 
 type (
 	Inline interface {
 		CompareInline(n2 Inline) bool
+		Pos() lexeme.Position
 	}
 	Text struct {
 		Text lexeme.Item
@@ -100,12 +131,36 @@ func (n Script) CompareScript(n2 Script) bool {
 			return false
 		}
 	}
+	if len(n.Skipped) != len(n2.Skipped) {
+		return false
+	}
+	for i := range n.Skipped {
+		if !n.Skipped[i].CompareBlock(n2.Skipped[i]) {
+			return false
+		}
+	}
 	if !n.FrontMatter.CompareFrontMatter(n2.FrontMatter) {
 		return false
 	}
 	return n.Eof.CompareItem(n2.Eof)
 }
 
+func (n Skipped) CompareBlock(n2 Block) bool {
+	b, ok := n2.(Skipped)
+	if !ok {
+		return false
+	}
+	if len(n.Tokens) != len(b.Tokens) {
+		return false
+	}
+	for i := range n.Tokens {
+		if !n.Tokens[i].CompareItem(b.Tokens[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 func (n FrontMatter) CompareFrontMatter(n2 FrontMatter) bool {
 	if len(n.FuncDecls) != len(n2.FuncDecls) {
 		return false
@@ -115,6 +170,14 @@ func (n FrontMatter) CompareFrontMatter(n2 FrontMatter) bool {
 			return false
 		}
 	}
+	if len(n.VarDecls) != len(n2.VarDecls) {
+		return false
+	}
+	for i := range n.VarDecls {
+		if !n.VarDecls[i].CompareVarDecl(n2.VarDecls[i]) {
+			return false
+		}
+	}
 	if !n.Delimiter.CompareItem(n2.Delimiter) {
 		return false
 	}
@@ -142,6 +205,44 @@ func (n FuncDecl) CompareFuncDecl(n2 FuncDecl) bool {
 	if !n.CloseParen.CompareItem(n2.CloseParen) {
 		return false
 	}
+	if !n.Colon.CompareItem(n2.Colon) {
+		return false
+	}
+	if !n.ReturnType.CompareItem(n2.ReturnType) {
+		return false
+	}
+	if !n.Semicolon.CompareItem(n2.Semicolon) {
+		return false
+	}
+	return n.EndLine.CompareItem(n2.EndLine)
+}
+
+func (n VarDecl) CompareVarDecl(n2 VarDecl) bool {
+	if !n.ExternKeyword.CompareItem(n2.ExternKeyword) {
+		return false
+	}
+	if !n.VarKeyword.CompareItem(n2.VarKeyword) {
+		return false
+	}
+	if !n.Symbol.CompareItem(n2.Symbol) {
+		return false
+	}
+	if !n.Colon.CompareItem(n2.Colon) {
+		return false
+	}
+	if !n.Type.CompareItem(n2.Type) {
+		return false
+	}
+	if !n.EqualSign.CompareItem(n2.EqualSign) {
+		return false
+	}
+	if n.Value == nil || n2.Value == nil {
+		if n.Value != nil || n2.Value != nil {
+			return false
+		}
+	} else if !n.Value.CompareExpression(n2.Value) {
+		return false
+	}
 	if !n.Semicolon.CompareItem(n2.Semicolon) {
 		return false
 	}
@@ -173,6 +274,9 @@ func (n Header) CompareHeader(n2 Header) bool {
 	if !n.Name.CompareItem(n2.Name) {
 		return false
 	}
+	if !n.Entry.CompareItem(n2.Entry) {
+		return false
+	}
 	return n.EndLine.CompareItem(n2.EndLine)
 }
 
@@ -232,9 +336,14 @@ func (n Link) CompareLink(n2 Link) bool {
 	if !n.OpenParen.CompareItem(n2.OpenParen) {
 		return false
 	}
-	if !n.Text.CompareInline(n2.Text) {
+	if len(n.Text) != len(n2.Text) {
 		return false
 	}
+	for i := range n.Text {
+		if !n.Text[i].CompareInline(n2.Text[i]) {
+			return false
+		}
+	}
 	if !n.CloseParen.CompareItem(n2.CloseParen) {
 		return false
 	}
@@ -273,7 +382,16 @@ func (n ListItem) CompareListItem(n2 ListItem) bool {
 	if !n.Prefix.CompareItem(n2.Prefix) {
 		return false
 	}
-	return n.Link.CompareLink(n2.Link)
+	if !n.Link.CompareLink(n2.Link) {
+		return false
+	}
+	if !n.If.CompareItem(n2.If) {
+		return false
+	}
+	if n.Guard == nil || n2.Guard == nil {
+		return n.Guard == nil && n2.Guard == nil
+	}
+	return n.Guard.CompareExpression(n2.Guard)
 }
 
 func (n Line) CompareLine(n2 Line) bool {
@@ -309,3 +427,74 @@ func (n InlineCode) CompareInline(n2 Inline) bool {
 	}
 	return n.CodeEnd.CompareItem(b.CodeEnd)
 }
+
+func (n Node) Pos() lexeme.Position {
+	return n.Header.Pos()
+}
+
+func (n Header) Pos() lexeme.Position {
+	return n.Hash.Pos
+}
+
+func (n FuncDecl) Pos() lexeme.Position {
+	return n.ExternKeyword.Pos
+}
+
+func (n VarDecl) Pos() lexeme.Position {
+	if n.ExternKeyword.Type == lexeme.ExternKeyword {
+		return n.ExternKeyword.Pos
+	}
+	return n.VarKeyword.Pos
+}
+
+func (n Paragraph) Pos() lexeme.Position {
+	if len(n.Lines) == 0 {
+		return n.EndLine.Pos
+	}
+	return n.Lines[0].Pos()
+}
+
+func (n List) Pos() lexeme.Position {
+	if len(n.Links) == 0 {
+		return n.EndLine.Pos
+	}
+	return n.Links[0].Pos()
+}
+
+func (n LinkBlock) Pos() lexeme.Position {
+	return n.Link.Pos()
+}
+
+func (n Link) Pos() lexeme.Position {
+	return n.OpenBrace.Pos
+}
+
+func (n CodeBlock) Pos() lexeme.Position {
+	return n.StartFence.Pos
+}
+
+func (n Skipped) Pos() lexeme.Position {
+	if len(n.Tokens) == 0 {
+		return lexeme.Position{}
+	}
+	return n.Tokens[0].Pos
+}
+
+func (n ListItem) Pos() lexeme.Position {
+	return n.Prefix.Pos
+}
+
+func (n Line) Pos() lexeme.Position {
+	if len(n.Items) == 0 {
+		return n.EndLine.Pos
+	}
+	return n.Items[0].Pos()
+}
+
+func (n Text) Pos() lexeme.Position {
+	return n.Text.Pos
+}
+
+func (n InlineCode) Pos() lexeme.Position {
+	return n.CodeStart.Pos
+}
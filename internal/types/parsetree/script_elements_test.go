@@ -20,7 +20,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -32,7 +32,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -47,7 +47,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -59,7 +59,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -74,7 +74,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -86,7 +86,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "ac"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -101,7 +101,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -113,7 +113,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: ")"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -128,7 +128,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -140,7 +140,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "["},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -155,7 +155,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -167,7 +167,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "de"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "de"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -182,7 +182,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -194,7 +194,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: "]"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -209,7 +209,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -221,7 +221,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "/n"},
 				},
@@ -236,7 +236,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -248,7 +248,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -263,7 +263,7 @@ func TestBlocks(t *testing.T) {
 					Symbol:     lexeme.Item{Type: lexeme.Symbol, Val: "abc"},
 					CloseBrace: lexeme.Item{Type: lexeme.CloseSquareBrace, Val: "]"},
 					OpenParen:  lexeme.Item{Type: lexeme.OpenParen, Val: "("},
-					Text:       Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+					Text:       []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 					CloseParen: lexeme.Item{Type: lexeme.CloseParen, Val: ")"},
 					EndLine:    lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
 				},
@@ -277,11 +277,11 @@ func TestBlocks(t *testing.T) {
 				Links: []ListItem{
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 				},
 				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
@@ -290,11 +290,11 @@ func TestBlocks(t *testing.T) {
 				Links: []ListItem{
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 				},
 				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
@@ -306,11 +306,11 @@ func TestBlocks(t *testing.T) {
 				Links: []ListItem{
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 				},
 				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
@@ -319,11 +319,11 @@ func TestBlocks(t *testing.T) {
 				Links: []ListItem{
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "--"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 				},
 				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
@@ -335,11 +335,11 @@ func TestBlocks(t *testing.T) {
 				Links: []ListItem{
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 				},
 				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
@@ -348,11 +348,11 @@ func TestBlocks(t *testing.T) {
 				Links: []ListItem{
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "ab"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "ab"}}}},
 					},
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 				},
 				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
@@ -364,11 +364,11 @@ func TestBlocks(t *testing.T) {
 				Links: []ListItem{
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 				},
 				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
@@ -377,7 +377,7 @@ func TestBlocks(t *testing.T) {
 				Links: []ListItem{
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 				},
 				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
@@ -389,11 +389,11 @@ func TestBlocks(t *testing.T) {
 				Links: []ListItem{
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 				},
 				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
@@ -402,11 +402,11 @@ func TestBlocks(t *testing.T) {
 				Links: []ListItem{
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 				},
 				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "/n"},
@@ -418,11 +418,11 @@ func TestBlocks(t *testing.T) {
 				Links: []ListItem{
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 					{
 						Prefix: lexeme.Item{Type: lexeme.ListItemPrefix, Val: "-"},
-						Link:   Link{Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}},
+						Link:   Link{Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "abc"}}}},
 					},
 				},
 				EndLine: lexeme.Item{Type: lexeme.LineBreak, Val: "\n"},
@@ -1317,12 +1317,12 @@ func TestNode(t *testing.T) {
 				Blocks: []Block{
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 				},
@@ -1337,12 +1337,12 @@ func TestNode(t *testing.T) {
 				Blocks: []Block{
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 				},
@@ -1360,12 +1360,12 @@ func TestNode(t *testing.T) {
 				Blocks: []Block{
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 				},
@@ -1380,12 +1380,12 @@ func TestNode(t *testing.T) {
 				Blocks: []Block{
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 				},
@@ -1403,12 +1403,12 @@ func TestNode(t *testing.T) {
 				Blocks: []Block{
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 				},
@@ -1423,12 +1423,12 @@ func TestNode(t *testing.T) {
 				Blocks: []Block{
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 				},
@@ -1446,12 +1446,12 @@ func TestNode(t *testing.T) {
 				Blocks: []Block{
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 				},
@@ -1466,12 +1466,12 @@ func TestNode(t *testing.T) {
 				Blocks: []Block{
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 				},
@@ -1489,12 +1489,12 @@ func TestNode(t *testing.T) {
 				Blocks: []Block{
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 				},
@@ -1509,12 +1509,12 @@ func TestNode(t *testing.T) {
 				Blocks: []Block{
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 				},
@@ -1532,12 +1532,12 @@ func TestNode(t *testing.T) {
 				Blocks: []Block{
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 				},
@@ -1552,7 +1552,7 @@ func TestNode(t *testing.T) {
 				Blocks: []Block{
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 				},
@@ -1570,12 +1570,12 @@ func TestNode(t *testing.T) {
 				Blocks: []Block{
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 				},
@@ -1590,12 +1590,12 @@ func TestNode(t *testing.T) {
 				Blocks: []Block{
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "ef"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "ef"}}},
 						},
 					},
 					LinkBlock{
 						Link: Link{
-							Text: Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}},
+							Text: []Inline{Text{Text: lexeme.Item{Type: lexeme.TextLiteral, Val: "def"}}},
 						},
 					},
 				},
@@ -4,6 +4,7 @@ import "github.com/mcvoid/dialogue/internal/types/lexeme"
 
 type Statement interface {
 	CompareStatement(n2 Statement) bool
+	Pos() lexeme.Position
 }
 
 type StatementBlock struct {
@@ -152,6 +153,38 @@ func (n Loop) CompareStatement(n2 Statement) bool {
 	return n.Body.CompareStatement(b.Body)
 }
 
+type Break struct {
+	BreakLiteral lexeme.Item
+	Semicolon    lexeme.Item
+}
+
+func (n Break) CompareStatement(n2 Statement) bool {
+	b, ok := n2.(Break)
+	if !ok {
+		return false
+	}
+	if !n.BreakLiteral.CompareItem(b.BreakLiteral) {
+		return false
+	}
+	return n.Semicolon.CompareItem(b.Semicolon)
+}
+
+type Continue struct {
+	ContinueLiteral lexeme.Item
+	Semicolon       lexeme.Item
+}
+
+func (n Continue) CompareStatement(n2 Statement) bool {
+	b, ok := n2.(Continue)
+	if !ok {
+		return false
+	}
+	if !n.ContinueLiteral.CompareItem(b.ContinueLiteral) {
+		return false
+	}
+	return n.Semicolon.CompareItem(b.Semicolon)
+}
+
 type Assignment struct {
 	Symbol    lexeme.Item
 	EqualSign lexeme.Item
@@ -175,3 +208,155 @@ func (n Assignment) CompareStatement(n2 Statement) bool {
 	}
 	return n.Semicolon.CompareItem(b.Semicolon)
 }
+
+// IndexAssignment sets an item of a list or an entry of a map held
+// in a variable.
+type IndexAssignment struct {
+	Symbol     lexeme.Item
+	OpenBrace  lexeme.Item
+	Key        Expression
+	CloseBrace lexeme.Item
+	EqualSign  lexeme.Item
+	Value      Expression
+	Semicolon  lexeme.Item
+}
+
+func (n IndexAssignment) CompareStatement(n2 Statement) bool {
+	b, ok := n2.(IndexAssignment)
+	if !ok {
+		return false
+	}
+	if !n.Symbol.CompareItem(b.Symbol) {
+		return false
+	}
+	if !n.OpenBrace.CompareItem(b.OpenBrace) {
+		return false
+	}
+	if !n.Key.CompareExpression(b.Key) {
+		return false
+	}
+	if !n.CloseBrace.CompareItem(b.CloseBrace) {
+		return false
+	}
+	if !n.EqualSign.CompareItem(b.EqualSign) {
+		return false
+	}
+	if !n.Value.CompareExpression(b.Value) {
+		return false
+	}
+	return n.Semicolon.CompareItem(b.Semicolon)
+}
+
+// ForLoop runs its body once for each item of a list or key of a map.
+type ForLoop struct {
+	ForLiteral lexeme.Item
+	Symbol     lexeme.Item
+	InLiteral  lexeme.Item
+	Iterable   Expression
+	Body       Statement
+}
+
+func (n ForLoop) CompareStatement(n2 Statement) bool {
+	b, ok := n2.(ForLoop)
+	if !ok {
+		return false
+	}
+	if !n.ForLiteral.CompareItem(b.ForLiteral) {
+		return false
+	}
+	if !n.Symbol.CompareItem(b.Symbol) {
+		return false
+	}
+	if !n.InLiteral.CompareItem(b.InLiteral) {
+		return false
+	}
+	if !n.Iterable.CompareExpression(b.Iterable) {
+		return false
+	}
+	return n.Body.CompareStatement(b.Body)
+}
+
+// LocalDeclaration declares a variable which lasts until the node exits.
+type LocalDeclaration struct {
+	LocalKeyword lexeme.Item
+	Symbol       lexeme.Item
+	Colon        lexeme.Item
+	Type         lexeme.Item
+	EqualSign    lexeme.Item
+	Value        Expression
+	Semicolon    lexeme.Item
+}
+
+func (n LocalDeclaration) CompareStatement(n2 Statement) bool {
+	b, ok := n2.(LocalDeclaration)
+	if !ok {
+		return false
+	}
+	if !n.LocalKeyword.CompareItem(b.LocalKeyword) {
+		return false
+	}
+	if !n.Symbol.CompareItem(b.Symbol) {
+		return false
+	}
+	if !n.Colon.CompareItem(b.Colon) {
+		return false
+	}
+	if !n.Type.CompareItem(b.Type) {
+		return false
+	}
+	if !n.EqualSign.CompareItem(b.EqualSign) {
+		return false
+	}
+	if !n.Value.CompareExpression(b.Value) {
+		return false
+	}
+	return n.Semicolon.CompareItem(b.Semicolon)
+}
+
+func (n StatementBlock) Pos() lexeme.Position {
+	return n.OpenBrace.Pos
+}
+
+func (n FunctionCall) Pos() lexeme.Position {
+	return n.Symbol.Pos
+}
+
+func (n Goto) Pos() lexeme.Position {
+	return n.GotoLiteral.Pos
+}
+
+func (n Conditional) Pos() lexeme.Position {
+	return n.IfLiteral.Pos
+}
+
+func (n ConditionalWithElse) Pos() lexeme.Position {
+	return n.IfLiteral.Pos
+}
+
+func (n Loop) Pos() lexeme.Position {
+	return n.WhileLiteral.Pos
+}
+
+func (n Break) Pos() lexeme.Position {
+	return n.BreakLiteral.Pos
+}
+
+func (n Continue) Pos() lexeme.Position {
+	return n.ContinueLiteral.Pos
+}
+
+func (n Assignment) Pos() lexeme.Position {
+	return n.Symbol.Pos
+}
+
+func (n LocalDeclaration) Pos() lexeme.Position {
+	return n.LocalKeyword.Pos
+}
+
+func (n IndexAssignment) Pos() lexeme.Position {
+	return n.Symbol.Pos
+}
+
+func (n ForLoop) Pos() lexeme.Position {
+	return n.ForLiteral.Pos
+}
@@ -0,0 +1,90 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mcvoid/dialogue/internal/types/asm"
+)
+
+// builtins are the native implementations of asm.Builtins. Arguments
+// have already been checked against the builtin's signature.
+var builtins = map[string]func(vm *VM, args ...asm.Value) (asm.Value, error){
+	"length": func(vm *VM, args ...asm.Value) (asm.Value, error) {
+		return number(utf8.RuneCountInString(args[0].Val.(string))), nil
+	},
+	"upper": func(vm *VM, args ...asm.Value) (asm.Value, error) {
+		return asm.Value{Type: asm.StringType, Val: strings.ToUpper(args[0].Val.(string))}, nil
+	},
+	"lower": func(vm *VM, args ...asm.Value) (asm.Value, error) {
+		return asm.Value{Type: asm.StringType, Val: strings.ToLower(args[0].Val.(string))}, nil
+	},
+	"min": func(vm *VM, args ...asm.Value) (asm.Value, error) {
+		if asm.CompareNumbers(args[1].Val, args[0].Val) < 0 {
+			return args[1], nil
+		}
+		return args[0], nil
+	},
+	"max": func(vm *VM, args ...asm.Value) (asm.Value, error) {
+		if asm.CompareNumbers(args[1].Val, args[0].Val) > 0 {
+			return args[1], nil
+		}
+		return args[0], nil
+	},
+	"abs": func(vm *VM, args ...asm.Value) (asm.Value, error) {
+		if n, ok := args[0].Val.(int); ok && n == math.MinInt {
+			return asm.Null, fmt.Errorf("abs of %d is out of range", n)
+		}
+		if asm.CompareNumbers(args[0].Val, 0) < 0 {
+			// the smallest int, which can't be negated, is refused above
+			neg, _ := asm.Negate(args[0].Val)
+			return asm.Value{Type: asm.NumberType, Val: neg}, nil
+		}
+		return args[0], nil
+	},
+	"clamp": func(vm *VM, args ...asm.Value) (asm.Value, error) {
+		val, lo, hi := args[0], args[1], args[2]
+		if asm.CompareNumbers(lo.Val, hi.Val) > 0 {
+			return asm.Null, fmt.Errorf("clamp range %s to %s is empty", asm.FormatNumber(lo.Val), asm.FormatNumber(hi.Val))
+		}
+		if asm.CompareNumbers(val.Val, lo.Val) < 0 {
+			return lo, nil
+		}
+		if asm.CompareNumbers(val.Val, hi.Val) > 0 {
+			return hi, nil
+		}
+		return val, nil
+	},
+	"random": func(vm *VM, args ...asm.Value) (asm.Value, error) {
+		lo, loWhole := args[0].Val.(int)
+		hi, hiWhole := args[1].Val.(int)
+		if !loWhole || !hiWhole {
+			return asm.Null, fmt.Errorf("random range %s to %s must be whole numbers", asm.FormatNumber(args[0].Val), asm.FormatNumber(args[1].Val))
+		}
+		if lo > hi {
+			return asm.Null, fmt.Errorf("random range %d to %d is empty", lo, hi)
+		}
+		// the span can be wider than an int holds
+		span := uint64(hi) - uint64(lo)
+		if span >= math.MaxInt {
+			return asm.Null, fmt.Errorf("random range %d to %d is too wide", lo, hi)
+		}
+		return number(lo + vm.rand.Intn(int(span)+1)), nil
+	},
+	"to_string": func(vm *VM, args ...asm.Value) (asm.Value, error) {
+		return asm.Value{Type: asm.StringType, Val: asm.FormatNumber(args[0].Val)}, nil
+	},
+	"count": func(vm *VM, args ...asm.Value) (asm.Value, error) {
+		n, err := asm.Size(args[0])
+		return number(n), err
+	},
+	"keys": func(vm *VM, args ...asm.Value) (asm.Value, error) {
+		return asm.LoopItems(args[0])
+	},
+}
+
+func number(n int) asm.Value {
+	return asm.Value{Type: asm.NumberType, Val: n}
+}
@@ -0,0 +1,194 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mcvoid/dialogue/internal/types/asm"
+)
+
+// FaultCode is the reason an instruction failed.
+type FaultCode int
+
+const (
+	// InvalidInstruction is an instruction the VM can't run, such as an
+	// unknown opcode or an argument of the wrong kind.
+	InvalidInstruction FaultCode = iota + 1
+	// StackUnderflow is an instruction taking more values than the stack has.
+	StackUnderflow
+	// TypeMismatch is a value of the wrong type for an instruction or call.
+	TypeMismatch
+	// DivideByZero is a Divide or Modulo by zero.
+	DivideByZero
+	// OutOfBounds is a jump to an address outside the code.
+	OutOfBounds
+	// UnknownFunction is a call to a function without a callback.
+	UnknownFunction
+	// BuiltinFailed is a builtin function given arguments it can't use.
+	BuiltinFailed
+	// HandlerPanic is a panic in one of the host's handlers or callbacks.
+	HandlerPanic
+	// IndexOutOfRange is a list index past either end of the list.
+	IndexOutOfRange
+	// NumberOutOfRange is arithmetic giving a fraction too large to hold.
+	NumberOutOfRange
+)
+
+var faultNames = map[FaultCode]string{
+	InvalidInstruction: "invalid-instruction",
+	StackUnderflow:     "stack-underflow",
+	TypeMismatch:       "type-mismatch",
+	DivideByZero:       "divide-by-zero",
+	OutOfBounds:        "out-of-bounds",
+	UnknownFunction:    "unknown-function",
+	BuiltinFailed:      "builtin-failed",
+	HandlerPanic:       "handler-panic",
+	IndexOutOfRange:    "index-out-of-range",
+	NumberOutOfRange:   "number-out-of-range",
+}
+
+func (c FaultCode) String() string {
+	if name, ok := faultNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("fault(%d)", int(c))
+}
+
+// RuntimeError is an instruction which failed while the VM was running.
+type RuntimeError struct {
+	// PC is the address of the instruction.
+	PC int
+	// Opcode is the instruction's opcode, or empty if PC is outside the code.
+	Opcode asm.Opcode
+	// Node is the node the VM was in, or empty outside of any node.
+	Node string
+	Code FaultCode
+	Err  error
+}
+
+func (e RuntimeError) Error() string {
+	return fmt.Sprintf("%d: %v", e.PC, e.Err)
+}
+
+func (e RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// faultError is a failure inside an instruction before it's known where.
+type faultError struct {
+	code FaultCode
+	err  error
+}
+
+func (e faultError) Error() string {
+	return e.err.Error()
+}
+
+func fault(code FaultCode, format string, args ...interface{}) error {
+	return faultError{code, fmt.Errorf(format, args...)}
+}
+
+type faultActionKind int
+
+const (
+	abortFault faultActionKind = iota
+	skipFault
+	recoverFault
+)
+
+// FaultAction is what a fault handler tells the VM to do about a
+// RuntimeError.
+type FaultAction struct {
+	kind faultActionKind
+	node string
+}
+
+var (
+	// AbortOnFault stops the VM in an error state and returns the
+	// RuntimeError. It's what the VM does without a fault handler.
+	AbortOnFault = FaultAction{kind: abortFault}
+	// SkipInstruction carries on with the instruction after the one which
+	// failed, leaving the stack as the failed instruction left it.
+	SkipInstruction = FaultAction{kind: skipFault}
+)
+
+// RecoverAt carries on at the start of the named node with an empty
+// stack, as if the node had been jumped to.
+func RecoverAt(node string) FaultAction {
+	return FaultAction{kind: recoverFault, node: node}
+}
+
+// HandleFault assigns a handler which decides what to do when an
+// instruction fails. Pass as an option to NewVM.
+func HandleFault(handler func(*VM, RuntimeError) FaultAction) Option {
+	return func(vm *VM) error {
+		if handler == nil {
+			return fmt.Errorf("HandleFault is a null handler")
+		}
+		vm.handleFault = handler
+		return nil
+	}
+}
+
+// step runs a single instruction, turning any failure, including a
+// panic, into a RuntimeError.
+func step(vm *VM) (err error) {
+	pc := vm.pc
+	defer func() {
+		if r := recover(); r != nil {
+			code := InvalidInstruction
+			if vm.inHost {
+				code = HandlerPanic
+			}
+			vm.inHost = false
+			err = fault(code, "panic: %v", r)
+		}
+		if err != nil {
+			err = runtimeError(vm, pc, err)
+		}
+	}()
+	return singleStep(vm)
+}
+
+func runtimeError(vm *VM, pc int, err error) RuntimeError {
+	rerr := RuntimeError{PC: pc, Node: vm.node, Code: InvalidInstruction, Err: err}
+	if pc >= 0 && pc < len(vm.code) {
+		rerr.Opcode = vm.code[pc].Opcode
+	}
+	var ferr faultError
+	if errors.As(err, &ferr) {
+		rerr.Code = ferr.code
+		rerr.Err = ferr.err
+	}
+	return rerr
+}
+
+// recoverFrom asks the fault handler what to do about a RuntimeError,
+// and gives the error to return if the VM has to stop.
+func recoverFrom(vm *VM, err RuntimeError) error {
+	action := vm.handleFault(vm, err)
+	switch action.kind {
+	case skipFault:
+		if err.Code == OutOfBounds {
+			// there's no instruction to skip
+			return err
+		}
+		return nil
+	case recoverFault:
+		addr, ok := vm.program.Nodes[action.node]
+		if !ok {
+			// the error is still the fault, so its code isn't lost
+			err.Err = fmt.Errorf("cannot recover at unknown node %s: %w", action.node, err.Err)
+			return err
+		}
+		vm.runState = runningState
+		vm.pc = addr
+		vm.startAt = 0
+		vm.node = ""
+		vm.locals = map[string]asm.Value{}
+		vm.stack = []asm.Value{}
+		vm.choices = []choice{}
+		return nil
+	}
+	return err
+}
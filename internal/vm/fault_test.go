@@ -0,0 +1,288 @@
+package vm
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/mcvoid/dialogue/internal/program"
+	"github.com/mcvoid/dialogue/internal/types/asm"
+)
+
+func TestRuntimeError(t *testing.T) {
+	num := func(n int) asm.Value {
+		return asm.Value{Type: asm.NumberType, Val: n}
+	}
+	sym := func(s string) asm.Value {
+		return asm.Value{Type: asm.SymbolType, Val: s}
+	}
+	enter := asm.Instruction{Opcode: asm.EnterNode, Arg: sym("start")}
+	end := asm.Instruction{Opcode: asm.EndDialogue}
+
+	for name, test := range map[string]struct {
+		code     []asm.Instruction
+		options  []Option
+		expected RuntimeError
+		message  string
+	}{
+		"divide by zero": {
+			code: []asm.Instruction{
+				enter,
+				{Opcode: asm.PushNumber, Arg: num(1)},
+				{Opcode: asm.PushNumber, Arg: num(0)},
+				{Opcode: asm.Divide},
+				end,
+			},
+			expected: RuntimeError{PC: 3, Opcode: asm.Divide, Node: "start", Code: DivideByZero},
+			message:  "3: division by zero",
+		},
+		"modulo by zero": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushNumber, Arg: num(1)},
+				{Opcode: asm.PushNumber, Arg: num(0)},
+				{Opcode: asm.Modulo},
+				end,
+			},
+			expected: RuntimeError{PC: 2, Opcode: asm.Modulo, Code: DivideByZero},
+			message:  "2: modulo by zero",
+		},
+		"number out of range": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 1e300}},
+				{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 1e300}},
+				{Opcode: asm.Multiply},
+				end,
+			},
+			expected: RuntimeError{PC: 2, Opcode: asm.Multiply, Code: NumberOutOfRange},
+			message:  "2: number out of range",
+		},
+		"whole number out of range": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushNumber, Arg: num(math.MaxInt)},
+				{Opcode: asm.PushNumber, Arg: num(1)},
+				{Opcode: asm.Add},
+				end,
+			},
+			expected: RuntimeError{PC: 2, Opcode: asm.Add, Code: NumberOutOfRange},
+			message:  "2: number out of range",
+		},
+		"increment out of range": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushNumber, Arg: num(math.MaxInt)},
+				{Opcode: asm.Increment},
+				end,
+			},
+			expected: RuntimeError{PC: 1, Opcode: asm.Increment, Code: NumberOutOfRange},
+			message:  "1: number out of range",
+		},
+		"negative out of range": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushNumber, Arg: num(math.MinInt)},
+				{Opcode: asm.Negative},
+				end,
+			},
+			expected: RuntimeError{PC: 1, Opcode: asm.Negative, Code: NumberOutOfRange},
+			message:  "1: number out of range",
+		},
+		"stack underflow": {
+			code:     []asm.Instruction{enter, {Opcode: asm.ShowLine}, end},
+			expected: RuntimeError{PC: 1, Opcode: asm.ShowLine, Node: "start", Code: StackUnderflow},
+			message:  "1: vm stack underflow",
+		},
+		"type mismatch": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushNumber, Arg: num(1)},
+				{Opcode: asm.Not},
+				end,
+			},
+			expected: RuntimeError{PC: 1, Opcode: asm.Not, Code: TypeMismatch},
+			message:  "1: value {number 1} is not of type Boolean",
+		},
+		"index out of range": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushNumber, Arg: num(1)},
+				{Opcode: asm.MakeList, Arg: num(1)},
+				{Opcode: asm.PushNumber, Arg: num(1)},
+				{Opcode: asm.Index},
+				end,
+			},
+			expected: RuntimeError{PC: 3, Opcode: asm.Index, Code: IndexOutOfRange},
+			message:  "3: list index 1 with length 1: index out of range",
+		},
+		"index of a number": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushNumber, Arg: num(1)},
+				{Opcode: asm.PushNumber, Arg: num(0)},
+				{Opcode: asm.Index},
+				end,
+			},
+			expected: RuntimeError{PC: 2, Opcode: asm.Index, Code: TypeMismatch},
+			message:  "2: cannot index number",
+		},
+		"malformed jump": {
+			code:     []asm.Instruction{{Opcode: asm.Jump, Arg: sym("here")}, end},
+			expected: RuntimeError{PC: 0, Opcode: asm.Jump, Code: InvalidInstruction},
+			message:  "0: jump destination {symbol here} is not a number",
+		},
+		"malformed literal": {
+			code:     []asm.Instruction{{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: "one"}}, end},
+			expected: RuntimeError{PC: 0, Opcode: asm.PushNumber, Code: TypeMismatch},
+			message:  "0: value {number one} is not of type Number",
+		},
+		"malformed node": {
+			code:     []asm.Instruction{{Opcode: asm.EnterNode, Arg: num(1)}, end},
+			expected: RuntimeError{PC: 0, Opcode: asm.EnterNode, Code: InvalidInstruction},
+			message:  "0: node name {number 1} is not a symbol",
+		},
+		"invalid opcode": {
+			code:     []asm.Instruction{{Opcode: "Explode"}, end},
+			expected: RuntimeError{PC: 0, Opcode: "Explode", Code: InvalidInstruction},
+			message:  "0: invalid instruction: {Explode { <nil>}}",
+		},
+		"out of bounds": {
+			code:     []asm.Instruction{{Opcode: asm.Jump, Arg: num(7)}},
+			expected: RuntimeError{PC: 7, Code: OutOfBounds},
+			message:  "7: jumped to out of bounds location",
+		},
+		"unknown function": {
+			code:     []asm.Instruction{{Opcode: asm.Call, Arg: sym("f")}, end},
+			expected: RuntimeError{PC: 0, Opcode: asm.Call, Code: UnknownFunction},
+			message:  "0: callback not found: {symbol f}",
+		},
+		"builtin failed": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushNumber, Arg: num(1)},
+				{Opcode: asm.PushNumber, Arg: num(5)},
+				{Opcode: asm.PushNumber, Arg: num(1)},
+				{Opcode: asm.Call, Arg: sym("clamp")},
+				end,
+			},
+			expected: RuntimeError{PC: 3, Opcode: asm.Call, Code: BuiltinFailed},
+			message:  "3: clamp range 5 to 1 is empty",
+		},
+		"handler panic": {
+			code: []asm.Instruction{enter, end},
+			options: []Option{HandleEnterNode(func(vm *VM, node string) ExecutionType {
+				panic("oops")
+			})},
+			expected: RuntimeError{PC: 0, Opcode: asm.EnterNode, Node: "start", Code: HandlerPanic},
+			message:  "0: panic: oops",
+		},
+		"observer panic": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushNumber, Arg: num(1)},
+				{Opcode: asm.StoreVariable, Arg: sym("x")},
+				end,
+			},
+			options: []Option{HandleVariableChange(func(vm *VM, change VariableChange) ExecutionType {
+				panic("oops")
+			})},
+			expected: RuntimeError{PC: 1, Opcode: asm.StoreVariable, Code: HandlerPanic},
+			message:  "1: panic: oops",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			vm, err := New(program.Program{Code: test.code}, test.options...)
+			if err != nil {
+				t.Fatalf("no error expected got %v", err)
+			}
+			err = vm.Run()
+			var actual RuntimeError
+			if !errors.As(err, &actual) {
+				t.Fatalf("expected RuntimeError got %v", err)
+			}
+			if err.Error() != test.message {
+				t.Errorf("expected %q got %q", test.message, err.Error())
+			}
+			actual.Err = nil
+			if actual != test.expected {
+				t.Errorf("expected %+v got %+v", test.expected, actual)
+			}
+			if vm.runState != errorState {
+				t.Errorf("expected error state got %v", vm.runState)
+			}
+		})
+	}
+}
+
+func TestHandleFault(t *testing.T) {
+	str := func(s string) asm.Value {
+		return asm.Value{Type: asm.StringType, Val: s}
+	}
+	p := program.Program{
+		Code: []asm.Instruction{
+			{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "start"}},
+			{Opcode: asm.PushString, Arg: str("a")},
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 1}},
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 0}},
+			{Opcode: asm.Divide},
+			{Opcode: asm.ShowLine},
+			{Opcode: asm.EndDialogue},
+			{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "oops"}},
+			{Opcode: asm.PushString, Arg: str("recovered")},
+			{Opcode: asm.ShowLine},
+			{Opcode: asm.EndDialogue},
+		},
+		Nodes: map[string]int{"start": 0, "oops": 7},
+	}
+
+	for name, test := range map[string]struct {
+		action   FaultAction
+		lines    []string
+		expected string
+	}{
+		"abort": {
+			action:   AbortOnFault,
+			lines:    []string{},
+			expected: "4: division by zero",
+		},
+		"skip": {
+			action: SkipInstruction,
+			lines:  []string{"a"},
+		},
+		"recover": {
+			action: RecoverAt("oops"),
+			lines:  []string{"recovered"},
+		},
+		"recover at unknown node": {
+			action:   RecoverAt("nowhere"),
+			lines:    []string{},
+			expected: "4: cannot recover at unknown node nowhere: division by zero",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			lines := []string{}
+			faults := []RuntimeError{}
+			vm, _ := New(p,
+				HandleShowLine(func(vm *VM, line string) ExecutionType {
+					lines = append(lines, line)
+					return ContinueExecution
+				}),
+				HandleFault(func(vm *VM, err RuntimeError) FaultAction {
+					faults = append(faults, err)
+					return test.action
+				}),
+			)
+			err := vm.Run()
+			if test.expected == "" && err != nil {
+				t.Errorf("no error expected got %v", err)
+			}
+			if test.expected != "" && (err == nil || err.Error() != test.expected) {
+				t.Errorf("expected %q got %v", test.expected, err)
+			}
+			if rerr, ok := err.(RuntimeError); test.expected != "" && (!ok || rerr.Code != DivideByZero) {
+				t.Errorf("expected the error to keep its fault code, got %v", err)
+			}
+			if !compareStrings(lines, test.lines) {
+				t.Errorf("expected lines %v got %v", test.lines, lines)
+			}
+			if len(faults) != 1 || faults[0].Code != DivideByZero || faults[0].Node != "start" {
+				t.Errorf("expected one divide by zero fault got %v", faults)
+			}
+		})
+	}
+
+	if _, err := New(p, HandleFault(nil)); err == nil {
+		t.Error("expected error on null handler")
+	}
+}
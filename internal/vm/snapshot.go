@@ -0,0 +1,209 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/mcvoid/dialogue/internal/types/asm"
+)
+
+const (
+	// SnapshotFormat names the encoding of a Snapshot.
+	SnapshotFormat = "dialogue-vm-snapshot"
+	// SnapshotVersion is the version of the Snapshot layout written by
+	// this VM. Restore rejects any other version.
+	SnapshotVersion = 1
+)
+
+// Snapshot is the saved state of a paused, waiting or stopped VM.
+type Snapshot struct {
+	Format    string               `json:"format"`
+	Version   int                  `json:"version"`
+	Program   string               `json:"program"`
+	State     string               `json:"state"`
+	PC        int                  `json:"pc"`
+	StartAt   int                  `json:"start_at,omitempty"`
+	Node      string               `json:"node,omitempty"`
+	Stack     []asm.Value          `json:"stack"`
+	Choices   []SnapshotChoice     `json:"choices"`
+	Variables map[string]asm.Value `json:"variables"`
+	Locals    map[string]asm.Value `json:"locals,omitempty"`
+	Random    *SnapshotRandom      `json:"random,omitempty"`
+}
+
+// SnapshotRandom is where the random builtin is up to: its seed and how
+// many numbers have been drawn since.
+type SnapshotRandom struct {
+	Seed  int64  `json:"seed"`
+	Draws uint64 `json:"draws"`
+}
+
+// SnapshotChoice is an option waiting to be chosen.
+type SnapshotChoice struct {
+	Text string `json:"text"`
+	Dest int    `json:"dest"`
+}
+
+var snapshotStates = map[runState]string{
+	stoppedState:         "stopped",
+	suspendedState:       "suspended",
+	waitingForInputState: "waiting",
+}
+
+// Snapshot saves the VM's state so it can be restored later, even by
+// another VM running the same program. A VM can only be saved between
+// runs, while suspended, or while waiting for a choice. Only the global
+// variables the program uses are saved, along with where the random
+// builtin is up to so that a seeded VM carries on with the same numbers.
+func (vm *VM) Snapshot() (Snapshot, error) {
+	state, ok := snapshotStates[vm.runState]
+	if !ok {
+		return Snapshot{}, fmt.Errorf("cannot snapshot a vm which is running or in an error state")
+	}
+	hash, err := vm.program.Hash()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	s := Snapshot{
+		Format:    SnapshotFormat,
+		Version:   SnapshotVersion,
+		Program:   hash,
+		State:     state,
+		PC:        vm.pc,
+		StartAt:   vm.startAt,
+		Node:      vm.node,
+		Stack:     append([]asm.Value{}, vm.stack...),
+		Choices:   []SnapshotChoice{},
+		Variables: map[string]asm.Value{},
+		Random:    &SnapshotRandom{Seed: vm.source.seed, Draws: vm.source.draws},
+	}
+	for _, c := range vm.choices {
+		s.Choices = append(s.Choices, SnapshotChoice{Text: c.text.Val.(string), Dest: c.dest.Val.(int)})
+	}
+	for name := range vm.assigned {
+		val, ok := vm.variables.Get(name)
+		if !ok {
+			continue
+		}
+		if !asm.IsValue(val) {
+			return Snapshot{}, fmt.Errorf("variable %s holds a %v, which cannot be saved", name, val.Type)
+		}
+		s.Variables[name] = val
+	}
+	if len(vm.locals) > 0 {
+		s.Locals = map[string]asm.Value{}
+		for name, val := range vm.locals {
+			s.Locals[name] = val
+		}
+	}
+	return s, nil
+}
+
+// Restore replaces the VM's state with a snapshot taken from a VM
+// running the same program. The global variables the program assigns
+// are set to the snapshot's, and deleted if the snapshot doesn't have
+// them. Variables the program only reads, like flags the game sets, and
+// any others in the store are left alone.
+func (vm *VM) Restore(s Snapshot) error {
+	if s.Format != SnapshotFormat {
+		return fmt.Errorf("not a vm snapshot")
+	}
+	if s.Version != SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	hash, err := vm.program.Hash()
+	if err != nil {
+		return err
+	}
+	if s.Program != hash {
+		return fmt.Errorf("snapshot was taken from a different program")
+	}
+
+	state, ok := stoppedState, false
+	for rs, name := range snapshotStates {
+		if name == s.State {
+			state, ok = rs, true
+		}
+	}
+	if !ok {
+		return fmt.Errorf("unknown snapshot state %q", s.State)
+	}
+	if state != stoppedState && (s.PC < 0 || s.PC >= len(vm.code)) {
+		return fmt.Errorf("snapshot pc %d is out of bounds", s.PC)
+	}
+	if s.StartAt < 0 || (s.StartAt != 0 && s.StartAt >= len(vm.code)) {
+		return fmt.Errorf("snapshot start %d is out of bounds", s.StartAt)
+	}
+
+	if s.Random != nil && s.Random.Draws > maxDraws {
+		return fmt.Errorf("snapshot random draws %d is out of bounds", s.Random.Draws)
+	}
+	for i, val := range s.Stack {
+		if !asm.IsValue(val) {
+			return fmt.Errorf("snapshot stack value %d is invalid", i)
+		}
+	}
+	for name, val := range s.Locals {
+		if !asm.IsValue(val) {
+			return fmt.Errorf("snapshot local %s has an invalid value", name)
+		}
+	}
+	for name, val := range s.Variables {
+		if !vm.globals[name] {
+			return fmt.Errorf("snapshot variable %s is not used by the program", name)
+		}
+		if !asm.IsValue(val) {
+			return fmt.Errorf("snapshot variable %s has an invalid value", name)
+		}
+	}
+
+	choices := []choice{}
+	for _, c := range s.Choices {
+		if c.Dest < 0 || c.Dest >= len(vm.code) {
+			return fmt.Errorf("snapshot choice %q goes out of bounds", c.Text)
+		}
+		choices = append(choices, choice{
+			text: asm.Value{Type: asm.StringType, Val: c.Text},
+			dest: asm.Value{Type: asm.NumberType, Val: c.Dest},
+		})
+	}
+
+	vm.runState = state
+	vm.pc = s.PC
+	vm.startAt = s.StartAt
+	vm.node = s.Node
+	vm.locals = map[string]asm.Value{}
+	for name, val := range s.Locals {
+		vm.locals[name] = val
+	}
+	vm.stack = append([]asm.Value{}, s.Stack...)
+	// snapshots from before the random numbers were saved keep the VM's
+	// own random numbers
+	if s.Random != nil {
+		seedRandom(vm, s.Random.Seed)
+		for i := uint64(0); i < s.Random.Draws; i++ {
+			vm.source.Int63()
+		}
+	}
+	vm.choices = choices
+	for name := range vm.assigned {
+		if val, ok := s.Variables[name]; ok {
+			vm.variables.Set(name, val)
+		} else {
+			vm.variables.Delete(name)
+		}
+	}
+	return nil
+}
+
+// Choices gives the text of the options the VM is waiting on.
+func (vm *VM) Choices() []string {
+	if vm.runState != waitingForInputState {
+		return nil
+	}
+	optionText := []string{}
+	for _, choice := range vm.choices {
+		optionText = append(optionText, choice.text.Val.(string))
+	}
+	return optionText
+}
@@ -0,0 +1,308 @@
+package vm
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/mcvoid/dialogue/internal/program"
+	"github.com/mcvoid/dialogue/internal/types/asm"
+)
+
+func TestSnapshot(t *testing.T) {
+	prog := program.Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 3}},
+			{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "first"}},
+			{Opcode: asm.ShowLine},
+			{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "left"}},
+			{Opcode: asm.PushChoice, Arg: asm.Value{Type: asm.NumberType, Val: 6}},
+			{Opcode: asm.ShowChoice},
+			{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "x"}},
+			{Opcode: asm.EndDialogue},
+		},
+	}
+
+	lines := []string{}
+	v, _ := New(prog, HandleShowLine(func(v *VM, s string) ExecutionType {
+		lines = append(lines, s)
+		return PauseExecution
+	}))
+	v.SetVariableNull("empty")
+	if err := v.Run(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+
+	suspended, err := v.Snapshot()
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if suspended.State != "suspended" || suspended.PC != 3 || len(suspended.Stack) != 1 {
+		t.Errorf("unexpected snapshot %+v", suspended)
+	}
+	if _, ok := suspended.Variables["empty"]; ok {
+		t.Errorf("expected a variable the program doesn't use to be left out, got %v", suspended.Variables)
+	}
+
+	v.Resume()
+	waiting, err := v.Snapshot()
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if waiting.State != "waiting" || len(waiting.Choices) != 1 || waiting.Choices[0] != (SnapshotChoice{Text: "left", Dest: 6}) {
+		t.Errorf("unexpected snapshot %+v", waiting)
+	}
+
+	restored, _ := New(prog)
+	if err := restored.Restore(waiting); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if choices := restored.Choices(); len(choices) != 1 || choices[0] != "left" {
+		t.Errorf("expected to be waiting on [left] got %v", choices)
+	}
+	if err := restored.ChooseAndResume(0); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if x, _ := restored.GetVariable("x"); x.Val != 3 {
+		t.Errorf("expected the saved stack to be stored in x, got %v", x)
+	}
+	if restored.Choices() != nil {
+		t.Errorf("expected no choices once the vm has stopped")
+	}
+
+	restored, _ = New(prog)
+	restored.Restore(suspended)
+	if err := restored.Resume(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if restored.runState != waitingForInputState {
+		t.Errorf("expected the restored vm to carry on to the choice, got %v", restored.runState)
+	}
+}
+
+func TestSnapshotCollections(t *testing.T) {
+	prog := program.Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "gold"}},
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 3}},
+			{Opcode: asm.MakeMap, Arg: asm.Value{Type: asm.NumberType, Val: 1}},
+			{Opcode: asm.MakeList, Arg: asm.Value{Type: asm.NumberType, Val: 1}},
+			{Opcode: asm.StoreLocal, Arg: asm.Value{Type: asm.SymbolType, Val: "purses"}},
+			{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "line"}},
+			{Opcode: asm.ShowLine},
+			{Opcode: asm.LoadLocal, Arg: asm.Value{Type: asm.SymbolType, Val: "purses"}},
+			{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "x"}},
+			{Opcode: asm.EndDialogue},
+		},
+	}
+	v, _ := New(prog, HandleShowLine(func(v *VM, s string) ExecutionType {
+		return PauseExecution
+	}))
+	if err := v.Run(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	snapshot, err := v.Snapshot()
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	var read Snapshot
+	if err := json.Unmarshal(b, &read); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+
+	restored, _ := New(prog)
+	if err := restored.Restore(read); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := restored.Resume(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	expected := asm.NewList([]asm.Value{
+		asm.NewMap(map[string]asm.Value{"gold": {Type: asm.NumberType, Val: 3}}),
+	})
+	if x, _ := restored.GetVariable("x"); !asm.ValuesEqual(x, expected) {
+		t.Errorf("expected the saved list to be stored in x, got %v", x)
+	}
+}
+
+func TestSnapshotVariables(t *testing.T) {
+	prog := program.Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.LoadVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "quest"}},
+			{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "gold"}},
+			{Opcode: asm.EndDialogue},
+		},
+	}
+	v, _ := New(prog, WithVariables(MapStore{
+		"quest": asm.True,
+		"other": {Type: "[]string", Val: []string{"a"}},
+	}))
+	v.Run()
+	saved, err := v.Snapshot()
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if len(saved.Variables) != 1 || saved.Variables["gold"] != asm.True {
+		t.Errorf("expected only the variables the program assigns to be saved, got %v", saved.Variables)
+	}
+
+	store := MapStore{"gold": asm.False, "quest": asm.False, "other": {Type: asm.NumberType, Val: 42}}
+	restored, _ := New(prog, WithVariables(store))
+	saved.Variables = map[string]asm.Value{"gold": asm.True, "quest": asm.True}
+	if err := restored.Restore(saved); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	expected := MapStore{"gold": asm.True, "quest": asm.False, "other": {Type: asm.NumberType, Val: 42}}
+	if len(store) != len(expected) || store["gold"] != expected["gold"] || store["quest"] != expected["quest"] || store["other"] != expected["other"] {
+		t.Errorf("expected %v got %v", expected, store)
+	}
+	saved.Variables = map[string]asm.Value{}
+	if err := restored.Restore(saved); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if _, ok := store["gold"]; ok || store["quest"] != asm.False {
+		t.Errorf("expected gold to be deleted and quest to be kept, got %v", store)
+	}
+
+	saved.Variables = map[string]asm.Value{"other": asm.True}
+	if err := restored.Restore(saved); err == nil || err.Error() != "snapshot variable other is not used by the program" {
+		t.Errorf("expected a variable the program doesn't use to be refused, got %v", err)
+	}
+	saved.Variables = map[string]asm.Value{"gold": {Type: asm.StringType, Val: 1}}
+	if err := restored.Restore(saved); err == nil || err.Error() != "snapshot variable gold has an invalid value" {
+		t.Errorf("expected an invalid value to be refused, got %v", err)
+	}
+
+	store["gold"] = asm.Value{Type: "[]string", Val: []string{"a"}}
+	if _, err := restored.Snapshot(); err == nil || err.Error() != "variable gold holds a []string, which cannot be saved" {
+		t.Errorf("expected a host value to be refused, got %v", err)
+	}
+}
+
+func TestSnapshotRandom(t *testing.T) {
+	roll := []asm.Instruction{
+		{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 1}},
+		{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 1000000}},
+		{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "random"}},
+	}
+	code := append([]asm.Instruction{}, roll...)
+	code = append(code,
+		asm.Instruction{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "first"}},
+		asm.Instruction{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "pause"}},
+		asm.Instruction{Opcode: asm.ShowLine},
+	)
+	code = append(code, roll...)
+	code = append(code,
+		asm.Instruction{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "second"}},
+		asm.Instruction{Opcode: asm.EndDialogue},
+	)
+	prog := program.Program{Code: code}
+	pause := HandleShowLine(func(v *VM, s string) ExecutionType { return PauseExecution })
+
+	v, _ := New(prog, Seed(42), pause)
+	v.Run()
+	saved, err := v.Snapshot()
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	v.Resume()
+	expected, _ := v.GetVariable("second")
+
+	// the snapshot goes through JSON as it would when saved
+	b, _ := json.Marshal(saved)
+	decoded := Snapshot{}
+	json.Unmarshal(b, &decoded)
+	restored, _ := New(prog)
+	if err := restored.Restore(decoded); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	restored.Resume()
+	if actual, _ := restored.GetVariable("second"); actual != expected {
+		t.Errorf("expected the restored vm to roll %v got %v", expected, actual)
+	}
+
+	// the source is reseeded after maxDraws numbers, and a snapshot
+	// taken after that still rolls the same
+	v.source.draws = maxDraws
+	v.Reset()
+	v.Run()
+	if v.source.draws != 1 {
+		t.Fatalf("expected the source to be reseeded, got %d draws", v.source.draws)
+	}
+	saved, _ = v.Snapshot()
+	v.Resume()
+	expected, _ = v.GetVariable("second")
+	restored.Restore(saved)
+	restored.Resume()
+	if actual, _ := restored.GetVariable("second"); actual != expected {
+		t.Errorf("expected the reseeded vm to roll %v got %v", expected, actual)
+	}
+
+	// snapshots which don't save the random numbers still restore
+	saved.Random = nil
+	if err := restored.Restore(saved); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	prog := program.Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "left"}},
+			{Opcode: asm.PushChoice, Arg: asm.Value{Type: asm.NumberType, Val: 3}},
+			{Opcode: asm.ShowChoice},
+			{Opcode: asm.EndDialogue},
+		},
+	}
+	v, _ := New(prog)
+	v.Run()
+	good, _ := v.Snapshot()
+
+	for name, test := range map[string]func(s *Snapshot){
+		"format":      func(s *Snapshot) { s.Format = "something" },
+		"version":     func(s *Snapshot) { s.Version = SnapshotVersion + 1 },
+		"program":     func(s *Snapshot) { s.Program = "abc" },
+		"state":       func(s *Snapshot) { s.State = "running" },
+		"pc":          func(s *Snapshot) { s.PC = 4 },
+		"choice dest": func(s *Snapshot) { s.Choices = []SnapshotChoice{{Text: "left", Dest: -1}} },
+		"stack value": func(s *Snapshot) { s.Stack = []asm.Value{{Type: asm.StringType, Val: 1}} },
+		"local value": func(s *Snapshot) { s.Locals = map[string]asm.Value{"x": {Type: asm.NumberType, Val: "1"}} },
+		"draws":       func(s *Snapshot) { s.Random = &SnapshotRandom{Draws: math.MaxUint64} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := good
+			test(&s)
+			restored, _ := New(prog)
+			if err := restored.Restore(s); err == nil {
+				t.Errorf("expected error restoring a bad snapshot")
+			}
+			if restored.runState != stoppedState {
+				t.Errorf("expected a failed restore to leave the vm alone")
+			}
+		})
+	}
+
+	v.runState = errorState
+	if _, err := v.Snapshot(); err == nil {
+		t.Errorf("expected error snapshotting a vm in an error state")
+	}
+	v.runState = runningState
+	if _, err := v.Snapshot(); err == nil {
+		t.Errorf("expected error snapshotting a running vm")
+	}
+	bad, _ := New(program.Program{Code: []asm.Instruction{{Opcode: "Bogus"}}})
+	if _, err := bad.Snapshot(); err == nil {
+		t.Errorf("expected error snapshotting a program which can't be hashed")
+	}
+	if err := bad.Restore(good); err == nil {
+		t.Errorf("expected error restoring a program which can't be hashed")
+	}
+}
@@ -0,0 +1,132 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/mcvoid/dialogue/internal/types/asm"
+)
+
+// VariableStore holds the variables read by LoadVariable and written by
+// StoreVariable, so that they can live outside the VM.
+type VariableStore interface {
+	// Get gives the named variable and whether it exists.
+	Get(name string) (val asm.Value, ok bool)
+	// Set assigns the named variable.
+	Set(name string, val asm.Value)
+	// Delete removes the named variable.
+	Delete(name string)
+	// Range calls f for each variable until f returns false.
+	Range(f func(name string, val asm.Value) bool)
+}
+
+// VariableChange describes a write by the StoreVariable instruction which
+// changed a variable's value.
+type VariableChange struct {
+	// Name is the variable written.
+	Name string
+	// Old is the value before the write, or Null if the variable is new.
+	Old asm.Value
+	// New is the value written.
+	New asm.Value
+	// Node is the node the VM was in, or empty outside of any node.
+	Node string
+	// PC is the address of the StoreVariable instruction.
+	PC int
+}
+
+// MapStore is the VariableStore a VM uses unless given another.
+type MapStore map[string]asm.Value
+
+func (m MapStore) Get(name string) (asm.Value, bool) {
+	val, ok := m[name]
+	return val, ok
+}
+
+func (m MapStore) Set(name string, val asm.Value) {
+	m[name] = val
+}
+
+func (m MapStore) Delete(name string) {
+	delete(m, name)
+}
+
+func (m MapStore) Range(f func(name string, val asm.Value) bool) {
+	for name, val := range m {
+		if !f(name, val) {
+			return
+		}
+	}
+}
+
+// WithVariables makes the VM keep its variables in store. Pass as an
+// option to NewVM.
+func WithVariables(store VariableStore) Option {
+	return func(vm *VM) error {
+		if store == nil {
+			return fmt.Errorf("WithVariables store is null")
+		}
+		vm.variables = store
+		vm.ownsVariables = false
+		return nil
+	}
+}
+
+// storeVariable assigns a variable, telling the observers if its value
+// changed.
+func storeVariable(vm *VM, name string, val asm.Value) {
+	if len(vm.variableObservers) == 0 {
+		vm.variables.Set(name, val)
+		return
+	}
+	old, ok := vm.variables.Get(name)
+	vm.variables.Set(name, val)
+	if ok && asm.ValuesEqual(old, val) {
+		return
+	}
+	if !ok {
+		old = asm.Null
+	}
+	change := VariableChange{Name: name, Old: old, New: val, Node: vm.node, PC: vm.pc - 1}
+	for _, observer := range vm.variableObservers {
+		vm.inHost = true
+		executionType := observer(vm, change)
+		vm.inHost = false
+		if executionType == PauseExecution {
+			vm.runState = suspendedState
+		}
+	}
+}
+
+// clearVariables deletes every variable in the store.
+func clearVariables(store VariableStore) {
+	names := []string{}
+	store.Range(func(name string, val asm.Value) bool {
+		names = append(names, name)
+		return true
+	})
+	for _, name := range names {
+		store.Delete(name)
+	}
+}
+
+// scriptVariables finds the global variables a program uses, and of
+// those, the ones it assigns. The assigned ones are the only variables
+// the VM changes in a store it was given, which can hold the game's own
+// state.
+func scriptVariables(code []asm.Instruction) (used, assigned map[string]bool) {
+	used, assigned = map[string]bool{}, map[string]bool{}
+	for _, instr := range code {
+		name, ok := instr.Arg.Val.(string)
+		if !ok {
+			continue
+		}
+		switch instr.Opcode {
+		case asm.LoadVariable:
+			used[name] = true
+		case asm.StoreVariable, asm.InitVariable:
+			used[name] = true
+			assigned[name] = true
+		}
+	}
+	return used, assigned
+}
@@ -2,6 +2,8 @@ package vm
 
 import (
 	"fmt"
+	"math/rand"
+	"time"
 
 	"github.com/mcvoid/dialogue/internal/program"
 	"github.com/mcvoid/dialogue/internal/types/asm"
@@ -11,8 +13,9 @@ import (
 
 // Function is a callback for custom events fired with the Call instruction.
 type Function struct {
-	// Func is the actual code which handles the events
-	Func func(vm *VM, args ...asm.Value) ExecutionType
+	// Func is the actual code which handles the events. The value it returns
+	// is pushed onto the stack if the function is declared to return one.
+	Func func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType)
 }
 
 // ExecutionType tells the VM whether to suspend ot keep running after a callback is executed.
@@ -33,27 +36,38 @@ func New(program program.Program, options ...Option) (*VM, error) {
 	ignoreAndContinue := func(vm *VM, text string) ExecutionType { return ContinueExecution }
 	ignore := func(vm *VM) {}
 	ignoreChoice := func(vm *VM, choices []string) {}
+	abortOnFault := func(vm *VM, err RuntimeError) FaultAction { return AbortOnFault }
 
 	vm := VM{
+		program:           program,
 		code:              program.Code,
 		start:             program.Start,
 		runState:          stoppedState,
 		pc:                0,
 		stack:             []asm.Value{},
-		variables:         map[asm.Value]asm.Value{},
+		variables:         MapStore{},
+		ownsVariables:     true,
+		locals:            map[string]asm.Value{},
 		choices:           []choice{},
 		functions:         map[asm.Value]Function{},
 		prototypes:        map[asm.Value][]asm.Type{},
+		returns:           map[asm.Value]asm.Type{},
 		handleEnterNode:   ignoreAndContinue,
 		handleExitNode:    ignoreAndContinue,
 		handleShowLine:    ignoreAndContinue,
 		handleEndDialogue: ignore,
 		handleShowChoice:  ignoreChoice,
+		handleFault:       abortOnFault,
 	}
 
+	seedRandom(&vm, time.Now().UnixNano())
+	vm.globals, vm.assigned = scriptVariables(program.Code)
 	for name, proto := range program.Funcs {
 		vm.prototypes[asm.Value{Type: asm.SymbolType, Val: name}] = proto
 	}
+	for name, returnType := range program.Returns {
+		vm.returns[asm.Value{Type: asm.SymbolType, Val: name}] = returnType
+	}
 
 	for _, opt := range options {
 		if err := opt(&vm); err != nil {
@@ -119,6 +133,20 @@ func HandleShowChoice(handler func(*VM, []string)) Option {
 	}
 }
 
+// HandleVariableChange adds an observer which is told whenever the
+// StoreVariable instruction changes a variable. Every observer added is
+// told; the VM suspends if any of them returns PauseExecution.
+// Pass as an option to NewVM.
+func HandleVariableChange(handler func(*VM, VariableChange) ExecutionType) Option {
+	return func(vm *VM) error {
+		if handler == nil {
+			return fmt.Errorf("HandleVariableChange is a null handler")
+		}
+		vm.variableObservers = append(vm.variableObservers, handler)
+		return nil
+	}
+}
+
 // RegisterCallback assigns a handler for a custom event which can be fired with the Call instruction.
 func RegisterCallback(function Function) Option {
 	return func(vm *VM) error {
@@ -132,42 +160,102 @@ func RegisterCallback(function Function) Option {
 	}
 }
 
+// RegisterFunction assigns a handler for calls to a single named function,
+// replacing any handler given to RegisterCallback for that function.
+func RegisterFunction(name string, function Function) Option {
+	return func(vm *VM) error {
+		if function.Func == nil {
+			return fmt.Errorf("RegisterFunction function %s is null", name)
+		}
+		vm.functions[asm.Value{Type: asm.SymbolType, Val: name}] = function
+		return nil
+	}
+}
+
+// Seed sets the seed of the random numbers given by the random builtin,
+// so that runs can be repeated. Pass as an option to NewVM.
+func Seed(seed int64) Option {
+	return func(vm *VM) error {
+		seedRandom(vm, seed)
+		return nil
+	}
+}
+
+// maxDraws is how many numbers are drawn from a seed before the source
+// is reseeded, which keeps a restored snapshot's replay short.
+const maxDraws = 1 << 20
+
+// countingSource counts the numbers drawn from it, so that a snapshot
+// can save where the random numbers are up to.
+type countingSource struct {
+	rand.Source
+	seed  int64
+	draws uint64
+}
+
+func (s *countingSource) Int63() int64 {
+	if s.draws == maxDraws {
+		s.seed = s.Source.Int63()
+		s.Source.Seed(s.seed)
+		s.draws = 0
+	}
+	s.draws++
+	return s.Source.Int63()
+}
+
+// seedRandom starts the random numbers again from seed.
+func seedRandom(vm *VM, seed int64) {
+	vm.source = &countingSource{Source: rand.NewSource(seed), seed: seed}
+	vm.rand = rand.New(vm.source)
+}
+
 // VM is the virtual machine which runs the instructions generated by the dialogue tree.
 type VM struct {
+	program           program.Program
 	runState          runState
 	code              []asm.Instruction
 	start             int
+	startAt           int
 	pc                int
+	node              string
 	stack             []asm.Value
 	choices           []choice
-	variables         map[asm.Value]asm.Value
+	variables         VariableStore
+	ownsVariables     bool
+	globals           map[string]bool
+	assigned          map[string]bool
+	locals            map[string]asm.Value
 	functions         map[asm.Value]Function
 	prototypes        map[asm.Value][]asm.Type
+	returns           map[asm.Value]asm.Type
+	rand              *rand.Rand
+	source            *countingSource
 	handleEnterNode   func(*VM, string) ExecutionType
 	handleExitNode    func(*VM, string) ExecutionType
 	handleShowLine    func(*VM, string) ExecutionType
 	handleEndDialogue func(*VM)
 	handleShowChoice  func(*VM, []string)
+	variableObservers []func(*VM, VariableChange) ExecutionType
+	handleFault       func(*VM, RuntimeError) FaultAction
+	// inHost is set while one of the host's handlers or callbacks runs,
+	// so that a panic can be blamed on it.
+	inHost bool
 }
 
 // Run executes the program from its start point.
 // Assigned variables are persisted across runs.
 func (vm *VM) Run() error {
-	switch vm.runState {
-	case runningState:
-		fallthrough
-	case suspendedState:
-		fallthrough
-	case waitingForInputState:
-		return fmt.Errorf("cannot run a vm that is already running")
-	case errorState:
-		return fmt.Errorf("vm is in an error state - reset the vm to continue")
+	return startRun(vm, 0)
+}
+
+// RunAt executes the program from the named node instead of its start
+// point. The global variables are still given their initial values first.
+func (vm *VM) RunAt(node string) error {
+	addr, ok := vm.program.Nodes[node]
+	if !ok {
+		return fmt.Errorf("unknown node %s", node)
 	}
-	vm.runState = runningState
-	vm.pc = vm.start
-	vm.stack = []asm.Value{}
-	vm.choices = []choice{}
-	return run(vm)
+	return startRun(vm, addr)
 }
 
 // Resume continues the execution of a paused VM from the point it was paused.
@@ -180,10 +268,18 @@ func (vm *VM) Resume() error {
 }
 
 // Reset stops a VM and clears its variables so that the next time it runs,
-// it will be as if running for the first time.
+// it will be as if running for the first time. A store given with
+// WithVariables only loses the variables the program assigns.
 func (vm *VM) Reset() {
 	vm.runState = stoppedState
-	vm.variables = map[asm.Value]asm.Value{}
+	vm.locals = map[string]asm.Value{}
+	if vm.ownsVariables {
+		clearVariables(vm.variables)
+		return
+	}
+	for name := range vm.assigned {
+		vm.variables.Delete(name)
+	}
 }
 
 // ChooseAndResume will notify the VM that an option was selected and resumes from the decision point
@@ -210,42 +306,51 @@ func (vm *VM) ChooseAndResume(selectedChoice int) error {
 // instruction or by the SetVarableT() series of methods.
 // If the variable does not exist, val is Null and exists is false.
 func (vm *VM) GetVariable(name string) (val asm.Value, exists bool) {
-	sym := asm.Value{Type: asm.SymbolType, Val: name}
-	val, ok := vm.variables[sym]
+	val, ok := vm.variables.Get(name)
 	if !ok {
 		val = asm.Null
 	}
 	return val, ok
 }
 
-// SetVariableNumber stores val as a number under the given name. Name strings
-// are converted into symbol values.
+// SetVariableNumber stores val as a number under the given name.
 // Saved variables are persisted across runs.
 func (vm *VM) SetVariableNumber(name string, val int) {
-	sym := asm.Value{Type: asm.SymbolType, Val: name}
-	vm.variables[sym] = asm.Value{Type: asm.NumberType, Val: val}
+	vm.variables.Set(name, asm.Value{Type: asm.NumberType, Val: val})
+}
+
+// SetVariableFloat stores val as a fractional number under the given name.
+// Saved variables are persisted across runs.
+func (vm *VM) SetVariableFloat(name string, val float64) {
+	vm.variables.Set(name, asm.Value{Type: asm.NumberType, Val: val})
 }
 
-// SetVariableBoolean stores val as a boolean under the given name. Name strings
-// are converted into symbol values.
+// SetVariableBoolean stores val as a boolean under the given name.
 // Saved variables are persisted across runs.
 func (vm *VM) SetVariableBoolean(name string, val bool) {
-	sym := asm.Value{Type: asm.SymbolType, Val: name}
-	vm.variables[sym] = asm.Value{Type: asm.BooleanType, Val: val}
+	vm.variables.Set(name, asm.Value{Type: asm.BooleanType, Val: val})
 }
 
-// SetVariableString stores val as a string under the given name. Name strings
-// are converted into symbol values.
+// SetVariableString stores val as a string under the given name.
 // Saved variables are persisted across runs.
 func (vm *VM) SetVariableString(name string, val string) {
-	sym := asm.Value{Type: asm.SymbolType, Val: name}
-	vm.variables[sym] = asm.Value{Type: asm.StringType, Val: val}
+	vm.variables.Set(name, asm.Value{Type: asm.StringType, Val: val})
+}
+
+// SetVariableList stores items as a list under the given name.
+// Saved variables are persisted across runs.
+func (vm *VM) SetVariableList(name string, items []asm.Value) {
+	vm.variables.Set(name, asm.NewList(items))
+}
+
+// SetVariableMap stores entries as a map under the given name.
+// Saved variables are persisted across runs.
+func (vm *VM) SetVariableMap(name string, entries map[string]asm.Value) {
+	vm.variables.Set(name, asm.NewMap(entries))
 }
 
-// SetVariableNull stores a null value under the given name. Name strings
-// are converted into symbol values.
+// SetVariableNull stores a null value under the given name.
 // Saved variables are persisted across runs.
 func (vm *VM) SetVariableNull(name string) {
-	sym := asm.Value{Type: asm.SymbolType, Val: name}
-	vm.variables[sym] = asm.Null
+	vm.variables.Set(name, asm.Null)
 }
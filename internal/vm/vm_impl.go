@@ -1,6 +1,7 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/mcvoid/dialogue/internal/types/asm"
@@ -22,41 +23,39 @@ const (
 	errorState
 )
 
-var stackNeeded = map[asm.Opcode]int{
-	asm.PopValue:      1,
-	asm.PushBool:      0,
-	asm.PushNull:      0,
-	asm.PushNumber:    0,
-	asm.PushString:    0,
-	asm.GreaterThan:   2,
-	asm.Lessthan:      2,
-	asm.Concat:        2,
-	asm.And:           2,
-	asm.Or:            2,
-	asm.Not:           1,
-	asm.Equal:         2,
-	asm.Add:           2,
-	asm.Subtract:      2,
-	asm.Multiply:      2,
-	asm.Divide:        2,
-	asm.Increment:     1,
-	asm.Decrement:     1,
-	asm.LoadVariable:  0,
-	asm.StoreVariable: 1,
-	asm.ShowLine:      1,
-	asm.Jump:          0,
-	asm.JumpIfFalse:   1,
-	asm.PushChoice:    1,
-	asm.ShowChoice:    0,
-	asm.EnterNode:     0,
-	asm.ExitNode:      0,
-	asm.EndDialogue:   0,
-	asm.Call:          0,
+// startRun begins a new run. If startAt isn't zero, the run goes to that
+// address once the globals before the program's entry are initialized.
+func startRun(vm *VM, startAt int) error {
+	switch vm.runState {
+	case runningState:
+		fallthrough
+	case suspendedState:
+		fallthrough
+	case waitingForInputState:
+		return fmt.Errorf("cannot run a vm that is already running")
+	case errorState:
+		return fmt.Errorf("vm is in an error state - reset the vm to continue")
+	}
+	vm.runState = runningState
+	vm.pc = vm.start
+	vm.startAt = startAt
+	vm.node = ""
+	vm.locals = map[string]asm.Value{}
+	vm.stack = []asm.Value{}
+	vm.choices = []choice{}
+	return run(vm)
 }
 
 func run(vm *VM) error {
 	for vm.runState == runningState {
-		if err := singleStep(vm); err != nil {
+		if vm.startAt != 0 && vm.pc == vm.program.Entry {
+			vm.pc, vm.startAt = vm.startAt, 0
+		}
+		rerr, ok := step(vm).(RuntimeError)
+		if !ok {
+			continue
+		}
+		if err := recoverFrom(vm, rerr); err != nil {
 			vm.runState = errorState
 			return err
 		}
@@ -75,58 +74,132 @@ func pop(vm *VM) asm.Value {
 	return val
 }
 
+// popArgs takes a call's arguments off the stack, checking them
+// against the function's parameter types.
+func popArgs(vm *VM, funcName asm.Value, prototype []asm.Type) ([]asm.Value, error) {
+	if len(vm.stack) < len(prototype) {
+		return nil, fault(StackUnderflow, "vm stack underflow")
+	}
+	args := []asm.Value{}
+	for range prototype {
+		arg := pop(vm)
+		args = append([]asm.Value{arg}, args...)
+	}
+	for i, paramType := range prototype {
+		if args[i].Type != paramType {
+			return nil, fault(TypeMismatch, "callback %v param %d type error, expected %v got %v", funcName, i, paramType, args[i].Type)
+		}
+	}
+	return args, nil
+}
+
+// callBuiltin runs one of the VM's own functions, which always
+// leave a result on the stack.
+func callBuiltin(vm *VM, name string, builtin asm.Builtin) error {
+	args, err := popArgs(vm, asm.Value{Type: asm.SymbolType, Val: name}, builtin.Params)
+	if err != nil {
+		return err
+	}
+	result, err := builtins[name](vm, args...)
+	if err != nil {
+		return fault(BuiltinFailed, "%v", err)
+	}
+	push(vm, result)
+	return nil
+}
+
+// popNumbers takes the two numbers an arithmetic or comparison
+// instruction works on off the stack.
+func popNumbers(vm *VM) (asm.Value, asm.Value, error) {
+	val2 := pop(vm)
+	val1 := pop(vm)
+	if val1.Type != asm.NumberType || !asm.IsNumber(val1.Val) {
+		return val1, val2, fault(TypeMismatch, "value %v is not of type Number", val1)
+	}
+	if val2.Type != asm.NumberType || !asm.IsNumber(val2.Val) {
+		return val1, val2, fault(TypeMismatch, "value %v is not of type Number", val2)
+	}
+	return val1, val2, nil
+}
+
 func singleStep(vm *VM) error {
 	if vm.pc < 0 || vm.pc >= len(vm.code) {
-		return fmt.Errorf("%d: jumped to out of bounds location", vm.pc)
+		return fault(OutOfBounds, "jumped to out of bounds location")
 	}
 	instr := vm.code[vm.pc]
 	vm.pc++
 
-	if size := stackNeeded[instr.Opcode]; size > len(vm.stack) {
-		return fmt.Errorf("%d: vm stack underflow", vm.pc)
+	if _, ok := asm.Lookup(instr.Opcode); !ok {
+		return fault(InvalidInstruction, "invalid instruction: %v", instr)
+	}
+	if pops, _ := asm.StackEffect(instr); pops > len(vm.stack) {
+		return fault(StackUnderflow, "vm stack underflow")
 	}
 
 	switch instr.Opcode {
 	case asm.Call:
 		{
 			funcName := instr.Arg
-			callback, ok := vm.functions[funcName]
 			prototype, protoOk := vm.prototypes[funcName]
-			if !ok || !protoOk {
-				return fmt.Errorf("%d: callback not found: %v", vm.pc, funcName)
+			if !protoOk {
+				name, _ := funcName.Val.(string)
+				if builtin, ok := asm.Builtins[name]; ok {
+					return callBuiltin(vm, name, builtin)
+				}
 			}
-			if len(vm.stack) < len(prototype) {
-				return fmt.Errorf("%d: vm stack underflow", vm.pc)
+			callback, ok := vm.functions[funcName]
+			if !ok || !protoOk {
+				return fault(UnknownFunction, "callback not found: %v", funcName)
 			}
-			args := []asm.Value{}
-			for range prototype {
-				arg := pop(vm)
-				args = append([]asm.Value{arg}, args...)
+			args, err := popArgs(vm, funcName, prototype)
+			if err != nil {
+				return err
 			}
-			for i, paramType := range prototype {
-				if args[i].Type != paramType {
-					return fmt.Errorf("%d: callback %v param %d type error, expected %v got %v", vm.pc, funcName, i, paramType, args[i].Type)
+
+			vm.inHost = true
+			result, executionType := callback.Func(vm, args...)
+			vm.inHost = false
+			if returnType, ok := vm.returns[funcName]; ok {
+				if result.Type != returnType {
+					return fault(TypeMismatch, "callback %v return type error, expected %v got %v", funcName, returnType, result.Type)
 				}
+				push(vm, result)
 			}
-
-			if executionType := callback.Func(vm, args...); executionType == PauseExecution {
+			if executionType == PauseExecution {
 				vm.runState = suspendedState
 			}
 		}
 	case asm.EndDialogue:
+		vm.inHost = true
 		vm.handleEndDialogue(vm)
+		vm.inHost = false
 		vm.runState = stoppedState
 	case asm.EnterNode:
 		{
-			nodeName := instr.Arg.Val.(string)
-			if executionType := vm.handleEnterNode(vm, nodeName); executionType == PauseExecution {
+			nodeName, ok := instr.Arg.Val.(string)
+			if !ok {
+				return fault(InvalidInstruction, "node name %v is not a symbol", instr.Arg)
+			}
+			vm.node = nodeName
+			vm.inHost = true
+			executionType := vm.handleEnterNode(vm, nodeName)
+			vm.inHost = false
+			if executionType == PauseExecution {
 				vm.runState = suspendedState
 			}
 		}
 	case asm.ExitNode:
 		{
-			nodeName := instr.Arg.Val.(string)
-			if executionType := vm.handleExitNode(vm, nodeName); executionType == PauseExecution {
+			nodeName, ok := instr.Arg.Val.(string)
+			if !ok {
+				return fault(InvalidInstruction, "node name %v is not a symbol", instr.Arg)
+			}
+			vm.node = ""
+			vm.locals = map[string]asm.Value{}
+			vm.inHost = true
+			executionType := vm.handleExitNode(vm, nodeName)
+			vm.inHost = false
+			if executionType == PauseExecution {
 				vm.runState = suspendedState
 			}
 		}
@@ -135,16 +208,19 @@ func singleStep(vm *VM) error {
 			line := pop(vm)
 			lineText, ok := line.Val.(string)
 			if line.Type != asm.StringType || !ok {
-				return fmt.Errorf("%d: value %v is not of type string", vm.pc, line)
+				return fault(TypeMismatch, "value %v is not of type string", line)
 			}
-			if executionType := vm.handleShowLine(vm, lineText); executionType == PauseExecution {
+			vm.inHost = true
+			executionType := vm.handleShowLine(vm, lineText)
+			vm.inHost = false
+			if executionType == PauseExecution {
 				vm.runState = suspendedState
 			}
 		}
 	case asm.LoadVariable:
 		{
-			symbol := instr.Arg
-			val, ok := vm.variables[symbol]
+			name, _ := instr.Arg.Val.(string)
+			val, ok := vm.variables.Get(name)
 			if !ok {
 				val = asm.Null
 			}
@@ -152,42 +228,79 @@ func singleStep(vm *VM) error {
 		}
 	case asm.StoreVariable:
 		{
-			symbol := instr.Arg
+			name, _ := instr.Arg.Val.(string)
+			storeVariable(vm, name, pop(vm))
+		}
+	case asm.InitVariable:
+		{
+			name, _ := instr.Arg.Val.(string)
 			val := pop(vm)
-			vm.variables[symbol] = val
+			if _, ok := vm.variables.Get(name); !ok {
+				storeVariable(vm, name, val)
+			}
+		}
+	case asm.LoadLocal:
+		{
+			name, _ := instr.Arg.Val.(string)
+			val, ok := vm.locals[name]
+			if !ok {
+				val = asm.Null
+			}
+			push(vm, val)
+		}
+	case asm.StoreLocal:
+		{
+			name, _ := instr.Arg.Val.(string)
+			vm.locals[name] = pop(vm)
 		}
 	case asm.PushChoice:
 		{
 			dest := instr.Arg
 			str := pop(vm)
 			if str.Type != asm.StringType {
-				return fmt.Errorf("value %v is not of type String", str)
+				return fault(TypeMismatch, "value %v is not of type String", str)
 			}
-			if dest.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", dest)
+			if _, ok := dest.Val.(int); dest.Type != asm.NumberType || !ok {
+				return fault(InvalidInstruction, "choice destination %v is not a number", dest)
 			}
 			vm.choices = append(vm.choices, choice{text: str, dest: dest})
 		}
 	case asm.ShowChoice:
 		{
+			if len(vm.choices) == 0 {
+				// every option was guarded away, so there's nowhere to go
+				vm.inHost = true
+				vm.handleEndDialogue(vm)
+				vm.inHost = false
+				vm.runState = stoppedState
+				break
+			}
 			optionText := []string{}
 			for _, choice := range vm.choices {
 				optionText = append(optionText, string(choice.text.Val.(string)))
 			}
 			vm.runState = waitingForInputState
+			vm.inHost = true
 			vm.handleShowChoice(vm, optionText)
+			vm.inHost = false
 		}
 	case asm.Jump:
 		{
-			dest := instr.Arg.Val.(int)
+			dest, ok := instr.Arg.Val.(int)
+			if !ok {
+				return fault(InvalidInstruction, "jump destination %v is not a number", instr.Arg)
+			}
 			vm.pc = dest
 		}
 	case asm.JumpIfFalse:
 		{
-			dest := instr.Arg.Val.(int)
+			dest, ok := instr.Arg.Val.(int)
+			if !ok {
+				return fault(InvalidInstruction, "jump destination %v is not a number", instr.Arg)
+			}
 			val := pop(vm)
 			if val.Type != asm.BooleanType {
-				return fmt.Errorf("%d: value %v is not of type Boolean", vm.pc, val)
+				return fault(TypeMismatch, "value %v is not of type Boolean", val)
 			}
 			if val == asm.False {
 				vm.pc = dest
@@ -196,24 +309,24 @@ func singleStep(vm *VM) error {
 	case asm.PushNumber:
 		{
 			val := instr.Arg
-			if val.Type != asm.NumberType {
-				return fmt.Errorf("%d: value %v is not of type Number", vm.pc, val)
+			if val.Type != asm.NumberType || !asm.IsNumber(val.Val) {
+				return fault(TypeMismatch, "value %v is not of type Number", val)
 			}
 			push(vm, val)
 		}
 	case asm.PushString:
 		{
 			val := instr.Arg
-			if val.Type != asm.StringType {
-				return fmt.Errorf("%d: value %v is not of type String", vm.pc, val)
+			if _, ok := val.Val.(string); val.Type != asm.StringType || !ok {
+				return fault(TypeMismatch, "value %v is not of type String", val)
 			}
 			push(vm, val)
 		}
 	case asm.PushBool:
 		{
 			val := instr.Arg
-			if val.Type != asm.BooleanType {
-				return fmt.Errorf("%d: value %v is not of type Boolean", vm.pc, val)
+			if _, ok := val.Val.(bool); val.Type != asm.BooleanType || !ok {
+				return fault(TypeMismatch, "value %v is not of type Boolean", val)
 			}
 			push(vm, val)
 		}
@@ -226,171 +339,84 @@ func singleStep(vm *VM) error {
 	case asm.Negative:
 		{
 			val := pop(vm)
-			if val.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val)
+			if val.Type != asm.NumberType || !asm.IsNumber(val.Val) {
+				return fault(TypeMismatch, "value %v is not of type Number", val)
 			}
-			push(vm, asm.Value{Type: asm.NumberType, Val: -(val.Val.(int))})
+			result, err := asm.Negate(val.Val)
+			if err != nil {
+				return fault(NumberOutOfRange, "%v", err)
+			}
+			push(vm, asm.Value{Type: asm.NumberType, Val: result})
 		}
 	case asm.Concat:
 		{
 			val2 := pop(vm)
 			val1 := pop(vm)
-			if val1 == asm.Null {
-				val1.Val = "null"
-			}
-			if val2 == asm.Null {
-				val2.Val = "null"
-			}
-			str := fmt.Sprintf("%v%v", val1.Val, val2.Val)
+			str := asm.Format(val1.Val) + asm.Format(val2.Val)
 			push(vm, asm.Value{Type: asm.StringType, Val: str})
 		}
-	case asm.Add:
+	case asm.Add, asm.Subtract, asm.Multiply, asm.Divide, asm.Modulo:
 		{
-			val2 := pop(vm)
-			val1 := pop(vm)
-			if val1.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val1)
+			val1, val2, err := popNumbers(vm)
+			if err != nil {
+				return err
 			}
-			if val2.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val2)
+			result, err := asm.Arithmetic(instr.Opcode, val1.Val, val2.Val)
+			if errors.Is(err, asm.ErrDivideByZero) && instr.Opcode == asm.Modulo {
+				return fault(DivideByZero, "modulo by zero")
 			}
-			num1 := val1.Val.(int)
-			num2 := val2.Val.(int)
-			push(vm, asm.Value{Type: asm.NumberType, Val: num1 + num2})
-		}
-	case asm.Subtract:
-		{
-			val2 := pop(vm)
-			val1 := pop(vm)
-			if val1.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val1)
+			if errors.Is(err, asm.ErrDivideByZero) {
+				return fault(DivideByZero, "%v", err)
 			}
-			if val2.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val2)
+			if errors.Is(err, asm.ErrNumberOutOfRange) {
+				return fault(NumberOutOfRange, "%v", err)
 			}
-			num1 := val1.Val.(int)
-			num2 := val2.Val.(int)
-			push(vm, asm.Value{Type: asm.NumberType, Val: num1 - num2})
-		}
-	case asm.Multiply:
-		{
-			val2 := pop(vm)
-			val1 := pop(vm)
-			if val1.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val1)
-			}
-			if val2.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val2)
-			}
-			num1 := val1.Val.(int)
-			num2 := val2.Val.(int)
-			push(vm, asm.Value{Type: asm.NumberType, Val: num1 * num2})
-		}
-	case asm.Divide:
-		{
-			val2 := pop(vm)
-			val1 := pop(vm)
-			if val1.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val1)
-			}
-			if val2.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val2)
+			if err != nil {
+				return fault(InvalidInstruction, "%v", err)
 			}
-			num1 := val1.Val.(int)
-			num2 := val2.Val.(int)
-			push(vm, asm.Value{Type: asm.NumberType, Val: num1 / num2})
-		}
-	case asm.Modulo:
-		{
-			val2 := pop(vm)
-			val1 := pop(vm)
-			if val1.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val1)
-			}
-			if val2.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val2)
-			}
-			num1 := val1.Val.(int)
-			num2 := val2.Val.(int)
-			push(vm, asm.Value{Type: asm.NumberType, Val: num1 % num2})
+			push(vm, asm.Value{Type: asm.NumberType, Val: result})
 		}
 	case asm.Equal:
 		{
 			val2 := pop(vm)
 			val1 := pop(vm)
-			push(vm, asm.Value{Type: asm.BooleanType, Val: val1 == val2})
+			push(vm, asm.Value{Type: asm.BooleanType, Val: asm.ValuesEqual(val1, val2)})
 		}
 	case asm.NotEqual:
 		{
 			val2 := pop(vm)
 			val1 := pop(vm)
-			push(vm, asm.Value{Type: asm.BooleanType, Val: val1 != val2})
+			push(vm, asm.Value{Type: asm.BooleanType, Val: !asm.ValuesEqual(val1, val2)})
 		}
-	case asm.GreaterThan:
+	case asm.GreaterThan, asm.Lessthan, asm.GreaterThanOrEqual, asm.LessthanOrEqual:
 		{
-			val2 := pop(vm)
-			val1 := pop(vm)
-			if val1.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val1)
-			}
-			if val2.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val2)
-			}
-			num1 := val1.Val.(int)
-			num2 := val2.Val.(int)
-			push(vm, asm.Value{Type: asm.BooleanType, Val: num1 > num2})
-		}
-	case asm.Lessthan:
-		{
-			val2 := pop(vm)
-			val1 := pop(vm)
-			if val1.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val1)
+			val1, val2, err := popNumbers(vm)
+			if err != nil {
+				return err
 			}
-			if val2.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val2)
+			order := asm.CompareNumbers(val1.Val, val2.Val)
+			result := false
+			switch instr.Opcode {
+			case asm.GreaterThan:
+				result = order > 0
+			case asm.Lessthan:
+				result = order < 0
+			case asm.GreaterThanOrEqual:
+				result = order >= 0
+			case asm.LessthanOrEqual:
+				result = order <= 0
 			}
-			num1 := val1.Val.(int)
-			num2 := val2.Val.(int)
-			push(vm, asm.Value{Type: asm.BooleanType, Val: num1 < num2})
-		}
-	case asm.GreaterThanOrEqual:
-		{
-			val2 := pop(vm)
-			val1 := pop(vm)
-			if val1.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val1)
-			}
-			if val2.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val2)
-			}
-			num1 := val1.Val.(int)
-			num2 := val2.Val.(int)
-			push(vm, asm.Value{Type: asm.BooleanType, Val: num1 >= num2})
-		}
-	case asm.LessthanOrEqual:
-		{
-			val2 := pop(vm)
-			val1 := pop(vm)
-			if val1.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val1)
-			}
-			if val2.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val2)
-			}
-			num1 := val1.Val.(int)
-			num2 := val2.Val.(int)
-			push(vm, asm.Value{Type: asm.BooleanType, Val: num1 <= num2})
+			push(vm, asm.Value{Type: asm.BooleanType, Val: result})
 		}
 	case asm.And:
 		{
 			val2 := pop(vm)
 			val1 := pop(vm)
 			if val1.Type != asm.BooleanType {
-				return fmt.Errorf("value %v is not of type Boolean", val1)
+				return fault(TypeMismatch, "value %v is not of type Boolean", val1)
 			}
 			if val2.Type != asm.BooleanType {
-				return fmt.Errorf("value %v is not of type Boolean", val2)
+				return fault(TypeMismatch, "value %v is not of type Boolean", val2)
 			}
 			bool1 := val1.Val.(bool)
 			bool2 := val2.Val.(bool)
@@ -401,10 +427,10 @@ func singleStep(vm *VM) error {
 			val2 := pop(vm)
 			val1 := pop(vm)
 			if val1.Type != asm.BooleanType {
-				return fmt.Errorf("value %v is not of type Boolean", val1)
+				return fault(TypeMismatch, "value %v is not of type Boolean", val1)
 			}
 			if val2.Type != asm.BooleanType {
-				return fmt.Errorf("value %v is not of type Boolean", val2)
+				return fault(TypeMismatch, "value %v is not of type Boolean", val2)
 			}
 			bool1 := val1.Val.(bool)
 			bool2 := val2.Val.(bool)
@@ -414,32 +440,103 @@ func singleStep(vm *VM) error {
 		{
 			val := pop(vm)
 			if val.Type != asm.BooleanType {
-				return fmt.Errorf("value %v is not of type Boolean", val)
+				return fault(TypeMismatch, "value %v is not of type Boolean", val)
 			}
 			bool1 := val.Val.(bool)
 			push(vm, asm.Value{Type: asm.BooleanType, Val: !bool1})
 		}
-	case asm.Increment:
+	case asm.Increment, asm.Decrement:
 		{
 			val := pop(vm)
-			if val.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val)
+			if val.Type != asm.NumberType || !asm.IsNumber(val.Val) {
+				return fault(TypeMismatch, "value %v is not of type Number", val)
+			}
+			step := 1
+			if instr.Opcode == asm.Decrement {
+				step = -1
+			}
+			result, err := asm.Arithmetic(asm.Add, val.Val, step)
+			if err != nil {
+				return fault(NumberOutOfRange, "%v", err)
+			}
+			push(vm, asm.Value{Type: asm.NumberType, Val: result})
+		}
+	case asm.MakeList:
+		{
+			n, ok := instr.Arg.Val.(int)
+			if !ok || n < 0 {
+				return fault(InvalidInstruction, "list length %v is not a count", instr.Arg)
+			}
+			items := append([]asm.Value{}, vm.stack[len(vm.stack)-n:]...)
+			vm.stack = vm.stack[:len(vm.stack)-n]
+			push(vm, asm.NewList(items))
+		}
+	case asm.MakeMap:
+		{
+			n, ok := instr.Arg.Val.(int)
+			if !ok || n < 0 {
+				return fault(InvalidInstruction, "map length %v is not a count", instr.Arg)
+			}
+			pairs := vm.stack[len(vm.stack)-2*n:]
+			vm.stack = vm.stack[:len(vm.stack)-2*n]
+			entries := map[string]asm.Value{}
+			for i := 0; i < len(pairs); i += 2 {
+				key, ok := pairs[i].Val.(string)
+				if pairs[i].Type != asm.StringType || !ok {
+					return fault(TypeMismatch, "map key %v is not of type String", pairs[i])
+				}
+				entries[key] = pairs[i+1]
 			}
-			num := val.Val.(int)
-			push(vm, asm.Value{Type: asm.NumberType, Val: num + 1})
+			push(vm, asm.NewMap(entries))
 		}
-	case asm.Decrement:
+	case asm.Index:
+		{
+			key := pop(vm)
+			container := pop(vm)
+			val, err := asm.GetIndex(container, key)
+			if err != nil {
+				return indexFault(err)
+			}
+			push(vm, val)
+		}
+	case asm.SetIndex:
 		{
 			val := pop(vm)
-			if val.Type != asm.NumberType {
-				return fmt.Errorf("value %v is not of type Number", val)
+			key := pop(vm)
+			container := pop(vm)
+			result, err := asm.PutIndex(container, key, val)
+			if err != nil {
+				return indexFault(err)
+			}
+			push(vm, result)
+		}
+	case asm.Iterate:
+		{
+			items, err := asm.LoopItems(pop(vm))
+			if err != nil {
+				return fault(TypeMismatch, "%v", err)
+			}
+			push(vm, items)
+		}
+	case asm.Length:
+		{
+			n, err := asm.Size(pop(vm))
+			if err != nil {
+				return fault(TypeMismatch, "%v", err)
 			}
-			num := val.Val.(int)
-			push(vm, asm.Value{Type: asm.NumberType, Val: num - 1})
+			push(vm, number(n))
 		}
 	default:
-		return fmt.Errorf("%d: invalid instruction: %v", vm.pc, instr)
+		return fault(InvalidInstruction, "invalid instruction: %v", instr)
 	}
 
 	return nil
 }
+
+// indexFault is the fault for a list or map which couldn't be indexed.
+func indexFault(err error) error {
+	if errors.Is(err, asm.ErrIndexOutOfRange) {
+		return fault(IndexOutOfRange, "%v", err)
+	}
+	return fault(TypeMismatch, "%v", err)
+}
@@ -2,6 +2,7 @@ package vm
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/mcvoid/dialogue/internal/program"
@@ -428,6 +429,30 @@ func TestOpcodes(t *testing.T) {
 			},
 			[]asm.Value{{Type: asm.NumberType, Val: -16}},
 		},
+		{
+			[]asm.Instruction{
+				{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 1}},
+				{Opcode: asm.InitVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "a"}},
+				{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 2}},
+				{Opcode: asm.InitVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "a"}},
+				{Opcode: asm.LoadVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "a"}},
+				{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+			},
+			[]asm.Value{{Type: asm.NumberType, Val: 1}},
+		},
+		{
+			[]asm.Instruction{
+				{Opcode: asm.LoadLocal, Arg: asm.Value{Type: asm.SymbolType, Val: "a"}},
+				{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 3}},
+				{Opcode: asm.StoreLocal, Arg: asm.Value{Type: asm.SymbolType, Val: "a"}},
+				{Opcode: asm.LoadLocal, Arg: asm.Value{Type: asm.SymbolType, Val: "a"}},
+				{Opcode: asm.LoadVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "a"}},
+				{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "node"}},
+				{Opcode: asm.LoadLocal, Arg: asm.Value{Type: asm.SymbolType, Val: "a"}},
+				{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+			},
+			[]asm.Value{asm.Null, {Type: asm.NumberType, Val: 3}, asm.Null, asm.Null},
+		},
 	}
 	for i, test := range tests {
 		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
@@ -631,6 +656,26 @@ func TestOpcodeErrors(t *testing.T) {
 			{Opcode: asm.Negative, Arg: asm.Value{}},
 			{Opcode: asm.EndDialogue, Arg: asm.Value{}},
 		},
+		{
+			{Opcode: asm.Negative, Arg: asm.Value{}},
+			{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+		},
+		{
+			{Opcode: asm.Modulo, Arg: asm.Value{}},
+			{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+		},
+		{
+			{Opcode: asm.NotEqual, Arg: asm.Value{}},
+			{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+		},
+		{
+			{Opcode: asm.GreaterThanOrEqual, Arg: asm.Value{}},
+			{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+		},
+		{
+			{Opcode: asm.LessthanOrEqual, Arg: asm.Value{}},
+			{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+		},
 	}
 	for i, test := range tests {
 		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
@@ -801,6 +846,31 @@ func TestVMPushChoice(t *testing.T) {
 	}
 }
 
+func TestVMShowNoChoices(t *testing.T) {
+	prog := program.Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.ShowChoice, Arg: asm.Value{}},
+			{Opcode: asm.PushNull, Arg: asm.Value{}},
+			{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+		},
+	}
+	choiceHandled, ended := false, false
+	vm, _ := New(prog,
+		HandleShowChoice(func(v *VM, s []string) { choiceHandled = true }),
+		HandleEndDialogue(func(v *VM) { ended = true }),
+	)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if choiceHandled || !ended {
+		t.Errorf("expected an empty choice to end the dialogue")
+	}
+	if vm.runState != stoppedState || len(vm.stack) != 0 {
+		t.Errorf("expected the vm to stop at the choice, got state %v stack %v", vm.runState, vm.stack)
+	}
+}
+
 func TestVmCall(t *testing.T) {
 	prog := program.Program{
 		Start: 0,
@@ -824,11 +894,11 @@ func TestVmCall(t *testing.T) {
 	}{
 		{
 			Function{
-				func(vm *VM, args ...asm.Value) ExecutionType {
+				func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) {
 					if len(args) != 0 {
 						t.Errorf("Wrong number of params, expected %v got %v", 0, len(args))
 					}
-					return ContinueExecution
+					return asm.Null, ContinueExecution
 				},
 			},
 			[]asm.Type{},
@@ -838,11 +908,11 @@ func TestVmCall(t *testing.T) {
 		},
 		{
 			Function{
-				func(vm *VM, args ...asm.Value) ExecutionType {
+				func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) {
 					if len(args) != 0 {
 						t.Errorf("Wrong number of params, expected %v got %v", 0, len(args))
 					}
-					return ContinueExecution
+					return asm.Null, ContinueExecution
 				},
 			},
 			[]asm.Type{},
@@ -852,11 +922,11 @@ func TestVmCall(t *testing.T) {
 		},
 		{
 			Function{
-				func(vm *VM, args ...asm.Value) ExecutionType {
+				func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) {
 					if len(args) != 0 {
 						t.Errorf("Wrong number of params, expected %v got %v", 0, len(args))
 					}
-					return PauseExecution
+					return asm.Null, PauseExecution
 				},
 			},
 			[]asm.Type{},
@@ -866,9 +936,9 @@ func TestVmCall(t *testing.T) {
 		},
 		{
 			Function{
-				func(vm *VM, args ...asm.Value) ExecutionType {
+				func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) {
 					t.Errorf("Did not expect callback invoked")
-					return ContinueExecution
+					return asm.Null, ContinueExecution
 				},
 			},
 			[]asm.Type{asm.NumberType, asm.NumberType, asm.NumberType, asm.NumberType, asm.NumberType},
@@ -878,9 +948,9 @@ func TestVmCall(t *testing.T) {
 		},
 		{
 			Function{
-				func(vm *VM, args ...asm.Value) ExecutionType {
+				func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) {
 					t.Errorf("Did not expect callback invoked")
-					return ContinueExecution
+					return asm.Null, ContinueExecution
 				},
 			},
 			[]asm.Type{asm.NumberType, asm.NumberType, asm.NumberType, asm.NumberType},
@@ -890,9 +960,9 @@ func TestVmCall(t *testing.T) {
 		},
 		{
 			Function{
-				func(vm *VM, args ...asm.Value) ExecutionType {
+				func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) {
 					t.Errorf("Did not expect callback invoked")
-					return ContinueExecution
+					return asm.Null, ContinueExecution
 				},
 			},
 			[]asm.Type{asm.NumberType, asm.NumberType, asm.NumberType, asm.NumberType},
@@ -902,12 +972,12 @@ func TestVmCall(t *testing.T) {
 		},
 		{
 			Function{
-				func(vm *VM, args ...asm.Value) ExecutionType {
+				func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) {
 					expectedArgs := []asm.Value{asm.Null}
 					if !compareValue(expectedArgs, args) {
 						t.Errorf("Wrong params, expected %v, got %v", expectedArgs, args)
 					}
-					return ContinueExecution
+					return asm.Null, ContinueExecution
 				},
 			},
 			[]asm.Type{asm.NullType},
@@ -917,12 +987,12 @@ func TestVmCall(t *testing.T) {
 		},
 		{
 			Function{
-				func(vm *VM, args ...asm.Value) ExecutionType {
+				func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) {
 					expectedArgs := []asm.Value{{Type: asm.NumberType, Val: 17}, asm.Null}
 					if !compareValue(expectedArgs, args) {
 						t.Errorf("Wrong params, expected %v, got %v", expectedArgs, args)
 					}
-					return ContinueExecution
+					return asm.Null, ContinueExecution
 				},
 			},
 			[]asm.Type{asm.NumberType, asm.NullType},
@@ -932,12 +1002,12 @@ func TestVmCall(t *testing.T) {
 		},
 		{
 			Function{
-				func(vm *VM, args ...asm.Value) ExecutionType {
+				func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) {
 					expectedArgs := []asm.Value{{Type: asm.BooleanType, Val: true}, {Type: asm.NumberType, Val: 17}, asm.Null}
 					if !compareValue(expectedArgs, args) {
 						t.Errorf("Wrong params, expected %v, got %v", expectedArgs, args)
 					}
-					return ContinueExecution
+					return asm.Null, ContinueExecution
 				},
 			},
 			[]asm.Type{asm.BooleanType, asm.NumberType, asm.NullType},
@@ -947,12 +1017,12 @@ func TestVmCall(t *testing.T) {
 		},
 		{
 			Function{
-				func(vm *VM, args ...asm.Value) ExecutionType {
+				func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) {
 					expectedArgs := []asm.Value{{Type: asm.StringType, Val: "abc"}, {Type: asm.BooleanType, Val: true}, {Type: asm.NumberType, Val: 17}, asm.Null}
 					if !compareValue(expectedArgs, args) {
 						t.Errorf("Wrong params, expected %v, got %v", expectedArgs, args)
 					}
-					return ContinueExecution
+					return asm.Null, ContinueExecution
 				},
 			},
 			[]asm.Type{asm.StringType, asm.BooleanType, asm.NumberType, asm.NullType},
@@ -962,12 +1032,12 @@ func TestVmCall(t *testing.T) {
 		},
 		{
 			Function{
-				func(vm *VM, args ...asm.Value) ExecutionType {
+				func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) {
 					expectedArgs := []asm.Value{{Type: asm.StringType, Val: "abc"}, {Type: asm.BooleanType, Val: true}, {Type: asm.NumberType, Val: 17}, asm.Null}
 					if !compareValue(expectedArgs, args) {
 						t.Errorf("Wrong params, expected %v, got %v", expectedArgs, args)
 					}
-					return PauseExecution
+					return asm.Null, PauseExecution
 				},
 			},
 			[]asm.Type{asm.StringType, asm.BooleanType, asm.NumberType, asm.NullType},
@@ -993,3 +1063,366 @@ func TestVmCall(t *testing.T) {
 		})
 	}
 }
+
+func TestVmCallReturn(t *testing.T) {
+	prog := program.Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 17}},
+			{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "func"}},
+			{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+		},
+		Funcs: map[string][]asm.Type{
+			"func": {asm.NumberType},
+		},
+	}
+
+	tests := map[string]struct {
+		returnType    asm.Type
+		hasReturn     bool
+		result        asm.Value
+		expectedStack []asm.Value
+		expectedErr   bool
+	}{
+		"no return type": {
+			"", false,
+			asm.Value{Type: asm.NumberType, Val: 5},
+			[]asm.Value{},
+			false,
+		},
+		"returns value": {
+			asm.NumberType, true,
+			asm.Value{Type: asm.NumberType, Val: 5},
+			[]asm.Value{{Type: asm.NumberType, Val: 5}},
+			false,
+		},
+		"wrong return type": {
+			asm.BooleanType, true,
+			asm.Value{Type: asm.NumberType, Val: 5},
+			[]asm.Value{},
+			true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			testProg := prog
+			testProg.Returns = map[string]asm.Type{}
+			if test.hasReturn {
+				testProg.Returns["func"] = test.returnType
+			}
+			vm, _ := New(testProg, RegisterFunction("func", Function{
+				func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) {
+					return test.result, ContinueExecution
+				},
+			}))
+			vm.handleEndDialogue = func(*VM) {}
+			err := vm.Run()
+			if (err != nil) != test.expectedErr {
+				t.Errorf("unexpected error %v", err)
+			}
+			if !test.expectedErr && !compareValue(test.expectedStack, vm.stack) {
+				t.Errorf("expected stack %v got %v", test.expectedStack, vm.stack)
+			}
+		})
+	}
+}
+
+func TestVmBuiltins(t *testing.T) {
+	str := func(s string) asm.Value { return asm.Value{Type: asm.StringType, Val: s} }
+	num := func(n int) asm.Value { return asm.Value{Type: asm.NumberType, Val: n} }
+
+	for name, test := range map[string]struct {
+		function    string
+		args        []asm.Value
+		expected    asm.Value
+		expectedErr bool
+	}{
+		"length":            {"length", []asm.Value{str("héllo")}, num(5), false},
+		"upper":             {"upper", []asm.Value{str("abc")}, str("ABC"), false},
+		"lower":             {"lower", []asm.Value{str("ABC")}, str("abc"), false},
+		"min":               {"min", []asm.Value{num(3), num(-2)}, num(-2), false},
+		"max":               {"max", []asm.Value{num(3), num(-2)}, num(3), false},
+		"abs":               {"abs", []asm.Value{num(-7)}, num(7), false},
+		"clamp low":         {"clamp", []asm.Value{num(-7), num(0), num(10)}, num(0), false},
+		"clamp high":        {"clamp", []asm.Value{num(17), num(0), num(10)}, num(10), false},
+		"clamp within":      {"clamp", []asm.Value{num(7), num(0), num(10)}, num(7), false},
+		"clamp empty range": {"clamp", []asm.Value{num(7), num(10), num(0)}, asm.Null, true},
+		"random single":     {"random", []asm.Value{num(4), num(4)}, num(4), false},
+		"random empty":      {"random", []asm.Value{num(5), num(4)}, asm.Null, true},
+		"to_string":         {"to_string", []asm.Value{num(-12)}, str("-12"), false},
+		"wrong type":        {"upper", []asm.Value{num(1)}, asm.Null, true},
+		"underflow":         {"min", []asm.Value{num(1)}, asm.Null, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			code := []asm.Instruction{}
+			for _, arg := range test.args {
+				opcode := asm.PushNumber
+				if arg.Type == asm.StringType {
+					opcode = asm.PushString
+				}
+				code = append(code, asm.Instruction{Opcode: opcode, Arg: arg})
+			}
+			code = append(code,
+				asm.Instruction{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: test.function}},
+				asm.Instruction{Opcode: asm.EndDialogue},
+			)
+			vm, _ := New(program.Program{Code: code})
+			err := vm.Run()
+			if (err != nil) != test.expectedErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if test.expectedErr {
+				return
+			}
+			if len(vm.stack) != 1 || vm.stack[0] != test.expected {
+				t.Errorf("expected %v got %v", test.expected, vm.stack)
+			}
+		})
+	}
+}
+
+func TestVmBuiltinRanges(t *testing.T) {
+	num := func(n int) asm.Value { return asm.Value{Type: asm.NumberType, Val: n} }
+
+	for name, test := range map[string]struct {
+		function string
+		args     []asm.Value
+		message  string
+	}{
+		"abs of the smallest int": {"abs", []asm.Value{num(math.MinInt)}, fmt.Sprintf("abs of %d is out of range", math.MinInt)},
+		"random too wide":         {"random", []asm.Value{num(0), num(math.MaxInt)}, fmt.Sprintf("random range 0 to %d is too wide", math.MaxInt)},
+		"random widest":           {"random", []asm.Value{num(math.MinInt), num(math.MaxInt)}, fmt.Sprintf("random range %d to %d is too wide", math.MinInt, math.MaxInt)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			code := []asm.Instruction{}
+			for _, arg := range test.args {
+				code = append(code, asm.Instruction{Opcode: asm.PushNumber, Arg: arg})
+			}
+			code = append(code,
+				asm.Instruction{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: test.function}},
+				asm.Instruction{Opcode: asm.EndDialogue},
+			)
+			vm, _ := New(program.Program{Code: code})
+			err, ok := vm.Run().(RuntimeError)
+			if !ok || err.Code != BuiltinFailed || err.Err.Error() != test.message {
+				t.Errorf("expected a builtin fault %q got %v", test.message, err)
+			}
+		})
+	}
+
+	// the widest range that can be drawn from
+	vm, _ := New(program.Program{Code: []asm.Instruction{
+		{Opcode: asm.PushNumber, Arg: num(1)},
+		{Opcode: asm.PushNumber, Arg: num(math.MaxInt)},
+		{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "random"}},
+		{Opcode: asm.EndDialogue},
+	}})
+	if err := vm.Run(); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+}
+
+func TestVmRandom(t *testing.T) {
+	prog := program.Program{
+		Code: []asm.Instruction{
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 1}},
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 6}},
+			{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "random"}},
+			{Opcode: asm.EndDialogue},
+		},
+	}
+	rolls := func() []asm.Value {
+		vm, _ := New(prog, Seed(42))
+		for i := 0; i < 20; i++ {
+			if err := vm.Run(); err != nil {
+				t.Fatalf("no error expected, got %v", err)
+			}
+		}
+		return vm.stack
+	}
+
+	first := rolls()
+	for _, roll := range first {
+		if n := roll.Val.(int); n < 1 || n > 6 {
+			t.Errorf("expected a roll from 1 to 6, got %v", n)
+		}
+	}
+	if second := rolls(); !compareValue(first, second) {
+		t.Errorf("expected the same seed to give the same rolls, got %v and %v", first, second)
+	}
+}
+
+func TestVmExternShadowsBuiltin(t *testing.T) {
+	prog := program.Program{
+		Code: []asm.Instruction{
+			{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "abc"}},
+			{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "upper"}},
+			{Opcode: asm.EndDialogue},
+		},
+		Funcs: map[string][]asm.Type{
+			"upper": {asm.StringType},
+		},
+	}
+	called := false
+	vm, _ := New(prog, RegisterFunction("upper", Function{
+		func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) {
+			called = true
+			return asm.Null, ContinueExecution
+		},
+	}))
+	if err := vm.Run(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if !called || len(vm.stack) != 0 {
+		t.Errorf("expected the extern to be called instead of the builtin")
+	}
+}
+
+func TestVmCollections(t *testing.T) {
+	num := func(n int) asm.Value {
+		return asm.Value{Type: asm.NumberType, Val: n}
+	}
+	str := func(s string) asm.Value {
+		return asm.Value{Type: asm.StringType, Val: s}
+	}
+	end := asm.Instruction{Opcode: asm.EndDialogue}
+
+	for name, test := range map[string]struct {
+		code     []asm.Instruction
+		expected asm.Value
+	}{
+		"make list": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushNumber, Arg: num(1)},
+				{Opcode: asm.PushString, Arg: str("a")},
+				{Opcode: asm.MakeList, Arg: num(2)},
+				end,
+			},
+			expected: asm.NewList([]asm.Value{num(1), str("a")}),
+		},
+		"make empty list": {
+			code:     []asm.Instruction{{Opcode: asm.MakeList, Arg: num(0)}, end},
+			expected: asm.NewList([]asm.Value{}),
+		},
+		"make map": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushString, Arg: str("gold")},
+				{Opcode: asm.PushNumber, Arg: num(3)},
+				{Opcode: asm.MakeMap, Arg: num(1)},
+				end,
+			},
+			expected: asm.NewMap(map[string]asm.Value{"gold": num(3)}),
+		},
+		"index": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushString, Arg: str("a")},
+				{Opcode: asm.PushString, Arg: str("b")},
+				{Opcode: asm.MakeList, Arg: num(2)},
+				{Opcode: asm.PushNumber, Arg: num(1)},
+				{Opcode: asm.Index},
+				end,
+			},
+			expected: str("b"),
+		},
+		"set index": {
+			code: []asm.Instruction{
+				{Opcode: asm.MakeList, Arg: num(0)},
+				{Opcode: asm.PushNumber, Arg: num(0)},
+				{Opcode: asm.PushString, Arg: str("a")},
+				{Opcode: asm.SetIndex},
+				end,
+			},
+			expected: asm.NewList([]asm.Value{str("a")}),
+		},
+		"iterate map": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushString, Arg: str("b")},
+				{Opcode: asm.PushNull},
+				{Opcode: asm.PushString, Arg: str("a")},
+				{Opcode: asm.PushNull},
+				{Opcode: asm.MakeMap, Arg: num(2)},
+				{Opcode: asm.Iterate},
+				end,
+			},
+			expected: asm.NewList([]asm.Value{str("a"), str("b")}),
+		},
+		"length": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushNull},
+				{Opcode: asm.PushNull},
+				{Opcode: asm.MakeList, Arg: num(2)},
+				{Opcode: asm.Length},
+				end,
+			},
+			expected: num(2),
+		},
+		"count builtin": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushNull},
+				{Opcode: asm.MakeList, Arg: num(1)},
+				{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "count"}},
+				end,
+			},
+			expected: num(1),
+		},
+		"keys builtin": {
+			code: []asm.Instruction{
+				{Opcode: asm.PushString, Arg: str("gold")},
+				{Opcode: asm.PushNumber, Arg: num(3)},
+				{Opcode: asm.MakeMap, Arg: num(1)},
+				{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "keys"}},
+				end,
+			},
+			expected: asm.NewList([]asm.Value{str("gold")}),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			vm, _ := New(program.Program{Code: test.code})
+			if err := vm.Run(); err != nil {
+				t.Fatalf("no error expected got %v", err)
+			}
+			if len(vm.stack) != 1 || !asm.ValuesEqual(vm.stack[0], test.expected) {
+				t.Errorf("expected [%v] got %v", test.expected, vm.stack)
+			}
+		})
+	}
+
+	for name, code := range map[string][]asm.Instruction{
+		"number map key": {
+			{Opcode: asm.PushNumber, Arg: num(1)},
+			{Opcode: asm.PushNull},
+			{Opcode: asm.MakeMap, Arg: num(1)},
+			end,
+		},
+		"make list underflow": {
+			{Opcode: asm.PushNull},
+			{Opcode: asm.MakeList, Arg: num(2)},
+			end,
+		},
+		"iterate a string": {
+			{Opcode: asm.PushString, Arg: str("abc")},
+			{Opcode: asm.Iterate},
+			end,
+		},
+		"length of null": {
+			{Opcode: asm.PushNull},
+			{Opcode: asm.Length},
+			end,
+		},
+		"set index past end": {
+			{Opcode: asm.MakeList, Arg: num(0)},
+			{Opcode: asm.PushNumber, Arg: num(1)},
+			{Opcode: asm.PushNull},
+			{Opcode: asm.SetIndex},
+			end,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			vm, _ := New(program.Program{Code: code})
+			if err := vm.Run(); err == nil {
+				t.Error("error expected on incorrect program")
+			}
+		})
+	}
+}
@@ -118,7 +118,7 @@ func TestHandlers(t *testing.T) {
 	p.Funcs["callback"] = []asm.Type{}
 	_, err = New(
 		p,
-		RegisterCallback(Function{func(vm *VM, args ...asm.Value) ExecutionType { return ContinueExecution }}),
+		RegisterCallback(Function{func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) { return asm.Null, ContinueExecution }}),
 	)
 	if err != nil {
 		t.Error("No error expected when supplied valid handler")
@@ -133,7 +133,7 @@ func TestHandlers(t *testing.T) {
 		HandleShowLine(func(v *VM, s string) ExecutionType { return ContinueExecution }),
 		HandleEndDialogue(nil),
 		HandleShowChoice(func(v *VM, s []string) {}),
-		RegisterCallback(Function{func(vm *VM, args ...asm.Value) ExecutionType { return ContinueExecution }}),
+		RegisterCallback(Function{func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) { return asm.Null, ContinueExecution }}),
 	)
 	if err == nil {
 		t.Error("Expected error when supplied invalid handler")
@@ -148,13 +148,46 @@ func TestHandlers(t *testing.T) {
 		HandleShowLine(func(v *VM, s string) ExecutionType { return ContinueExecution }),
 		HandleEndDialogue(func(v *VM) {}),
 		HandleShowChoice(func(v *VM, s []string) {}),
-		RegisterCallback(Function{func(vm *VM, args ...asm.Value) ExecutionType { return ContinueExecution }}),
+		RegisterCallback(Function{func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) { return asm.Null, ContinueExecution }}),
 	)
 	if err != nil {
 		t.Error("No error expected when supplied valid handler")
 	}
 }
 
+func TestRegisterFunction(t *testing.T) {
+	_, err := New(emptyProgram, RegisterFunction("callback", Function{nil}))
+	if err == nil {
+		t.Error("Expected error when supplied invalid handler")
+	}
+
+	p := program.Program{
+		Code: []asm.Instruction{
+			{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "abc"}},
+			{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "def"}},
+			{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+		},
+		Funcs: map[string][]asm.Type{"abc": {}, "def": {}},
+	}
+	calls := []string{}
+	record := func(name string) Function {
+		return Function{func(vm *VM, args ...asm.Value) (asm.Value, ExecutionType) {
+			calls = append(calls, name)
+			return asm.Null, ContinueExecution
+		}}
+	}
+	vm, err := New(p, RegisterCallback(record("any")), RegisterFunction("def", record("def")))
+	if err != nil {
+		t.Fatalf("No error expected when supplied valid handler, got %v", err)
+	}
+	if err := vm.Run(); err != nil {
+		t.Fatalf("No error expected, got %v", err)
+	}
+	if len(calls) != 2 || calls[0] != "any" || calls[1] != "def" {
+		t.Errorf("expected calls [any def] got %v", calls)
+	}
+}
+
 func TestVmRun(t *testing.T) {
 	vm, _ := New(emptyProgram)
 	vm.runState = runningState
@@ -222,6 +255,48 @@ func TestVmRun(t *testing.T) {
 	}
 }
 
+func TestVmRunAt(t *testing.T) {
+	p := program.Program{
+		Code: []asm.Instruction{
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 1}},
+			{Opcode: asm.InitVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "x"}},
+			{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "start"}},
+			{Opcode: asm.EndDialogue},
+			{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "other"}},
+			{Opcode: asm.EndDialogue},
+		},
+		Entry: 2,
+		Nodes: map[string]int{"start": 2, "other": 4},
+	}
+	entered := []string{}
+	vm, _ := New(p, HandleEnterNode(func(vm *VM, node string) ExecutionType {
+		entered = append(entered, node)
+		return ContinueExecution
+	}))
+
+	if err := vm.RunAt("nowhere"); err == nil || err.Error() != "unknown node nowhere" {
+		t.Errorf("Expected unknown node error, got %v", err)
+	}
+
+	if err := vm.RunAt("other"); err != nil {
+		t.Errorf("No error expected, got %v", err)
+	}
+	if len(entered) != 1 || entered[0] != "other" {
+		t.Errorf("Expected to enter only other, got %v", entered)
+	}
+	if x, ok := vm.GetVariable("x"); !ok || x.Val != 1 {
+		t.Errorf("Expected globals to be initialized, got %v", x)
+	}
+
+	entered = []string{}
+	if err := vm.Run(); err != nil {
+		t.Errorf("No error expected, got %v", err)
+	}
+	if len(entered) != 1 || entered[0] != "start" {
+		t.Errorf("Expected Run to enter start, got %v", entered)
+	}
+}
+
 func TestVmResume(t *testing.T) {
 	vm, _ := New(emptyProgram)
 
@@ -307,7 +382,7 @@ func TestVmReset(t *testing.T) {
 	vm.SetVariableNumber("bcd", 15)
 	vm.SetVariableString("cde", "cde")
 	vm.Reset()
-	if len(vm.variables) != 0 {
+	if len(vm.variables.(MapStore)) != 0 {
 		t.Error("Expected Reset to clear variables")
 	}
 }
@@ -443,7 +518,7 @@ func TestVmGetVariable(t *testing.T) {
 	}
 
 	abc := asm.Value{Type: asm.NumberType, Val: 1}
-	vm.variables[asm.Value{Type: asm.SymbolType, Val: "abc"}] = abc
+	vm.variables.Set("abc", abc)
 	val, ok = vm.GetVariable("abc")
 	if val != abc {
 		t.Errorf("Expected %v got %v", abc, val)
@@ -462,10 +537,10 @@ func TestVmGetVariable(t *testing.T) {
 
 func TestVmSetVariableNumber(t *testing.T) {
 	vm := VM{}
-	vm.variables = map[asm.Value]asm.Value{}
+	vm.variables = MapStore{}
 
 	vm.SetVariableNumber("abc", 5)
-	val, ok := vm.variables[asm.Value{Type: asm.SymbolType, Val: "abc"}]
+	val, ok := vm.variables.Get("abc")
 	if !ok {
 		t.Error("Expected added value to be in variables")
 	}
@@ -475,12 +550,27 @@ func TestVmSetVariableNumber(t *testing.T) {
 	}
 }
 
+func TestVmSetVariableFloat(t *testing.T) {
+	vm := VM{}
+	vm.variables = MapStore{}
+
+	vm.SetVariableFloat("abc", 2.5)
+	val, ok := vm.variables.Get("abc")
+	if !ok {
+		t.Error("Expected added value to be in variables")
+	}
+	expected := asm.Value{Type: asm.NumberType, Val: 2.5}
+	if val != expected {
+		t.Errorf("Expected %v got %v", expected, val)
+	}
+}
+
 func TestVmSetVariableBoolean(t *testing.T) {
 	vm := VM{}
-	vm.variables = map[asm.Value]asm.Value{}
+	vm.variables = MapStore{}
 
 	vm.SetVariableBoolean("abc", true)
-	val, ok := vm.variables[asm.Value{Type: asm.SymbolType, Val: "abc"}]
+	val, ok := vm.variables.Get("abc")
 	if !ok {
 		t.Error("Expected added value to be in variables")
 	}
@@ -490,7 +580,7 @@ func TestVmSetVariableBoolean(t *testing.T) {
 	}
 
 	vm.SetVariableBoolean("abc", false)
-	val, ok = vm.variables[asm.Value{Type: asm.SymbolType, Val: "abc"}]
+	val, ok = vm.variables.Get("abc")
 	if !ok {
 		t.Error("Expected added value to be in variables")
 	}
@@ -502,10 +592,10 @@ func TestVmSetVariableBoolean(t *testing.T) {
 
 func TestVmSetVariableString(t *testing.T) {
 	vm := VM{}
-	vm.variables = map[asm.Value]asm.Value{}
+	vm.variables = MapStore{}
 
 	vm.SetVariableString("abc", "5")
-	val, ok := vm.variables[asm.Value{Type: asm.SymbolType, Val: "abc"}]
+	val, ok := vm.variables.Get("abc")
 	if !ok {
 		t.Error("Expected added value to be in variables")
 	}
@@ -515,12 +605,42 @@ func TestVmSetVariableString(t *testing.T) {
 	}
 }
 
+func TestVmSetVariableList(t *testing.T) {
+	vm := VM{}
+	vm.variables = MapStore{}
+
+	vm.SetVariableList("abc", []asm.Value{{Type: asm.NumberType, Val: 5}})
+	val, ok := vm.variables.Get("abc")
+	if !ok {
+		t.Error("Expected added value to be in variables")
+	}
+	expected := asm.NewList([]asm.Value{{Type: asm.NumberType, Val: 5}})
+	if !asm.ValuesEqual(val, expected) {
+		t.Errorf("Expected %v got %v", expected, val)
+	}
+}
+
+func TestVmSetVariableMap(t *testing.T) {
+	vm := VM{}
+	vm.variables = MapStore{}
+
+	vm.SetVariableMap("abc", map[string]asm.Value{"gold": {Type: asm.NumberType, Val: 5}})
+	val, ok := vm.variables.Get("abc")
+	if !ok {
+		t.Error("Expected added value to be in variables")
+	}
+	expected := asm.NewMap(map[string]asm.Value{"gold": {Type: asm.NumberType, Val: 5}})
+	if !asm.ValuesEqual(val, expected) {
+		t.Errorf("Expected %v got %v", expected, val)
+	}
+}
+
 func TestVmSetVariableNull(t *testing.T) {
 	vm := VM{}
-	vm.variables = map[asm.Value]asm.Value{}
+	vm.variables = MapStore{}
 
 	vm.SetVariableNull("abc")
-	val, ok := vm.variables[asm.Value{Type: asm.SymbolType, Val: "abc"}]
+	val, ok := vm.variables.Get("abc")
 	if !ok {
 		t.Error("Expected added value to be in variables")
 	}
@@ -613,3 +733,131 @@ func TestVmStoreVariable(t *testing.T) {
 		t.Errorf("expected %v got %v", expected, actual)
 	}
 }
+
+func TestVmWithVariables(t *testing.T) {
+	if _, err := New(emptyProgram, WithVariables(nil)); err == nil {
+		t.Errorf("expected error with a nil store")
+	}
+
+	prog := program.Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.LoadVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "a"}},
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 1}},
+			{Opcode: asm.Add, Arg: asm.Value{}},
+			{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "b"}},
+			{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+		},
+	}
+	store := MapStore{"a": {Type: asm.NumberType, Val: 4}}
+	vm, err := New(prog, WithVariables(store))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := vm.Run(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	expected := asm.Value{Type: asm.NumberType, Val: 5}
+	if actual := store["b"]; actual != expected {
+		t.Errorf("expected %v in the store got %v", expected, actual)
+	}
+
+	vm.Reset()
+	if _, ok := store["b"]; ok || len(store) != 1 {
+		t.Errorf("expected reset to only delete the variables the program assigns, got %v", store)
+	}
+}
+
+func TestVmHandleVariableChange(t *testing.T) {
+	if _, err := New(emptyProgram, HandleVariableChange(nil)); err == nil {
+		t.Errorf("expected error with a nil handler")
+	}
+
+	prog := program.Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "shop"}},
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 3}},
+			{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "gold"}},
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 3}},
+			{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "gold"}},
+			{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 4}},
+			{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "gold"}},
+			{Opcode: asm.ExitNode, Arg: asm.Value{Type: asm.SymbolType, Val: "shop"}},
+			{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+		},
+	}
+	changes := []VariableChange{}
+	others := 0
+	vm, _ := New(
+		prog,
+		HandleVariableChange(func(vm *VM, change VariableChange) ExecutionType {
+			changes = append(changes, change)
+			return PauseExecution
+		}),
+		HandleVariableChange(func(vm *VM, change VariableChange) ExecutionType {
+			others++
+			return ContinueExecution
+		}),
+	)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if vm.runState != suspendedState {
+		t.Fatalf("expected the observer to suspend the vm")
+	}
+	snapshot, _ := vm.Snapshot()
+	restored, _ := New(prog)
+	restored.Restore(snapshot)
+	if restored.node != "shop" {
+		t.Errorf("expected the snapshot to keep the current node, got %q", restored.node)
+	}
+	for vm.runState == suspendedState {
+		if err := vm.Resume(); err != nil {
+			t.Fatalf("no error expected, got %v", err)
+		}
+	}
+	if vm.runState != stoppedState {
+		t.Errorf("expected the vm to run to the end")
+	}
+
+	expected := []VariableChange{
+		{Name: "gold", Old: asm.Null, New: asm.Value{Type: asm.NumberType, Val: 3}, Node: "shop", PC: 2},
+		{Name: "gold", Old: asm.Value{Type: asm.NumberType, Val: 3}, New: asm.Value{Type: asm.NumberType, Val: 4}, Node: "shop", PC: 6},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("expected %v got %v", expected[i], changes[i])
+		}
+	}
+	if others != 2 {
+		t.Errorf("expected every observer to be told, got %d", others)
+	}
+
+	// values from the host can't always be compared
+	tags := asm.Value{Type: "[]string", Val: []string{"a"}}
+	prog = program.Program{
+		Start: 0,
+		Code: []asm.Instruction{
+			{Opcode: asm.LoadVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "tags"}},
+			{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "tags"}},
+			{Opcode: asm.EndDialogue, Arg: asm.Value{}},
+		},
+	}
+	for name, opts := range map[string][]Option{
+		"unobserved": {},
+		"observed": {HandleVariableChange(func(vm *VM, change VariableChange) ExecutionType {
+			return ContinueExecution
+		})},
+	} {
+		t.Run(name, func(t *testing.T) {
+			vm, _ := New(prog, append(opts, WithVariables(MapStore{"tags": tags}))...)
+			if err := vm.Run(); err != nil {
+				t.Errorf("no error expected, got %v", err)
+			}
+		})
+	}
+}
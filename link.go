@@ -0,0 +1,72 @@
+package dialogue
+
+import (
+	"io"
+	"os"
+
+	"github.com/mcvoid/dialogue/internal/program"
+)
+
+type (
+	LinkArg func(*LinkArgs)
+
+	LinkArgs struct {
+		readers []io.Reader
+		writer  io.Writer
+		format  Format
+	}
+)
+
+// LinkInput adds an object written by Compile with CompileObject, in any
+// of the formats Compile writes. The linked script starts at the first
+// node of the first object added.
+func LinkInput(r io.Reader) LinkArg {
+	return func(la *LinkArgs) {
+		la.readers = append(la.readers, r)
+	}
+}
+
+func LinkOutput(w io.Writer) LinkArg {
+	return func(la *LinkArgs) {
+		la.writer = w
+	}
+}
+
+// LinkFormat sets the format the linked script is written in. It is
+// CompressedFormat if not given.
+func LinkFormat(f Format) LinkArg {
+	return func(la *LinkArgs) {
+		la.format = f
+	}
+}
+
+// Link combines objects compiled separately into a single script, which
+// can be loaded with FromReader. Each object's references to nodes in
+// the others are resolved, and an error is returned if a node can't be
+// found or the objects disagree about an extern function.
+func Link(options ...LinkArg) error {
+	args := LinkArgs{
+		writer: os.Stdout,
+		format: CompressedFormat,
+	}
+
+	for _, opt := range options {
+		opt(&args)
+	}
+
+	objects := []program.Object{}
+	for _, r := range args.readers {
+		obj, err := program.DecodeObject(r)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, obj)
+	}
+
+	prog, err := program.Link(objects...)
+	if err != nil {
+		return err
+	}
+
+	return writeFormat(args.writer, args.format, &prog)
+}
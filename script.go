@@ -1,12 +1,13 @@
 package dialogue
 
 import (
-	"compress/zlib"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 
 	"github.com/mcvoid/dialogue/internal/program"
+	"github.com/mcvoid/dialogue/internal/types/asm"
 	"github.com/mcvoid/dialogue/internal/vm"
 )
 
@@ -23,8 +24,7 @@ type (
 	}
 
 	scriptOptions struct {
-		reader       io.Reader
-		uncompressed bool
+		reader io.Reader
 	}
 
 	ScriptOption struct {
@@ -39,6 +39,27 @@ type (
 
 	HandlerFunc func(m Message) ExecutionType
 
+	// Function handles calls a script makes to one of its extern
+	// functions. Arguments are passed as bool, int, float64, string, nil,
+	// []interface{} for a list or map[string]interface{} for a map, and
+	// the result, if the function has a return type, must be one too.
+	Function func(args ...interface{}) (interface{}, ExecutionType)
+
+	processOptions struct {
+		functions map[string]Function
+		seeded    bool
+		seed      int64
+		store     VariableStore
+		observers []VariableObserver
+
+		handlesFaults bool
+		faultHandler  FaultHandler
+	}
+
+	ProcessOption struct {
+		apply func(*processOptions)
+	}
+
 	MessageType int
 
 	Message struct {
@@ -70,9 +91,12 @@ type (
 		Options []string
 	}
 
+	// FunctionCall is a call to one of the script's extern functions.
+	// Functions declared with a return type give their result with Return.
 	FunctionCall struct {
-		Name string
-		Args []interface{}
+		Name   string
+		Args   []interface{}
+		result *interface{}
 	}
 
 	// Process is an instance of a script to execute. Run the script by
@@ -109,42 +133,35 @@ func ScriptInput(r io.Reader) ScriptOption {
 	}
 }
 
+// IsUncompressed was needed to load a script compiled without dead code
+// elimination.
+//
+// Deprecated: FromReader finds out how a script is encoded by itself.
 func IsUncompressed() ScriptOption {
 	return ScriptOption{
-		apply: func(so *scriptOptions) {
-			so.uncompressed = true
-		},
+		apply: func(so *scriptOptions) {},
 	}
 }
 
-// FromReader loads a compiled script from a file or other reader.
+// FromReader loads a compiled script from a file or other reader. The
+// script can be in any of the formats written by Compile. It's checked
+// before it's returned, so a corrupt script gives an error here rather
+// than when it runs.
 func FromReader(opts ...ScriptOption) (*Script, error) {
 	args := scriptOptions{
-		reader:       os.Stdin,
-		uncompressed: false,
+		reader: os.Stdin,
 	}
-	var err error
 
 	for _, opt := range opts {
 		opt.apply(&args)
 	}
 
-	p := program.Program{}
-	if args.uncompressed {
-		_, err = (&p).ReadFrom(args.reader)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		rc, err := zlib.NewReader(args.reader)
-		if err != nil {
-			return nil, err
-		}
-		_, err = (&p).ReadFrom(rc)
-		rc.Close()
-		if err != nil {
-			return nil, err
-		}
+	p, err := program.Decode(args.reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := program.Verify(p); err != nil {
+		return nil, err
 	}
 	return &Script{p}, nil
 }
@@ -153,15 +170,112 @@ func (h HandlerFunc) Handle(m Message) ExecutionType {
 	return h(m)
 }
 
+// WithFunction sends calls to the named extern function to fn instead
+// of to the Process's Handler.
+func WithFunction(name string, fn Function) ProcessOption {
+	return ProcessOption{
+		apply: func(po *processOptions) {
+			po.functions[name] = fn
+		},
+	}
+}
+
+// WithSeed seeds the random builtin so that the Process makes the
+// same choices each time it runs.
+func WithSeed(seed int64) ProcessOption {
+	return ProcessOption{
+		apply: func(po *processOptions) {
+			po.seeded = true
+			po.seed = seed
+		},
+	}
+}
+
+// Return sets the value the call gives back to the script.
+func (f FunctionCall) Return(val interface{}) {
+	if f.result != nil {
+		*f.result = val
+	}
+}
+
+// goValues unwraps VM values into the plain Go values handed to the host.
+func goValues(args []asm.Value) []interface{} {
+	vals := []interface{}{}
+	for _, arg := range args {
+		vals = append(vals, goValue(arg))
+	}
+	return vals
+}
+
+// goValue unwraps a VM value. Lists and maps are copied into a
+// []interface{} and a map[string]interface{}.
+func goValue(val asm.Value) interface{} {
+	switch v := val.Val.(type) {
+	case *asm.List:
+		return goValues(v.Items)
+	case *asm.Map:
+		entries := map[string]interface{}{}
+		for k, entry := range v.Entries {
+			entries[k] = goValue(entry)
+		}
+		return entries
+	}
+	return val.Val
+}
+
+// asmValue wraps a value from the host for the VM. Values of any other
+// type are tagged with their Go type so the VM can report the mismatch.
+func asmValue(val interface{}) asm.Value {
+	switch v := val.(type) {
+	case nil:
+		return asm.Null
+	case bool:
+		return asm.Value{Type: asm.BooleanType, Val: val}
+	case int, float64:
+		return asm.Value{Type: asm.NumberType, Val: val}
+	case string:
+		return asm.Value{Type: asm.StringType, Val: val}
+	case []interface{}:
+		return asm.NewList(asmValues(v))
+	case map[string]interface{}:
+		entries := map[string]asm.Value{}
+		for k, entry := range v {
+			entries[k] = asmValue(entry)
+		}
+		return asm.NewMap(entries)
+	}
+	return asm.Value{Type: asm.Type(fmt.Sprintf("%T", val)), Val: val}
+}
+
+func asmValues(vals []interface{}) []asm.Value {
+	items := []asm.Value{}
+	for _, val := range vals {
+		items = append(items, asmValue(val))
+	}
+	return items
+}
+
 // New spawns a new Process which runs this particular script.
 // The Process interacts with the rest of the program by
 // invoking various callbacks supplied by the ScriptHandler.
 // A new Process is created for each invocation of New.
-func (s *Script) New(h Handler) (*Process, error) {
+// Calls to extern functions are sent to the Handler as FunctionCall
+// messages unless a Function is given for them with WithFunction.
+// Variables are kept in a new MemoryStore unless a store is given with
+// WithVariableStore.
+func (s *Script) New(h Handler, opts ...ProcessOption) (*Process, error) {
 	if h == nil {
 		return nil, fmt.Errorf("cannot have nil handler")
 	}
-	v, _ := vm.New(s.program,
+	args := processOptions{
+		functions: map[string]Function{},
+		store:     MemoryStore{},
+	}
+	for _, opt := range opts {
+		opt.apply(&args)
+	}
+
+	vmOptions := []vm.Option{
 		vm.HandleShowLine(func(v *vm.VM, s string) vm.ExecutionType {
 			return vm.ExecutionType(h.Handle(Message{
 				Type:     ShowLineType,
@@ -192,7 +306,61 @@ func (s *Script) New(h Handler) (*Process, error) {
 				ExitNode: ExitNode{NodeExited: s},
 			}))
 		}),
-	)
+	}
+	// every function goes to the handler unless it has its own
+	for name := range s.program.Funcs {
+		name := name
+		vmOptions = append(vmOptions, vm.RegisterFunction(name, vm.Function{
+			Func: func(v *vm.VM, args ...asm.Value) (asm.Value, vm.ExecutionType) {
+				var result interface{}
+				executionType := h.Handle(Message{
+					Type:         FunctionCallType,
+					FunctionCall: FunctionCall{Name: name, Args: goValues(args), result: &result},
+				})
+				return asmValue(result), vm.ExecutionType(executionType)
+			},
+		}))
+	}
+	for name, fn := range args.functions {
+		if fn == nil {
+			return nil, fmt.Errorf("cannot have nil function %s", name)
+		}
+		fn := fn
+		vmOptions = append(vmOptions, vm.RegisterFunction(name, vm.Function{
+			Func: func(v *vm.VM, args ...asm.Value) (asm.Value, vm.ExecutionType) {
+				result, executionType := fn(goValues(args)...)
+				return asmValue(result), vm.ExecutionType(executionType)
+			},
+		}))
+	}
+
+	if args.seeded {
+		vmOptions = append(vmOptions, vm.Seed(args.seed))
+	}
+	storeOption, err := variableStoreOption(args.store)
+	if err != nil {
+		return nil, err
+	}
+	vmOptions = append(vmOptions, storeOption)
+	for _, observer := range args.observers {
+		observerOption, err := variableObserverOption(observer)
+		if err != nil {
+			return nil, err
+		}
+		vmOptions = append(vmOptions, observerOption)
+	}
+	if args.handlesFaults {
+		faultOption, err := faultHandlerOption(args.faultHandler)
+		if err != nil {
+			return nil, err
+		}
+		vmOptions = append(vmOptions, faultOption)
+	}
+
+	v, err := vm.New(s.program, vmOptions...)
+	if err != nil {
+		return nil, err
+	}
 	return &Process{v}, nil
 }
 
@@ -202,6 +370,14 @@ func (p *Process) Start() error {
 	return p.vm.Run()
 }
 
+// StartAt begins execution on a script at the named node instead of its
+// first node. The script's global variables are initialized as usual.
+// Nodes unreachable from the first node are only kept by dead code
+// elimination when their header is marked with @entry.
+func (p *Process) StartAt(node string) error {
+	return p.vm.RunAt(node)
+}
+
 // Resume continues execution of a suspended Process. Calling this on
 // a Process which isn't suspended, or is waiting for user input, will
 // result in an error.
@@ -215,3 +391,40 @@ func (p *Process) Resume() error {
 func (p *Process) ChooseAndResume(choice int) error {
 	return p.vm.ChooseAndResume(choice)
 }
+
+// Snapshot saves the state of a suspended, waiting or finished Process,
+// including the script's variables and any choice it's waiting on, so that it
+// can be picked back up later with Script.Restore.
+func (p *Process) Snapshot() ([]byte, error) {
+	snapshot, err := p.vm.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(snapshot)
+}
+
+// Choices gives the options a Process is waiting on, for showing them
+// again after it's restored. It's empty if the Process isn't waiting.
+func (p *Process) Choices() []string {
+	return p.vm.Choices()
+}
+
+// Restore spawns a new Process which picks up where the Process the
+// snapshot was taken from left off. The snapshot must have been taken
+// from a Process running this same Script. The variables the script
+// assigns are replaced by the snapshot's; variables it only reads and
+// anything else the store holds are left alone.
+func (s *Script) Restore(snapshot []byte, h Handler, opts ...ProcessOption) (*Process, error) {
+	p, err := s.New(h, opts...)
+	if err != nil {
+		return nil, err
+	}
+	saved := vm.Snapshot{}
+	if err := json.Unmarshal(snapshot, &saved); err != nil {
+		return nil, err
+	}
+	if err := p.vm.Restore(saved); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
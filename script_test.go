@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"compress/zlib"
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/mcvoid/dialogue/internal/program"
@@ -100,6 +101,21 @@ func TestFromReader(t *testing.T) {
 	if err == nil {
 		t.Fatalf("error expected, got nil")
 	}
+	// program which fails verification
+	b = bytes.Buffer{}
+	corrupt := program.Program{
+		Code: []asm.Instruction{
+			{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "start"}},
+			{Opcode: asm.Jump, Arg: asm.Value{Type: asm.NumberType, Val: 10}},
+		},
+	}
+	corrupt.WriteTo(&b)
+	_, err = FromReader(
+		ScriptInput(&b),
+	)
+	if err == nil || err.Error() != "1: goes to 10, which is out of bounds" {
+		t.Fatalf("expected a verify error, got %v", err)
+	}
 }
 
 type mockHandler struct {
@@ -229,3 +245,371 @@ func TestNew(t *testing.T) {
 		t.Errorf("vm did not continue on resume")
 	}
 }
+
+func TestFunctionCalls(t *testing.T) {
+	script := Script{
+		program: program.Program{
+			Start: 0,
+			Code: []asm.Instruction{
+				{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "sword"}},
+				{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 1}},
+				{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "give_item"}},
+				{Opcode: asm.PushBool, Arg: asm.Value{Type: asm.BooleanType, Val: true}},
+				{Opcode: asm.PushNull, Arg: asm.Value{Type: asm.NullType, Val: nil}},
+				{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "log"}},
+				{Opcode: asm.EndDialogue},
+			},
+			Funcs: map[string][]asm.Type{
+				"give_item": {asm.StringType, asm.NumberType},
+				"log":       {asm.BooleanType, asm.NullType},
+			},
+		},
+	}
+
+	calls := []FunctionCall{}
+	scriptEnded := false
+	var hf HandlerFunc = func(m Message) ExecutionType {
+		switch m.Type {
+		case FunctionCallType:
+			calls = append(calls, m.FunctionCall)
+			return Pause
+		case EndScriptType:
+			scriptEnded = true
+		}
+		return Continue
+	}
+
+	proc, err := script.New(hf)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	proc.Start()
+	if len(calls) != 1 || calls[0].Name != "give_item" || calls[0].Args[0] != "sword" || calls[0].Args[1] != 1 {
+		t.Fatalf("expected give_item(\"sword\", 1) got %v", calls)
+	}
+	if scriptEnded {
+		t.Fatalf("process should pause when the handler returns Pause")
+	}
+	proc.Resume()
+	if len(calls) != 2 || calls[1].Name != "log" || calls[1].Args[0] != true || calls[1].Args[1] != nil {
+		t.Fatalf("expected log(true, nil) got %v", calls)
+	}
+	proc.Resume()
+	if !scriptEnded {
+		t.Errorf("expected script to end")
+	}
+
+	given := []interface{}{}
+	calls = []FunctionCall{}
+	scriptEnded = false
+	proc, err = script.New(hf, WithFunction("give_item", func(args ...interface{}) (interface{}, ExecutionType) {
+		given = args
+		return nil, Continue
+	}))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	proc.Start()
+	if len(given) != 2 || given[0] != "sword" || given[1] != 1 {
+		t.Errorf("expected give_item to receive [sword 1] got %v", given)
+	}
+	if len(calls) != 1 || calls[0].Name != "log" {
+		t.Errorf("expected only log to reach the handler, got %v", calls)
+	}
+
+	_, err = script.New(hf, WithFunction("give_item", nil))
+	if err == nil {
+		t.Errorf("error expected for nil function")
+	}
+}
+
+func TestFunctionReturns(t *testing.T) {
+	script := Script{
+		program: program.Program{
+			Start: 0,
+			Code: []asm.Instruction{
+				{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "key"}},
+				{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "has_item"}},
+				{Opcode: asm.JumpIfFalse, Arg: asm.Value{Type: asm.NumberType, Val: 5}},
+				{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "unlocked"}},
+				{Opcode: asm.ShowLine},
+				{Opcode: asm.Call, Arg: asm.Value{Type: asm.SymbolType, Val: "player_name"}},
+				{Opcode: asm.ShowLine},
+				{Opcode: asm.EndDialogue},
+			},
+			Funcs: map[string][]asm.Type{
+				"has_item":    {asm.StringType},
+				"player_name": {},
+			},
+			Returns: map[string]asm.Type{
+				"has_item":    asm.BooleanType,
+				"player_name": asm.StringType,
+			},
+		},
+	}
+
+	lines := []string{}
+	var hf HandlerFunc = func(m Message) ExecutionType {
+		switch m.Type {
+		case FunctionCallType:
+			if m.FunctionCall.Name == "has_item" {
+				m.FunctionCall.Return(m.FunctionCall.Args[0] == "key")
+			}
+		case ShowLineType:
+			lines = append(lines, m.ShowLine.Line)
+		}
+		return Continue
+	}
+
+	proc, err := script.New(hf, WithFunction("player_name", func(args ...interface{}) (interface{}, ExecutionType) {
+		return "Alice", Continue
+	}))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := proc.Start(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if len(lines) != 2 || lines[0] != "unlocked" || lines[1] != "Alice" {
+		t.Errorf("expected [unlocked Alice] got %v", lines)
+	}
+
+	proc, err = script.New(hf, WithFunction("player_name", func(args ...interface{}) (interface{}, ExecutionType) {
+		return 3.5, Continue
+	}))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := proc.Start(); err == nil {
+		t.Errorf("error expected for a result of the wrong type")
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	script := Script{
+		program: program.Program{
+			Start: 0,
+			Code: []asm.Instruction{
+				{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "12 gold"}},
+				{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "gold"}},
+				{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "Buy"}},
+				{Opcode: asm.PushChoice, Arg: asm.Value{Type: asm.NumberType, Val: 7}},
+				{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "Leave"}},
+				{Opcode: asm.PushChoice, Arg: asm.Value{Type: asm.NumberType, Val: 10}},
+				{Opcode: asm.ShowChoice},
+				{Opcode: asm.LoadVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "gold"}},
+				{Opcode: asm.ShowLine},
+				{Opcode: asm.EndDialogue},
+				{Opcode: asm.EndDialogue},
+			},
+		},
+	}
+
+	lines := []string{}
+	var hf HandlerFunc = func(m Message) ExecutionType {
+		if m.Type == ShowLineType {
+			lines = append(lines, m.ShowLine.Line)
+		}
+		return Continue
+	}
+	proc, _ := script.New(hf)
+	proc.Start()
+	saved, err := proc.Snapshot()
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+
+	restored, err := script.Restore(saved, hf)
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if choices := restored.Choices(); len(choices) != 2 || choices[0] != "Buy" || choices[1] != "Leave" {
+		t.Errorf("expected to be waiting on [Buy Leave] got %v", choices)
+	}
+	if err := restored.ChooseAndResume(0); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if len(lines) != 1 || lines[0] != "12 gold" {
+		t.Errorf("expected the restored variables to be shown, got %v", lines)
+	}
+
+	other := Script{program: program.Program{Code: []asm.Instruction{{Opcode: asm.EndDialogue}}}}
+	if _, err := other.Restore(saved, hf); err == nil {
+		t.Errorf("expected error restoring a snapshot of a different script")
+	}
+	if _, err := script.Restore([]byte("{"), hf); err == nil {
+		t.Errorf("expected error restoring a malformed snapshot")
+	}
+	if _, err := script.Restore(saved, nil); err == nil {
+		t.Errorf("expected error restoring with a nil handler")
+	}
+
+	// the store is shared with the rest of the game
+	store := MemoryStore{"quest": true, "other_system": 42, "tags": []string{"a"}}
+	proc, _ = script.New(hf, WithVariableStore(store))
+	proc.Start()
+	if saved, err = proc.Snapshot(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if _, err := script.Restore(saved, hf, WithVariableStore(store)); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if store["other_system"] != 42 || store["quest"] != true || store["gold"] != "12 gold" {
+		t.Errorf("expected the game's variables to be kept, got %v", store)
+	}
+}
+
+func TestVariableStore(t *testing.T) {
+	script := Script{
+		program: program.Program{
+			Start: 0,
+			Code: []asm.Instruction{
+				{Opcode: asm.LoadVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "gold"}},
+				{Opcode: asm.PushNumber, Arg: asm.Value{Type: asm.NumberType, Val: 5}},
+				{Opcode: asm.Add},
+				{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "gold"}},
+				{Opcode: asm.PushBool, Arg: asm.Value{Type: asm.BooleanType, Val: true}},
+				{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "paid"}},
+				{Opcode: asm.EndDialogue},
+			},
+		},
+	}
+	var hf HandlerFunc = func(m Message) ExecutionType { return Continue }
+
+	if _, err := script.New(hf, WithVariableStore(nil)); err == nil {
+		t.Errorf("expected error with a nil variable store")
+	}
+
+	store := MemoryStore{"gold": 10}
+	proc, err := script.New(hf, WithVariableStore(store))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if err := proc.Start(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if store["gold"] != 15 || store["paid"] != true {
+		t.Errorf("expected the script to update the store, got %v", store)
+	}
+
+	if gold, ok := proc.GetVariableNumber("gold"); !ok || gold != 15 {
+		t.Errorf("expected gold to be 15 got %v %v", gold, ok)
+	}
+	if paid, ok := proc.GetVariableBoolean("paid"); !ok || !paid {
+		t.Errorf("expected paid to be true got %v %v", paid, ok)
+	}
+	if _, ok := proc.GetVariableString("gold"); ok {
+		t.Errorf("expected gold not to be a string")
+	}
+	if _, ok := proc.GetVariable("missing"); ok {
+		t.Errorf("expected missing variable not to exist")
+	}
+
+	proc.SetVariableString("name", "Alice")
+	proc.SetVariableNumber("gold", 2)
+	proc.SetVariableBoolean("paid", false)
+	proc.SetVariableNull("quest")
+	if name, ok := proc.GetVariableString("name"); !ok || name != "Alice" {
+		t.Errorf("expected name to be Alice got %v %v", name, ok)
+	}
+	if val, ok := proc.GetVariable("quest"); !ok || val != nil {
+		t.Errorf("expected quest to be null got %v %v", val, ok)
+	}
+	expected := MemoryStore{"gold": 2, "paid": false, "name": "Alice", "quest": nil}
+	if !reflect.DeepEqual(store, expected) {
+		t.Errorf("expected %v got %v", expected, store)
+	}
+}
+
+func TestVariableObserver(t *testing.T) {
+	script := Script{
+		program: program.Program{
+			Start: 0,
+			Code: []asm.Instruction{
+				{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "mayor"}},
+				{Opcode: asm.PushBool, Arg: asm.Value{Type: asm.BooleanType, Val: true}},
+				{Opcode: asm.StoreVariable, Arg: asm.Value{Type: asm.SymbolType, Val: "met_mayor"}},
+				{Opcode: asm.EndDialogue},
+			},
+		},
+	}
+	var hf HandlerFunc = func(m Message) ExecutionType { return Continue }
+
+	if _, err := script.New(hf, WithVariableObserver(nil)); err == nil {
+		t.Errorf("expected error with a nil variable observer")
+	}
+
+	changes := []VariableChange{}
+	proc, err := script.New(hf, WithVariableObserver(func(change VariableChange) ExecutionType {
+		changes = append(changes, change)
+		return Continue
+	}))
+	if err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	proc.SetVariableBoolean("met_mayor", false)
+	if err := proc.Start(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	expected := []VariableChange{{Name: "met_mayor", Old: false, New: true, Node: "mayor", PC: 2}}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v got %v", expected, changes)
+	}
+}
+
+func TestFaultHandler(t *testing.T) {
+	script := Script{
+		program: program.Program{
+			Start: 0,
+			Code: []asm.Instruction{
+				{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "mayor"}},
+				{Opcode: asm.PushString, Arg: asm.Value{Type: asm.StringType, Val: "boom"}},
+				{Opcode: asm.ShowLine},
+				{Opcode: asm.EndDialogue},
+				{Opcode: asm.EnterNode, Arg: asm.Value{Type: asm.SymbolType, Val: "fallback"}},
+				{Opcode: asm.EndDialogue},
+			},
+			Nodes: map[string]int{"mayor": 0, "fallback": 4},
+		},
+	}
+	entered := []string{}
+	var hf HandlerFunc = func(m Message) ExecutionType {
+		switch m.Type {
+		case ShowLineType:
+			panic(m.Line)
+		case EnterNodeType:
+			entered = append(entered, m.NodeEntered)
+		}
+		return Continue
+	}
+
+	if _, err := script.New(hf, WithFaultHandler(nil)); err == nil {
+		t.Errorf("expected error with a nil fault handler")
+	}
+
+	proc, _ := script.New(hf)
+	err := proc.Start()
+	rerr, ok := err.(RuntimeError)
+	if !ok {
+		t.Fatalf("expected RuntimeError got %v", err)
+	}
+	if rerr.Code != HandlerPanicFault || rerr.PC != 2 || rerr.Node != "mayor" || err.Error() != "2: panic: boom" {
+		t.Errorf("unexpected error %+v", rerr)
+	}
+
+	entered = []string{}
+	faults := []RuntimeError{}
+	proc, _ = script.New(hf, WithFaultHandler(func(err RuntimeError) FaultAction {
+		faults = append(faults, err)
+		return RecoverAt("fallback")
+	}))
+	if err := proc.Start(); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	if len(faults) != 1 || faults[0].Code != HandlerPanicFault {
+		t.Errorf("expected one handler panic got %v", faults)
+	}
+	if !reflect.DeepEqual(entered, []string{"mayor", "fallback"}) {
+		t.Errorf("expected to recover at fallback, entered %v", entered)
+	}
+}
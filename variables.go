@@ -0,0 +1,228 @@
+package dialogue
+
+import (
+	"fmt"
+
+	"github.com/mcvoid/dialogue/internal/types/asm"
+	"github.com/mcvoid/dialogue/internal/vm"
+)
+
+// VariableStore holds a Process's variables, letting them live in the
+// rest of the program. Values are bool, int, float64, string, nil,
+// []interface{} for a list or map[string]interface{} for a map.
+type VariableStore interface {
+	// Get gives the named variable and whether it exists.
+	Get(name string) (val interface{}, ok bool)
+	// Set assigns the named variable.
+	Set(name string, val interface{})
+	// Delete removes the named variable.
+	Delete(name string)
+	// Range calls f for each variable until f returns false.
+	Range(f func(name string, val interface{}) bool)
+}
+
+// MemoryStore is a VariableStore kept in memory. It's what a Process
+// uses unless given another store with WithVariableStore.
+type MemoryStore map[string]interface{}
+
+func (m MemoryStore) Get(name string) (interface{}, bool) {
+	val, ok := m[name]
+	return val, ok
+}
+
+func (m MemoryStore) Set(name string, val interface{}) {
+	m[name] = val
+}
+
+func (m MemoryStore) Delete(name string) {
+	delete(m, name)
+}
+
+func (m MemoryStore) Range(f func(name string, val interface{}) bool) {
+	for name, val := range m {
+		if !f(name, val) {
+			return
+		}
+	}
+}
+
+// VariableChange describes a write by the script which changed one of
+// its variables.
+type VariableChange struct {
+	// Name is the variable written.
+	Name string
+	// Old is the value before the write, or nil if the variable is new.
+	Old interface{}
+	// New is the value written.
+	New interface{}
+	// Node is the node the script was in.
+	Node string
+	// PC is the address of the instruction which wrote the variable.
+	PC int
+}
+
+// VariableObserver is told about each change a script makes to its
+// variables. Returning Pause suspends the Process until its Resume()
+// method is invoked.
+type VariableObserver func(change VariableChange) ExecutionType
+
+// WithVariableObserver adds an observer which is told whenever the script
+// changes a variable. Changes made through the Process's SetVariableT
+// methods or directly to the variable store aren't observed.
+func WithVariableObserver(observer VariableObserver) ProcessOption {
+	return ProcessOption{
+		apply: func(po *processOptions) {
+			po.observers = append(po.observers, observer)
+		},
+	}
+}
+
+// WithVariableStore keeps the Process's variables in store, so they
+// can be shared with the rest of the program.
+func WithVariableStore(store VariableStore) ProcessOption {
+	return ProcessOption{
+		apply: func(po *processOptions) {
+			po.store = store
+		},
+	}
+}
+
+// vmStore lets the VM use a VariableStore.
+type vmStore struct {
+	store VariableStore
+}
+
+func (s vmStore) Get(name string) (asm.Value, bool) {
+	val, ok := s.store.Get(name)
+	if !ok {
+		return asm.Null, false
+	}
+	return asmValue(val), true
+}
+
+func (s vmStore) Set(name string, val asm.Value) {
+	s.store.Set(name, goValue(val))
+}
+
+func (s vmStore) Delete(name string) {
+	s.store.Delete(name)
+}
+
+func (s vmStore) Range(f func(name string, val asm.Value) bool) {
+	s.store.Range(func(name string, val interface{}) bool {
+		return f(name, asmValue(val))
+	})
+}
+
+func variableObserverOption(observer VariableObserver) (vm.Option, error) {
+	if observer == nil {
+		return nil, fmt.Errorf("cannot have nil variable observer")
+	}
+	return vm.HandleVariableChange(func(v *vm.VM, change vm.VariableChange) vm.ExecutionType {
+		return vm.ExecutionType(observer(VariableChange{
+			Name: change.Name,
+			Old:  goValue(change.Old),
+			New:  goValue(change.New),
+			Node: change.Node,
+			PC:   change.PC,
+		}))
+	}), nil
+}
+
+func variableStoreOption(store VariableStore) (vm.Option, error) {
+	if store == nil {
+		return nil, fmt.Errorf("cannot have nil variable store")
+	}
+	return vm.WithVariables(vmStore{store}), nil
+}
+
+// GetVariable gives the value of the named variable and whether it
+// exists.
+func (p *Process) GetVariable(name string) (val interface{}, ok bool) {
+	v, ok := p.vm.GetVariable(name)
+	return goValue(v), ok
+}
+
+// GetVariableNumber gives the named variable if it exists and is a whole number.
+func (p *Process) GetVariableNumber(name string) (val int, ok bool) {
+	v, _ := p.vm.GetVariable(name)
+	val, ok = v.Val.(int)
+	return val, ok && v.Type == asm.NumberType
+}
+
+// GetVariableFloat gives the named variable if it exists and is a
+// number, whether whole or fractional.
+func (p *Process) GetVariableFloat(name string) (val float64, ok bool) {
+	v, _ := p.vm.GetVariable(name)
+	if v.Type != asm.NumberType || !asm.IsNumber(v.Val) {
+		return 0, false
+	}
+	return asm.Float(v.Val), true
+}
+
+// GetVariableBoolean gives the named variable if it exists and is a boolean.
+func (p *Process) GetVariableBoolean(name string) (val bool, ok bool) {
+	v, _ := p.vm.GetVariable(name)
+	val, ok = v.Val.(bool)
+	return val, ok && v.Type == asm.BooleanType
+}
+
+// GetVariableString gives the named variable if it exists and is a string.
+func (p *Process) GetVariableString(name string) (val string, ok bool) {
+	v, _ := p.vm.GetVariable(name)
+	val, ok = v.Val.(string)
+	return val, ok && v.Type == asm.StringType
+}
+
+// GetVariableList gives the named variable if it exists and is a list.
+func (p *Process) GetVariableList(name string) (val []interface{}, ok bool) {
+	v, _ := p.vm.GetVariable(name)
+	if _, ok := v.Val.(*asm.List); !ok || v.Type != asm.ListType {
+		return nil, false
+	}
+	return goValue(v).([]interface{}), true
+}
+
+// GetVariableMap gives the named variable if it exists and is a map.
+func (p *Process) GetVariableMap(name string) (val map[string]interface{}, ok bool) {
+	v, _ := p.vm.GetVariable(name)
+	if _, ok := v.Val.(*asm.Map); !ok || v.Type != asm.MapType {
+		return nil, false
+	}
+	return goValue(v).(map[string]interface{}), true
+}
+
+// SetVariableNumber stores val as a number under the given name.
+func (p *Process) SetVariableNumber(name string, val int) {
+	p.vm.SetVariableNumber(name, val)
+}
+
+// SetVariableFloat stores val as a fractional number under the given name.
+func (p *Process) SetVariableFloat(name string, val float64) {
+	p.vm.SetVariableFloat(name, val)
+}
+
+// SetVariableBoolean stores val as a boolean under the given name.
+func (p *Process) SetVariableBoolean(name string, val bool) {
+	p.vm.SetVariableBoolean(name, val)
+}
+
+// SetVariableString stores val as a string under the given name.
+func (p *Process) SetVariableString(name string, val string) {
+	p.vm.SetVariableString(name, val)
+}
+
+// SetVariableList stores val as a list under the given name.
+func (p *Process) SetVariableList(name string, val []interface{}) {
+	p.vm.SetVariableList(name, asmValues(val))
+}
+
+// SetVariableMap stores val as a map under the given name.
+func (p *Process) SetVariableMap(name string, val map[string]interface{}) {
+	p.vm.SetVariableMap(name, asmValue(val).Val.(*asm.Map).Entries)
+}
+
+// SetVariableNull stores a null value under the given name.
+func (p *Process) SetVariableNull(name string) {
+	p.vm.SetVariableNull(name)
+}